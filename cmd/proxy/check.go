@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"jq-proxy-service/internal/config"
+	"jq-proxy-service/internal/models"
+	"jq-proxy-service/internal/transform"
+)
+
+// newConfigProvider returns a file provider with environment overrides when a
+// config path or endpoint directory is given, otherwise a provider reading
+// everything from the environment. A comma-separated list of paths is merged
+// in order.
+func newConfigProvider(configPath, configDir string) models.ConfigProvider {
+	var paths []string
+	for _, path := range strings.Split(configPath, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	if configDir != "" {
+		return config.NewEnvDirProvider(configDir, paths...)
+	}
+	if len(paths) > 0 {
+		return config.NewEnvProvider(paths...)
+	}
+	return config.NewFullEnvProvider()
+}
+
+// loadJQLibrary installs the configured jq function library, read from
+// jq_library_path followed by the inline jq_library definitions
+func loadJQLibrary(transformer *transform.UnifiedTransformer, server models.ServerConfig) error {
+	var library strings.Builder
+	if server.JQLibraryPath != "" {
+		data, err := os.ReadFile(server.JQLibraryPath)
+		if err != nil {
+			return fmt.Errorf("failed to read jq library: %w", err)
+		}
+		library.Write(data)
+		library.WriteByte('\n')
+	}
+	library.WriteString(server.JQLibrary)
+	return transformer.SetJQLibrary(library.String())
+}
+
+// responseBuckets converts response time histogram bounds from milliseconds
+func responseBuckets(millis []int) []time.Duration {
+	bounds := make([]time.Duration, len(millis))
+	for i, ms := range millis {
+		bounds[i] = time.Duration(ms) * time.Millisecond
+	}
+	return bounds
+}
+
+// runConfigCheck loads and validates the configuration and writes a summary of it to out
+func runConfigCheck(provider models.ConfigProvider, out io.Writer) error {
+	proxyConfig, err := provider.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	if err := loadJQLibrary(transform.NewUnifiedTransformer(), proxyConfig.Server); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(proxyConfig.Endpoints))
+	for name := range proxyConfig.Endpoints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(out, "Configuration OK: port %d, %d endpoint(s)\n", proxyConfig.Server.Port, len(names))
+	for _, name := range names {
+		fmt.Fprintf(out, "  %s -> %s\n", name, strings.Join(proxyConfig.Endpoints[name].TargetURLs(), ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"jq-proxy-service/internal/models"
+	"jq-proxy-service/internal/transform"
+)
+
+func TestRunConfigCheck(t *testing.T) {
+	tests := []struct {
+		name        string
+		configData  string
+		expectError bool
+		errorMsg    string
+		output      []string
+	}{
+		{
+			name: "valid configuration",
+			configData: `{
+				"server": {"port": 9000, "read_timeout": 30, "write_timeout": 30},
+				"endpoints": {
+					"service1": {"name": "service1", "target": "https://api1.example.com"},
+					"service2": {"name": "service2", "target": "http://api2.example.com"}
+				}
+			}`,
+			output: []string{
+				"Configuration OK: port 9000, 2 endpoint(s)",
+				"service1 -> https://api1.example.com",
+				"service2 -> http://api2.example.com",
+			},
+		},
+		{
+			name: "invalid endpoint target",
+			configData: `{
+				"server": {"port": 8080, "read_timeout": 30, "write_timeout": 30},
+				"endpoints": {
+					"service1": {"name": "service1", "target": "not-a-url"}
+				}
+			}`,
+			expectError: true,
+			errorMsg:    "endpoint target must be a valid HTTP/HTTPS URL",
+		},
+		{
+			name: "invalid jq library",
+			configData: `{
+				"server": {"port": 8080, "read_timeout": 30, "write_timeout": 30, "jq_library": "def cents: . * 100 | nosuch;"},
+				"endpoints": {
+					"service1": {"name": "service1", "target": "https://api1.example.com"}
+				}
+			}`,
+			expectError: true,
+			errorMsg:    "failed to compile jq library",
+		},
+		{
+			name:        "invalid JSON",
+			configData:  `{"server": {`,
+			expectError: true,
+			errorMsg:    "failed to parse configuration",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configFile := filepath.Join(t.TempDir(), "config.json")
+			require.NoError(t, os.WriteFile(configFile, []byte(tt.configData), 0644))
+
+			var out bytes.Buffer
+			err := runConfigCheck(newConfigProvider(configFile, ""), &out)
+
+			if tt.expectError {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+				assert.Empty(t, out.String())
+				return
+			}
+
+			require.NoError(t, err)
+			for _, line := range tt.output {
+				assert.Contains(t, out.String(), line)
+			}
+		})
+	}
+}
+
+func TestRunConfigCheck_MissingFile(t *testing.T) {
+	var out bytes.Buffer
+	err := runConfigCheck(newConfigProvider(filepath.Join(t.TempDir(), "missing.json"), ""), &out)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "configuration file not found")
+}
+
+func TestRunConfigCheck_EndpointDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "users.json"),
+		[]byte(`{"endpoints": {"users": {"name": "users", "target": "https://users.example.com"}}}`), 0644))
+
+	var out bytes.Buffer
+	err := runConfigCheck(newConfigProvider("", dir), &out)
+
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), "Configuration OK: port 8080, 1 endpoint(s)")
+	assert.Contains(t, out.String(), "users -> https://users.example.com")
+}
+
+func TestLoadJQLibrary(t *testing.T) {
+	libraryFile := filepath.Join(t.TempDir(), "helpers.jq")
+	require.NoError(t, os.WriteFile(libraryFile, []byte("def cents: . * 100 | round;\n"), 0644))
+
+	transformer := transform.NewUnifiedTransformer()
+	require.NoError(t, loadJQLibrary(transformer, models.ServerConfig{
+		JQLibraryPath: libraryFile,
+		JQLibrary:     "def total: map(.price | cents) | add;",
+	}))
+
+	// Inline definitions can use those from the file
+	result, err := transformer.GetJQTransformer().TransformWithQuery(
+		[]interface{}{map[string]interface{}{"price": 1.25}, map[string]interface{}{"price": 0.5}}, "total", nil)
+	require.NoError(t, err)
+	assert.Equal(t, float64(175), result)
+
+	err = loadJQLibrary(transformer, models.ServerConfig{JQLibraryPath: filepath.Join(t.TempDir(), "missing.jq")})
+	assert.ErrorContains(t, err, "failed to read jq library")
+}
@@ -11,51 +11,109 @@ import (
 	"time"
 
 	"jq-proxy-service/internal/client"
-	"jq-proxy-service/internal/config"
+	"jq-proxy-service/internal/ipfilter"
 	"jq-proxy-service/internal/logging"
-	"jq-proxy-service/internal/models"
 	"jq-proxy-service/internal/proxy"
+	"jq-proxy-service/internal/tracing"
 	"jq-proxy-service/internal/transform"
+	"jq-proxy-service/internal/version"
 
 	"github.com/sirupsen/logrus"
 )
 
 func main() {
-	var configPath = flag.String("config", "", "Path to configuration file (optional, uses env vars if not provided)")
+	var configPath = flag.String("config", "", "Path to configuration file, or a comma-separated list merged in order (optional, uses env vars if not provided)")
+	var configDir = flag.String("config-dir", "", "Directory of *.json files defining endpoints, merged with the -config files (optional)")
 	var port = flag.String("port", "", "Port to listen on (overrides config)")
 	var logLevel = flag.String("log-level", "info", "Log level (debug, info, warn, error)")
+	var logFormat = flag.String("log-format", "", "Log format (json, text); overrides config")
+	var logOutput = flag.String("log-output", "", "Log destination (stdout, stderr or a file path); overrides config")
+	var check = flag.Bool("check", false, "Validate the configuration and exit without starting the server")
+	var checkTargets = flag.Bool("check-targets", false, "Warn at startup about endpoint targets that cannot be resolved or connected to")
 	flag.Parse()
 
+	// Validate configuration only
+	if *check {
+		if err := runConfigCheck(newConfigProvider(*configPath, *configDir), os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Configuration check failed: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	// Initialize logger with metrics
-	logger, err := logging.NewLogger(*logLevel)
+	logger, err := logging.NewLoggerWithOptions(logging.Options{
+		Level:  *logLevel,
+		Format: *logFormat,
+		Output: *logOutput,
+	})
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to initialize logger: %v\n", err)
 		os.Exit(1)
 	}
-	logger.WithField("config", *configPath).WithField("port", *port).WithField("log-level", *logLevel).Info("command line args")
+	defer logger.Close()
+	buildInfo := version.Get()
+	logger.WithFields(logrus.Fields{
+		"version":    buildInfo.Version,
+		"git_commit": buildInfo.GitCommit,
+		"build_date": buildInfo.BuildDate,
+	}).Info("JQ Proxy Service build info")
+	logger.WithField("config", *configPath).WithField("config-dir", *configDir).WithField("port", *port).WithField("log-level", *logLevel).Info("command line args")
 
 	// Initialize configuration provider
-	var configProvider models.ConfigProvider
-	if *configPath != "" {
+	if *configPath != "" || *configDir != "" {
 		// Load from file with environment variable overrides
-		logger.WithField("config_path", *configPath).Info("Starting JQ Proxy Service with file configuration")
-		configProvider = config.NewEnvProvider(*configPath)
+		logger.WithFields(logrus.Fields{
+			"config_path": *configPath,
+			"config_dir":  *configDir,
+		}).Info("Starting JQ Proxy Service with file configuration")
 	} else {
 		// Load entirely from environment variables
 		logger.Info("Starting JQ Proxy Service with environment variable configuration")
-		configProvider = config.NewFullEnvProvider()
 	}
+	configProvider := newConfigProvider(*configPath, *configDir)
 
 	proxyConfig, err := configProvider.LoadConfig()
 	if err != nil {
 		logger.WithError(err).Fatal("Failed to load configuration")
 	}
 
+	// Apply logging settings from configuration unless overridden by flags
+	if *logFormat == "" || *logOutput == "" {
+		format, output := *logFormat, *logOutput
+		if format == "" {
+			format = proxyConfig.Server.Logging.Format
+		}
+		if output == "" {
+			output = proxyConfig.Server.Logging.Output
+		}
+		if err := logger.Configure(format, output); err != nil {
+			logger.WithError(err).Fatal("Failed to configure logging")
+		}
+	}
+
 	logger.WithFields(logrus.Fields{
 		"endpoints": len(proxyConfig.Endpoints),
 		"port":      proxyConfig.Server.Port,
 	}).Info("Configuration loaded successfully")
 
+	// Apply metrics settings from configuration
+	logger.GetMetrics().SetSampleSize(proxyConfig.Server.Metrics.SampleSize)
+	logger.GetMetrics().SetResponseBuckets(responseBuckets(proxyConfig.Server.Metrics.ResponseBuckets))
+
+	// Probe endpoint targets in the background so typos show up early
+	// without delaying startup
+	if *checkTargets {
+		go func() {
+			for _, target := range probeTargets(context.Background(), proxyConfig.Endpoints, targetProbeTimeout) {
+				logger.WithError(target.Err).WithFields(logrus.Fields{
+					"endpoint": target.Endpoint,
+					"target":   target.Target,
+				}).Warn("Endpoint target is unreachable")
+			}
+		}()
+	}
+
 	// Override port if specified via command line
 	if *port != "" {
 		if _, err := fmt.Sscanf(*port, "%d", &proxyConfig.Server.Port); err != nil {
@@ -63,19 +121,87 @@ func main() {
 		}
 		logger.WithField("port", proxyConfig.Server.Port).Info("Port overridden by command line")
 	}
+	logger.WithFields(effectiveConfigFields(proxyConfig)).Info("Effective configuration")
+
+	// Initialize tracing (no-op unless enabled in configuration)
+	shutdownTracing, err := tracing.Setup(proxyConfig.Server.Tracing)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to initialize tracing")
+	}
+	if proxyConfig.Server.Tracing.Enabled {
+		logger.WithField("exporter", proxyConfig.Server.Tracing.Exporter).Info("OpenTelemetry tracing enabled")
+	}
 
 	// Initialize HTTP client
-	httpClient := client.NewClient(time.Duration(proxyConfig.Server.ReadTimeout) * time.Second)
+	clientSettings := client.DefaultSettings(time.Duration(proxyConfig.Server.ReadTimeout) * time.Second)
+	clientSettings.MaxRedirects = proxyConfig.Server.MaxRedirects
+	clientSettings.DisableRedirects = proxyConfig.Server.DisableRedirects
+	clientSettings.ProxyURL = proxyConfig.Server.UpstreamProxy
+	if proxyConfig.Server.DialTimeout > 0 {
+		clientSettings.DialTimeout = time.Duration(proxyConfig.Server.DialTimeout) * time.Second
+	}
+	clientSettings.ResponseHeaderTimeout = time.Duration(proxyConfig.Server.ResponseHeaderTimeout) * time.Second
+	if proxyConfig.Server.UserAgent != "" {
+		clientSettings.UserAgent = proxyConfig.Server.UserAgent
+	}
+	httpClient := client.NewClientWithSettings(clientSettings)
 
 	// Initialize unified transformer (supports jq)
 	transformer := transform.NewUnifiedTransformer()
+	transformer.SetMaxTransformTime(time.Duration(proxyConfig.Server.MaxTransformTime) * time.Second)
+	transformer.SetMaxQueryComplexity(proxyConfig.Server.MaxQueryComplexity)
+	transformer.SetJQEnv(proxyConfig.Server.JQEnv)
+	if err := loadJQLibrary(transformer, proxyConfig.Server); err != nil {
+		logger.WithError(err).Fatal("Failed to load jq library")
+	}
 
 	// Initialize proxy service
-	proxyService := proxy.NewService(configProvider, httpClient, transformer, logger)
+	proxyService := proxy.NewService(
+		configProvider, httpClient, transformer, logger,
+		proxy.WithMaxConcurrentUpstream(proxyConfig.Server.MaxConcurrentUpstream),
+		proxy.WithMaxConcurrentTransforms(proxyConfig.Server.MaxConcurrentTransforms),
+		proxy.WithMaxResultBytes(proxyConfig.Server.MaxResultBytes),
+		proxy.WithMaxResultMemory(proxyConfig.Server.MaxResultMemory),
+		proxy.WithTransformCacheSize(proxyConfig.Server.TransformCacheSize),
+		proxy.WithClientSettings(clientSettings),
+		proxy.WithBodyPreviewBytes(proxyConfig.Server.Logging.BodyPreviewBytes),
+	)
 
 	// Initialize HTTP handler
-	handler := proxy.NewHandler(proxyService, logger)
-	router := handler.SetupRoutes()
+	ipFilter, err := ipfilter.New(proxyConfig.Server.IPAllowlist, proxyConfig.Server.IPDenylist)
+	if err != nil {
+		logger.WithError(err).Fatal("Invalid client IP restrictions")
+	}
+	trustedProxies, err := ipfilter.ParseCIDRs(proxyConfig.Server.TrustedProxies)
+	if err != nil {
+		logger.WithError(err).Fatal("Invalid trusted proxies")
+	}
+	handler := proxy.NewHandler(proxyService, logger,
+		proxy.WithErrorFormat(proxyConfig.Server.ErrorFormat),
+		proxy.WithIPFilter(ipFilter),
+		proxy.WithTrustedProxies(trustedProxies),
+		proxy.WithAdminPrefix(proxyConfig.Server.AdminPrefix),
+		proxy.WithMaxPathLength(proxyConfig.Server.MaxPathLength),
+		proxy.WithDebugEndpoints(proxyConfig.Server.DebugEndpoints),
+		proxy.WithRequestIDHeader(proxyConfig.Server.RequestIDHeader),
+		proxy.WithResponseBufferBytes(proxyConfig.Server.ResponseBufferBytes),
+	)
+	// Split the admin routes onto their own port when one is configured
+	var router http.Handler = handler.SetupRoutes()
+	var adminServer *http.Server
+	if proxyConfig.Server.AdminPort != 0 {
+		router = handler.SetupProxyRoutes()
+		adminServer = &http.Server{
+			Addr:         fmt.Sprintf(":%d", proxyConfig.Server.AdminPort),
+			Handler:      handler.SetupAdminRoutes(),
+			ReadTimeout:  time.Duration(proxyConfig.Server.ReadTimeout) * time.Second,
+			WriteTimeout: time.Duration(proxyConfig.Server.WriteTimeout) * time.Second,
+			IdleTimeout:  60 * time.Second,
+		}
+	}
+	if proxyConfig.Server.Tracing.Enabled {
+		router = tracing.Middleware(router)
+	}
 
 	// Create HTTP server
 	server := &http.Server{
@@ -93,24 +219,40 @@ func main() {
 			logger.WithError(err).Fatal("Failed to start HTTP server")
 		}
 	}()
+	if adminServer != nil {
+		go func() {
+			logger.WithField("port", proxyConfig.Server.AdminPort).Info("Starting admin HTTP server")
+			if err := adminServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.WithError(err).Fatal("Failed to start admin HTTP server")
+			}
+		}()
+	}
 
 	// Wait for interrupt signal to gracefully shutdown the server
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	logger.Info("Shutting down server...")
-
-	// Create a deadline for shutdown
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	// Attempt graceful shutdown
-	if err := server.Shutdown(ctx); err != nil {
-		logger.WithError(err).Error("Server forced to shutdown")
+	// Stop taking admin requests; they are short and need no drain
+	if adminServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := adminServer.Shutdown(ctx); err != nil {
+			logger.WithError(err).Error("Failed to shut down admin HTTP server")
+		}
 		cancel()
+	}
+
+	// Drain in-flight requests before exiting
+	if _, err := shutdownServer(server, logger.GetMetrics(), logger, 30*time.Second); err != nil {
 		return
 	}
 
+	// Flush any pending spans
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		logger.WithError(err).Error("Failed to shut down tracing")
+	}
+
 	logger.Info("Server shutdown complete")
 }
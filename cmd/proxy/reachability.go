@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"jq-proxy-service/internal/models"
+)
+
+// targetProbeTimeout bounds the DNS lookup and TCP connect to a single target
+const targetProbeTimeout = 3 * time.Second
+
+// unreachableTarget is an endpoint target that failed the startup probe
+type unreachableTarget struct {
+	Endpoint string
+	Target   string
+	Err      error
+}
+
+// probeTargets resolves and connects to every endpoint target, including
+// header route targets, and returns the ones that could not be reached sorted
+// by endpoint and target. Targets are probed concurrently, each for at most
+// timeout. Templated targets are skipped since they are only complete per
+// request.
+func probeTargets(ctx context.Context, endpoints map[string]*models.Endpoint, timeout time.Duration) []unreachableTarget {
+	var (
+		mu          sync.Mutex
+		wg          sync.WaitGroup
+		unreachable []unreachableTarget
+	)
+	for name, endpoint := range endpoints {
+		targets := endpoint.TargetURLs()
+		for _, route := range endpoint.Routes {
+			targets = append(targets, route.Target)
+		}
+		for _, target := range targets {
+			if models.IsTargetTemplate(target) {
+				continue
+			}
+			wg.Add(1)
+			go func(name, target string) {
+				defer wg.Done()
+				if err := probeTarget(ctx, target, timeout); err != nil {
+					mu.Lock()
+					unreachable = append(unreachable, unreachableTarget{Endpoint: name, Target: target, Err: err})
+					mu.Unlock()
+				}
+			}(name, target)
+		}
+	}
+	wg.Wait()
+
+	sort.Slice(unreachable, func(i, j int) bool {
+		if unreachable[i].Endpoint != unreachable[j].Endpoint {
+			return unreachable[i].Endpoint < unreachable[j].Endpoint
+		}
+		return unreachable[i].Target < unreachable[j].Target
+	})
+	return unreachable
+}
+
+// probeTarget opens and closes a TCP connection to the target's host,
+// using the scheme's default port when the URL has none
+func probeTarget(ctx context.Context, target string, timeout time.Duration) error {
+	u, err := url.Parse(target)
+	if err != nil {
+		return err
+	}
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(u.Hostname(), port))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"jq-proxy-service/internal/models"
+)
+
+func TestProbeTargets(t *testing.T) {
+	reachable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer reachable.Close()
+
+	// A port that was just released refuses connections
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedAddr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	endpoints := map[string]*models.Endpoint{
+		"up":      {Name: "up", Target: reachable.URL},
+		"refused": {Name: "refused", Target: "http://" + closedAddr},
+		"typo":    {Name: "typo", Target: "https://api.example.invalid"},
+		"routed": {
+			Name:   "routed",
+			Target: reachable.URL,
+			Routes: []models.HeaderRoute{{Header: "X-Tenant", Value: "acme", Target: "http://acme.example.invalid:8080"}},
+		},
+		"regional": {
+			Name:            "regional",
+			Target:          "http://{region}.example.invalid",
+			TargetVariables: map[string]string{"region": "header:X-Region"},
+		},
+	}
+
+	start := time.Now()
+	unreachable := probeTargets(context.Background(), endpoints, time.Second)
+	assert.Less(t, time.Since(start), 2*time.Second)
+
+	require.Len(t, unreachable, 3)
+	assert.Equal(t, "refused", unreachable[0].Endpoint)
+	assert.Equal(t, "http://"+closedAddr, unreachable[0].Target)
+	assert.Equal(t, "routed", unreachable[1].Endpoint)
+	assert.Equal(t, "http://acme.example.invalid:8080", unreachable[1].Target)
+	assert.Equal(t, "typo", unreachable[2].Endpoint)
+	for _, target := range unreachable {
+		assert.Error(t, target.Err)
+	}
+}
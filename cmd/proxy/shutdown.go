@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"jq-proxy-service/internal/logging"
+
+	"github.com/sirupsen/logrus"
+)
+
+// shutdownStats describes how in-flight requests fared during shutdown
+type shutdownStats struct {
+	InFlight  int64
+	Completed int64
+	Aborted   int64
+}
+
+// shutdownServer stops accepting new connections and waits up to timeout for
+// in-flight requests to finish. Requests still running at the deadline are
+// forcibly closed. Both phases are logged with their request counts.
+func shutdownServer(server *http.Server, metrics *logging.Metrics, logger *logging.Logger, timeout time.Duration) (shutdownStats, error) {
+	stats := shutdownStats{InFlight: metrics.InFlight()}
+	logger.WithFields(logrus.Fields{
+		"in_flight_requests": stats.InFlight,
+		"drain_timeout":      timeout.String(),
+	}).Info("Shutting down server, draining in-flight requests")
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := server.Shutdown(ctx)
+	if err != nil {
+		// Count what is left before closing the remaining connections
+		stats.Aborted = metrics.InFlight()
+		server.Close()
+	}
+	stats.Completed = stats.InFlight - stats.Aborted
+	if stats.Completed < 0 {
+		stats.Completed = 0
+	}
+
+	entry := logger.WithFields(logrus.Fields{
+		"completed_requests": stats.Completed,
+		"aborted_requests":   stats.Aborted,
+	})
+	if err != nil {
+		entry.WithError(err).Error("Drain deadline reached, forcibly closed remaining requests")
+	} else {
+		entry.Info("All in-flight requests drained")
+	}
+
+	return stats, err
+}
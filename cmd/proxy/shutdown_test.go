@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"jq-proxy-service/internal/logging"
+)
+
+// startSlowServer serves requests that block until release is closed and
+// returns once one request is in flight
+func startSlowServer(t *testing.T, logger *logging.Logger, release <-chan struct{}) *http.Server {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusOK)
+	})
+	server := &http.Server{Handler: logging.InFlightMiddleware(logger.GetMetrics())(slow)}
+	go server.Serve(listener)
+
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String() + "/slow")
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+
+	require.Eventually(t, func() bool {
+		return logger.GetMetrics().InFlight() == 1
+	}, 5*time.Second, 5*time.Millisecond)
+
+	return server
+}
+
+func TestShutdownServer_Drained(t *testing.T) {
+	logger, err := logging.NewLogger("error")
+	require.NoError(t, err)
+
+	release := make(chan struct{})
+	server := startSlowServer(t, logger, release)
+
+	// The in-flight request finishes within the drain window
+	time.AfterFunc(50*time.Millisecond, func() { close(release) })
+	stats, err := shutdownServer(server, logger.GetMetrics(), logger, 5*time.Second)
+
+	require.NoError(t, err)
+	assert.Equal(t, shutdownStats{InFlight: 1, Completed: 1, Aborted: 0}, stats)
+	assert.Equal(t, int64(0), logger.GetMetrics().InFlight())
+}
+
+func TestShutdownServer_DeadlineExceeded(t *testing.T) {
+	logger, err := logging.NewLogger("error")
+	require.NoError(t, err)
+
+	release := make(chan struct{})
+	defer close(release)
+	server := startSlowServer(t, logger, release)
+
+	// The in-flight request outlives the drain window
+	stats, err := shutdownServer(server, logger.GetMetrics(), logger, 50*time.Millisecond)
+
+	assert.Error(t, err)
+	assert.Equal(t, shutdownStats{InFlight: 1, Completed: 0, Aborted: 1}, stats)
+}
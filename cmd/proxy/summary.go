@@ -0,0 +1,89 @@
+package main
+
+import (
+	"net/url"
+
+	"github.com/sirupsen/logrus"
+
+	"jq-proxy-service/internal/models"
+)
+
+// effectiveConfigFields summarizes the resolved configuration, including
+// environment and command line overrides, for logging at startup. Values that
+// may carry credentials are redacted and endpoint targets are left out.
+func effectiveConfigFields(config *models.ProxyConfig) logrus.Fields {
+	server := config.Server
+
+	errorFormat := server.ErrorFormat
+	if errorFormat == "" {
+		errorFormat = "default"
+	}
+
+	cached, rateLimited, disabled := 0, 0, 0
+	for _, endpoint := range config.Endpoints {
+		if endpoint.CacheTTL > 0 {
+			cached++
+		}
+		if endpoint.RateLimit > 0 {
+			rateLimited++
+		}
+		if !endpoint.IsEnabled() {
+			disabled++
+		}
+	}
+
+	return logrus.Fields{
+		"port":                      server.Port,
+		"admin_port":                server.AdminPort,
+		"admin_prefix":              server.AdminPrefix,
+		"read_timeout":              server.ReadTimeout,
+		"write_timeout":             server.WriteTimeout,
+		"dial_timeout":              server.DialTimeout,
+		"response_header_timeout":   server.ResponseHeaderTimeout,
+		"max_transform_time":        server.MaxTransformTime,
+		"max_query_complexity":      server.MaxQueryComplexity,
+		"max_concurrent_upstream":   server.MaxConcurrentUpstream,
+		"max_concurrent_transforms": server.MaxConcurrentTransforms,
+		"max_result_bytes":          server.MaxResultBytes,
+		"max_result_memory":         server.MaxResultMemory,
+		"response_buffer_bytes":     server.ResponseBufferBytes,
+		"transform_cache_size":      server.TransformCacheSize,
+		"max_path_length":           server.MaxPathLength,
+		"max_redirects":             server.MaxRedirects,
+		"disable_redirects":         server.DisableRedirects,
+		"error_format":              errorFormat,
+		"user_agent":                server.UserAgent,
+		"upstream_proxy":            redactURL(server.UpstreamProxy),
+		"ip_allowlist":              len(server.IPAllowlist),
+		"ip_denylist":               len(server.IPDenylist),
+		"trusted_proxies":           len(server.TrustedProxies),
+		"jq_library":                server.JQLibrary != "" || server.JQLibraryPath != "",
+		"jq_env":                    server.JQEnv,
+		"default_endpoint":          server.DefaultEndpoint,
+		"request_id_header":         server.RequestIDHeader,
+		"debug_endpoints":           server.DebugEndpoints,
+		"tracing":                   server.Tracing.Enabled,
+		"tracing_exporter":          server.Tracing.Exporter,
+		"log_format":                server.Logging.Format,
+		"body_preview_bytes":        server.Logging.BodyPreviewBytes,
+		"metrics_sample_size":       server.Metrics.SampleSize,
+		"metrics_response_buckets":  server.Metrics.ResponseBuckets,
+		"endpoints":                 len(config.Endpoints),
+		"cached_endpoints":          cached,
+		"rate_limited_endpoints":    rateLimited,
+		"disabled_endpoints":        disabled,
+	}
+}
+
+// redactURL hides the password of a URL; values that do not parse are
+// replaced entirely since they may still hold credentials
+func redactURL(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return "[REDACTED]"
+	}
+	return parsed.Redacted()
+}
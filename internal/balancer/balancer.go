@@ -0,0 +1,128 @@
+// Package balancer distributes requests across the upstream targets of an endpoint.
+package balancer
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// DefaultFailureCooldown is how long a failed target is skipped before it is tried again
+const DefaultFailureCooldown = 10 * time.Second
+
+// Balancer picks targets round-robin per endpoint, skipping targets that
+// failed within the cooldown period. It is safe for concurrent use.
+type Balancer struct {
+	mu          sync.Mutex
+	cooldown    time.Duration
+	counters    map[string]uint64
+	failedUntil map[string]time.Time
+	now         func() time.Time
+	randN       func(n int) int
+}
+
+// New creates a balancer that skips failed targets for the given cooldown
+func New(cooldown time.Duration) *Balancer {
+	if cooldown <= 0 {
+		cooldown = DefaultFailureCooldown
+	}
+	return &Balancer{
+		cooldown:    cooldown,
+		counters:    make(map[string]uint64),
+		failedUntil: make(map[string]time.Time),
+		now:         time.Now,
+		randN:       rand.IntN,
+	}
+}
+
+// SetClock replaces the time source used for cooldowns, mainly for tests
+func (b *Balancer) SetClock(now func() time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.now = now
+}
+
+// Next returns the target to use for the next request to the named endpoint.
+// Healthy targets are used in round-robin order; if every target has recently
+// failed, the rotation continues over all of them rather than failing the request.
+func (b *Balancer) Next(endpointName string, targets []string) string {
+	if len(targets) == 1 {
+		return targets[0]
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	start := b.counters[endpointName]
+	now := b.now()
+	for i := 0; i < len(targets); i++ {
+		index := int((start + uint64(i)) % uint64(len(targets)))
+		if !now.Before(b.failedUntil[targets[index]]) {
+			b.counters[endpointName] = start + uint64(i) + 1
+			return targets[index]
+		}
+	}
+
+	b.counters[endpointName] = start + 1
+	return targets[start%uint64(len(targets))]
+}
+
+// NextWeighted returns a target picked at random, each healthy target with
+// a probability proportional to its weight. weights holds a positive weight
+// per target. If every target has recently failed, the pick is made over all
+// of them rather than failing the request.
+func (b *Balancer) NextWeighted(targets []string, weights []int) string {
+	if len(targets) == 1 {
+		return targets[0]
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	total := 0
+	for i, target := range targets {
+		if !now.Before(b.failedUntil[target]) {
+			total += weights[i]
+		}
+	}
+	healthyOnly := total > 0
+	if !healthyOnly {
+		for _, weight := range weights {
+			total += weight
+		}
+	}
+
+	pick := b.randN(total)
+	for i, target := range targets {
+		if healthyOnly && now.Before(b.failedUntil[target]) {
+			continue
+		}
+		if pick < weights[i] {
+			return target
+		}
+		pick -= weights[i]
+	}
+	return targets[len(targets)-1]
+}
+
+// ReportFailure marks a target as unhealthy for the cooldown period
+func (b *Balancer) ReportFailure(target string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failedUntil[target] = b.now().Add(b.cooldown)
+}
+
+// ReportSuccess clears any failure recorded for a target
+func (b *Balancer) ReportSuccess(target string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.failedUntil, target)
+}
+
+// Healthy reports whether a target is currently considered healthy
+func (b *Balancer) Healthy(target string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !b.now().Before(b.failedUntil[target])
+}
@@ -0,0 +1,131 @@
+package balancer
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBalancer_RoundRobin(t *testing.T) {
+	b := New(time.Minute)
+	targets := []string{"http://a", "http://b", "http://c"}
+
+	counts := make(map[string]int)
+	for i := 0; i < 9; i++ {
+		counts[b.Next("svc", targets)]++
+	}
+
+	assert.Equal(t, map[string]int{"http://a": 3, "http://b": 3, "http://c": 3}, counts)
+}
+
+func TestBalancer_SingleTarget(t *testing.T) {
+	b := New(time.Minute)
+	b.ReportFailure("http://a")
+
+	assert.Equal(t, "http://a", b.Next("svc", []string{"http://a"}))
+}
+
+func TestBalancer_SkipsFailedTargets(t *testing.T) {
+	b := New(10 * time.Second)
+	now := time.Now()
+	b.SetClock(func() time.Time { return now })
+	targets := []string{"http://a", "http://b"}
+
+	b.ReportFailure("http://a")
+	assert.False(t, b.Healthy("http://a"))
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, "http://b", b.Next("svc", targets))
+	}
+
+	// After the cooldown the failed target rejoins the rotation
+	now = now.Add(10 * time.Second)
+	assert.True(t, b.Healthy("http://a"))
+	counts := make(map[string]int)
+	for i := 0; i < 4; i++ {
+		counts[b.Next("svc", targets)]++
+	}
+	assert.Equal(t, 2, counts["http://a"])
+	assert.Equal(t, 2, counts["http://b"])
+}
+
+func TestBalancer_NextWeighted(t *testing.T) {
+	b := New(time.Minute)
+	targets := []string{"http://primary", "http://canary"}
+	weights := []int{9, 1}
+
+	const requests = 20000
+	counts := make(map[string]int)
+	for i := 0; i < requests; i++ {
+		counts[b.NextWeighted(targets, weights)]++
+	}
+
+	assert.InDelta(t, 0.9, float64(counts["http://primary"])/requests, 0.02)
+	assert.InDelta(t, 0.1, float64(counts["http://canary"])/requests, 0.02)
+}
+
+func TestBalancer_NextWeightedSkipsFailedTargets(t *testing.T) {
+	b := New(time.Minute)
+	targets := []string{"http://a", "http://b", "http://c"}
+	weights := []int{5, 1, 1}
+
+	b.ReportFailure("http://a")
+	for i := 0; i < 50; i++ {
+		assert.NotEqual(t, "http://a", b.NextWeighted(targets, weights))
+	}
+
+	// With every target failed, picks are made over all of them
+	b.ReportFailure("http://b")
+	b.ReportFailure("http://c")
+	counts := make(map[string]int)
+	for i := 0; i < 700; i++ {
+		counts[b.NextWeighted(targets, weights)]++
+	}
+	assert.Len(t, counts, 3)
+}
+
+func TestBalancer_ReportSuccessClearsFailure(t *testing.T) {
+	b := New(time.Minute)
+	b.ReportFailure("http://a")
+	b.ReportSuccess("http://a")
+
+	assert.True(t, b.Healthy("http://a"))
+}
+
+func TestBalancer_AllTargetsFailed(t *testing.T) {
+	b := New(time.Minute)
+	targets := []string{"http://a", "http://b"}
+	b.ReportFailure("http://a")
+	b.ReportFailure("http://b")
+
+	counts := make(map[string]int)
+	for i := 0; i < 4; i++ {
+		counts[b.Next("svc", targets)]++
+	}
+	assert.Equal(t, 2, counts["http://a"])
+	assert.Equal(t, 2, counts["http://b"])
+}
+
+func TestBalancer_ThreadSafety(t *testing.T) {
+	b := New(time.Minute)
+	targets := []string{"http://a", "http://b", "http://c"}
+	var wg sync.WaitGroup
+
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				target := b.Next("svc", targets)
+				if (id+j)%5 == 0 {
+					b.ReportFailure(target)
+				} else {
+					b.ReportSuccess(target)
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,149 @@
+// Package cache provides a bounded, concurrency-safe in-memory cache with per-entry expiry.
+package cache
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxEntries is the capacity used when a non-positive size is requested
+const DefaultMaxEntries = 1000
+
+// Cache is a size-bounded LRU cache whose entries expire after their TTL
+type Cache struct {
+	mu         sync.Mutex
+	maxEntries int
+	entries    map[string]*list.Element
+	order      *list.List
+	now        func() time.Time
+}
+
+// entry is a single cached value
+type entry struct {
+	key       string
+	value     interface{}
+	expiresAt time.Time
+}
+
+// New creates a cache holding at most maxEntries values
+func New(maxEntries int) *Cache {
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+	return &Cache{
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+		now:        time.Now,
+	}
+}
+
+// SetClock replaces the time source used for expiry, mainly for tests
+func (c *Cache) SetClock(now func() time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+// Get returns the value stored under key if it exists and has not expired
+func (c *Cache) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, exists := c.entries[key]
+	if !exists {
+		return nil, false
+	}
+
+	e := element.Value.(*entry)
+	if !c.now().Before(e.expiresAt) {
+		c.removeElement(element)
+		return nil, false
+	}
+
+	c.order.MoveToFront(element)
+	return e.value, true
+}
+
+// Lookup returns the value stored under key and whether it is still fresh.
+// Unlike Get it also returns expired entries, without removing them, so the
+// caller can revalidate them; they are still evicted as least recently used.
+func (c *Cache) Lookup(key string) (value interface{}, fresh bool, found bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, exists := c.entries[key]
+	if !exists {
+		return nil, false, false
+	}
+
+	e := element.Value.(*entry)
+	c.order.MoveToFront(element)
+	return e.value, c.now().Before(e.expiresAt), true
+}
+
+// Set stores value under key for the given TTL, evicting the least recently
+// used entry if the cache is full. Non-positive TTLs are ignored.
+func (c *Cache) Set(key string, value interface{}, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := c.now().Add(ttl)
+	if element, exists := c.entries[key]; exists {
+		e := element.Value.(*entry)
+		e.value = value
+		e.expiresAt = expiresAt
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&entry{key: key, value: value, expiresAt: expiresAt})
+	for c.order.Len() > c.maxEntries {
+		c.removeElement(c.order.Back())
+	}
+}
+
+// Delete removes the entry stored under key
+func (c *Cache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, exists := c.entries[key]; exists {
+		c.removeElement(element)
+	}
+}
+
+// PurgePrefix removes all entries whose key starts with prefix and returns how many were removed.
+// An empty prefix removes everything.
+func (c *Cache) PurgePrefix(prefix string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removed := 0
+	for key, element := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			c.removeElement(element)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Len returns the number of entries currently held, including expired ones not yet evicted
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// removeElement removes an element from both the index and the LRU list
+func (c *Cache) removeElement(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*entry).key)
+}
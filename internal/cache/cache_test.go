@@ -0,0 +1,133 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache_GetSet(t *testing.T) {
+	c := New(10)
+
+	// Miss
+	_, found := c.Get("missing")
+	assert.False(t, found)
+
+	// Hit
+	c.Set("key", "value", time.Minute)
+	value, found := c.Get("key")
+	assert.True(t, found)
+	assert.Equal(t, "value", value)
+
+	// Overwrite
+	c.Set("key", "updated", time.Minute)
+	value, _ = c.Get("key")
+	assert.Equal(t, "updated", value)
+	assert.Equal(t, 1, c.Len())
+}
+
+func TestCache_Expiry(t *testing.T) {
+	c := New(10)
+	now := time.Now()
+	c.SetClock(func() time.Time { return now })
+
+	c.Set("key", "value", 10*time.Second)
+
+	now = now.Add(9 * time.Second)
+	_, found := c.Get("key")
+	assert.True(t, found)
+
+	now = now.Add(time.Second)
+	_, found = c.Get("key")
+	assert.False(t, found)
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestCache_LookupStale(t *testing.T) {
+	c := New(10)
+	now := time.Now()
+	c.SetClock(func() time.Time { return now })
+
+	_, _, found := c.Lookup("missing")
+	assert.False(t, found)
+
+	c.Set("key", "value", 10*time.Second)
+	value, fresh, found := c.Lookup("key")
+	assert.True(t, found)
+	assert.True(t, fresh)
+	assert.Equal(t, "value", value)
+
+	// Expired entries are still returned, and kept, for revalidation
+	now = now.Add(10 * time.Second)
+	value, fresh, found = c.Lookup("key")
+	assert.True(t, found)
+	assert.False(t, fresh)
+	assert.Equal(t, "value", value)
+	assert.Equal(t, 1, c.Len())
+}
+
+func TestCache_NonPositiveTTL(t *testing.T) {
+	c := New(10)
+	c.Set("key", "value", 0)
+
+	_, found := c.Get("key")
+	assert.False(t, found)
+}
+
+func TestCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := New(2)
+
+	c.Set("a", 1, time.Minute)
+	c.Set("b", 2, time.Minute)
+
+	// Touch "a" so "b" becomes the least recently used
+	_, _ = c.Get("a")
+	c.Set("c", 3, time.Minute)
+
+	assert.Equal(t, 2, c.Len())
+	_, found := c.Get("b")
+	assert.False(t, found)
+	_, found = c.Get("a")
+	assert.True(t, found)
+	_, found = c.Get("c")
+	assert.True(t, found)
+}
+
+func TestCache_PurgePrefix(t *testing.T) {
+	c := New(10)
+	c.Set("users|/1", 1, time.Minute)
+	c.Set("users|/2", 2, time.Minute)
+	c.Set("posts|/1", 3, time.Minute)
+
+	assert.Equal(t, 2, c.PurgePrefix("users|"))
+	assert.Equal(t, 1, c.Len())
+
+	c.Delete("posts|/1")
+	assert.Equal(t, 0, c.Len())
+
+	c.Set("posts|/1", 3, time.Minute)
+	assert.Equal(t, 1, c.PurgePrefix(""))
+}
+
+func TestCache_ThreadSafety(t *testing.T) {
+	c := New(50)
+	var wg sync.WaitGroup
+
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				key := fmt.Sprintf("key-%d", (id+j)%75)
+				c.Set(key, j, time.Minute)
+				c.Get(key)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	assert.LessOrEqual(t, c.Len(), 50)
+}
@@ -7,10 +7,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime"
+	"net"
 	"net/http"
 	"net/url"
 	"strings"
 	"time"
+
+	"jq-proxy-service/internal/version"
 )
 
 // HTTPClient defines the interface for HTTP client operations
@@ -30,27 +34,135 @@ type Response struct {
 	StatusCode int
 	Headers    http.Header
 	Body       []byte
+
+	// Duration is how long the upstream call took, excluding time spent
+	// waiting on the proxy's own rate and concurrency limits
+	Duration time.Duration
 }
 
 // Client implements HTTPClient with connection pooling and timeout management
 type Client struct {
 	httpClient *http.Client
+	userAgent  string
+}
+
+// Settings controls the timeout and connection pooling of a Client
+type Settings struct {
+	Timeout             time.Duration
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+	KeepAlive           time.Duration
+
+	// DialTimeout bounds establishing each connection, separately from Timeout
+	DialTimeout time.Duration
+	// ResponseHeaderTimeout bounds the wait for response headers after the
+	// request is written; zero means only Timeout applies
+	ResponseHeaderTimeout time.Duration
+
+	// MaxRedirects caps the redirects followed per request; zero means DefaultMaxRedirects
+	MaxRedirects int
+	// DisableRedirects returns 3xx responses to the caller instead of following them
+	DisableRedirects bool
+
+	// UserAgent is sent when the request does not carry its own User-Agent
+	UserAgent string
+
+	// ProxyURL routes requests through this proxy; empty uses the
+	// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables
+	ProxyURL string
+}
+
+// DefaultMaxRedirects is the number of redirects followed when no limit is configured
+const DefaultMaxRedirects = 10
+
+// DefaultSettings returns the connection pooling settings used by NewClient
+func DefaultSettings(timeout time.Duration) Settings {
+	return Settings{
+		Timeout:             timeout,
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     90 * time.Second,
+		KeepAlive:           30 * time.Second,
+		DialTimeout:         30 * time.Second,
+		UserAgent:           version.UserAgent(),
+	}
 }
 
 // NewClient creates a new HTTP client with connection pooling
 func NewClient(timeout time.Duration) *Client {
+	return NewClientWithSettings(DefaultSettings(timeout))
+}
+
+// NewClientWithSettings creates a new HTTP client with its own transport
+func NewClientWithSettings(settings Settings) *Client {
+	dialer := newDialer(settings)
+
 	return &Client{
+		userAgent: settings.UserAgent,
 		httpClient: &http.Client{
-			Timeout: timeout,
+			Timeout:       settings.Timeout,
+			CheckRedirect: redirectPolicy(settings),
 			Transport: &http.Transport{
-				MaxIdleConns:        100,
-				MaxIdleConnsPerHost: 10,
-				IdleConnTimeout:     90 * time.Second,
+				Proxy:                 proxyFunc(settings.ProxyURL),
+				DialContext:           dialer.DialContext,
+				MaxIdleConns:          settings.MaxIdleConns,
+				MaxIdleConnsPerHost:   settings.MaxIdleConnsPerHost,
+				IdleConnTimeout:       settings.IdleConnTimeout,
+				ResponseHeaderTimeout: settings.ResponseHeaderTimeout,
 			},
 		},
 	}
 }
 
+// newDialer creates the dialer for a client's connections
+func newDialer(settings Settings) *net.Dialer {
+	return &net.Dialer{
+		Timeout:   settings.DialTimeout,
+		KeepAlive: settings.KeepAlive,
+	}
+}
+
+// proxyFunc returns the transport proxy function for a configured proxy URL,
+// falling back to the environment when none is set
+func proxyFunc(proxyURL string) func(*http.Request) (*url.URL, error) {
+	if proxyURL == "" {
+		return http.ProxyFromEnvironment
+	}
+	parsed, err := url.Parse(proxyURL)
+	if err != nil {
+		return func(*http.Request) (*url.URL, error) {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+	}
+	return http.ProxyURL(parsed)
+}
+
+// redirectPolicy limits how many redirects are followed and keeps jpx- headers
+// from being carried over to the redirected request
+func redirectPolicy(settings Settings) func(req *http.Request, via []*http.Request) error {
+	maxRedirects := settings.MaxRedirects
+	if maxRedirects <= 0 {
+		maxRedirects = DefaultMaxRedirects
+	}
+
+	return func(req *http.Request, via []*http.Request) error {
+		if settings.DisableRedirects {
+			return http.ErrUseLastResponse
+		}
+		if len(via) > maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		req.Header = filterHeaders(req.Header)
+		return nil
+	}
+}
+
+// CloseIdleConnections closes connections left idle by the client's transport
+func (c *Client) CloseIdleConnections() {
+	c.httpClient.CloseIdleConnections()
+}
+
 // Do performs an HTTP request with the specified parameters
 func (c *Client) Do(
 	ctx context.Context,
@@ -89,6 +201,11 @@ func (c *Client) Do(
 		}
 	}
 
+	// Identify the proxy unless the caller sent its own User-Agent
+	if req.Header.Get("User-Agent") == "" && c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+
 	// Set Content-Type for JSON body if not already set
 	if body != nil && req.Header.Get("Content-Type") == "" {
 		req.Header.Set("Content-Type", "application/json")
@@ -183,6 +300,41 @@ func (r *Response) IsJSONResponse() bool {
 	return strings.Contains(strings.ToLower(contentType), "application/json")
 }
 
+// HasUnspecificContentType reports whether the response does not say what its
+// body is: the Content-Type is missing, unparseable, or a generic type such as
+// text/plain or application/octet-stream
+func (r *Response) HasUnspecificContentType() bool {
+	contentType := r.Headers.Get("Content-Type")
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return true
+	}
+	return mediaType == "text/plain" || mediaType == "application/octet-stream"
+}
+
+// ParseJSONBodyWithNumbers parses the response body as JSON, keeping numbers
+// as json.Number so integers too large for a float64 keep every digit
+func (r *Response) ParseJSONBodyWithNumbers() (interface{}, error) {
+	if len(r.Body) == 0 {
+		return nil, nil
+	}
+
+	var result interface{}
+	decoder := json.NewDecoder(bytes.NewReader(r.Body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("failed to parse JSON response: invalid data after top-level value")
+	}
+
+	return result, nil
+}
+
 // ParseJSONBody parses the response body as JSON
 func (r *Response) ParseJSONBody() (interface{}, error) {
 	if len(r.Body) == 0 {
@@ -46,6 +46,7 @@ func TestClient_Do(t *testing.T) {
 		method      string
 		headers     http.Header
 		body        interface{}
+		expectBody  string
 		expectError bool
 	}{
 		{
@@ -57,6 +58,15 @@ func TestClient_Do(t *testing.T) {
 			body:        nil,
 			expectError: false,
 		},
+		{
+			name:   "GET with JSON body",
+			method: "GET",
+			body: map[string]interface{}{
+				"query": map[string]interface{}{"match": map[string]interface{}{"title": "jq"}},
+			},
+			expectBody:  `{"query": {"match": {"title": "jq"}}}`,
+			expectError: false,
+		},
 		{
 			name:   "POST with JSON body",
 			method: "POST",
@@ -117,6 +127,9 @@ func TestClient_Do(t *testing.T) {
 				if tt.body != nil {
 					assert.Equal(t, "application/json", responseData["content_type"])
 				}
+				if tt.expectBody != "" {
+					assert.JSONEq(t, tt.expectBody, responseData["body"].(string))
+				}
 			}
 		})
 	}
@@ -218,6 +231,204 @@ func TestClient_ForwardRequest(t *testing.T) {
 	}
 }
 
+func TestNewClientWithSettings(t *testing.T) {
+	settings := Settings{
+		Timeout:             5 * time.Second,
+		MaxIdleConns:        20,
+		MaxIdleConnsPerHost: 4,
+		IdleConnTimeout:     15 * time.Second,
+		KeepAlive:           10 * time.Second,
+	}
+
+	c := NewClientWithSettings(settings)
+
+	assert.Equal(t, 5*time.Second, c.httpClient.Timeout)
+	transport, ok := c.httpClient.Transport.(*http.Transport)
+	require.True(t, ok)
+	assert.Equal(t, 20, transport.MaxIdleConns)
+	assert.Equal(t, 4, transport.MaxIdleConnsPerHost)
+	assert.Equal(t, 15*time.Second, transport.IdleConnTimeout)
+	assert.NotNil(t, transport.DialContext)
+
+	// NewClient keeps the shared defaults
+	defaults := NewClient(30 * time.Second).httpClient.Transport.(*http.Transport)
+	assert.Equal(t, 100, defaults.MaxIdleConns)
+	assert.Equal(t, 10, defaults.MaxIdleConnsPerHost)
+	assert.Equal(t, 90*time.Second, defaults.IdleConnTimeout)
+}
+
+func TestClient_Redirects(t *testing.T) {
+	var finalHeaders http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/once":
+			w.Header().Set("X-Redirected-From", "once")
+			http.Redirect(w, r, "/final", http.StatusFound)
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		default:
+			finalHeaders = r.Header.Clone()
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"ok":true}`))
+		}
+	}))
+	defer server.Close()
+
+	headers := http.Header{
+		"Authorization": []string{"Bearer token"},
+		"Jpx-Debug":     []string{"true"},
+	}
+
+	t.Run("follows a single redirect", func(t *testing.T) {
+		c := NewClient(5 * time.Second)
+		resp, err := c.ForwardRequest(context.Background(), "GET", server.URL, "/once", nil, headers, nil)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "Bearer token", finalHeaders.Get("Authorization"))
+		assert.Empty(t, finalHeaders.Get("Jpx-Debug"))
+	})
+
+	t.Run("jpx headers are not carried over a redirect", func(t *testing.T) {
+		c := NewClient(5 * time.Second)
+		_, err := c.Do(context.Background(), "GET", server.URL+"/once", headers, nil)
+		require.NoError(t, err)
+		assert.Empty(t, finalHeaders.Get("Jpx-Debug"))
+	})
+
+	t.Run("stops a redirect loop", func(t *testing.T) {
+		settings := DefaultSettings(5 * time.Second)
+		settings.MaxRedirects = 3
+		c := NewClientWithSettings(settings)
+		_, err := c.ForwardRequest(context.Background(), "GET", server.URL, "/loop", nil, nil, nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "stopped after 3 redirects")
+	})
+
+	t.Run("default limit stops a redirect loop", func(t *testing.T) {
+		c := NewClient(5 * time.Second)
+		_, err := c.ForwardRequest(context.Background(), "GET", server.URL, "/loop", nil, nil, nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "stopped after 10 redirects")
+	})
+
+	t.Run("disabled redirects return the 3xx", func(t *testing.T) {
+		settings := DefaultSettings(5 * time.Second)
+		settings.DisableRedirects = true
+		c := NewClientWithSettings(settings)
+		resp, err := c.ForwardRequest(context.Background(), "GET", server.URL, "/once", nil, nil, nil)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusFound, resp.StatusCode)
+		assert.Equal(t, "/final", resp.Headers.Get("Location"))
+		assert.Equal(t, "once", resp.Headers.Get("X-Redirected-From"))
+	})
+}
+
+func TestClient_UserAgent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.UserAgent()))
+	}))
+	defer server.Close()
+
+	custom := DefaultSettings(5 * time.Second)
+	custom.UserAgent = "acme-proxy/1.0"
+
+	tests := []struct {
+		name     string
+		client   *Client
+		headers  http.Header
+		expected string
+	}{
+		{
+			name:     "default user agent",
+			client:   NewClient(5 * time.Second),
+			expected: "jq-proxy-service/dev",
+		},
+		{
+			name:     "configured user agent",
+			client:   NewClientWithSettings(custom),
+			expected: "acme-proxy/1.0",
+		},
+		{
+			name:     "caller user agent is kept",
+			client:   NewClientWithSettings(custom),
+			headers:  http.Header{"User-Agent": []string{"curl/8.0"}},
+			expected: "curl/8.0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := tt.client.ForwardRequest(context.Background(), "GET", server.URL, "/", nil, tt.headers, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(resp.Body))
+		})
+	}
+}
+
+func TestClient_Timeouts(t *testing.T) {
+	slowHeaders := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+		w.Write([]byte(`{}`))
+	}))
+	defer slowHeaders.Close()
+
+	// Headers arrive at once but the body trickles in
+	slowBody := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		for i := 0; i < 3; i++ {
+			w.Write([]byte(" "))
+			w.(http.Flusher).Flush()
+			time.Sleep(100 * time.Millisecond)
+		}
+	}))
+	defer slowBody.Close()
+
+	settings := DefaultSettings(5 * time.Second)
+	settings.DialTimeout = 2 * time.Second
+	settings.ResponseHeaderTimeout = 100 * time.Millisecond
+	c := NewClientWithSettings(settings)
+
+	// A target slow to respond fails on the header timeout, well before the overall timeout
+	start := time.Now()
+	_, err := c.Do(context.Background(), "GET", slowHeaders.URL, nil, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "timeout awaiting response headers")
+	assert.Less(t, time.Since(start), 2*time.Second)
+
+	// A slow body is only bound by the overall timeout
+	resp, err := c.Do(context.Background(), "GET", slowBody.URL, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "   ", string(resp.Body))
+
+	// Connecting is bound by the dial timeout rather than the overall timeout
+	assert.Equal(t, 2*time.Second, newDialer(settings).Timeout)
+	assert.Equal(t, 30*time.Second, newDialer(DefaultSettings(5*time.Second)).Timeout)
+}
+
+func TestClient_Proxy(t *testing.T) {
+	// The proxy answers in place of the target, recording the requested URL
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"via":"proxy"}`))
+	}))
+	defer proxy.Close()
+
+	settings := DefaultSettings(5 * time.Second)
+	settings.ProxyURL = proxy.URL
+	c := NewClientWithSettings(settings)
+
+	resp, err := c.ForwardRequest(context.Background(), "GET", "http://upstream.invalid", "/users", url.Values{"page": []string{"2"}}, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, `{"via":"proxy"}`, string(resp.Body))
+	assert.Equal(t, []string{"http://upstream.invalid/users?page=2"}, proxied)
+
+	// Without a proxy URL the environment decides
+	transport := NewClient(5 * time.Second).httpClient.Transport.(*http.Transport)
+	assert.NotNil(t, transport.Proxy)
+}
+
 func TestBuildTargetURL(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -320,6 +531,16 @@ func TestFilterHeaders(t *testing.T) {
 				"Content-Type":  []string{"application/json"},
 			},
 		},
+		{
+			name: "binary upload keeps its content type",
+			headers: http.Header{
+				"Content-Type": []string{"image/png"},
+				"Jpx-Jq-Query": []string{"{id}"},
+			},
+			expected: http.Header{
+				"Content-Type": []string{"image/png"},
+			},
+		},
 		{
 			name: "only jpx headers",
 			headers: http.Header{
@@ -383,6 +604,53 @@ func TestResponse_IsJSONResponse(t *testing.T) {
 	}
 }
 
+func TestResponse_HasUnspecificContentType(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		expected    bool
+	}{
+		{name: "missing", contentType: "", expected: true},
+		{name: "text/plain", contentType: "text/plain; charset=utf-8", expected: true},
+		{name: "octet stream", contentType: "application/octet-stream", expected: true},
+		{name: "unparseable", contentType: "json;;", expected: true},
+		{name: "json", contentType: "application/json", expected: false},
+		{name: "html", contentType: "text/html", expected: false},
+		{name: "csv", contentType: "text/csv", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &Response{Headers: http.Header{}}
+			if tt.contentType != "" {
+				resp.Headers.Set("Content-Type", tt.contentType)
+			}
+			assert.Equal(t, tt.expected, resp.HasUnspecificContentType())
+		})
+	}
+}
+
+func TestResponse_ParseJSONBodyWithNumbers(t *testing.T) {
+	resp := &Response{Body: []byte(`{"id": 1234567890123456789, "price": 9.99, "items": [1e3]}`)}
+	result, err := resp.ParseJSONBodyWithNumbers()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"id":    json.Number("1234567890123456789"),
+		"price": json.Number("9.99"),
+		"items": []interface{}{json.Number("1e3")},
+	}, result)
+
+	result, err = (&Response{}).ParseJSONBodyWithNumbers()
+	assert.NoError(t, err)
+	assert.Nil(t, result)
+
+	for _, body := range []string{`{"key": "value",}`, `{"a": 1} {"b": 2}`} {
+		result, err = (&Response{Body: []byte(body)}).ParseJSONBodyWithNumbers()
+		assert.Error(t, err, body)
+		assert.Nil(t, result)
+	}
+}
+
 func TestResponse_ParseJSONBody(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -0,0 +1,116 @@
+// Package config provides configuration loading and management functionality.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// NewDirProvider creates a file-based configuration provider that also reads
+// endpoints from every *.json file in endpointDir. The configuration files,
+// if any, supply the server settings and may define endpoints of their own;
+// without them the server settings are the defaults.
+func NewDirProvider(endpointDir string, filePaths ...string) *FileProvider {
+	return &FileProvider{
+		filePaths:   filePaths,
+		endpointDir: endpointDir,
+	}
+}
+
+// endpointFile is the content of a file in the endpoint directory
+type endpointFile struct {
+	Endpoints map[string]json.RawMessage `json:"endpoints"`
+}
+
+// readDirConfig builds the configuration document from the configuration
+// files, or the default server settings, and the endpoint directory
+func (fp *FileProvider) readDirConfig() ([]byte, error) {
+	var data []byte
+	var err error
+	switch len(fp.filePaths) {
+	case 0:
+		data, err = json.Marshal(map[string]interface{}{"server": defaultServerConfig()})
+	case 1:
+		data, err = readConfigFile(fp.filePaths[0])
+	default:
+		data, err = mergeConfigFiles(fp.filePaths)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var document map[string]json.RawMessage
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse configuration: %w", err)
+	}
+	var endpoints map[string]json.RawMessage
+	if raw, ok := document["endpoints"]; ok {
+		if err := json.Unmarshal(raw, &endpoints); err != nil {
+			return nil, fmt.Errorf("failed to parse configuration: %w", err)
+		}
+	}
+
+	endpoints, err = mergeEndpointDir(endpoints, fp.endpointDir)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := json.Marshal(endpoints)
+	if err != nil {
+		return nil, err
+	}
+	document["endpoints"] = encoded
+	return json.Marshal(document)
+}
+
+// mergeEndpointDir adds the endpoints defined in every *.json file of dir, in
+// file name order. Each file holds an "endpoints" object like the main
+// configuration's and nothing else. An endpoint name may only be defined once
+// across the configuration and all the files.
+func mergeEndpointDir(endpoints map[string]json.RawMessage, dir string) (map[string]json.RawMessage, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read endpoint directory: %w", err)
+	}
+
+	merged := make(map[string]json.RawMessage, len(endpoints))
+	sources := make(map[string]string, len(endpoints))
+	for name, endpoint := range endpoints {
+		merged[name] = endpoint
+		sources[name] = "the main configuration"
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		filePath := filepath.Join(dir, entry.Name())
+		data, err := readConfigFile(filePath)
+		if err != nil {
+			return nil, err
+		}
+
+		var file endpointFile
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&file); err != nil {
+			return nil, fmt.Errorf("failed to parse endpoint file %s: %w", filePath, err)
+		}
+		if len(file.Endpoints) == 0 {
+			return nil, fmt.Errorf("endpoint file %s defines no endpoints", filePath)
+		}
+
+		for name, endpoint := range file.Endpoints {
+			if source, exists := sources[name]; exists {
+				return nil, fmt.Errorf("endpoint %s is defined in both %s and %s", name, source, filePath)
+			}
+			merged[name] = endpoint
+			sources[name] = filePath
+		}
+	}
+	return merged, nil
+}
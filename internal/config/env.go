@@ -3,8 +3,7 @@ package config
 
 import (
 	"fmt"
-	"os"
-	"strconv"
+	"sync"
 
 	"jq-proxy-service/internal/models"
 )
@@ -12,18 +11,47 @@ import (
 // EnvProvider implements ConfigProvider with environment variable support
 type EnvProvider struct {
 	fileProvider *FileProvider
+
+	// config caches the merged configuration until Reload
+	mu     sync.RWMutex
+	config *models.ProxyConfig
 }
 
 // NewEnvProvider creates a new environment-based configuration provider
 // It wraps a FileProvider for endpoint configuration while using env vars for server config
-func NewEnvProvider(filePath string) *EnvProvider {
+func NewEnvProvider(filePaths ...string) *EnvProvider {
+	return &EnvProvider{
+		fileProvider: NewFileProvider(filePaths...),
+	}
+}
+
+// NewEnvDirProvider creates an environment-based configuration provider that
+// also reads endpoints from every *.json file in endpointDir
+func NewEnvDirProvider(endpointDir string, filePaths ...string) *EnvProvider {
 	return &EnvProvider{
-		fileProvider: NewFileProvider(filePath),
+		fileProvider: NewDirProvider(endpointDir, filePaths...),
 	}
 }
 
-// LoadConfig loads configuration from file and overrides server config with environment variables
+// LoadConfig returns the configuration from file with server config overridden
+// by environment variables. The file is read on first use and then cached
+// until Reload.
 func (ep *EnvProvider) LoadConfig() (*models.ProxyConfig, error) {
+	ep.mu.RLock()
+	config := ep.config
+	ep.mu.RUnlock()
+	if config != nil {
+		return config, nil
+	}
+	return ep.load()
+}
+
+// load reads the file and environment and caches the merged configuration.
+// If loading fails the previously cached configuration stays in effect.
+func (ep *EnvProvider) load() (*models.ProxyConfig, error) {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+
 	// Load base configuration from file
 	config, err := ep.fileProvider.LoadConfig()
 	if err != nil {
@@ -31,7 +59,7 @@ func (ep *EnvProvider) LoadConfig() (*models.ProxyConfig, error) {
 	}
 
 	// Override server configuration with environment variables
-	serverConfig, err := ep.loadServerConfigFromEnv()
+	serverConfig, err := applyServerEnvOverrides(config.Server)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load server config from environment: %w", err)
 	}
@@ -39,67 +67,35 @@ func (ep *EnvProvider) LoadConfig() (*models.ProxyConfig, error) {
 	// Merge server config
 	config.Server = *serverConfig
 
-	return config, nil
-}
-
-// loadServerConfigFromEnv loads server configuration from environment variables
-func (ep *EnvProvider) loadServerConfigFromEnv() (*models.ServerConfig, error) {
-	config := &models.ServerConfig{
-		Port:         8080, // Default port
-		ReadTimeout:  30,   // Default read timeout in seconds
-		WriteTimeout: 30,   // Default write timeout in seconds
-	}
-
-	// Load port from environment
-	if portStr := os.Getenv("PROXY_PORT"); portStr != "" {
-		port, err := strconv.Atoi(portStr)
-		if err != nil {
-			return nil, fmt.Errorf("invalid PROXY_PORT value: %s", portStr)
-		}
-		config.Port = port
-	}
-
-	// Load read timeout from environment
-	if timeoutStr := os.Getenv("PROXY_READ_TIMEOUT"); timeoutStr != "" {
-		timeout, err := strconv.Atoi(timeoutStr)
-		if err != nil {
-			return nil, fmt.Errorf("invalid PROXY_READ_TIMEOUT value: %s", timeoutStr)
-		}
-		config.ReadTimeout = timeout
-	}
-
-	// Load write timeout from environment
-	if timeoutStr := os.Getenv("PROXY_WRITE_TIMEOUT"); timeoutStr != "" {
-		timeout, err := strconv.Atoi(timeoutStr)
-		if err != nil {
-			return nil, fmt.Errorf("invalid PROXY_WRITE_TIMEOUT value: %s", timeoutStr)
-		}
-		config.WriteTimeout = timeout
-	}
-
-	// Validate the configuration
-	if err := config.Validate(); err != nil {
+	// Disable endpoints listed in the environment
+	if err := applyEndpointEnvOverrides(config.Endpoints); err != nil {
 		return nil, err
 	}
 
+	ep.config = config
 	return config, nil
 }
 
-// GetEndpoint retrieves an endpoint by name (delegates to file provider)
+// GetEndpoint retrieves an endpoint by name from the cached configuration
 func (ep *EnvProvider) GetEndpoint(name string) (*models.Endpoint, bool) {
-	return ep.fileProvider.GetEndpoint(name)
+	ep.mu.RLock()
+	defer ep.mu.RUnlock()
+
+	if ep.config == nil {
+		return nil, false
+	}
+
+	return ep.config.FindEndpoint(name)
 }
 
-// Reload reloads the configuration
+// Reload re-reads the file and environment, replacing the cached configuration
 func (ep *EnvProvider) Reload() error {
-	_, err := ep.LoadConfig()
+	_, err := ep.load()
 	return err
 }
 
-// GetConfig returns the current configuration
+// GetConfig returns the current configuration, loading it on first use
 func (ep *EnvProvider) GetConfig() *models.ProxyConfig {
-	// This will return the file provider's config, but we should reload to get env vars
-	// For a more robust implementation, we might want to cache the merged config
 	config, err := ep.LoadConfig()
 	if err != nil {
 		return ep.fileProvider.GetConfig() // Fallback to file config
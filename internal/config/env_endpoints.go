@@ -23,8 +23,21 @@ func NewFullEnvProvider() *FullEnvProvider {
 	return &FullEnvProvider{}
 }
 
-// LoadConfig loads configuration entirely from environment variables
+// LoadConfig returns the configuration loaded entirely from environment
+// variables. The environment is read on first use and then cached until Reload.
 func (fep *FullEnvProvider) LoadConfig() (*models.ProxyConfig, error) {
+	fep.mu.RLock()
+	config := fep.config
+	fep.mu.RUnlock()
+	if config != nil {
+		return config, nil
+	}
+	return fep.load()
+}
+
+// load reads the environment and caches the resulting configuration.
+// If loading fails the previously cached configuration stays in effect.
+func (fep *FullEnvProvider) load() (*models.ProxyConfig, error) {
 	fep.mu.Lock()
 	defer fep.mu.Unlock()
 
@@ -40,6 +53,10 @@ func (fep *FullEnvProvider) LoadConfig() (*models.ProxyConfig, error) {
 		return nil, fmt.Errorf("failed to load endpoints from environment: %w", err)
 	}
 
+	if err := applyEndpointEnvOverrides(endpoints); err != nil {
+		return nil, err
+	}
+
 	config := &models.ProxyConfig{
 		Server:    *serverConfig,
 		Endpoints: endpoints,
@@ -54,39 +71,156 @@ func (fep *FullEnvProvider) LoadConfig() (*models.ProxyConfig, error) {
 	return config, nil
 }
 
-// loadServerConfigFromEnv loads server configuration from environment variables
-func loadServerConfigFromEnv() (*models.ServerConfig, error) {
-	config := &models.ServerConfig{
+// defaultServerConfig returns the server configuration used when nothing else is specified
+func defaultServerConfig() models.ServerConfig {
+	return models.ServerConfig{
 		Port:         8080, // Default port
 		ReadTimeout:  30,   // Default read timeout in seconds
 		WriteTimeout: 30,   // Default write timeout in seconds
 	}
+}
 
+// loadServerConfigFromEnv loads server configuration from environment variables
+func loadServerConfigFromEnv() (*models.ServerConfig, error) {
+	return applyServerEnvOverrides(defaultServerConfig())
+}
+
+// applyServerEnvOverrides overrides the given server configuration with environment variables
+func applyServerEnvOverrides(config models.ServerConfig) (*models.ServerConfig, error) {
 	// Load port from environment
-	if portStr := os.Getenv("PROXY_PORT"); portStr != "" {
-		port, err := strconv.Atoi(portStr)
-		if err != nil {
-			return nil, fmt.Errorf("invalid PROXY_PORT value: %s", portStr)
-		}
-		config.Port = port
+	if err := envInt("PROXY_PORT", &config.Port); err != nil {
+		return nil, err
 	}
 
 	// Load read timeout from environment
-	if timeoutStr := os.Getenv("PROXY_READ_TIMEOUT"); timeoutStr != "" {
-		timeout, err := strconv.Atoi(timeoutStr)
-		if err != nil {
-			return nil, fmt.Errorf("invalid PROXY_READ_TIMEOUT value: %s", timeoutStr)
-		}
-		config.ReadTimeout = timeout
+	if err := envInt("PROXY_READ_TIMEOUT", &config.ReadTimeout); err != nil {
+		return nil, err
 	}
 
 	// Load write timeout from environment
-	if timeoutStr := os.Getenv("PROXY_WRITE_TIMEOUT"); timeoutStr != "" {
-		timeout, err := strconv.Atoi(timeoutStr)
-		if err != nil {
-			return nil, fmt.Errorf("invalid PROXY_WRITE_TIMEOUT value: %s", timeoutStr)
-		}
-		config.WriteTimeout = timeout
+	if err := envInt("PROXY_WRITE_TIMEOUT", &config.WriteTimeout); err != nil {
+		return nil, err
+	}
+
+	// Load upstream connection timeouts from environment
+	if err := envInt("PROXY_DIAL_TIMEOUT", &config.DialTimeout); err != nil {
+		return nil, err
+	}
+	if err := envInt("PROXY_RESPONSE_HEADER_TIMEOUT", &config.ResponseHeaderTimeout); err != nil {
+		return nil, err
+	}
+
+	// Load max transform time from environment
+	if err := envInt("PROXY_MAX_TRANSFORM_TIME", &config.MaxTransformTime); err != nil {
+		return nil, err
+	}
+
+	// Load jq query complexity budget from environment
+	if err := envInt("PROXY_MAX_QUERY_COMPLEXITY", &config.MaxQueryComplexity); err != nil {
+		return nil, err
+	}
+
+	// Load upstream concurrency limit from environment
+	if err := envInt("PROXY_MAX_CONCURRENT_UPSTREAM", &config.MaxConcurrentUpstream); err != nil {
+		return nil, err
+	}
+
+	// Load transformation concurrency limit from environment
+	if err := envInt("PROXY_MAX_CONCURRENT_TRANSFORMS", &config.MaxConcurrentTransforms); err != nil {
+		return nil, err
+	}
+
+	// Load result size limit from environment
+	if err := envInt("PROXY_MAX_RESULT_BYTES", &config.MaxResultBytes); err != nil {
+		return nil, err
+	}
+
+	// Load result memory limit from environment
+	if err := envInt("PROXY_MAX_RESULT_MEMORY", &config.MaxResultMemory); err != nil {
+		return nil, err
+	}
+
+	// Load incremental encoding threshold from environment
+	if err := envInt("PROXY_RESPONSE_BUFFER_BYTES", &config.ResponseBufferBytes); err != nil {
+		return nil, err
+	}
+
+	// Load transformation cache size from environment
+	if err := envInt("PROXY_TRANSFORM_CACHE_SIZE", &config.TransformCacheSize); err != nil {
+		return nil, err
+	}
+
+	// Load path length limit from environment
+	if err := envInt("PROXY_MAX_PATH_LENGTH", &config.MaxPathLength); err != nil {
+		return nil, err
+	}
+
+	// Load redirect policy from environment
+	if err := envInt("PROXY_MAX_REDIRECTS", &config.MaxRedirects); err != nil {
+		return nil, err
+	}
+	if err := envBool("PROXY_DISABLE_REDIRECTS", &config.DisableRedirects); err != nil {
+		return nil, err
+	}
+
+	// Load error response format from environment
+	envString("PROXY_ERROR_FORMAT", &config.ErrorFormat)
+
+	// Load default upstream User-Agent from environment
+	envString("PROXY_USER_AGENT", &config.UserAgent)
+
+	// Load upstream proxy URL from environment
+	envString("PROXY_UPSTREAM_PROXY", &config.UpstreamProxy)
+
+	// Load jq function library from environment
+	envString("PROXY_JQ_LIBRARY", &config.JQLibrary)
+	envString("PROXY_JQ_LIBRARY_PATH", &config.JQLibraryPath)
+
+	// Load environment variables exposed to jq queries from environment
+	envList("PROXY_JQ_ENV", &config.JQEnv)
+
+	// Load default endpoint from environment
+	envString("PROXY_DEFAULT_ENDPOINT", &config.DefaultEndpoint)
+
+	// Load request ID header name from environment
+	envString("PROXY_REQUEST_ID_HEADER", &config.RequestIDHeader)
+
+	// Load admin route prefix and port from environment
+	envString("PROXY_ADMIN_PREFIX", &config.AdminPrefix)
+	if err := envInt("PROXY_ADMIN_PORT", &config.AdminPort); err != nil {
+		return nil, err
+	}
+
+	// Load debug endpoints toggle from environment
+	if err := envBool("PROXY_DEBUG_ENDPOINTS", &config.DebugEndpoints); err != nil {
+		return nil, err
+	}
+
+	// Load client IP restrictions from environment
+	envList("PROXY_IP_ALLOWLIST", &config.IPAllowlist)
+	envList("PROXY_IP_DENYLIST", &config.IPDenylist)
+	envList("PROXY_TRUSTED_PROXIES", &config.TrustedProxies)
+
+	// Load tracing settings from environment
+	if err := envBool("PROXY_TRACING_ENABLED", &config.Tracing.Enabled); err != nil {
+		return nil, err
+	}
+	envString("PROXY_TRACING_SERVICE_NAME", &config.Tracing.ServiceName)
+	envString("PROXY_TRACING_EXPORTER", &config.Tracing.Exporter)
+
+	// Load logging settings from environment
+	envString("PROXY_LOG_FORMAT", &config.Logging.Format)
+	envString("PROXY_LOG_OUTPUT", &config.Logging.Output)
+	if err := envInt("PROXY_LOG_BODY_PREVIEW_BYTES", &config.Logging.BodyPreviewBytes); err != nil {
+		return nil, err
+	}
+
+	// Load metrics settings from environment
+	if err := envInt("PROXY_METRICS_SAMPLE_SIZE", &config.Metrics.SampleSize); err != nil {
+		return nil, err
+	}
+	if err := envIntList("PROXY_METRICS_RESPONSE_BUCKETS", &config.Metrics.ResponseBuckets); err != nil {
+		return nil, err
 	}
 
 	// Validate the configuration
@@ -94,7 +228,76 @@ func loadServerConfigFromEnv() (*models.ServerConfig, error) {
 		return nil, err
 	}
 
-	return config, nil
+	return &config, nil
+}
+
+// envInt overrides dst with the integer value of the named environment variable, if set
+func envInt(name string, dst *int) error {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s value: %s", name, value)
+	}
+	*dst = parsed
+	return nil
+}
+
+// envBool overrides dst with the boolean value of the named environment variable, if set
+func envBool(name string, dst *bool) error {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s value: %s", name, value)
+	}
+	*dst = parsed
+	return nil
+}
+
+// envString overrides dst with the value of the named environment variable, if set
+func envString(name string, dst *string) {
+	if value := os.Getenv(name); value != "" {
+		*dst = value
+	}
+}
+
+// envList overrides dst with the comma-separated values of the named environment variable, if set
+func envList(name string, dst *[]string) {
+	value := os.Getenv(name)
+	if value == "" {
+		return
+	}
+	var values []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+	*dst = values
+}
+
+// envIntList overrides dst with the comma-separated integer values of the named environment variable, if set
+func envIntList(name string, dst *[]int) error {
+	var items []string
+	envList(name, &items)
+	if items == nil {
+		return nil
+	}
+	values := make([]int, len(items))
+	for i, item := range items {
+		parsed, err := strconv.Atoi(item)
+		if err != nil {
+			return fmt.Errorf("invalid %s value: %s", name, item)
+		}
+		values[i] = parsed
+	}
+	*dst = values
+	return nil
 }
 
 // loadEndpointsFromEnv loads endpoint configurations from environment variables
@@ -175,6 +378,22 @@ func loadEndpointsFromEnv() (map[string]*models.Endpoint, error) {
 	return endpoints, nil
 }
 
+// applyEndpointEnvOverrides disables the endpoints listed by key in
+// PROXY_DISABLED_ENDPOINTS
+func applyEndpointEnvOverrides(endpoints map[string]*models.Endpoint) error {
+	var disabled []string
+	envList("PROXY_DISABLED_ENDPOINTS", &disabled)
+	for _, key := range disabled {
+		endpoint, exists := endpoints[key]
+		if !exists {
+			return fmt.Errorf("PROXY_DISABLED_ENDPOINTS: unknown endpoint %s", key)
+		}
+		enabled := false
+		endpoint.Enabled = &enabled
+	}
+	return nil
+}
+
 // GetEndpoint retrieves an endpoint by name
 func (fep *FullEnvProvider) GetEndpoint(name string) (*models.Endpoint, bool) {
 	fep.mu.RLock()
@@ -184,13 +403,12 @@ func (fep *FullEnvProvider) GetEndpoint(name string) (*models.Endpoint, bool) {
 		return nil, false
 	}
 
-	endpoint, exists := fep.config.Endpoints[name]
-	return endpoint, exists
+	return fep.config.FindEndpoint(name)
 }
 
-// Reload reloads the configuration from environment variables
+// Reload re-reads the environment, replacing the cached configuration
 func (fep *FullEnvProvider) Reload() error {
-	_, err := fep.LoadConfig()
+	_, err := fep.load()
 	return err
 }
 
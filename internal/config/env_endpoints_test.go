@@ -85,6 +85,42 @@ func TestFullEnvProvider_LoadConfig(t *testing.T) {
 	}
 }
 
+func TestFullEnvProvider_LoadConfig_IPRestrictions(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+	t.Setenv("PROXY_ENDPOINT_API_TARGET", "https://api.example.com")
+	t.Setenv("PROXY_IP_ALLOWLIST", "10.0.0.0/8, 192.168.1.1")
+	t.Setenv("PROXY_IP_DENYLIST", "10.0.0.66")
+	t.Setenv("PROXY_TRUSTED_PROXIES", "172.16.0.0/12")
+
+	config, err := NewFullEnvProvider().LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.0/8", "192.168.1.1"}, config.Server.IPAllowlist)
+	assert.Equal(t, []string{"10.0.0.66"}, config.Server.IPDenylist)
+	assert.Equal(t, []string{"172.16.0.0/12"}, config.Server.TrustedProxies)
+
+	t.Setenv("PROXY_IP_DENYLIST", "10.0.0.0/64")
+	_, err = NewFullEnvProvider().LoadConfig()
+	assert.ErrorContains(t, err, "invalid deny list")
+}
+
+func TestFullEnvProvider_LoadConfig_Metrics(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+	t.Setenv("PROXY_ENDPOINT_API_TARGET", "https://api.example.com")
+	t.Setenv("PROXY_METRICS_SAMPLE_SIZE", "500")
+	t.Setenv("PROXY_METRICS_RESPONSE_BUCKETS", "10, 100,1000")
+
+	config, err := NewFullEnvProvider().LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, 500, config.Server.Metrics.SampleSize)
+	assert.Equal(t, []int{10, 100, 1000}, config.Server.Metrics.ResponseBuckets)
+
+	t.Setenv("PROXY_METRICS_RESPONSE_BUCKETS", "10,1s")
+	_, err = NewFullEnvProvider().LoadConfig()
+	assert.ErrorContains(t, err, "invalid PROXY_METRICS_RESPONSE_BUCKETS value: 1s")
+}
+
 func TestFullEnvProvider_GetEndpoint(t *testing.T) {
 	clearEnv()
 	os.Setenv("PROXY_PORT", "8080")
@@ -107,6 +143,25 @@ func TestFullEnvProvider_GetEndpoint(t *testing.T) {
 	assert.False(t, exists)
 }
 
+func TestFullEnvProvider_CachesConfig(t *testing.T) {
+	t.Setenv("PROXY_ENDPOINT_API_TARGET", "https://api1.example.com")
+
+	provider := NewFullEnvProvider()
+	_, err := provider.LoadConfig()
+	require.NoError(t, err)
+
+	// Environment changes are only picked up on reload
+	t.Setenv("PROXY_ENDPOINT_API_TARGET", "https://api2.example.com")
+	config, err := provider.LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "https://api1.example.com", config.Endpoints["API"].Target)
+
+	require.NoError(t, provider.Reload())
+	endpoint, found := provider.GetEndpoint("API")
+	require.True(t, found)
+	assert.Equal(t, "https://api2.example.com", endpoint.Target)
+}
+
 func TestLoadEndpointsFromEnv_IndividualEndpoints(t *testing.T) {
 	clearEnv()
 	os.Setenv("PROXY_ENDPOINT_USER_API_TARGET", "https://users.example.com")
@@ -125,6 +180,25 @@ func TestLoadEndpointsFromEnv_IndividualEndpoints(t *testing.T) {
 	assert.Equal(t, "post-service", endpoints["post-service"].Name)
 }
 
+func TestFullEnvProvider_DisabledEndpoints(t *testing.T) {
+	clearEnv()
+	os.Setenv("PROXY_ENDPOINT_USERS_TARGET", "https://users.example.com")
+	os.Setenv("PROXY_ENDPOINT_POSTS_TARGET", "https://posts.example.com")
+	os.Setenv("PROXY_DISABLED_ENDPOINTS", "POSTS")
+	defer clearEnv()
+
+	config, err := NewFullEnvProvider().LoadConfig()
+	require.NoError(t, err)
+	assert.True(t, config.Endpoints["USERS"].IsEnabled())
+	assert.False(t, config.Endpoints["POSTS"].IsEnabled())
+
+	// Unknown keys are reported rather than ignored
+	os.Setenv("PROXY_DISABLED_ENDPOINTS", "POSTS,COMMENTS")
+	_, err = NewFullEnvProvider().LoadConfig()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "PROXY_DISABLED_ENDPOINTS: unknown endpoint COMMENTS")
+}
+
 func TestLoadEndpointsFromEnv_JSONFormat(t *testing.T) {
 	clearEnv()
 	jsonEndpoints := `{
@@ -195,6 +269,7 @@ func clearEnv() {
 	os.Unsetenv("PROXY_READ_TIMEOUT")
 	os.Unsetenv("PROXY_WRITE_TIMEOUT")
 	os.Unsetenv("PROXY_ENDPOINTS_JSON")
+	os.Unsetenv("PROXY_DISABLED_ENDPOINTS")
 
 	// Clear all PROXY_ENDPOINT_* variables
 	for _, env := range os.Environ() {
@@ -232,6 +232,79 @@ func TestEnvProvider_GetEndpoint(t *testing.T) {
 	assert.Nil(t, endpoint)
 }
 
+func TestEnvProvider_CachesConfig(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.json")
+	writeConfig := func(target string) {
+		data := `{
+			"server": {"port": 8080, "read_timeout": 30, "write_timeout": 30},
+			"endpoints": {"service1": {"name": "service1", "target": "` + target + `"}}
+		}`
+		require.NoError(t, os.WriteFile(configFile, []byte(data), 0644))
+	}
+	writeConfig("https://api1.example.com")
+
+	provider := NewEnvProvider(configFile)
+	_, err := provider.LoadConfig()
+	require.NoError(t, err)
+
+	// With the file gone, every lookup must be served from the cached configuration
+	require.NoError(t, os.Remove(configFile))
+	for i := 0; i < 3; i++ {
+		endpoint, found := provider.GetEndpoint("service1")
+		require.True(t, found)
+		assert.Equal(t, "https://api1.example.com", endpoint.Target)
+
+		config := provider.GetConfig()
+		require.NotNil(t, config)
+		assert.Len(t, config.Endpoints, 1)
+
+		_, err := provider.LoadConfig()
+		require.NoError(t, err)
+	}
+
+	// A failed reload keeps the cached configuration
+	assert.Error(t, provider.Reload())
+	endpoint, found := provider.GetEndpoint("service1")
+	require.True(t, found)
+	assert.Equal(t, "https://api1.example.com", endpoint.Target)
+
+	// A successful reload replaces it
+	writeConfig("https://api2.example.com")
+	require.NoError(t, provider.Reload())
+	endpoint, found = provider.GetEndpoint("service1")
+	require.True(t, found)
+	assert.Equal(t, "https://api2.example.com", endpoint.Target)
+}
+
+func TestEnvProvider_DisabledEndpoints(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(configFile, []byte(`{
+		"server": {"port": 8080},
+		"endpoints": {
+			"service1": {"name": "service1", "target": "https://api1.example.com"},
+			"service2": {"name": "service2", "target": "https://api2.example.com", "enabled": false}
+		}
+	}`), 0644))
+
+	provider := NewEnvProvider(configFile)
+	require.NoError(t, provider.Reload())
+	endpoint, _ := provider.GetEndpoint("service1")
+	assert.True(t, endpoint.IsEnabled())
+	endpoint, _ = provider.GetEndpoint("service2")
+	assert.False(t, endpoint.IsEnabled())
+
+	// The environment disables endpoints until it is cleared and the config reloaded
+	t.Setenv("PROXY_DISABLED_ENDPOINTS", "service1")
+	require.NoError(t, provider.Reload())
+	endpoint, _ = provider.GetEndpoint("service1")
+	assert.False(t, endpoint.IsEnabled())
+
+	os.Unsetenv("PROXY_DISABLED_ENDPOINTS")
+	require.NoError(t, provider.Reload())
+	endpoint, _ = provider.GetEndpoint("service1")
+	assert.True(t, endpoint.IsEnabled())
+}
+
 func TestEnvProvider_FileNotFound(t *testing.T) {
 	provider := NewEnvProvider("nonexistent.json")
 	config, err := provider.LoadConfig()
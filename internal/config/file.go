@@ -11,32 +11,38 @@ import (
 
 // FileProvider implements ConfigProvider for file-based configuration
 type FileProvider struct {
-	filePath string
-	config   *models.ProxyConfig
-	mutex    sync.RWMutex
+	filePaths []string
+	config    *models.ProxyConfig
+	mutex     sync.RWMutex
+
+	// endpointDir holds additional endpoint files; empty reads none
+	endpointDir string
 }
 
-// NewFileProvider creates a new file-based configuration provider
-func NewFileProvider(filePath string) *FileProvider {
+// NewFileProvider creates a new file-based configuration provider. Several
+// files are deep-merged in order, later files overriding earlier ones.
+func NewFileProvider(filePaths ...string) *FileProvider {
 	return &FileProvider{
-		filePath: filePath,
+		filePaths: filePaths,
 	}
 }
 
-// LoadConfig loads configuration from the file
+// LoadConfig loads configuration from the file, or the merged files
 func (fp *FileProvider) LoadConfig() (*models.ProxyConfig, error) {
 	fp.mutex.Lock()
 	defer fp.mutex.Unlock()
 
-	// Check if file exists
-	if _, err := os.Stat(fp.filePath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("configuration file not found: %s", fp.filePath)
+	var data []byte
+	var err error
+	if fp.endpointDir != "" {
+		data, err = fp.readDirConfig()
+	} else if len(fp.filePaths) == 1 {
+		data, err = readConfigFile(fp.filePaths[0])
+	} else {
+		data, err = mergeConfigFiles(fp.filePaths)
 	}
-
-	// Read file content
-	data, err := os.ReadFile(fp.filePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read configuration file: %w", err)
+		return nil, err
 	}
 
 	// Parse configuration
@@ -51,6 +57,23 @@ func (fp *FileProvider) LoadConfig() (*models.ProxyConfig, error) {
 	return config, nil
 }
 
+// readConfigFile reads a configuration file, expanding ${VAR} references so
+// secrets can stay out of the file
+func readConfigFile(filePath string) ([]byte, error) {
+	// Check if file exists
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("configuration file not found: %s", filePath)
+	}
+
+	// Read file content
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read configuration file: %w", err)
+	}
+
+	return interpolateEnv(data)
+}
+
 // GetEndpoint retrieves an endpoint by name
 func (fp *FileProvider) GetEndpoint(name string) (*models.Endpoint, bool) {
 	fp.mutex.RLock()
@@ -60,8 +83,7 @@ func (fp *FileProvider) GetEndpoint(name string) (*models.Endpoint, bool) {
 		return nil, false
 	}
 
-	endpoint, exists := fp.config.Endpoints[name]
-	return endpoint, exists
+	return fp.config.FindEndpoint(name)
 }
 
 // Reload reloads the configuration from the file
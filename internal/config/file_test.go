@@ -120,6 +120,171 @@ func TestFileProvider_LoadConfig_FileNotFound(t *testing.T) {
 	assert.Nil(t, config)
 }
 
+func TestFileProvider_LoadConfig_MultipleFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	writeFile := func(name, data string) string {
+		path := filepath.Join(tempDir, name)
+		require.NoError(t, os.WriteFile(path, []byte(data), 0644))
+		return path
+	}
+
+	base := writeFile("base.json", `{
+		"server": {"port": 8080, "read_timeout": 30, "write_timeout": 30, "max_result_bytes": 1000},
+		"endpoints": {
+			"users": {"name": "users", "target": "https://users.example.com", "cache_ttl": 60},
+			"posts": {"name": "posts", "target": "https://posts.example.com"}
+		}
+	}`)
+	override := writeFile("production.json", `{
+		"server": {"port": 9090},
+		"endpoints": {
+			"users": {"target": "https://users.prod.example.com"},
+			"billing": {"name": "billing", "target": "https://billing.example.com"}
+		}
+	}`)
+
+	t.Run("later files override earlier ones", func(t *testing.T) {
+		config, err := NewFileProvider(base, override).LoadConfig()
+		require.NoError(t, err)
+
+		// Server settings are merged field by field
+		assert.Equal(t, 9090, config.Server.Port)
+		assert.Equal(t, 30, config.Server.ReadTimeout)
+		assert.Equal(t, 1000, config.Server.MaxResultBytes)
+
+		// Endpoints are the union, with overridden fields replaced
+		require.Len(t, config.Endpoints, 3)
+		assert.Equal(t, "https://users.prod.example.com", config.Endpoints["users"].Target)
+		assert.Equal(t, 60, config.Endpoints["users"].CacheTTL)
+		assert.Equal(t, "https://posts.example.com", config.Endpoints["posts"].Target)
+		assert.Equal(t, "https://billing.example.com", config.Endpoints["billing"].Target)
+	})
+
+	t.Run("order determines precedence", func(t *testing.T) {
+		config, err := NewFileProvider(override, base).LoadConfig()
+		require.NoError(t, err)
+		assert.Equal(t, 8080, config.Server.Port)
+		assert.Equal(t, "https://users.example.com", config.Endpoints["users"].Target)
+	})
+
+	t.Run("merged result is validated", func(t *testing.T) {
+		invalid := writeFile("invalid.json", `{"endpoints": {"posts": {"target": "ftp://posts.example.com"}}}`)
+		_, err := NewFileProvider(base, invalid).LoadConfig()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "validation failed")
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := NewFileProvider(base, filepath.Join(tempDir, "missing.json")).LoadConfig()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "configuration file not found")
+	})
+
+	t.Run("malformed file", func(t *testing.T) {
+		malformed := writeFile("malformed.json", `{"server": {`)
+		_, err := NewFileProvider(base, malformed).LoadConfig()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "malformed.json")
+	})
+}
+
+func TestDirProvider_LoadConfig(t *testing.T) {
+	writeFile := func(dir, name, data string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(data), 0644))
+		return path
+	}
+	endpointDir := func(files map[string]string) string {
+		dir := t.TempDir()
+		for name, data := range files {
+			writeFile(dir, name, data)
+		}
+		return dir
+	}
+
+	users := `{"endpoints": {"users": {"name": "users", "target": "https://users.example.com"}}}`
+	billing := `{"endpoints": {
+		"invoices": {"name": "invoices", "target": "https://billing.example.com/invoices"},
+		"payments": {"name": "payments", "target": "https://billing.example.com/payments"}
+	}}`
+
+	t.Run("endpoints from every file", func(t *testing.T) {
+		dir := endpointDir(map[string]string{
+			"users.json":   users,
+			"billing.json": billing,
+			"README.md":    "not configuration",
+		})
+		require.NoError(t, os.Mkdir(filepath.Join(dir, "nested.json"), 0755))
+
+		config, err := NewDirProvider(dir).LoadConfig()
+		require.NoError(t, err)
+		assert.Len(t, config.Endpoints, 3)
+		assert.Equal(t, "https://billing.example.com/payments", config.Endpoints["payments"].Target)
+		assert.Equal(t, 8080, config.Server.Port, "server settings default without a configuration file")
+
+		endpoint, found := NewDirProvider(dir).GetEndpoint("users")
+		assert.False(t, found, "nothing is found before loading")
+		assert.Nil(t, endpoint)
+	})
+
+	t.Run("merged with configuration files", func(t *testing.T) {
+		base := writeFile(t.TempDir(), "config.json", `{
+			"server": {"port": 9090, "read_timeout": 30, "write_timeout": 30},
+			"endpoints": {"posts": {"name": "posts", "target": "https://posts.example.com"}}
+		}`)
+		config, err := NewDirProvider(endpointDir(map[string]string{"users.json": users}), base).LoadConfig()
+		require.NoError(t, err)
+		assert.Equal(t, 9090, config.Server.Port)
+		assert.Len(t, config.Endpoints, 2)
+	})
+
+	t.Run("duplicate endpoint across files", func(t *testing.T) {
+		dir := endpointDir(map[string]string{
+			"a.json": users,
+			"b.json": `{"endpoints": {"users": {"name": "users", "target": "https://other.example.com"}}}`,
+		})
+		_, err := NewDirProvider(dir).LoadConfig()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "endpoint users is defined in both "+filepath.Join(dir, "a.json")+" and "+filepath.Join(dir, "b.json"))
+	})
+
+	t.Run("duplicate endpoint in the configuration file", func(t *testing.T) {
+		base := writeFile(t.TempDir(), "config.json", `{
+			"server": {"port": 8080, "read_timeout": 30, "write_timeout": 30},
+			"endpoints": {"users": {"name": "users", "target": "https://users.example.com"}}
+		}`)
+		_, err := NewDirProvider(endpointDir(map[string]string{"users.json": users}), base).LoadConfig()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "endpoint users is defined in both the main configuration and")
+	})
+
+	t.Run("invalid files", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			data   string
+			errMsg string
+		}{
+			{name: "malformed", data: `{"endpoints": {`, errMsg: "failed to parse endpoint file"},
+			{name: "server settings", data: `{"server": {"port": 9090}, "endpoints": {}}`, errMsg: "unknown field"},
+			{name: "no endpoints", data: `{"endpoints": {}}`, errMsg: "defines no endpoints"},
+			{name: "invalid endpoint", data: `{"endpoints": {"users": {"name": "users", "target": "ftp://users"}}}`, errMsg: "validation failed"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := NewDirProvider(endpointDir(map[string]string{"endpoints.json": tt.data})).LoadConfig()
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errMsg)
+			})
+		}
+	})
+
+	t.Run("missing directory", func(t *testing.T) {
+		_, err := NewDirProvider(filepath.Join(t.TempDir(), "missing")).LoadConfig()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to read endpoint directory")
+	})
+}
+
 func TestFileProvider_GetEndpoint(t *testing.T) {
 	// Create a temporary config file
 	tempDir, err := ioutil.TempDir("", "config_test")
@@ -193,6 +358,44 @@ func TestFileProvider_GetEndpoint(t *testing.T) {
 	}
 }
 
+func TestFileProvider_GetEndpoint_Pattern(t *testing.T) {
+	tempDir := t.TempDir()
+
+	configData := `{
+		"server": {
+			"port": 8080,
+			"read_timeout": 30,
+			"write_timeout": 30
+		},
+		"endpoints": {
+			"service-a": {
+				"name": "service-a",
+				"target": "https://a.example.com"
+			},
+			"service-*": {
+				"name": "service-*",
+				"target": "https://{1}.internal.example.com"
+			}
+		}
+	}`
+
+	configFile := filepath.Join(tempDir, "config.json")
+	require.NoError(t, os.WriteFile(configFile, []byte(configData), 0644))
+
+	provider := NewFileProvider(configFile)
+	_, err := provider.LoadConfig()
+	require.NoError(t, err)
+
+	endpoint, found := provider.GetEndpoint("service-a")
+	require.True(t, found)
+	assert.Equal(t, "https://a.example.com", endpoint.Target)
+
+	endpoint, found = provider.GetEndpoint("service-b")
+	require.True(t, found)
+	assert.Equal(t, "service-b", endpoint.Name)
+	assert.Equal(t, "https://b.internal.example.com", endpoint.Target)
+}
+
 func TestFileProvider_GetEndpoint_NoConfigLoaded(t *testing.T) {
 	provider := NewFileProvider("nonexistent.json")
 	endpoint, found := provider.GetEndpoint("service1")
@@ -327,3 +530,55 @@ func TestFileProvider_ThreadSafety(t *testing.T) {
 	assert.True(t, found)
 	assert.NotNil(t, endpoint)
 }
+
+func TestFileProvider_LoadConfig_EnvInterpolation(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.json")
+	configData := `{
+		"server": {
+			"port": 8080,
+			"read_timeout": 30,
+			"write_timeout": 30
+		},
+		"endpoints": {
+			"api": {
+				"name": "api",
+				"target": "https://${TEST_API_HOST}/v1"
+			}
+		}
+	}`
+	require.NoError(t, os.WriteFile(configFile, []byte(configData), 0644))
+
+	t.Run("defined variable is expanded", func(t *testing.T) {
+		t.Setenv("TEST_API_HOST", `api.example.com`)
+
+		provider := NewFileProvider(configFile)
+		config, err := provider.LoadConfig()
+		require.NoError(t, err)
+		assert.Equal(t, "https://api.example.com/v1", config.Endpoints["api"].Target)
+		assert.Equal(t, 8080, config.Server.Port)
+	})
+
+	t.Run("undefined variable is an error", func(t *testing.T) {
+		t.Setenv("TEST_API_HOST", "")
+		os.Unsetenv("TEST_API_HOST")
+
+		provider := NewFileProvider(configFile)
+		config, err := provider.LoadConfig()
+		assert.Error(t, err)
+		assert.Nil(t, config)
+		assert.Contains(t, err.Error(), "undefined environment variable(s) referenced in configuration: TEST_API_HOST")
+	})
+}
+
+func TestInterpolateEnv(t *testing.T) {
+	t.Setenv("TEST_QUOTED", `va"lue`)
+	t.Setenv("TEST_EMPTY", "")
+
+	data, err := interpolateEnv([]byte(`{"a": "${TEST_QUOTED}", "b": ["x${TEST_EMPTY}y"], "c": 1, "d": "$HOME"}`))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"a": "va\"lue", "b": ["xy"], "c": 1, "d": "$HOME"}`, string(data))
+
+	_, err = interpolateEnv([]byte(`{"a": "${TEST_MISSING_B}", "b": "${TEST_MISSING_A}"}`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "TEST_MISSING_A, TEST_MISSING_B")
+}
@@ -0,0 +1,80 @@
+// Package config provides configuration loading and management functionality.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// envReferencePattern matches ${VAR} references in configuration strings
+var envReferencePattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// interpolateEnv expands ${VAR} references in every JSON string value (and
+// object key) of a configuration document. Expansion happens on the decoded
+// values so substituted secrets never need JSON escaping. References to
+// undefined variables are reported together in a single error.
+func interpolateEnv(data []byte) ([]byte, error) {
+	if !envReferencePattern.Match(data) {
+		return data, nil
+	}
+
+	var document interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&document); err != nil {
+		// Leave malformed JSON for the parser to report
+		return data, nil
+	}
+
+	missing := make(map[string]bool)
+	document = expandValue(document, missing)
+
+	if len(missing) > 0 {
+		names := make([]string, 0, len(missing))
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("undefined environment variable(s) referenced in configuration: %s", strings.Join(names, ", "))
+	}
+
+	return json.Marshal(document)
+}
+
+// expandValue recursively expands environment references in strings
+func expandValue(value interface{}, missing map[string]bool) interface{} {
+	switch v := value.(type) {
+	case string:
+		return expandString(v, missing)
+	case map[string]interface{}:
+		expanded := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			expanded[expandString(key, missing)] = expandValue(item, missing)
+		}
+		return expanded
+	case []interface{}:
+		for i, item := range v {
+			v[i] = expandValue(item, missing)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// expandString replaces ${VAR} references with their values, recording undefined variables
+func expandString(s string, missing map[string]bool) string {
+	return envReferencePattern.ReplaceAllStringFunc(s, func(reference string) string {
+		name := reference[2 : len(reference)-1]
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			missing[name] = true
+		}
+		return value
+	})
+}
@@ -0,0 +1,46 @@
+// Package config provides configuration loading and management functionality.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// mergeConfigFiles reads several configuration files and deep-merges them in
+// order. Only the merged document has to be a complete configuration, so
+// override files may hold just the settings they change.
+func mergeConfigFiles(filePaths []string) ([]byte, error) {
+	merged := make(map[string]interface{})
+	for _, filePath := range filePaths {
+		data, err := readConfigFile(filePath)
+		if err != nil {
+			return nil, err
+		}
+
+		var document map[string]interface{}
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&document); err != nil {
+			return nil, fmt.Errorf("failed to parse configuration file %s: %w", filePath, err)
+		}
+
+		merged = mergeObjects(merged, document)
+	}
+	return json.Marshal(merged)
+}
+
+// mergeObjects merges override into base. Nested objects are merged
+// recursively; any other value in override replaces the one in base.
+func mergeObjects(base, override map[string]interface{}) map[string]interface{} {
+	for key, value := range override {
+		overrideObject, isObject := value.(map[string]interface{})
+		baseObject, baseIsObject := base[key].(map[string]interface{})
+		if isObject && baseIsObject {
+			base[key] = mergeObjects(baseObject, overrideObject)
+		} else {
+			base[key] = value
+		}
+	}
+	return base
+}
@@ -0,0 +1,90 @@
+// Package ipfilter decides whether client IP addresses may use the proxy.
+package ipfilter
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Filter checks client IPs against allow and deny lists of networks. An IP
+// in the deny list is always rejected; when the allow list is non-empty, only
+// IPs in it are accepted. It is safe for concurrent use.
+type Filter struct {
+	allow []*net.IPNet
+	deny  []*net.IPNet
+}
+
+// New parses the allow and deny lists into a Filter
+func New(allow, deny []string) (*Filter, error) {
+	allowNets, err := ParseCIDRs(allow)
+	if err != nil {
+		return nil, fmt.Errorf("invalid allow list: %w", err)
+	}
+	denyNets, err := ParseCIDRs(deny)
+	if err != nil {
+		return nil, fmt.Errorf("invalid deny list: %w", err)
+	}
+	return &Filter{allow: allowNets, deny: denyNets}, nil
+}
+
+// ParseCIDRs parses networks in CIDR notation. Bare IP addresses are
+// accepted as single-host networks.
+func ParseCIDRs(entries []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address or CIDR: %q", entry)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP address or CIDR: %q", entry)
+		}
+		nets = append(nets, network)
+	}
+	return nets, nil
+}
+
+// Enabled reports whether the filter restricts any addresses
+func (f *Filter) Enabled() bool {
+	return len(f.allow) > 0 || len(f.deny) > 0
+}
+
+// Allowed reports whether the client IP may use the proxy. Unparseable
+// addresses are rejected whenever the filter is enabled.
+func (f *Filter) Allowed(clientIP string) bool {
+	if !f.Enabled() {
+		return true
+	}
+
+	ip := net.ParseIP(clientIP)
+	if ip == nil {
+		return false
+	}
+
+	if Contains(f.deny, ip) {
+		return false
+	}
+	return len(f.allow) == 0 || Contains(f.allow, ip)
+}
+
+// Contains reports whether ip lies in any of the networks
+func Contains(nets []*net.IPNet, ip net.IP) bool {
+	for _, network := range nets {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
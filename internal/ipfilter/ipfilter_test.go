@@ -0,0 +1,45 @@
+package ipfilter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilter_Allowed(t *testing.T) {
+	tests := []struct {
+		name    string
+		allow   []string
+		deny    []string
+		ip      string
+		allowed bool
+	}{
+		{name: "no lists", ip: "203.0.113.7", allowed: true},
+		{name: "in allow list", allow: []string{"10.0.0.0/8"}, ip: "10.1.2.3", allowed: true},
+		{name: "outside allow list", allow: []string{"10.0.0.0/8"}, ip: "192.168.1.1", allowed: false},
+		{name: "bare IP in allow list", allow: []string{"192.168.1.1"}, ip: "192.168.1.1", allowed: true},
+		{name: "in deny list", deny: []string{"203.0.113.0/24"}, ip: "203.0.113.7", allowed: false},
+		{name: "outside deny list", deny: []string{"203.0.113.0/24"}, ip: "198.51.100.1", allowed: true},
+		{name: "deny wins over allow", allow: []string{"10.0.0.0/8"}, deny: []string{"10.0.0.5"}, ip: "10.0.0.5", allowed: false},
+		{name: "IPv6 in allow list", allow: []string{"2001:db8::/32"}, ip: "2001:db8::1", allowed: true},
+		{name: "IPv4-mapped IPv6 address", allow: []string{"10.0.0.0/8"}, ip: "::ffff:10.0.0.1", allowed: true},
+		{name: "unparseable address", allow: []string{"10.0.0.0/8"}, ip: "not-an-ip", allowed: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := New(tt.allow, tt.deny)
+			require.NoError(t, err)
+			assert.Equal(t, tt.allowed, filter.Allowed(tt.ip))
+		})
+	}
+}
+
+func TestNew_InvalidEntries(t *testing.T) {
+	_, err := New([]string{"10.0.0.0/33"}, nil)
+	assert.ErrorContains(t, err, "invalid allow list")
+
+	_, err = New(nil, []string{"example.com"})
+	assert.ErrorContains(t, err, "invalid deny list")
+}
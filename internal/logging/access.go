@@ -0,0 +1,109 @@
+// Package logging provides structured logging and metrics collection functionality.
+package logging
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// AccessInfoKey is the context key for per-request access log details
+	AccessInfoKey ContextKey = "access_info"
+)
+
+// AccessInfo collects details about a proxied request that are only known
+// deep inside the service, so the request logging middleware can report them.
+// All methods are safe to call on a nil *AccessInfo.
+type AccessInfo struct {
+	mu             sync.Mutex
+	endpoint       string
+	upstreamStatus int
+	upstreamBytes  int
+	transformError bool
+	minimal        bool
+}
+
+// WithAccessInfoContext adds a new AccessInfo to the context
+func WithAccessInfoContext(ctx context.Context) (context.Context, *AccessInfo) {
+	info := &AccessInfo{}
+	return context.WithValue(ctx, AccessInfoKey, info), info
+}
+
+// GetAccessInfo retrieves the AccessInfo from context, or nil if none is present
+func GetAccessInfo(ctx context.Context) *AccessInfo {
+	info, _ := ctx.Value(AccessInfoKey).(*AccessInfo)
+	return info
+}
+
+// SetEndpoint records the resolved endpoint name
+func (a *AccessInfo) SetEndpoint(name string) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.endpoint = name
+}
+
+// SetUpstream records the upstream status code and response size
+func (a *AccessInfo) SetUpstream(statusCode, bytes int) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.upstreamStatus = statusCode
+	a.upstreamBytes = bytes
+}
+
+// SetTransformError records that the transformation step failed
+func (a *AccessInfo) SetTransformError() {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.transformError = true
+}
+
+// SetMinimal records that the request's path and other details must not be logged
+func (a *AccessInfo) SetMinimal() {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.minimal = true
+}
+
+// Minimal reports whether the request must be logged without its details
+func (a *AccessInfo) Minimal() bool {
+	if a == nil {
+		return false
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.minimal
+}
+
+// Fields returns the recorded details as log fields, omitting unset values
+func (a *AccessInfo) Fields() logrus.Fields {
+	fields := logrus.Fields{}
+	if a == nil {
+		return fields
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.endpoint != "" {
+		fields["endpoint"] = a.endpoint
+		fields["transform_error"] = a.transformError
+	}
+	if a.upstreamStatus != 0 {
+		fields["upstream_status"] = a.upstreamStatus
+		fields["upstream_bytes"] = a.upstreamBytes
+	}
+	return fields
+}
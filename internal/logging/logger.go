@@ -3,6 +3,10 @@ package logging
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,37 +19,143 @@ type ContextKey string
 const (
 	// RequestIDKey is the context key for request ID
 	RequestIDKey ContextKey = "request_id"
+
+	// RequestIDHeader is the HTTP header used to receive and return the request ID
+	RequestIDHeader = "X-Request-ID"
+
+	// maxRequestIDLength bounds client-supplied request IDs
+	maxRequestIDLength = 128
+)
+
+// Supported log formats
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// Special log output destinations; any other value is treated as a file path
+const (
+	OutputStdout = "stdout"
+	OutputStderr = "stderr"
 )
 
+// Options configures a logger. Empty fields fall back to JSON on stdout.
+type Options struct {
+	Level  string
+	Format string
+	Output string
+}
+
 // Logger wraps logrus.Logger with additional functionality
 type Logger struct {
 	*logrus.Logger
 	metrics *Metrics
+
+	mu   sync.Mutex
+	file *os.File
 }
 
 // NewLogger creates a new logger instance with metrics
 func NewLogger(level string) (*Logger, error) {
-	logger := logrus.New()
-	logger.SetFormatter(&logrus.JSONFormatter{
-		TimestampFormat: time.RFC3339Nano,
-		FieldMap: logrus.FieldMap{
-			logrus.FieldKeyTime:  "timestamp",
-			logrus.FieldKeyLevel: "level",
-			logrus.FieldKeyMsg:   "message",
-		},
-	})
+	return NewLoggerWithOptions(Options{Level: level})
+}
+
+// NewLoggerWithOptions creates a new logger instance with metrics and the given output options
+func NewLoggerWithOptions(opts Options) (*Logger, error) {
+	logger := &Logger{
+		Logger:  logrus.New(),
+		metrics: NewMetrics(),
+	}
 
 	// Parse and set log level
-	logLevel, err := logrus.ParseLevel(level)
+	logLevel, err := logrus.ParseLevel(opts.Level)
 	if err != nil {
 		return nil, err
 	}
 	logger.SetLevel(logLevel)
 
-	return &Logger{
-		Logger:  logger,
-		metrics: NewMetrics(),
-	}, nil
+	if err := logger.Configure(opts.Format, opts.Output); err != nil {
+		return nil, err
+	}
+
+	return logger, nil
+}
+
+// Configure sets the log format and output destination. Empty values select
+// JSON and stdout. A previously opened log file is closed when replaced.
+func (l *Logger) Configure(format, output string) error {
+	formatter, err := newFormatter(format)
+	if err != nil {
+		return err
+	}
+
+	var writer io.Writer
+	var file *os.File
+	switch output {
+	case "", OutputStdout:
+		writer = os.Stdout
+	case OutputStderr:
+		writer = os.Stderr
+	default:
+		file, err = os.OpenFile(output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open log file: %w", err)
+		}
+		writer = file
+	}
+
+	l.SetFormatter(formatter)
+	l.SetOutput(writer)
+
+	l.mu.Lock()
+	previous := l.file
+	l.file = file
+	l.mu.Unlock()
+
+	if previous != nil {
+		previous.Close()
+	}
+
+	return nil
+}
+
+// Close closes the log file, if logging to one
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// newFormatter returns the logrus formatter for the named format
+func newFormatter(format string) (logrus.Formatter, error) {
+	fieldMap := logrus.FieldMap{
+		logrus.FieldKeyTime:  "timestamp",
+		logrus.FieldKeyLevel: "level",
+		logrus.FieldKeyMsg:   "message",
+	}
+
+	switch format {
+	case "", FormatJSON:
+		return &logrus.JSONFormatter{
+			TimestampFormat: time.RFC3339Nano,
+			FieldMap:        fieldMap,
+		}, nil
+	case FormatText:
+		return &logrus.TextFormatter{
+			TimestampFormat: time.RFC3339Nano,
+			FullTimestamp:   true,
+			DisableColors:   true,
+			FieldMap:        fieldMap,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported log format: %s", format)
+	}
 }
 
 // WithRequestID adds a request ID to the logger context
@@ -78,6 +188,26 @@ func GetRequestID(ctx context.Context) string {
 	return "unknown"
 }
 
+// RequestIDFromContext retrieves the request ID from context, or an empty string if none is set
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(RequestIDKey).(string)
+	return requestID
+}
+
+// IsValidRequestID reports whether a client-supplied request ID is safe to reuse.
+// IDs must be non-empty, bounded in length and consist of visible ASCII characters.
+func IsValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(requestID); i++ {
+		if requestID[i] < '!' || requestID[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
 // WithRequestIDContext adds a request ID to the context
 func WithRequestIDContext(ctx context.Context, requestID string) context.Context {
 	return context.WithValue(ctx, RequestIDKey, requestID)
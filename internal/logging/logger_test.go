@@ -2,6 +2,10 @@ package logging
 
 import (
 	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/sirupsen/logrus"
@@ -151,3 +155,139 @@ func TestGetMetrics(t *testing.T) {
 		t.Error("Expected non-nil metrics")
 	}
 }
+
+func TestNewLoggerWithOptions_Format(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		want    logrus.Formatter
+		wantErr bool
+	}{
+		{name: "default is JSON", format: "", want: &logrus.JSONFormatter{}},
+		{name: "json", format: FormatJSON, want: &logrus.JSONFormatter{}},
+		{name: "text", format: FormatText, want: &logrus.TextFormatter{}},
+		{name: "invalid", format: "xml", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, err := NewLoggerWithOptions(Options{Level: "info", Format: tt.format})
+
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if reflect.TypeOf(logger.Formatter) != reflect.TypeOf(tt.want) {
+				t.Errorf("Expected formatter %T, got %T", tt.want, logger.Formatter)
+			}
+		})
+	}
+}
+
+func TestNewLoggerWithOptions_Output(t *testing.T) {
+	logger, err := NewLoggerWithOptions(Options{Level: "info", Output: OutputStderr})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if logger.Out != os.Stderr {
+		t.Error("Expected stderr output")
+	}
+
+	logger, err = NewLoggerWithOptions(Options{Level: "info"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if logger.Out != os.Stdout {
+		t.Error("Expected stdout output by default")
+	}
+}
+
+func TestNewLoggerWithOptions_FileOutput(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "proxy.log")
+
+	logger, err := NewLoggerWithOptions(Options{Level: "info", Format: FormatText, Output: logFile})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	logger.WithField("endpoint", "user-service").Info("hello file")
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Failed to close logger: %v", err)
+	}
+
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	content := string(data)
+	if !strings.Contains(content, `message="hello file"`) || !strings.Contains(content, "endpoint=user-service") {
+		t.Errorf("Unexpected log file content: %s", content)
+	}
+}
+
+func TestNewLoggerWithOptions_InvalidFile(t *testing.T) {
+	_, err := NewLoggerWithOptions(Options{Level: "info", Output: filepath.Join(t.TempDir(), "missing", "proxy.log")})
+	if err == nil {
+		t.Error("Expected error for unwritable log file")
+	}
+}
+
+func TestBodyPreview(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		maxBytes int
+		want     string
+	}{
+		{
+			name:     "short body unchanged",
+			body:     `{"id": 1}`,
+			maxBytes: 100,
+			want:     `{"id": 1}`,
+		},
+		{
+			name:     "long body truncated",
+			body:     `abcdefghijklmnopqrstuvwxyz`,
+			maxBytes: 10,
+			want:     `abcdefghij... (16 bytes truncated)`,
+		},
+		{
+			name:     "sensitive keys redacted",
+			body:     `{"user": "john", "password": "hunter2", "apiKey": "abc", "access_token": "x\"y"}`,
+			maxBytes: 0,
+			want:     `{"user": "john", "password": "[REDACTED]", "apiKey": "[REDACTED]", "access_token": "[REDACTED]"}`,
+		},
+		{
+			name:     "bearer tokens redacted",
+			body:     `upstream rejected Bearer eyJhbGciOi.payload.sig`,
+			maxBytes: 0,
+			want:     `upstream rejected Bearer [REDACTED]`,
+		},
+		{
+			name:     "redacted before truncation",
+			body:     `{"secret": "0123456789abcdef"}`,
+			maxBytes: 20,
+			want:     `{"secret": "[REDACTE... (4 bytes truncated)`,
+		},
+		{
+			name:     "truncation keeps runes whole",
+			body:     `héllo`,
+			maxBytes: 2,
+			want:     `h... (5 bytes truncated)`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BodyPreview([]byte(tt.body), tt.maxBytes); got != tt.want {
+				t.Errorf("BodyPreview() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
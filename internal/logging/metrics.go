@@ -2,25 +2,127 @@
 package logging
 
 import (
+	"encoding/json"
+	"math"
+	"slices"
 	"sync"
 	"time"
 )
 
+// defaultSampleSize bounds how many recent durations are kept for computing
+// percentiles when no sample size is set
+const defaultSampleSize = 1000
+
+// durationSamples is a ring of the most recent durations
+type durationSamples struct {
+	values []time.Duration
+	next   int
+	size   int
+}
+
+// add records a duration, replacing the oldest once the ring is full
+func (ds *durationSamples) add(d time.Duration) {
+	if ds.size == 0 {
+		ds.size = defaultSampleSize
+	}
+	if len(ds.values) < ds.size {
+		ds.values = append(ds.values, d)
+		return
+	}
+	ds.values[ds.next] = d
+	ds.next = (ds.next + 1) % ds.size
+}
+
+// resize changes how many durations are kept, keeping the most recent ones
+func (ds *durationSamples) resize(size int) {
+	recent := append(slices.Clone(ds.values[ds.next:]), ds.values[:ds.next]...)
+	if len(recent) > size {
+		recent = recent[len(recent)-size:]
+	}
+	ds.values = recent
+	ds.next = 0
+	ds.size = size
+}
+
+// summarize fills in the percentiles of the kept durations
+func (ds *durationSamples) summarize(timings *Timings) {
+	if len(ds.values) == 0 {
+		return
+	}
+	sorted := slices.Clone(ds.values)
+	slices.Sort(sorted)
+	timings.P50 = percentile(sorted, 50)
+	timings.P95 = percentile(sorted, 95)
+	timings.P99 = percentile(sorted, 99)
+}
+
+// histogram counts durations into cumulative buckets, Prometheus style
+type histogram struct {
+	bounds []time.Duration
+	counts []int64
+	total  int64
+}
+
+// observe counts a duration in every bucket whose bound it does not exceed
+func (h *histogram) observe(d time.Duration) {
+	if len(h.bounds) == 0 {
+		return
+	}
+	h.total++
+	for i, bound := range h.bounds {
+		if d <= bound {
+			h.counts[i]++
+		}
+	}
+}
+
+// buckets returns the bucket counts ending with the +Inf bucket, or nil when
+// no buckets are set
+func (h *histogram) buckets() []Bucket {
+	if len(h.bounds) == 0 {
+		return nil
+	}
+	buckets := make([]Bucket, len(h.bounds), len(h.bounds)+1)
+	for i, bound := range h.bounds {
+		buckets[i] = Bucket{UpperBound: bound, Count: h.counts[i]}
+	}
+	return append(buckets, Bucket{UpperBound: InfiniteBound, Count: h.total})
+}
+
 // Metrics collects application metrics
 type Metrics struct {
 	mu                sync.RWMutex
 	requestCount      int64
 	errorCount        int64
+	cacheHitCount     int64
+	cacheMissCount    int64
+	coalescedCount    int64
+	inFlight          int64
 	totalResponseTime time.Duration
+	responseSamples   durationSamples
+	responseHistogram histogram
 	endpointMetrics   map[string]*EndpointMetrics
+
+	// Transformation timings
+	transformCount     int64
+	totalTransformTime time.Duration
+	transformSamples   durationSamples
 }
 
 // EndpointMetrics tracks metrics for a specific endpoint
 type EndpointMetrics struct {
-	RequestCount      int64
-	ErrorCount        int64
-	TotalResponseTime time.Duration
-	AvgResponseTime   time.Duration
+	RequestCount       int64
+	ErrorCount         int64
+	CacheHits          int64
+	CacheMisses        int64
+	CoalescedRequests  int64
+	TotalResponseTime  time.Duration
+	AvgResponseTime    time.Duration
+	ThrottledRequests  int64
+	ThrottleWaitTime   time.Duration
+	TransformCount     int64
+	TotalTransformTime time.Duration
+	AvgTransformTime   time.Duration
 }
 
 // NewMetrics creates a new metrics collector
@@ -30,6 +132,31 @@ func NewMetrics() *Metrics {
 	}
 }
 
+// SetSampleSize sets how many recent response and transformation times are
+// kept for computing percentiles; zero or less keeps the default of 1000
+func (m *Metrics) SetSampleSize(size int) {
+	if size <= 0 {
+		size = defaultSampleSize
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.responseSamples.resize(size)
+	m.transformSamples.resize(size)
+}
+
+// SetResponseBuckets sets the upper bounds of the response time histogram.
+// Bounds are sorted and deduplicated, and a +Inf bucket is always added;
+// counting starts over from zero, and no bounds turns the histogram off.
+func (m *Metrics) SetResponseBuckets(bounds []time.Duration) {
+	sorted := slices.Compact(slices.Sorted(slices.Values(bounds)))
+	sorted = slices.DeleteFunc(sorted, func(bound time.Duration) bool { return bound == InfiniteBound })
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.responseHistogram = histogram{bounds: sorted, counts: make([]int64, len(sorted))}
+}
+
 // RecordRequest records a successful request
 func (m *Metrics) RecordRequest(endpoint string, duration time.Duration) {
 	m.mu.Lock()
@@ -37,6 +164,8 @@ func (m *Metrics) RecordRequest(endpoint string, duration time.Duration) {
 
 	m.requestCount++
 	m.totalResponseTime += duration
+	m.responseSamples.add(duration)
+	m.responseHistogram.observe(duration)
 
 	if _, exists := m.endpointMetrics[endpoint]; !exists {
 		m.endpointMetrics[endpoint] = &EndpointMetrics{}
@@ -62,14 +191,112 @@ func (m *Metrics) RecordError(endpoint string) {
 	m.endpointMetrics[endpoint].ErrorCount++
 }
 
+// RecordCacheHit records a request served from the response cache
+func (m *Metrics) RecordCacheHit(endpoint string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.cacheHitCount++
+
+	if _, exists := m.endpointMetrics[endpoint]; !exists {
+		m.endpointMetrics[endpoint] = &EndpointMetrics{}
+	}
+
+	m.endpointMetrics[endpoint].CacheHits++
+}
+
+// RecordCacheMiss records a cacheable request that had to be forwarded upstream
+func (m *Metrics) RecordCacheMiss(endpoint string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.cacheMissCount++
+
+	if _, exists := m.endpointMetrics[endpoint]; !exists {
+		m.endpointMetrics[endpoint] = &EndpointMetrics{}
+	}
+
+	m.endpointMetrics[endpoint].CacheMisses++
+}
+
+// RecordCoalesced records a request that shared another request's in-flight
+// upstream call instead of making its own
+func (m *Metrics) RecordCoalesced(endpoint string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.coalescedCount++
+
+	if _, exists := m.endpointMetrics[endpoint]; !exists {
+		m.endpointMetrics[endpoint] = &EndpointMetrics{}
+	}
+
+	m.endpointMetrics[endpoint].CoalescedRequests++
+}
+
+// RecordThrottleWait records time a request spent waiting for the endpoint's outbound rate limit
+func (m *Metrics) RecordThrottleWait(endpoint string, wait time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.endpointMetrics[endpoint]; !exists {
+		m.endpointMetrics[endpoint] = &EndpointMetrics{}
+	}
+
+	em := m.endpointMetrics[endpoint]
+	em.ThrottledRequests++
+	em.ThrottleWaitTime += wait
+}
+
+// RecordTransform records the time a request's response transformation took
+func (m *Metrics) RecordTransform(endpoint string, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.transformCount++
+	m.totalTransformTime += duration
+	m.transformSamples.add(duration)
+
+	if _, exists := m.endpointMetrics[endpoint]; !exists {
+		m.endpointMetrics[endpoint] = &EndpointMetrics{}
+	}
+
+	em := m.endpointMetrics[endpoint]
+	em.TransformCount++
+	em.TotalTransformTime += duration
+	em.AvgTransformTime = time.Duration(int64(em.TotalTransformTime) / em.TransformCount)
+}
+
+// IncInFlight records the start of a request being served
+func (m *Metrics) IncInFlight() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inFlight++
+}
+
+// DecInFlight records the end of a request being served
+func (m *Metrics) DecInFlight() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inFlight--
+}
+
+// InFlight returns the number of requests currently being served
+func (m *Metrics) InFlight() int64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.inFlight
+}
+
 // GetMetrics returns a snapshot of current metrics
 func (m *Metrics) GetMetrics() MetricsSnapshot {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	avgResponseTime := time.Duration(0)
+	responseTime := Timings{Count: m.requestCount, Buckets: m.responseHistogram.buckets()}
 	if m.requestCount > 0 {
-		avgResponseTime = time.Duration(int64(m.totalResponseTime) / m.requestCount)
+		responseTime.Average = time.Duration(int64(m.totalResponseTime) / m.requestCount)
+		m.responseSamples.summarize(&responseTime)
 	}
 
 	endpoints := make(map[string]EndpointMetrics)
@@ -77,18 +304,82 @@ func (m *Metrics) GetMetrics() MetricsSnapshot {
 		endpoints[name] = *em
 	}
 
+	transform := Timings{Count: m.transformCount}
+	if m.transformCount > 0 {
+		transform.Average = time.Duration(int64(m.totalTransformTime) / m.transformCount)
+		m.transformSamples.summarize(&transform)
+	}
+
+	cacheHitRatio := 0.0
+	if lookups := m.cacheHitCount + m.cacheMissCount; lookups > 0 {
+		cacheHitRatio = float64(m.cacheHitCount) / float64(lookups)
+	}
+
 	return MetricsSnapshot{
 		TotalRequests:       m.requestCount,
 		TotalErrors:         m.errorCount,
-		AverageResponseTime: avgResponseTime,
+		TotalCacheHits:      m.cacheHitCount,
+		TotalCacheMisses:    m.cacheMissCount,
+		CacheHitRatio:       cacheHitRatio,
+		TotalCoalesced:      m.coalescedCount,
+		InFlightRequests:    m.inFlight,
+		AverageResponseTime: responseTime.Average,
+		ResponseTime:        responseTime,
+		Transform:           transform,
 		Endpoints:           endpoints,
 	}
 }
 
+// percentile returns the nearest-rank percentile of sorted, non-empty samples
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	return sorted[max(rank-1, 0)]
+}
+
+// Timings summarizes how long requests or transformations take.
+// Percentiles cover the most recent samples only.
+type Timings struct {
+	Count   int64         `json:"count"`
+	Average time.Duration `json:"average"`
+	P50     time.Duration `json:"p50"`
+	P95     time.Duration `json:"p95"`
+	P99     time.Duration `json:"p99"`
+	Buckets []Bucket      `json:"buckets,omitempty"`
+}
+
+// InfiniteBound is the upper bound of the last histogram bucket, which counts
+// every duration
+const InfiniteBound = time.Duration(math.MaxInt64)
+
+// Bucket is a cumulative histogram bucket: how many durations were at most UpperBound
+type Bucket struct {
+	UpperBound time.Duration `json:"le"`
+	Count      int64         `json:"count"`
+}
+
+// MarshalJSON encodes the +Inf bucket's bound as "+Inf", as Prometheus does
+func (b Bucket) MarshalJSON() ([]byte, error) {
+	if b.UpperBound != InfiniteBound {
+		type bucket Bucket
+		return json.Marshal(bucket(b))
+	}
+	return json.Marshal(struct {
+		UpperBound string `json:"le"`
+		Count      int64  `json:"count"`
+	}{"+Inf", b.Count})
+}
+
 // MetricsSnapshot represents a point-in-time snapshot of metrics
 type MetricsSnapshot struct {
 	TotalRequests       int64                      `json:"total_requests"`
 	TotalErrors         int64                      `json:"total_errors"`
+	TotalCacheHits      int64                      `json:"total_cache_hits"`
+	TotalCacheMisses    int64                      `json:"total_cache_misses"`
+	CacheHitRatio       float64                    `json:"cache_hit_ratio"`
+	TotalCoalesced      int64                      `json:"total_coalesced_requests"`
+	InFlightRequests    int64                      `json:"in_flight_requests"`
 	AverageResponseTime time.Duration              `json:"average_response_time"`
+	ResponseTime        Timings                    `json:"response_time"`
+	Transform           Timings                    `json:"transform"`
 	Endpoints           map[string]EndpointMetrics `json:"endpoints"`
 }
@@ -1,6 +1,7 @@
 package logging
 
 import (
+	"encoding/json"
 	"testing"
 	"time"
 )
@@ -98,6 +99,259 @@ func TestRecordError(t *testing.T) {
 	}
 }
 
+func TestRecordCacheHit(t *testing.T) {
+	metrics := NewMetrics()
+
+	metrics.RecordCacheHit("endpoint1")
+	metrics.RecordCacheHit("endpoint1")
+	metrics.RecordCacheHit("endpoint2")
+
+	snapshot := metrics.GetMetrics()
+
+	if snapshot.TotalCacheHits != 3 {
+		t.Errorf("Expected 3 total cache hits, got %d", snapshot.TotalCacheHits)
+	}
+
+	if snapshot.Endpoints["endpoint1"].CacheHits != 2 {
+		t.Errorf("Expected 2 cache hits for endpoint1, got %d", snapshot.Endpoints["endpoint1"].CacheHits)
+	}
+
+	if snapshot.Endpoints["endpoint2"].CacheHits != 1 {
+		t.Errorf("Expected 1 cache hit for endpoint2, got %d", snapshot.Endpoints["endpoint2"].CacheHits)
+	}
+}
+
+func TestRecordCacheMiss(t *testing.T) {
+	metrics := NewMetrics()
+
+	snapshot := metrics.GetMetrics()
+	if snapshot.CacheHitRatio != 0 {
+		t.Errorf("Expected cache hit ratio 0 without lookups, got %v", snapshot.CacheHitRatio)
+	}
+
+	metrics.RecordCacheHit("endpoint1")
+	metrics.RecordCacheMiss("endpoint1")
+	metrics.RecordCacheMiss("endpoint1")
+	metrics.RecordCacheMiss("endpoint2")
+
+	snapshot = metrics.GetMetrics()
+
+	if snapshot.TotalCacheMisses != 3 {
+		t.Errorf("Expected 3 total cache misses, got %d", snapshot.TotalCacheMisses)
+	}
+
+	if snapshot.Endpoints["endpoint1"].CacheMisses != 2 {
+		t.Errorf("Expected 2 cache misses for endpoint1, got %d", snapshot.Endpoints["endpoint1"].CacheMisses)
+	}
+
+	if snapshot.CacheHitRatio != 0.25 {
+		t.Errorf("Expected cache hit ratio 0.25, got %v", snapshot.CacheHitRatio)
+	}
+}
+
+func TestRecordCoalesced(t *testing.T) {
+	metrics := NewMetrics()
+
+	metrics.RecordCoalesced("endpoint1")
+	metrics.RecordCoalesced("endpoint1")
+
+	snapshot := metrics.GetMetrics()
+
+	if snapshot.TotalCoalesced != 2 {
+		t.Errorf("Expected 2 coalesced requests, got %d", snapshot.TotalCoalesced)
+	}
+
+	if snapshot.Endpoints["endpoint1"].CoalescedRequests != 2 {
+		t.Errorf("Expected 2 coalesced requests for endpoint1, got %d", snapshot.Endpoints["endpoint1"].CoalescedRequests)
+	}
+}
+
+func TestRecordThrottleWait(t *testing.T) {
+	metrics := NewMetrics()
+
+	metrics.RecordThrottleWait("endpoint1", 50*time.Millisecond)
+	metrics.RecordThrottleWait("endpoint1", 30*time.Millisecond)
+
+	em := metrics.GetMetrics().Endpoints["endpoint1"]
+	if em.ThrottledRequests != 2 {
+		t.Errorf("Expected 2 throttled requests, got %d", em.ThrottledRequests)
+	}
+	if em.ThrottleWaitTime != 80*time.Millisecond {
+		t.Errorf("Expected 80ms throttle wait, got %v", em.ThrottleWaitTime)
+	}
+}
+
+func TestRecordTransform(t *testing.T) {
+	metrics := NewMetrics()
+
+	for i := 1; i <= 100; i++ {
+		metrics.RecordTransform("test-endpoint", time.Duration(i)*time.Millisecond)
+	}
+
+	snapshot := metrics.GetMetrics()
+
+	if snapshot.Transform.Count != 100 {
+		t.Errorf("Expected 100 transformations, got %d", snapshot.Transform.Count)
+	}
+	if snapshot.Transform.Average != 50500*time.Microsecond {
+		t.Errorf("Expected average transform time 50.5ms, got %v", snapshot.Transform.Average)
+	}
+	if snapshot.Transform.P50 != 50*time.Millisecond {
+		t.Errorf("Expected p50 50ms, got %v", snapshot.Transform.P50)
+	}
+	if snapshot.Transform.P95 != 95*time.Millisecond {
+		t.Errorf("Expected p95 95ms, got %v", snapshot.Transform.P95)
+	}
+	if snapshot.Transform.P99 != 99*time.Millisecond {
+		t.Errorf("Expected p99 99ms, got %v", snapshot.Transform.P99)
+	}
+
+	em := snapshot.Endpoints["test-endpoint"]
+	if em.TransformCount != 100 {
+		t.Errorf("Expected 100 transformations for endpoint, got %d", em.TransformCount)
+	}
+	if em.AvgTransformTime != 50500*time.Microsecond {
+		t.Errorf("Expected endpoint average transform time 50.5ms, got %v", em.AvgTransformTime)
+	}
+	if em.RequestCount != 0 {
+		t.Errorf("Expected transformations not to count as requests, got %d", em.RequestCount)
+	}
+}
+
+func TestRecordTransform_KeepsRecentSamples(t *testing.T) {
+	metrics := NewMetrics()
+
+	// Slow early transformations age out of the percentile window
+	for i := 0; i < defaultSampleSize; i++ {
+		metrics.RecordTransform("test-endpoint", time.Second)
+	}
+	for i := 0; i < defaultSampleSize; i++ {
+		metrics.RecordTransform("test-endpoint", time.Millisecond)
+	}
+
+	snapshot := metrics.GetMetrics()
+	if snapshot.Transform.P99 != time.Millisecond {
+		t.Errorf("Expected p99 of recent samples 1ms, got %v", snapshot.Transform.P99)
+	}
+	if snapshot.Transform.Count != 2*defaultSampleSize {
+		t.Errorf("Expected %d transformations, got %d", 2*defaultSampleSize, snapshot.Transform.Count)
+	}
+}
+
+func TestRecordRequest_Percentiles(t *testing.T) {
+	metrics := NewMetrics()
+
+	for i := 1; i <= 100; i++ {
+		metrics.RecordRequest("test-endpoint", time.Duration(i)*time.Millisecond)
+	}
+
+	snapshot := metrics.GetMetrics()
+	if snapshot.ResponseTime.Count != 100 {
+		t.Errorf("Expected 100 responses, got %d", snapshot.ResponseTime.Count)
+	}
+	if snapshot.ResponseTime.Average != snapshot.AverageResponseTime {
+		t.Errorf("Expected average %v to match average_response_time %v", snapshot.ResponseTime.Average, snapshot.AverageResponseTime)
+	}
+	if snapshot.ResponseTime.P50 != 50*time.Millisecond {
+		t.Errorf("Expected p50 50ms, got %v", snapshot.ResponseTime.P50)
+	}
+	if snapshot.ResponseTime.P95 != 95*time.Millisecond {
+		t.Errorf("Expected p95 95ms, got %v", snapshot.ResponseTime.P95)
+	}
+	if snapshot.ResponseTime.P99 != 99*time.Millisecond {
+		t.Errorf("Expected p99 99ms, got %v", snapshot.ResponseTime.P99)
+	}
+	if snapshot.ResponseTime.Buckets != nil {
+		t.Errorf("Expected no buckets by default, got %v", snapshot.ResponseTime.Buckets)
+	}
+}
+
+func TestSetSampleSize(t *testing.T) {
+	metrics := NewMetrics()
+
+	for i := 0; i < 20; i++ {
+		metrics.RecordRequest("test-endpoint", time.Second)
+	}
+	// Shrinking keeps only the most recent samples
+	metrics.RecordRequest("test-endpoint", time.Millisecond)
+	metrics.SetSampleSize(1)
+
+	snapshot := metrics.GetMetrics()
+	if snapshot.ResponseTime.P50 != time.Millisecond {
+		t.Errorf("Expected p50 of the last sample 1ms, got %v", snapshot.ResponseTime.P50)
+	}
+
+	metrics.SetSampleSize(10)
+	for i := 0; i < 10; i++ {
+		metrics.RecordRequest("test-endpoint", 5*time.Millisecond)
+	}
+
+	snapshot = metrics.GetMetrics()
+	if snapshot.ResponseTime.P50 != 5*time.Millisecond {
+		t.Errorf("Expected p50 of recent samples 5ms, got %v", snapshot.ResponseTime.P50)
+	}
+	if snapshot.ResponseTime.P99 != 5*time.Millisecond {
+		t.Errorf("Expected p99 of recent samples 5ms, got %v", snapshot.ResponseTime.P99)
+	}
+	if snapshot.ResponseTime.Count != 31 {
+		t.Errorf("Expected 31 responses, got %d", snapshot.ResponseTime.Count)
+	}
+}
+
+func TestSetResponseBuckets(t *testing.T) {
+	metrics := NewMetrics()
+	metrics.SetResponseBuckets([]time.Duration{time.Second, 10 * time.Millisecond, 100 * time.Millisecond, time.Second})
+
+	for _, d := range []time.Duration{5 * time.Millisecond, 10 * time.Millisecond, 50 * time.Millisecond, 500 * time.Millisecond, 2 * time.Second} {
+		metrics.RecordRequest("test-endpoint", d)
+	}
+
+	expected := []Bucket{
+		{UpperBound: 10 * time.Millisecond, Count: 2},
+		{UpperBound: 100 * time.Millisecond, Count: 3},
+		{UpperBound: time.Second, Count: 4},
+		{UpperBound: InfiniteBound, Count: 5},
+	}
+	buckets := metrics.GetMetrics().ResponseTime.Buckets
+	if len(buckets) != len(expected) {
+		t.Fatalf("Expected %d buckets, got %v", len(expected), buckets)
+	}
+	for i, b := range expected {
+		if buckets[i] != b {
+			t.Errorf("Expected bucket %d to be %+v, got %+v", i, b, buckets[i])
+		}
+	}
+
+	encoded, err := json.Marshal(buckets[2:])
+	if err != nil {
+		t.Fatalf("Failed to encode buckets: %v", err)
+	}
+	if string(encoded) != `[{"le":1000000000,"count":4},{"le":"+Inf","count":5}]` {
+		t.Errorf("Expected the last bucket to be +Inf, got %s", encoded)
+	}
+
+	metrics.SetResponseBuckets(nil)
+	if buckets := metrics.GetMetrics().ResponseTime.Buckets; buckets != nil {
+		t.Errorf("Expected no buckets after clearing, got %v", buckets)
+	}
+}
+
+func TestInFlight(t *testing.T) {
+	metrics := NewMetrics()
+
+	metrics.IncInFlight()
+	metrics.IncInFlight()
+	metrics.DecInFlight()
+
+	if metrics.InFlight() != 1 {
+		t.Errorf("Expected 1 in-flight request, got %d", metrics.InFlight())
+	}
+
+	if snapshot := metrics.GetMetrics(); snapshot.InFlightRequests != 1 {
+		t.Errorf("Expected 1 in-flight request in snapshot, got %d", snapshot.InFlightRequests)
+	}
+}
+
 func TestRecordMultipleEndpoints(t *testing.T) {
 	metrics := NewMetrics()
 
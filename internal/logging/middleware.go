@@ -2,19 +2,39 @@
 package logging
 
 import (
+	"net"
 	"net/http"
+	"strings"
 	"time"
 
+	"jq-proxy-service/internal/ipfilter"
+
 	"github.com/sirupsen/logrus"
 )
 
-// RequestLoggingMiddleware creates middleware for request logging with tracing
-func RequestLoggingMiddleware(logger *Logger) func(http.Handler) http.Handler {
+// RequestLogFilter reports whether a request must be logged without its path,
+// query and user agent, e.g. because it addresses a sensitive endpoint
+type RequestLogFilter func(r *http.Request) bool
+
+// RequestLoggingMiddleware creates middleware for request logging with tracing.
+// Client IPs are resolved with ClientIP using the given trusted proxies.
+// Requests matched by the optional quiet filter get a minimal log record.
+// Request IDs are read from and returned in requestIDHeader, or RequestIDHeader
+// when it is empty.
+func RequestLoggingMiddleware(logger *Logger, trustedProxies []*net.IPNet, quiet RequestLogFilter, requestIDHeader string) func(http.Handler) http.Handler {
+	if requestIDHeader == "" {
+		requestIDHeader = RequestIDHeader
+	}
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Generate request ID
-			requestID := GenerateRequestID()
+			// Reuse the client's request ID when valid, otherwise generate one
+			requestID := r.Header.Get(requestIDHeader)
+			if !IsValidRequestID(requestID) {
+				requestID = GenerateRequestID()
+			}
+			w.Header().Set(requestIDHeader, requestID)
 			ctx := WithRequestIDContext(r.Context(), requestID)
+			ctx, accessInfo := WithAccessInfoContext(ctx)
 			r = r.WithContext(ctx)
 
 			// Create response writer wrapper
@@ -26,14 +46,22 @@ func RequestLoggingMiddleware(logger *Logger) func(http.Handler) http.Handler {
 			// Record start time
 			startTime := time.Now()
 
-			// Log incoming request
-			logger.WithRequestID(ctx).WithFields(logrus.Fields{
-				"method":     r.Method,
-				"path":       r.URL.Path,
-				"query":      r.URL.RawQuery,
-				"user_agent": r.UserAgent(),
-				"remote_ip":  getClientIP(r),
-			}).Info("Request started")
+			// Log incoming request; the minimal flag is kept in the context so
+			// later log lines for the request can honor it
+			minimal := quiet != nil && quiet(r)
+			if minimal {
+				accessInfo.SetMinimal()
+			}
+			startFields := logrus.Fields{
+				"method":    r.Method,
+				"remote_ip": ClientIP(r, trustedProxies),
+			}
+			if !minimal {
+				startFields["path"] = r.URL.Path
+				startFields["query"] = r.URL.RawQuery
+				startFields["user_agent"] = r.UserAgent()
+			}
+			logger.WithRequestID(ctx).WithFields(startFields).Info("Request started")
 
 			// Process request
 			next.ServeHTTP(wrapper, r)
@@ -42,13 +70,27 @@ func RequestLoggingMiddleware(logger *Logger) func(http.Handler) http.Handler {
 			duration := time.Since(startTime)
 
 			// Log completed request
-			logger.WithRequestID(ctx).WithFields(logrus.Fields{
+			completedFields := logrus.Fields{
 				"method":        r.Method,
-				"path":          r.URL.Path,
 				"status_code":   wrapper.statusCode,
 				"duration_ms":   duration.Milliseconds(),
 				"response_size": wrapper.bytesWritten,
-			}).Info("Request completed")
+			}
+			if !accessInfo.Minimal() {
+				completedFields["path"] = r.URL.Path
+			}
+			logger.WithRequestID(ctx).WithFields(completedFields).WithFields(accessInfo.Fields()).Info("Request completed")
+		})
+	}
+}
+
+// InFlightMiddleware tracks the number of requests currently being served
+func InFlightMiddleware(metrics *Metrics) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			metrics.IncInFlight()
+			defer metrics.DecInFlight()
+			next.ServeHTTP(w, r)
 		})
 	}
 }
@@ -71,18 +113,53 @@ func (rw *responseWriter) Write(b []byte) (int, error) {
 	return n, err
 }
 
-// getClientIP extracts the client IP address from the request
-func getClientIP(r *http.Request) string {
-	// Check X-Forwarded-For header first
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		return xff
+// Unwrap exposes the underlying writer so http.ResponseController can flush it
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// ClientIP returns the IP address of the client that sent the request.
+// X-Forwarded-For and X-Real-IP are only honored when the connection comes
+// from one of the trusted proxies, since any client can send them. The
+// forwarded chain is then walked from the right, skipping trusted proxies,
+// and the first untrusted hop is the client. Without trusted proxies the
+// connection's remote address is used.
+func ClientIP(r *http.Request, trustedProxies []*net.IPNet) string {
+	remote := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	if !isTrustedProxy(remote, trustedProxies) {
+		return remote
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(header, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	if len(hops) == 0 {
+		if xri := strings.TrimSpace(r.Header.Get("X-Real-IP")); xri != "" {
+			return xri
+		}
+		return remote
 	}
 
-	// Check X-Real-IP header
-	if xri := r.Header.Get("X-Real-IP"); xri != "" {
-		return xri
+	for i := len(hops) - 1; i >= 0; i-- {
+		if !isTrustedProxy(hops[i], trustedProxies) {
+			return hops[i]
+		}
 	}
 
-	// Fall back to RemoteAddr
-	return r.RemoteAddr
+	// Every hop is a trusted proxy; the leftmost is the closest to the client
+	return hops[0]
+}
+
+// isTrustedProxy reports whether addr is in one of the trusted proxy networks
+func isTrustedProxy(addr string, trustedProxies []*net.IPNet) bool {
+	ip := net.ParseIP(addr)
+	return ip != nil && ipfilter.Contains(trustedProxies, ip)
 }
@@ -0,0 +1,300 @@
+package logging
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// completionEntry returns the "Request completed" entry from JSON log output
+func completionEntry(t *testing.T, output *bytes.Buffer) map[string]interface{} {
+	t.Helper()
+	scanner := bufio.NewScanner(output)
+	for scanner.Scan() {
+		var entry map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("Failed to parse log line: %v", err)
+		}
+		if entry["message"] == "Request completed" {
+			return entry
+		}
+	}
+	t.Fatal("No completion log entry found")
+	return nil
+}
+
+func TestRequestLoggingMiddleware_AccessInfo(t *testing.T) {
+	logger, err := NewLogger("info")
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	var output bytes.Buffer
+	logger.SetOutput(&output)
+
+	handler := RequestLoggingMiddleware(logger, nil, nil, "")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info := GetAccessInfo(r.Context())
+		info.SetEndpoint("user-service")
+		info.SetUpstream(http.StatusNotFound, 42)
+		info.SetTransformError()
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}))
+
+	req := httptest.NewRequest("POST", "/proxy/user-service/users", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	entry := completionEntry(t, &output)
+	if entry["endpoint"] != "user-service" {
+		t.Errorf("Expected endpoint user-service, got %v", entry["endpoint"])
+	}
+	if entry["upstream_status"] != float64(http.StatusNotFound) {
+		t.Errorf("Expected upstream_status 404, got %v", entry["upstream_status"])
+	}
+	if entry["upstream_bytes"] != float64(42) {
+		t.Errorf("Expected upstream_bytes 42, got %v", entry["upstream_bytes"])
+	}
+	if entry["transform_error"] != true {
+		t.Errorf("Expected transform_error true, got %v", entry["transform_error"])
+	}
+	if entry["status_code"] != float64(http.StatusUnprocessableEntity) {
+		t.Errorf("Expected status_code 422, got %v", entry["status_code"])
+	}
+}
+
+func TestRequestLoggingMiddleware_MinimalRecord(t *testing.T) {
+	logger, err := NewLogger("info")
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	var output bytes.Buffer
+	logger.SetOutput(&output)
+
+	quiet := func(r *http.Request) bool { return strings.HasPrefix(r.URL.Path, "/proxy/secret-service") }
+	var minimalInHandler bool
+	handler := RequestLoggingMiddleware(logger, nil, quiet, "")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		minimalInHandler = GetAccessInfo(r.Context()).Minimal()
+		GetAccessInfo(r.Context()).SetEndpoint("secret-service")
+	}))
+
+	req := httptest.NewRequest("POST", "/proxy/secret-service/patients/42?ssn=123", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if !minimalInHandler {
+		t.Error("Expected the minimal flag to be available in the request context")
+	}
+	logs := output.String()
+	for _, detail := range []string{"patients/42", "ssn=123"} {
+		if strings.Contains(logs, detail) {
+			t.Errorf("Expected logs to omit %q, got %s", detail, logs)
+		}
+	}
+	entry := completionEntry(t, &output)
+	if entry["endpoint"] != "secret-service" {
+		t.Errorf("Expected endpoint secret-service in minimal record, got %v", entry["endpoint"])
+	}
+}
+
+func TestRequestLoggingMiddleware_NoAccessInfo(t *testing.T) {
+	logger, err := NewLogger("info")
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	var output bytes.Buffer
+	logger.SetOutput(&output)
+
+	handler := RequestLoggingMiddleware(logger, nil, nil, "")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/health", nil))
+
+	entry := completionEntry(t, &output)
+	if _, ok := entry["endpoint"]; ok {
+		t.Errorf("Expected no endpoint field for non-proxy request, got %v", entry["endpoint"])
+	}
+}
+
+func TestAccessInfo_NilSafe(t *testing.T) {
+	var info *AccessInfo
+	info.SetEndpoint("svc")
+	info.SetUpstream(200, 10)
+	info.SetTransformError()
+
+	if len(info.Fields()) != 0 {
+		t.Error("Expected no fields from nil AccessInfo")
+	}
+}
+
+func TestRequestLoggingMiddleware_RequestIDHeader(t *testing.T) {
+	logger, err := NewLogger("error")
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	var contextID string
+	handler := RequestLoggingMiddleware(logger, nil, nil, "")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contextID = GetRequestID(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name       string
+		incoming   string
+		expectEcho bool
+	}{
+		{name: "generated when absent", incoming: "", expectEcho: false},
+		{name: "echoes client-provided ID", incoming: "client-abc-123", expectEcho: true},
+		{name: "replaces invalid ID", incoming: "bad id\twith spaces", expectEcho: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/health", nil)
+			if tt.incoming != "" {
+				req.Header.Set(RequestIDHeader, tt.incoming)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			headerID := rr.Header().Get(RequestIDHeader)
+			if headerID == "" {
+				t.Fatal("Expected X-Request-ID response header")
+			}
+			if headerID != contextID {
+				t.Errorf("Expected header ID %s to match context ID %s", headerID, contextID)
+			}
+			if tt.expectEcho && headerID != tt.incoming {
+				t.Errorf("Expected echoed ID %s, got %s", tt.incoming, headerID)
+			}
+			if !tt.expectEcho && headerID == tt.incoming {
+				t.Errorf("Expected generated ID, got client value %s", headerID)
+			}
+		})
+	}
+}
+
+func TestRequestLoggingMiddleware_CustomRequestIDHeader(t *testing.T) {
+	logger, err := NewLogger("error")
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	var contextID string
+	handler := RequestLoggingMiddleware(logger, nil, nil, "X-Correlation-ID")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contextID = GetRequestID(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/health", nil)
+	req.Header.Set("X-Correlation-ID", "corr-42")
+	req.Header.Set(RequestIDHeader, "ignored")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if got := rr.Header().Get("X-Correlation-ID"); got != "corr-42" {
+		t.Errorf("Expected echoed X-Correlation-ID corr-42, got %q", got)
+	}
+	if contextID != "corr-42" {
+		t.Errorf("Expected context request ID corr-42, got %q", contextID)
+	}
+	if got := rr.Header().Get(RequestIDHeader); got != "" {
+		t.Errorf("Expected no X-Request-ID response header, got %q", got)
+	}
+
+	// Without the custom header an ID is generated and returned in it
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/health", nil))
+	if got := rr.Header().Get("X-Correlation-ID"); got == "" || got != contextID {
+		t.Errorf("Expected generated ID %q in X-Correlation-ID, got %q", contextID, got)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	trusted := []*net.IPNet{
+		{IP: net.ParseIP("10.0.0.0").To4(), Mask: net.CIDRMask(8, 32)},
+		{IP: net.ParseIP("2001:db8::"), Mask: net.CIDRMask(32, 128)},
+	}
+
+	tests := []struct {
+		name           string
+		remoteAddr     string
+		headers        http.Header
+		trustedProxies []*net.IPNet
+		want           string
+	}{
+		{name: "remote address", remoteAddr: "192.0.2.1:1234", want: "192.0.2.1"},
+		{name: "IPv6 remote address", remoteAddr: "[2001:db8::1]:1234", want: "2001:db8::1"},
+		{
+			name:       "spoofed header without trusted proxies",
+			remoteAddr: "192.0.2.1:1234",
+			headers:    http.Header{"X-Forwarded-For": {"10.9.9.9"}},
+			want:       "192.0.2.1",
+		},
+		{
+			name:           "spoofed header from untrusted source",
+			remoteAddr:     "192.0.2.1:1234",
+			headers:        http.Header{"X-Forwarded-For": {"198.51.100.7"}, "X-Real-Ip": {"198.51.100.8"}},
+			trustedProxies: trusted,
+			want:           "192.0.2.1",
+		},
+		{
+			name:           "client behind trusted proxy",
+			remoteAddr:     "10.0.0.1:1234",
+			headers:        http.Header{"X-Forwarded-For": {"203.0.113.5"}},
+			trustedProxies: trusted,
+			want:           "203.0.113.5",
+		},
+		{
+			name:           "client-supplied hops left of the rightmost untrusted hop are ignored",
+			remoteAddr:     "10.0.0.1:1234",
+			headers:        http.Header{"X-Forwarded-For": {"1.2.3.4, 203.0.113.5, 10.0.0.2"}},
+			trustedProxies: trusted,
+			want:           "203.0.113.5",
+		},
+		{
+			name:           "hops across repeated headers",
+			remoteAddr:     "10.0.0.1:1234",
+			headers:        http.Header{"X-Forwarded-For": {"1.2.3.4", "203.0.113.5, 10.0.0.2"}},
+			trustedProxies: trusted,
+			want:           "203.0.113.5",
+		},
+		{
+			name:           "all hops trusted",
+			remoteAddr:     "10.0.0.1:1234",
+			headers:        http.Header{"X-Forwarded-For": {"10.0.0.3, 10.0.0.2"}},
+			trustedProxies: trusted,
+			want:           "10.0.0.3",
+		},
+		{
+			name:           "real IP from trusted proxy",
+			remoteAddr:     "10.0.0.1:1234",
+			headers:        http.Header{"X-Real-Ip": {"203.0.113.9"}},
+			trustedProxies: trusted,
+			want:           "203.0.113.9",
+		},
+		{
+			name:           "trusted proxy without headers",
+			remoteAddr:     "10.0.0.1:1234",
+			trustedProxies: trusted,
+			want:           "10.0.0.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header = tt.headers
+			if req.Header == nil {
+				req.Header = http.Header{}
+			}
+			if got := ClientIP(req, tt.trustedProxies); got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+// Package logging provides structured logging and metrics collection functionality.
+package logging
+
+import (
+	"fmt"
+	"regexp"
+	"unicode/utf8"
+)
+
+// redactedValue replaces sensitive values in body previews
+const redactedValue = "[REDACTED]"
+
+var (
+	// sensitiveJSONValue matches string values of JSON keys that look like credentials
+	sensitiveJSONValue = regexp.MustCompile(
+		`(?i)("[^"]*(?:password|passwd|secret|token|api[_-]?key|authorization|credential|session)[^"]*"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+
+	// bearerToken matches bearer tokens embedded anywhere in the body
+	bearerToken = regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9\-._~+/]+=*`)
+)
+
+// BodyPreview returns at most maxBytes of body for logging, with
+// sensitive-looking values redacted. Redaction happens before truncation so a
+// value cut off at the limit is still hidden.
+func BodyPreview(body []byte, maxBytes int) string {
+	preview := sensitiveJSONValue.ReplaceAllString(string(body), `$1"`+redactedValue+`"`)
+	preview = bearerToken.ReplaceAllString(preview, "${1}"+redactedValue)
+
+	if maxBytes <= 0 || len(preview) <= maxBytes {
+		return preview
+	}
+
+	// Cut on a rune boundary so the preview stays valid UTF-8
+	cut := maxBytes
+	for cut > 0 && !utf8.RuneStart(preview[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s... (%d bytes truncated)", preview[:cut], len(preview)-cut)
+}
@@ -0,0 +1,361 @@
+// Package models defines the core data structures and types used throughout the proxy service.
+package models
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// endpointWildcard marks a pattern endpoint key, e.g. "service-*"
+const endpointWildcard = "*"
+
+// placeholderPattern matches target placeholders such as {1} referring to wildcard captures
+var placeholderPattern = regexp.MustCompile(`\{(\d+)\}`)
+
+// targetVariablePattern matches named target placeholders such as {region}
+// filled per request from target_variables
+var targetVariablePattern = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// transformationModeName matches names that can be used as transformation modes
+var transformationModeName = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+// jqVariableName matches names that can be used as jq variables
+var jqVariableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// reservedJQVariables are defined by jq itself or bound to the upstream
+// response, and cannot be bound by requests
+var reservedJQVariables = map[string]bool{
+	"ENV":           true,
+	"__loc__":       true,
+	StatusVariable:  true,
+	HeadersVariable: true,
+}
+
+// endpointPattern is a compiled wildcard endpoint key
+type endpointPattern struct {
+	key      string
+	regex    *regexp.Regexp
+	literals int
+	endpoint *Endpoint
+}
+
+// IsEndpointPattern reports whether an endpoint key contains wildcards
+func IsEndpointPattern(key string) bool {
+	return strings.Contains(key, endpointWildcard)
+}
+
+// compileEndpointPattern converts a wildcard endpoint key into a regular expression.
+// Each "*" captures one or more characters that can be referenced in the target as {1}, {2}, ...
+func compileEndpointPattern(key string, endpoint *Endpoint) (*endpointPattern, error) {
+	parts := strings.Split(key, endpointWildcard)
+	literals := 0
+	for i, part := range parts {
+		literals += len(part)
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	wildcards := len(parts) - 1
+
+	regex, err := regexp.Compile("^" + strings.Join(parts, "(.+?)") + "$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint pattern %s: %w", key, err)
+	}
+
+	for _, target := range endpoint.TargetURLs() {
+		for _, match := range placeholderPattern.FindAllStringSubmatch(target, -1) {
+			index, _ := strconv.Atoi(match[1])
+			if index < 1 || index > wildcards {
+				return nil, fmt.Errorf("endpoint pattern %s has no wildcard for placeholder %s", key, match[0])
+			}
+		}
+	}
+
+	return &endpointPattern{
+		key:      key,
+		regex:    regex,
+		literals: literals,
+		endpoint: endpoint,
+	}, nil
+}
+
+// compileEndpointPatterns compiles all wildcard endpoints, most specific first
+func compileEndpointPatterns(endpoints map[string]*Endpoint) ([]*endpointPattern, error) {
+	var patterns []*endpointPattern
+	for key, endpoint := range endpoints {
+		if !IsEndpointPattern(key) {
+			continue
+		}
+		pattern, err := compileEndpointPattern(key, endpoint)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, pattern)
+	}
+
+	// Patterns with more literal characters are more specific and win
+	sort.Slice(patterns, func(i, j int) bool {
+		if patterns[i].literals != patterns[j].literals {
+			return patterns[i].literals > patterns[j].literals
+		}
+		return patterns[i].key < patterns[j].key
+	})
+
+	return patterns, nil
+}
+
+// match returns a concrete endpoint for name if it matches the pattern
+func (p *endpointPattern) match(name string) (*Endpoint, bool) {
+	captures := p.regex.FindStringSubmatch(name)
+	if captures == nil {
+		return nil, false
+	}
+
+	substitute := func(target string) string {
+		return placeholderPattern.ReplaceAllStringFunc(target, func(placeholder string) string {
+			index, _ := strconv.Atoi(placeholder[1 : len(placeholder)-1])
+			return captures[index]
+		})
+	}
+
+	endpoint := *p.endpoint
+	endpoint.Name = name
+	endpoint.Target = substitute(p.endpoint.Target)
+	if len(p.endpoint.Targets) > 0 {
+		endpoint.Targets = make([]WeightedTarget, len(p.endpoint.Targets))
+		for i, target := range p.endpoint.Targets {
+			endpoint.Targets[i] = WeightedTarget{URL: substitute(target.URL), Weight: target.Weight}
+		}
+	}
+	return &endpoint, true
+}
+
+// compileEndpointAliases maps every endpoint alias to its endpoint key. Aliases
+// must not collide with endpoint keys or with each other, and cannot contain wildcards.
+func compileEndpointAliases(endpoints map[string]*Endpoint) (map[string]string, error) {
+	aliases := make(map[string]string)
+	for key, endpoint := range endpoints {
+		for _, alias := range endpoint.Aliases {
+			switch {
+			case alias == "":
+				return nil, fmt.Errorf("invalid endpoint %s: alias must not be empty", key)
+			case IsEndpointPattern(alias):
+				return nil, fmt.Errorf("invalid endpoint %s: alias %s must not contain wildcards", key, alias)
+			}
+			if _, exists := endpoints[alias]; exists {
+				return nil, fmt.Errorf("endpoint alias %s collides with an endpoint name", alias)
+			}
+			if other, exists := aliases[alias]; exists && other != key {
+				return nil, fmt.Errorf("endpoint alias %s is used by both %s and %s", alias, other, key)
+			}
+			aliases[alias] = key
+		}
+	}
+	return aliases, nil
+}
+
+// FindEndpoint resolves an endpoint by name. Exact matches take precedence,
+// followed by endpoint aliases; otherwise the most specific wildcard endpoint
+// matching the name is used, with its target placeholders substituted from
+// the matched segments. Aliases and wildcard endpoints are only available
+// once the config has been validated.
+func (pc *ProxyConfig) FindEndpoint(name string) (*Endpoint, bool) {
+	if endpoint, exists := pc.Endpoints[name]; exists && !IsEndpointPattern(name) {
+		return endpoint, true
+	}
+
+	if key, exists := pc.aliases[name]; exists {
+		return pc.Endpoints[key], true
+	}
+
+	for _, pattern := range pc.patterns {
+		if endpoint, ok := pattern.match(name); ok {
+			return endpoint, true
+		}
+	}
+
+	return nil, false
+}
+
+// pathPatternSegments splits a path pattern such as "users/{id}/orders" into
+// its segments. A parameter must make up a whole segment and be named like a
+// jq variable, and each name may only be used once.
+func pathPatternSegments(pattern string) ([]string, error) {
+	segments := strings.Split(strings.Trim(pattern, "/"), "/")
+	names := make(map[string]bool)
+	for _, segment := range segments {
+		if !strings.ContainsAny(segment, "{}") {
+			continue
+		}
+		name, isParam := pathParamName(segment)
+		switch {
+		case !isParam:
+			return nil, fmt.Errorf("segment %s must be literal or a whole {name} parameter", segment)
+		case !jqVariableName.MatchString(name):
+			return nil, fmt.Errorf("parameter name %s is not a valid jq variable name", name)
+		case reservedJQVariables[name]:
+			return nil, fmt.Errorf("parameter name %s is reserved", name)
+		case names[name]:
+			return nil, fmt.Errorf("parameter %s is used more than once", name)
+		}
+		names[name] = true
+	}
+	return segments, nil
+}
+
+// pathParamName returns the name of a {name} path pattern segment
+func pathParamName(segment string) (string, bool) {
+	if len(segment) < 2 || segment[0] != '{' || segment[len(segment)-1] != '}' {
+		return "", false
+	}
+	return segment[1 : len(segment)-1], true
+}
+
+// PathParams matches path against the request's path pattern and returns the
+// value of each named segment. It reports false when the path has a different
+// number of segments or a literal segment differs.
+func (pr *ProxyRequest) PathParams(path string) (map[string]interface{}, bool) {
+	segments, err := pathPatternSegments(pr.PathPattern)
+	if err != nil {
+		return nil, false
+	}
+
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) != len(segments) {
+		return nil, false
+	}
+
+	params := make(map[string]interface{})
+	for i, segment := range segments {
+		if name, isParam := pathParamName(segment); isParam {
+			if parts[i] == "" {
+				return nil, false
+			}
+			params[name] = parts[i]
+			continue
+		}
+		if parts[i] != segment {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// Sources of target variables, given as "header:X-Region" or "path:region"
+const (
+	targetSourceHeader = "header"
+	targetSourcePath   = "path"
+)
+
+// IsTargetTemplate reports whether a target has named placeholders that are
+// only filled in per request
+func IsTargetTemplate(target string) bool {
+	return targetVariablePattern.MatchString(target)
+}
+
+// validateTargetVariables checks that every target variable has a valid
+// source and that every named placeholder in the endpoint's targets, header
+// route targets included, is a target variable
+func (e *Endpoint) validateTargetVariables() error {
+	for name, source := range e.TargetVariables {
+		if !jqVariableName.MatchString(name) {
+			return fmt.Errorf("target_variables: invalid variable name %q", name)
+		}
+		kind, ref, _ := strings.Cut(source, ":")
+		switch kind {
+		case targetSourceHeader:
+			if !isHeaderName(ref) {
+				return fmt.Errorf("target_variables: variable %s has invalid header name %q", name, ref)
+			}
+		case targetSourcePath:
+			if !jqVariableName.MatchString(ref) {
+				return fmt.Errorf("target_variables: variable %s has invalid path parameter name %q", name, ref)
+			}
+		default:
+			return fmt.Errorf("target_variables: variable %s source must be header:<name> or path:<name>", name)
+		}
+	}
+
+	targets := e.TargetURLs()
+	for _, route := range e.Routes {
+		targets = append(targets, route.Target)
+	}
+	for _, target := range targets {
+		for _, match := range targetVariablePattern.FindAllStringSubmatch(target, -1) {
+			if _, ok := e.TargetVariables[match[1]]; !ok {
+				return fmt.Errorf("target %s: placeholder %s has no source in target_variables", target, match[0])
+			}
+		}
+	}
+	return nil
+}
+
+// ResolveTarget fills the named placeholders of target from the request
+// headers and path parameters named in TargetVariables. Values are escaped
+// so they cannot change the target's host or add path segments. It returns
+// an error naming the first variable the request does not supply or gives a
+// dot segment for.
+func (e *Endpoint) ResolveTarget(target string, headers http.Header, pathParams map[string]interface{}) (string, error) {
+	var invalid error
+	resolved := targetVariablePattern.ReplaceAllStringFunc(target, func(placeholder string) string {
+		name := placeholder[1 : len(placeholder)-1]
+		kind, ref, _ := strings.Cut(e.TargetVariables[name], ":")
+
+		var value string
+		switch kind {
+		case targetSourceHeader:
+			value = headers.Get(ref)
+		case targetSourcePath:
+			value, _ = pathParams[ref].(string)
+		}
+		switch {
+		case invalid != nil:
+			return placeholder
+		case value == "":
+			invalid = fmt.Errorf("target variable %s requires a %s %s", name, targetSourceName(kind), ref)
+			return placeholder
+		case value == "." || value == "..":
+			invalid = fmt.Errorf("target variable %s has invalid value %q", name, value)
+			return placeholder
+		}
+		return url.PathEscape(value)
+	})
+	if invalid != nil {
+		return "", invalid
+	}
+	return resolved, nil
+}
+
+// targetSourceName describes a target variable source in error messages
+func targetSourceName(kind string) string {
+	if kind == targetSourcePath {
+		return "path parameter"
+	}
+	return "header"
+}
+
+// RouteKey identifies the upstream a request is sent to, for keying shared
+// and cached responses: the matching header route target and the values of
+// headers that fill target variables. Path parameters depend on the path and
+// the request's path_pattern, which callers key on as well.
+func (e *Endpoint) RouteKey(headers http.Header) string {
+	key := e.RouteTarget(headers)
+	if len(e.TargetVariables) == 0 {
+		return key
+	}
+
+	names := make([]string, 0, len(e.TargetVariables))
+	for name := range e.TargetVariables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if kind, ref, _ := strings.Cut(e.TargetVariables[name], ":"); kind == targetSourceHeader {
+			key += "|" + name + "=" + headers.Get(ref)
+		}
+	}
+	return key
+}
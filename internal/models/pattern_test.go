@@ -0,0 +1,366 @@
+package models
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyConfig_FindEndpoint(t *testing.T) {
+	config := ProxyConfig{
+		Server: ServerConfig{
+			Port:         8080,
+			ReadTimeout:  30,
+			WriteTimeout: 30,
+		},
+		Endpoints: map[string]*Endpoint{
+			"service-special": {
+				Name:   "service-special",
+				Target: "https://special.example.com",
+			},
+			"service-*": {
+				Name:   "service-*",
+				Target: "https://{1}.internal.example.com/api",
+			},
+			"service-eu-*": {
+				Name:   "service-eu-*",
+				Target: "https://eu.example.com/{1}",
+			},
+			"*-*-legacy": {
+				Name:   "*-*-legacy",
+				Target: "http://legacy.example.com/{2}/{1}",
+			},
+		},
+	}
+	require.NoError(t, config.Validate())
+
+	tests := []struct {
+		name           string
+		endpointName   string
+		expectFound    bool
+		expectedTarget string
+	}{
+		{
+			name:           "exact match takes precedence over pattern",
+			endpointName:   "service-special",
+			expectFound:    true,
+			expectedTarget: "https://special.example.com",
+		},
+		{
+			name:           "pattern substitution",
+			endpointName:   "service-billing",
+			expectFound:    true,
+			expectedTarget: "https://billing.internal.example.com/api",
+		},
+		{
+			name:           "more specific pattern wins",
+			endpointName:   "service-eu-orders",
+			expectFound:    true,
+			expectedTarget: "https://eu.example.com/orders",
+		},
+		{
+			name:           "multiple wildcards",
+			endpointName:   "acme-users-legacy",
+			expectFound:    true,
+			expectedTarget: "http://legacy.example.com/users/acme",
+		},
+		{
+			name:         "no match",
+			endpointName: "other",
+			expectFound:  false,
+		},
+		{
+			name:         "wildcard requires at least one character",
+			endpointName: "service-",
+			expectFound:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			endpoint, found := config.FindEndpoint(tt.endpointName)
+
+			assert.Equal(t, tt.expectFound, found)
+			if tt.expectFound {
+				require.NotNil(t, endpoint)
+				assert.Equal(t, tt.expectedTarget, endpoint.Target)
+				assert.Equal(t, tt.endpointName, endpoint.Name)
+			} else {
+				assert.Nil(t, endpoint)
+			}
+		})
+	}
+
+	// Resolving a pattern must not modify the configured endpoint
+	assert.Equal(t, "https://{1}.internal.example.com/api", config.Endpoints["service-*"].Target)
+}
+
+func TestProxyConfig_FindEndpoint_PatternTargets(t *testing.T) {
+	config := ProxyConfig{
+		Server: ServerConfig{Port: 8080},
+		Endpoints: map[string]*Endpoint{
+			"service-*": {
+				Name:    "service-*",
+				Targets: []WeightedTarget{{URL: "https://a.example.com/{1}", Weight: 3}, {URL: "https://b.example.com/{1}", Weight: 1}},
+			},
+		},
+	}
+	require.NoError(t, config.Validate())
+
+	endpoint, found := config.FindEndpoint("service-orders")
+	require.True(t, found)
+	assert.Equal(t, []string{"https://a.example.com/orders", "https://b.example.com/orders"}, endpoint.TargetURLs())
+	assert.Equal(t, []int{3, 1}, endpoint.TargetWeights())
+	assert.Equal(t, []string{"https://a.example.com/{1}", "https://b.example.com/{1}"}, config.Endpoints["service-*"].TargetURLs())
+}
+
+func TestProxyConfig_Validate_EndpointPatterns(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		target  string
+		wantErr bool
+	}{
+		{
+			name:   "placeholder within range",
+			key:    "api-*",
+			target: "https://{1}.example.com",
+		},
+		{
+			name:   "pattern without placeholders",
+			key:    "api-*",
+			target: "https://api.example.com",
+		},
+		{
+			name:    "placeholder without wildcard",
+			key:     "api-*",
+			target:  "https://{2}.example.com",
+			wantErr: true,
+		},
+		{
+			name:    "zero placeholder",
+			key:     "api-*",
+			target:  "https://{0}.example.com",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := ProxyConfig{
+				Server: ServerConfig{Port: 8080},
+				Endpoints: map[string]*Endpoint{
+					tt.key: {Name: tt.key, Target: tt.target},
+				},
+			}
+
+			err := config.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), "has no wildcard for placeholder")
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestProxyConfig_FindEndpoint_Aliases(t *testing.T) {
+	config := ProxyConfig{
+		Server: ServerConfig{Port: 8080},
+		Endpoints: map[string]*Endpoint{
+			"accounts": {
+				Name:    "accounts",
+				Target:  "https://accounts.example.com",
+				Aliases: []string{"users", "legacy-users"},
+			},
+			"service-*": {Name: "service-*", Target: "https://{1}.example.com"},
+		},
+	}
+	require.NoError(t, config.Validate())
+
+	canonical, found := config.FindEndpoint("accounts")
+	require.True(t, found)
+
+	for _, alias := range []string{"users", "legacy-users"} {
+		endpoint, found := config.FindEndpoint(alias)
+		require.True(t, found, alias)
+		assert.Same(t, canonical, endpoint)
+		assert.Equal(t, "https://accounts.example.com", endpoint.Target)
+	}
+
+	_, found = config.FindEndpoint("customers")
+	assert.False(t, found)
+}
+
+func TestProxyConfig_Validate_Aliases(t *testing.T) {
+	tests := []struct {
+		name      string
+		endpoints map[string]*Endpoint
+		errMsg    string
+	}{
+		{
+			name: "alias collides with endpoint name",
+			endpoints: map[string]*Endpoint{
+				"accounts": {Name: "accounts", Target: "https://accounts.example.com", Aliases: []string{"users"}},
+				"users":    {Name: "users", Target: "https://users.example.com"},
+			},
+			errMsg: "endpoint alias users collides with an endpoint name",
+		},
+		{
+			name: "alias used by two endpoints",
+			endpoints: map[string]*Endpoint{
+				"a": {Name: "a", Target: "https://a.example.com", Aliases: []string{"shared"}},
+				"b": {Name: "b", Target: "https://b.example.com", Aliases: []string{"shared"}},
+			},
+			errMsg: "endpoint alias shared is used by both",
+		},
+		{
+			name: "empty alias",
+			endpoints: map[string]*Endpoint{
+				"a": {Name: "a", Target: "https://a.example.com", Aliases: []string{""}},
+			},
+			errMsg: "alias must not be empty",
+		},
+		{
+			name: "wildcard alias",
+			endpoints: map[string]*Endpoint{
+				"a": {Name: "a", Target: "https://a.example.com", Aliases: []string{"old-*"}},
+			},
+			errMsg: "must not contain wildcards",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := ProxyConfig{
+				Server:    ServerConfig{Port: 8080},
+				Endpoints: tt.endpoints,
+			}
+
+			err := config.Validate()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
+
+func TestProxyRequest_PathParams(t *testing.T) {
+	tests := []struct {
+		name     string
+		pattern  string
+		path     string
+		expected map[string]interface{}
+		matches  bool
+	}{
+		{
+			name:     "single parameter",
+			pattern:  "users/{id}/orders",
+			path:     "/users/42/orders",
+			expected: map[string]interface{}{"id": "42"},
+			matches:  true,
+		},
+		{
+			name:     "several parameters and slashes",
+			pattern:  "/users/{user_id}/orders/{orderId}/",
+			path:     "users/42/orders/a-7",
+			expected: map[string]interface{}{"user_id": "42", "orderId": "a-7"},
+			matches:  true,
+		},
+		{
+			name:     "literal pattern",
+			pattern:  "health",
+			path:     "/health",
+			expected: map[string]interface{}{},
+			matches:  true,
+		},
+		{name: "literal differs", pattern: "users/{id}/orders", path: "/users/42/invoices"},
+		{name: "too few segments", pattern: "users/{id}/orders", path: "/users/42"},
+		{name: "too many segments", pattern: "users/{id}", path: "/users/42/orders"},
+		{name: "empty parameter", pattern: "users/{id}/orders", path: "/users//orders"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &ProxyRequest{PathPattern: tt.pattern}
+			params, ok := req.PathParams(tt.path)
+			assert.Equal(t, tt.matches, ok)
+			if tt.matches {
+				assert.Equal(t, tt.expected, params)
+			}
+		})
+	}
+}
+
+func TestEndpoint_ResolveTarget(t *testing.T) {
+	endpoint := &Endpoint{
+		Target:          "https://{region}.example.com/tenants/{tenant}",
+		TargetVariables: map[string]string{"region": "header:X-Region", "tenant": "path:tenant"},
+	}
+	params := map[string]interface{}{"tenant": "acme"}
+
+	resolved, err := endpoint.ResolveTarget(endpoint.Target, http.Header{"X-Region": {"eu"}}, params)
+	require.NoError(t, err)
+	assert.Equal(t, "https://eu.example.com/tenants/acme", resolved)
+
+	resolved, err = endpoint.ResolveTarget(endpoint.Target, http.Header{"X-Region": {"eu/evil.com?"}}, params)
+	require.NoError(t, err)
+	assert.Equal(t, "https://eu%2Fevil.com%3F.example.com/tenants/acme", resolved, "values are escaped")
+
+	_, err = endpoint.ResolveTarget(endpoint.Target, http.Header{}, params)
+	assert.EqualError(t, err, "target variable region requires a header X-Region")
+
+	_, err = endpoint.ResolveTarget(endpoint.Target, http.Header{"X-Region": {"eu"}}, nil)
+	assert.EqualError(t, err, "target variable tenant requires a path parameter tenant")
+
+	_, err = endpoint.ResolveTarget(endpoint.Target, http.Header{"X-Region": {"eu"}}, map[string]interface{}{"tenant": ".."})
+	assert.EqualError(t, err, `target variable tenant has invalid value ".."`)
+
+	resolved, err = endpoint.ResolveTarget("https://api.example.com/{1}", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "https://api.example.com/{1}", resolved, "wildcard placeholders are left alone")
+}
+
+func TestEndpoint_RouteKey(t *testing.T) {
+	endpoint := &Endpoint{
+		Target:          "https://{region}.example.com/tenants/{tenant}",
+		TargetVariables: map[string]string{"region": "header:X-Region", "tenant": "path:tenant"},
+		Routes:          []HeaderRoute{{Header: "X-Beta", Value: "1", Target: "https://beta-{region}.example.com"}},
+	}
+
+	eu := endpoint.RouteKey(http.Header{"X-Region": {"eu"}})
+	assert.NotEqual(t, eu, endpoint.RouteKey(http.Header{"X-Region": {"us"}}))
+	assert.NotEqual(t, eu, endpoint.RouteKey(http.Header{"X-Region": {"eu"}, "X-Beta": {"1"}}))
+	assert.Equal(t, eu, endpoint.RouteKey(http.Header{"X-Region": {"eu"}, "X-Other": {"1"}}))
+	assert.Equal(t, "", (&Endpoint{}).RouteKey(http.Header{"X-Region": {"eu"}}))
+}
+
+func TestProxyRequest_Validate_PathPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		errMsg  string
+	}{
+		{pattern: "users/{id}/orders"},
+		{pattern: "users/id-{id}", errMsg: "must be literal or a whole {name} parameter"},
+		{pattern: "users/{user-id}", errMsg: "not a valid jq variable name"},
+		{pattern: "users/{1st}", errMsg: "not a valid jq variable name"},
+		{pattern: "env/{ENV}", errMsg: "reserved"},
+		{pattern: "users/{id}/friends/{id}", errMsg: "used more than once"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			req := &ProxyRequest{Method: "GET", JQQuery: ".", PathPattern: tt.pattern}
+			err := req.Validate()
+			if tt.errMsg == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "invalid path_pattern")
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
@@ -5,9 +5,14 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"mime"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
+	"time"
+
+	"jq-proxy-service/internal/ipfilter"
 )
 
 // ConfigProvider defines the interface for configuration management
@@ -29,26 +34,420 @@ type ProxyService interface {
 type ProxyConfig struct {
 	Endpoints map[string]*Endpoint `json:"endpoints"`
 	Server    ServerConfig         `json:"server"`
+
+	// patterns holds the compiled wildcard endpoints, populated by Validate
+	patterns []*endpointPattern
+
+	// aliases maps each endpoint alias to its endpoint key, populated by Validate
+	aliases map[string]string
 }
 
 // Endpoint represents a target endpoint configuration
 type Endpoint struct {
 	Name   string `json:"name"`
+	Target string `json:"target,omitempty"`
+	// Targets load-balances requests across several upstreams instead of a single Target
+	Targets  []WeightedTarget `json:"targets,omitempty"`
+	CacheTTL int              `json:"cache_ttl,omitempty"`
+	// RateLimit caps outbound requests to the endpoint per second; zero means unlimited
+	RateLimit float64 `json:"rate_limit,omitempty"`
+	// Transport gives the endpoint its own connection pool instead of the shared client
+	Transport *TransportConfig `json:"transport,omitempty"`
+	// Aliases are additional names the endpoint can be called by, e.g. after a rename
+	Aliases []string `json:"aliases,omitempty"`
+	// UserAgent overrides the server's default User-Agent for this endpoint
+	UserAgent string `json:"user_agent,omitempty"`
+	// LogRequests can be set to false to keep the endpoint's paths and
+	// bodies out of the logs; unset means true
+	LogRequests *bool `json:"log_requests,omitempty"`
+	// ErrorOnNull fails every request to the endpoint whose transformation
+	// result is null
+	ErrorOnNull bool `json:"error_on_null,omitempty"`
+	// Routes send requests carrying a matching header to their own target
+	// instead of Target/Targets; the first matching route wins
+	Routes []HeaderRoute `json:"routes,omitempty"`
+	// ForwardCookies passes the upstream's Set-Cookie headers on to the client
+	ForwardCookies bool `json:"forward_cookies,omitempty"`
+	// UpstreamMethod forwards every request with this HTTP method instead of
+	// the one given in the request envelope
+	UpstreamMethod string `json:"upstream_method,omitempty"`
+	// Enabled can be set to false to reject requests to the endpoint, e.g.
+	// during maintenance, without removing it; unset means true
+	Enabled *bool `json:"enabled,omitempty"`
+	// DetectJSON parses response bodies without a specific Content-Type as
+	// JSON when they are valid JSON, instead of treating them as text
+	DetectJSON bool `json:"detect_json,omitempty"`
+	// PreciseNumbers decodes upstream JSON numbers exactly instead of as
+	// float64, so large integers such as 64-bit IDs survive jq queries
+	PreciseNumbers bool `json:"precise_numbers,omitempty"`
+	// DefaultBody holds fields deep-merged under every request body sent to
+	// the endpoint; fields the client sends win
+	DefaultBody map[string]interface{} `json:"default_body,omitempty"`
+	// IdempotencyTTL keeps the response to a mutating request carrying an
+	// Idempotency-Key header for this many seconds, returning it for
+	// duplicates instead of forwarding them; zero disables it
+	IdempotencyTTL int `json:"idempotency_ttl,omitempty"`
+	// JQVariables are bound to $name variables in every jq query run for the
+	// endpoint; path parameters of the same name win
+	JQVariables map[string]interface{} `json:"jq_variables,omitempty"`
+	// HeaderQueryParams copies inbound request headers into upstream query
+	// parameters, mapping each header name to a parameter name, for upstreams
+	// that take tenant or auth details in the query string
+	HeaderQueryParams map[string]string `json:"header_query_params,omitempty"`
+	// TargetVariables fill named {placeholders} in the endpoint's targets per
+	// request, mapping each to its source: "header:<name>" for an inbound
+	// request header or "path:<name>" for a path_pattern parameter
+	TargetVariables map[string]string `json:"target_variables,omitempty"`
+}
+
+// HeaderRoute forwards requests whose Header equals Value to Target
+type HeaderRoute struct {
+	Header string `json:"header"`
+	Value  string `json:"value"`
 	Target string `json:"target"`
 }
 
+// RouteTarget returns the target of the first route matching the request
+// headers, or an empty string when none matches
+func (e *Endpoint) RouteTarget(headers http.Header) string {
+	for _, route := range e.Routes {
+		if headers.Get(route.Header) == route.Value {
+			return route.Target
+		}
+	}
+	return ""
+}
+
+// MergeDefaultBody returns body with the endpoint's DefaultBody merged under
+// it. Nested objects are merged field by field and the client's value wins
+// any other conflict. Bodies that are not objects are returned unchanged, and
+// a missing body becomes the defaults for methods that carry a body.
+func (e *Endpoint) MergeDefaultBody(method string, body interface{}) interface{} {
+	if len(e.DefaultBody) == 0 {
+		return body
+	}
+	if body == nil {
+		if strings.EqualFold(method, http.MethodGet) || strings.EqualFold(method, http.MethodHead) {
+			return nil
+		}
+		return mergeObjects(e.DefaultBody, nil)
+	}
+	if object, ok := body.(map[string]interface{}); ok {
+		return mergeObjects(e.DefaultBody, object)
+	}
+	return body
+}
+
+// MergeHeaderQuery returns queryParams with the value of each header named in
+// HeaderQueryParams set as its mapped query parameter, replacing any value
+// the parameter already had. Headers the request does not carry are skipped.
+func (e *Endpoint) MergeHeaderQuery(queryParams url.Values, headers http.Header) url.Values {
+	var merged url.Values
+	for header, param := range e.HeaderQueryParams {
+		values := headers.Values(header)
+		if len(values) == 0 {
+			continue
+		}
+		if merged == nil {
+			merged = make(url.Values, len(queryParams)+len(e.HeaderQueryParams))
+			for name, existing := range queryParams {
+				merged[name] = existing
+			}
+		}
+		merged[param] = slices.Clone(values)
+	}
+	if merged == nil {
+		return queryParams
+	}
+	return merged
+}
+
+// MergeJQVariables returns the endpoint's JQVariables overridden by vars, or
+// vars itself when the endpoint defines none
+func (e *Endpoint) MergeJQVariables(vars map[string]interface{}) map[string]interface{} {
+	if len(e.JQVariables) == 0 {
+		return vars
+	}
+	merged := make(map[string]interface{}, len(e.JQVariables)+len(vars))
+	for name, value := range e.JQVariables {
+		merged[name] = value
+	}
+	for name, value := range vars {
+		merged[name] = value
+	}
+	return merged
+}
+
+// mergeObjects deep-merges overrides over defaults into a new object, leaving
+// both inputs untouched
+func mergeObjects(defaults, overrides map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(defaults)+len(overrides))
+	for key, value := range defaults {
+		if nested, ok := value.(map[string]interface{}); ok {
+			value = mergeObjects(nested, nil)
+		}
+		merged[key] = value
+	}
+	for key, value := range overrides {
+		nested, ok := value.(map[string]interface{})
+		if defaultNested, isObject := merged[key].(map[string]interface{}); ok && isObject {
+			value = mergeObjects(defaultNested, nested)
+		}
+		merged[key] = value
+	}
+	return merged
+}
+
+// IsEnabled reports whether the endpoint accepts requests
+func (e *Endpoint) IsEnabled() bool {
+	return e.Enabled == nil || *e.Enabled
+}
+
+// ShouldLogRequests reports whether request details may be logged for the endpoint
+func (e *Endpoint) ShouldLogRequests() bool {
+	return e.LogRequests == nil || *e.LogRequests
+}
+
+// TransportConfig tunes the connection pool used for an endpoint's upstreams.
+// Durations are in seconds; zero keeps the client default.
+type TransportConfig struct {
+	Timeout             int `json:"timeout,omitempty"`
+	MaxIdleConns        int `json:"max_idle_conns,omitempty"`
+	MaxIdleConnsPerHost int `json:"max_idle_conns_per_host,omitempty"`
+	IdleConnTimeout     int `json:"idle_conn_timeout,omitempty"`
+	KeepAlive           int `json:"keep_alive,omitempty"`
+	// DialTimeout bounds connecting to an upstream
+	DialTimeout int `json:"dial_timeout,omitempty"`
+	// ResponseHeaderTimeout bounds the wait for an upstream's response headers
+	ResponseHeaderTimeout int `json:"response_header_timeout,omitempty"`
+	// Proxy routes the endpoint's upstream requests through this proxy URL
+	// instead of the server's upstream proxy
+	Proxy string `json:"proxy,omitempty"`
+}
+
+// WeightedTarget is one of an endpoint's load-balanced upstreams. Targets
+// receive a share of requests proportional to their Weight.
+type WeightedTarget struct {
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+}
+
+// UnmarshalJSON accepts either a URL string, which has weight 1, or an
+// object with a url and a weight, which defaults to 1 when omitted
+func (t *WeightedTarget) UnmarshalJSON(data []byte) error {
+	var url string
+	if err := json.Unmarshal(data, &url); err == nil {
+		*t = WeightedTarget{URL: url, Weight: 1}
+		return nil
+	}
+
+	var raw struct {
+		URL    string `json:"url"`
+		Weight *int   `json:"weight"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("target must be a URL string or an object with url and weight")
+	}
+	*t = WeightedTarget{URL: raw.URL, Weight: 1}
+	if raw.Weight != nil {
+		t.Weight = *raw.Weight
+	}
+	return nil
+}
+
+// MarshalJSON writes targets with weight 1 as plain URL strings
+func (t WeightedTarget) MarshalJSON() ([]byte, error) {
+	if t.Weight == 1 {
+		return json.Marshal(t.URL)
+	}
+	type target WeightedTarget
+	return json.Marshal(target(t))
+}
+
+// TargetURLs returns the upstream URLs requests may be forwarded to
+func (e *Endpoint) TargetURLs() []string {
+	if len(e.Targets) > 0 {
+		urls := make([]string, len(e.Targets))
+		for i, target := range e.Targets {
+			urls[i] = target.URL
+		}
+		return urls
+	}
+	return []string{e.Target}
+}
+
+// TargetWeights returns the weights of the endpoint's targets, in the order
+// of TargetURLs, or nil when every target has the same weight
+func (e *Endpoint) TargetWeights() []int {
+	weights := make([]int, len(e.Targets))
+	weighted := false
+	for i, target := range e.Targets {
+		weights[i] = target.Weight
+		if target.Weight != e.Targets[0].Weight {
+			weighted = true
+		}
+	}
+	if !weighted {
+		return nil
+	}
+	return weights
+}
+
 // ServerConfig represents server-specific configuration
 type ServerConfig struct {
-	Port         int `json:"port"`
-	ReadTimeout  int `json:"read_timeout"`
-	WriteTimeout int `json:"write_timeout"`
+	Port         int           `json:"port"`
+	ReadTimeout  int           `json:"read_timeout"`
+	WriteTimeout int           `json:"write_timeout"`
+	Tracing      TracingConfig `json:"tracing,omitempty"`
+	Logging      LoggingConfig `json:"logging,omitempty"`
+	Metrics      MetricsConfig `json:"metrics,omitempty"`
+
+	// DialTimeout bounds connecting to upstreams in seconds; zero means the client default
+	DialTimeout int `json:"dial_timeout,omitempty"`
+
+	// ResponseHeaderTimeout bounds the wait for upstream response headers in
+	// seconds; zero means only the overall read timeout applies
+	ResponseHeaderTimeout int `json:"response_header_timeout,omitempty"`
+
+	// MaxTransformTime bounds jq execution in seconds; zero means no limit
+	MaxTransformTime int `json:"max_transform_time,omitempty"`
+
+	// MaxQueryComplexity rejects jq queries whose estimated cost exceeds it; zero means no limit
+	MaxQueryComplexity int `json:"max_query_complexity,omitempty"`
+
+	// MaxConcurrentUpstream caps in-flight upstream requests; zero means unlimited
+	MaxConcurrentUpstream int `json:"max_concurrent_upstream,omitempty"`
+
+	// MaxConcurrentTransforms caps response transformations running at once; zero means unlimited
+	MaxConcurrentTransforms int `json:"max_concurrent_transforms,omitempty"`
+
+	// MaxResultBytes caps the serialized size of transformation results; zero means unlimited
+	MaxResultBytes int `json:"max_result_bytes,omitempty"`
+
+	// MaxResultMemory caps the estimated in-memory size of transformation
+	// results; zero means unlimited
+	MaxResultMemory int `json:"max_result_memory,omitempty"`
+
+	// ResponseBufferBytes is the estimated result size above which JSON
+	// responses are encoded incrementally; zero always encodes them whole
+	ResponseBufferBytes int `json:"response_buffer_bytes,omitempty"`
+
+	// TransformCacheSize keeps up to this many transformation results, keyed
+	// by upstream body and query, for reuse; zero disables the cache
+	TransformCacheSize int `json:"transform_cache_size,omitempty"`
+
+	// MaxPathLength caps the length of proxied paths in bytes; zero means 8192
+	MaxPathLength int `json:"max_path_length,omitempty"`
+
+	// MaxRedirects caps the redirects followed per upstream request; zero means the client default
+	MaxRedirects int `json:"max_redirects,omitempty"`
+
+	// DisableRedirects returns upstream 3xx responses instead of following them
+	DisableRedirects bool `json:"disable_redirects,omitempty"`
+
+	// ErrorFormat selects the error body shape: "default" or "problem" (RFC 7807)
+	ErrorFormat string `json:"error_format,omitempty"`
+
+	// UserAgent is sent to upstreams when the caller sends none; empty means jq-proxy-service/<version>
+	UserAgent string `json:"user_agent,omitempty"`
+
+	// UpstreamProxy routes upstream requests through this proxy URL; empty
+	// uses the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables
+	UpstreamProxy string `json:"upstream_proxy,omitempty"`
+
+	// IPAllowlist restricts callers to these CIDRs or IPs; empty allows everyone not denied
+	IPAllowlist []string `json:"ip_allowlist,omitempty"`
+
+	// IPDenylist rejects callers from these CIDRs or IPs
+	IPDenylist []string `json:"ip_denylist,omitempty"`
+
+	// TrustedProxies lists the CIDRs or IPs of proxies whose X-Forwarded-For
+	// and X-Real-IP headers are honored when resolving the client IP
+	TrustedProxies []string `json:"trusted_proxies,omitempty"`
+
+	// JQLibrary holds jq function definitions available to every query
+	JQLibrary string `json:"jq_library,omitempty"`
+
+	// JQLibraryPath names a file of jq function definitions available to every query
+	JQLibraryPath string `json:"jq_library_path,omitempty"`
+
+	// JQEnv names the environment variables jq queries may read with env and
+	// $ENV; when empty, queries that read the environment are rejected
+	JQEnv []string `json:"jq_env,omitempty"`
+
+	// DefaultEndpoint is the endpoint served by the /p/{path} route, so
+	// single-upstream deployments can omit the endpoint name
+	DefaultEndpoint string `json:"default_endpoint,omitempty"`
+
+	// RequestIDHeader names the header request IDs are read from and
+	// returned in, such as X-Correlation-ID; empty means X-Request-ID
+	RequestIDHeader string `json:"request_id_header,omitempty"`
+
+	// AdminPrefix moves the health, metrics, config, version and cache routes
+	// under a path prefix such as /_admin; empty keeps them at the root
+	AdminPrefix string `json:"admin_prefix,omitempty"`
+
+	// AdminPort serves the admin routes on their own port, leaving only the
+	// proxy routes on Port; zero serves everything on Port
+	AdminPort int `json:"admin_port,omitempty"`
+
+	// DebugEndpoints enables admin routes that help clients debug their
+	// requests, such as /debug/echo
+	DebugEndpoints bool `json:"debug_endpoints,omitempty"`
+}
+
+// LoggingConfig represents the log format and output destination
+type LoggingConfig struct {
+	Format string `json:"format,omitempty"`
+	Output string `json:"output,omitempty"`
+
+	// BodyPreviewBytes logs a redacted preview of upstream bodies that fail to
+	// parse or transform, at debug level; zero disables the preview
+	BodyPreviewBytes int `json:"body_preview_bytes,omitempty"`
+}
+
+// MetricsConfig represents how response and transformation times are summarized
+type MetricsConfig struct {
+	// SampleSize is how many recent times are kept for computing percentiles;
+	// zero keeps the default of 1000
+	SampleSize int `json:"sample_size,omitempty"`
+
+	// ResponseBuckets are the upper bounds, in milliseconds, of the response
+	// time histogram; empty turns the histogram off
+	ResponseBuckets []int `json:"response_buckets,omitempty"`
+}
+
+// TracingConfig represents the optional OpenTelemetry tracing configuration
+type TracingConfig struct {
+	Enabled     bool   `json:"enabled"`
+	ServiceName string `json:"service_name,omitempty"`
+	Exporter    string `json:"exporter,omitempty"`
 }
 
+// Supported tracing exporters
+const (
+	TracingExporterNone   = "none"
+	TracingExporterStdout = "stdout"
+)
+
 // TransformationMode represents the type of transformation to apply
 type TransformationMode string
 
 const (
-	TransformationModeJQ TransformationMode = "jq"
+	TransformationModeJQ       TransformationMode = "jq"
+	TransformationModeJMESPath TransformationMode = "jmespath"
+	TransformationModeTemplate TransformationMode = "template"
+)
+
+// DefaultTemplateContentType is the content type of text rendered by a
+// template when the request does not set one
+const DefaultTemplateContentType = "text/plain; charset=utf-8"
+
+// Supported ways of handling jq runtime errors
+const (
+	OnErrorFail  = "fail"
+	OnErrorNull  = "null"
+	OnErrorEmpty = "empty"
 )
 
 // ProxyRequest represents the incoming request payload
@@ -57,12 +456,262 @@ type ProxyRequest struct {
 	Body               interface{}        `json:"body"`
 	TransformationMode TransformationMode `json:"transformation_mode,omitempty"`
 	JQQuery            string             `json:"jq_query,omitempty"`
+	// JQQueryHeader names an upstream response header whose value selects a
+	// query from JQQueryByHeader, falling back to JQQuery
+	JQQueryHeader   string            `json:"jq_query_header,omitempty"`
+	JQQueryByHeader map[string]string `json:"jq_query_by_header,omitempty"`
+	// JMESPathQuery is the response query in jmespath mode, used instead of JQQuery
+	JMESPathQuery string `json:"jmespath_query,omitempty"`
+	// Template is the Go text/template rendered in template mode, used instead of JQQuery
+	Template string `json:"template,omitempty"`
+	// ContentType is the content type of text rendered by a template
+	ContentType string `json:"content_type,omitempty"`
+	// ResponseContentType replaces the Content-Type header of a successful
+	// response, whatever format the result is written in
+	ResponseContentType string `json:"response_content_type,omitempty"`
+	// Exists lists paths such as "$.a.b" to check for in the response, which
+	// is replaced by an object mapping each path to whether it is present
+	Exists []string `json:"exists,omitempty"`
+	// ErrorJQQuery replaces JQQuery when the upstream responds with a 4xx/5xx status
+	ErrorJQQuery string `json:"error_jq_query,omitempty"`
+	// MaxResultBytes lowers the server's cap on the serialized transformation result
+	MaxResultBytes int `json:"max_result_bytes,omitempty"`
+	// Pipeline runs several transformation stages in order instead of JQQuery
+	Pipeline []TransformationStage `json:"pipeline,omitempty"`
+	// JQCollect always returns the final query's results as an array, even
+	// when it yields zero or one value
+	JQCollect bool `json:"jq_collect,omitempty"`
+	// StatusJQQuery computes the response status code from the transformed data
+	StatusJQQuery string `json:"status_jq_query,omitempty"`
+	// RequestJQQuery rewrites Body before it is sent to the upstream
+	RequestJQQuery string `json:"request_jq_query,omitempty"`
+	// Stream writes array results to the client element by element as the
+	// final query produces them
+	Stream bool `json:"stream,omitempty"`
+	// Query adds parameters to the upstream query string, replacing URL
+	// query parameters of the same name
+	Query QueryParams `json:"query,omitempty"`
+	// ErrorOnNull fails the request when the transformation result is null
+	// instead of returning null
+	ErrorOnNull bool `json:"error_on_null,omitempty"`
+	// NormalizeErrors returns 4xx/5xx responses as 200 with the transformed
+	// data wrapped in an {"error": ..., "status": ...} object
+	NormalizeErrors bool `json:"normalize_errors,omitempty"`
+	// OnError selects what a jq runtime error in the response query produces:
+	// a failed request ("fail", the default), null ("null") or no value ("empty")
+	OnError string `json:"on_error,omitempty"`
+	// Fallbacks fills top-level fields of an object result that are null or
+	// missing with the given values
+	Fallbacks map[string]interface{} `json:"fallbacks,omitempty"`
+	// PathPattern names segments of the proxied path, e.g. "users/{id}/orders",
+	// making each available to the jq queries as a variable such as $id
+	PathPattern string `json:"path_pattern,omitempty"`
+
+	// Variables are bound to $name variables in the request's jq queries
+	Variables map[string]interface{} `json:"-"`
+}
+
+// QueryParams holds query parameters given in the request envelope. Each
+// value may be a single string or an array of strings.
+type QueryParams map[string][]string
+
+// UnmarshalJSON accepts either a string or an array of strings per parameter
+func (qp *QueryParams) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	params := make(QueryParams, len(raw))
+	for name, value := range raw {
+		var single string
+		if err := json.Unmarshal(value, &single); err == nil {
+			params[name] = []string{single}
+			continue
+		}
+		var multiple []string
+		if err := json.Unmarshal(value, &multiple); err != nil {
+			return fmt.Errorf("query parameter %s must be a string or an array of strings", name)
+		}
+		params[name] = multiple
+	}
+	*qp = params
+	return nil
+}
+
+// MergeQuery returns the URL query parameters with the envelope's query
+// parameters applied over them. The URL values are not modified.
+func (pr *ProxyRequest) MergeQuery(queryParams url.Values) url.Values {
+	if len(pr.Query) == 0 {
+		return queryParams
+	}
+	merged := make(url.Values, len(queryParams)+len(pr.Query))
+	for name, values := range queryParams {
+		merged[name] = values
+	}
+	for name, values := range pr.Query {
+		merged[name] = values
+	}
+	return merged
+}
+
+// TransformationStage is a single step of a transformation pipeline
+type TransformationStage struct {
+	Mode  TransformationMode `json:"mode,omitempty"`
+	Query string             `json:"query"`
+}
+
+// QueryForStatus returns the jq query to apply to an upstream response with the given status
+func (pr *ProxyRequest) QueryForStatus(statusCode int) string {
+	if statusCode >= 400 && pr.ErrorJQQuery != "" {
+		return pr.ErrorJQQuery
+	}
+	return pr.JQQuery
+}
+
+// StagesForStatus returns the transformation stages to apply to an upstream
+// response with the given status. The error query takes precedence for 4xx/5xx
+// responses; otherwise the pipeline is used if set, or else the jq query.
+func (pr *ProxyRequest) StagesForStatus(statusCode int) []TransformationStage {
+	if statusCode >= 400 && pr.ErrorJQQuery != "" {
+		return []TransformationStage{{Mode: TransformationModeJQ, Query: pr.ErrorJQQuery}}
+	}
+	if len(pr.Pipeline) > 0 {
+		return pr.Pipeline
+	}
+	return []TransformationStage{{Mode: pr.TransformationMode, Query: pr.ModeQuery()}}
+}
+
+// ForResponseHeaders returns the request with JQQuery replaced by the query
+// JQQueryByHeader selects for the value of the JQQueryHeader response header.
+// The request itself is returned when no query is selected.
+func (pr *ProxyRequest) ForResponseHeaders(headers http.Header) *ProxyRequest {
+	if pr.JQQueryHeader == "" {
+		return pr
+	}
+	query, ok := pr.JQQueryByHeader[strings.TrimSpace(headers.Get(pr.JQQueryHeader))]
+	if !ok {
+		return pr
+	}
+	selected := *pr
+	selected.JQQuery = query
+	return &selected
+}
+
+// Names of the jq variables bound to the upstream response
+const (
+	// StatusVariable holds the upstream status code, e.g. $status == 404
+	StatusVariable = "status"
+	// HeadersVariable holds the upstream headers by lowercase name, with
+	// repeated headers joined by ", ", e.g. $headers["content-type"]
+	HeadersVariable = "headers"
+)
+
+// ForResponse returns the request with the upstream status code and headers
+// bound to the $status and $headers jq variables
+func (pr *ProxyRequest) ForResponse(statusCode int, headers http.Header) *ProxyRequest {
+	headerValues := make(map[string]interface{}, len(headers))
+	for name, values := range headers {
+		headerValues[strings.ToLower(name)] = strings.Join(values, ", ")
+	}
+
+	variables := make(map[string]interface{}, len(pr.Variables)+2)
+	for name, value := range pr.Variables {
+		variables[name] = value
+	}
+	variables[StatusVariable] = statusCode
+	variables[HeadersVariable] = headerValues
+
+	withResponse := *pr
+	withResponse.Variables = variables
+	return &withResponse
+}
+
+// ModeQuery returns the response query for the request's transformation
+// mode: JMESPathQuery in jmespath mode, Template in template mode and JQQuery otherwise
+func (pr *ProxyRequest) ModeQuery() string {
+	switch pr.TransformationMode {
+	case TransformationModeJMESPath:
+		return pr.JMESPathQuery
+	case TransformationModeTemplate:
+		return pr.Template
+	default:
+		return pr.JQQuery
+	}
+}
+
+// TextContentType returns the content type to send the transformed data with
+// when the final stage for the given upstream status renders a template, and
+// an empty string when the result is JSON
+func (pr *ProxyRequest) TextContentType(statusCode int) string {
+	stages := pr.StagesForStatus(statusCode)
+	if stages[len(stages)-1].Mode != TransformationModeTemplate {
+		return ""
+	}
+	if pr.ContentType != "" {
+		return pr.ContentType
+	}
+	return DefaultTemplateContentType
 }
 
 // ProxyResponse represents the response returned to the client
 type ProxyResponse struct {
 	Data   interface{} `json:"data"`
 	Status int         `json:"status"`
+
+	// Stream, when set, produces the transformed data incrementally instead of Data
+	Stream func(sink ResultSink) error `json:"-"`
+
+	// UpstreamDuration is how long the upstream call took; zero for cached responses
+	UpstreamDuration time.Duration `json:"-"`
+
+	// Cookies holds upstream Set-Cookie values for endpoints that forward them
+	Cookies []string `json:"-"`
+
+	// RetryAfter is the upstream's Retry-After header on 429 responses
+	RetryAfter string `json:"-"`
+
+	// ContentType, when set, sends Data, the text rendered by a template, with
+	// this content type instead of as JSON
+	ContentType string `json:"-"`
+
+	// HeadersOnly sends Status and Headers without a body, as for HEAD requests
+	HeadersOnly bool `json:"-"`
+
+	// Headers holds upstream headers passed on to the client
+	Headers http.Header `json:"-"`
+
+	// Replayed marks a response returned again for a duplicate idempotency key
+	Replayed bool `json:"-"`
+}
+
+// ResultSink receives a streamed transformation result. A result is delivered
+// either as one Value call, or as BeginArray, an Element call per element and
+// EndArray.
+type ResultSink interface {
+	Value(v interface{}) error
+	BeginArray() error
+	Element(v interface{}) error
+	EndArray() error
+}
+
+// MaxBatchItems bounds the number of operations in a single batch request
+const MaxBatchItems = 100
+
+// BatchItem is one proxy operation in a batch request. The proxy request
+// fields (method, body, jq_query, ...) sit alongside the endpoint and path.
+type BatchItem struct {
+	Endpoint string `json:"endpoint"`
+	Path     string `json:"path,omitempty"`
+	ProxyRequest
+}
+
+// BatchResult is the outcome of one batch item. Status is the upstream status
+// for successful items and the error status for failed ones.
+type BatchResult struct {
+	Status int          `json:"status"`
+	Data   interface{}  `json:"data,omitempty"`
+	Error  *ErrorDetail `json:"error,omitempty"`
 }
 
 // ErrorResponse represents an error response
@@ -70,11 +719,42 @@ type ErrorResponse struct {
 	Error ErrorDetail `json:"error"`
 }
 
+// ProblemDetail is an RFC 7807 problem details error body. Code, Details and
+// RequestID are extension members carrying the same data as ErrorDetail.
+type ProblemDetail struct {
+	Type      string      `json:"type"`
+	Title     string      `json:"title"`
+	Status    int         `json:"status"`
+	Detail    string      `json:"detail,omitempty"`
+	Instance  string      `json:"instance,omitempty"`
+	Code      string      `json:"code"`
+	Details   interface{} `json:"details,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+}
+
+// Supported error response formats
+const (
+	ErrorFormatDefault = "default"
+	ErrorFormatProblem = "problem"
+)
+
 // ErrorDetail contains error information
 type ErrorDetail struct {
-	Code    string      `json:"code"`
-	Message string      `json:"message"`
-	Details interface{} `json:"details,omitempty"`
+	Code      string      `json:"code"`
+	Message   string      `json:"message"`
+	Details   interface{} `json:"details,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+}
+
+// EndpointStatus summarizes the outcome of an endpoint's recent upstream
+// calls. Calls that fail to connect or get a 5xx response count as failures.
+type EndpointStatus struct {
+	Endpoint    string     `json:"endpoint"`
+	Requests    int        `json:"requests"`
+	Failures    int        `json:"failures"`
+	ErrorRate   float64    `json:"error_rate"`
+	LastFailure *time.Time `json:"last_failure,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
 }
 
 // Validate validates the ProxyRequest
@@ -84,16 +764,7 @@ func (pr *ProxyRequest) Validate() error {
 	}
 
 	// Validate HTTP method
-	validMethods := []string{"GET", "POST", "PUT", "DELETE", "PATCH", "HEAD", "OPTIONS"}
-	methodValid := false
-	upperMethod := strings.ToUpper(pr.Method)
-	for _, method := range validMethods {
-		if upperMethod == method {
-			methodValid = true
-			break
-		}
-	}
-	if !methodValid {
+	if !validHTTPMethod(pr.Method) {
 		return fmt.Errorf("invalid HTTP method: %s", pr.Method)
 	}
 
@@ -103,18 +774,153 @@ func (pr *ProxyRequest) Validate() error {
 	}
 
 	// Validate transformation mode
-	if pr.TransformationMode != TransformationModeJQ {
-		return fmt.Errorf("invalid transformation mode: %s. Must be 'jq'", pr.TransformationMode)
+	if !validTransformationMode(pr.TransformationMode) {
+		return fmt.Errorf("invalid transformation mode: %s", pr.TransformationMode)
 	}
 
-	// Validate jq query is provided
-	if pr.JQQuery == "" {
+	// Validate pipeline stages, which replace the jq query
+	if len(pr.Pipeline) > 0 {
+		if pr.JQQuery != "" || pr.JMESPathQuery != "" || pr.Template != "" {
+			return fmt.Errorf("jq_query and pipeline are mutually exclusive")
+		}
+		for i := range pr.Pipeline {
+			stage := &pr.Pipeline[i]
+			if stage.Mode == "" {
+				stage.Mode = TransformationModeJQ
+			}
+			if !validTransformationMode(stage.Mode) {
+				return fmt.Errorf("pipeline stage %d: invalid transformation mode: %s", i+1, stage.Mode)
+			}
+			if stage.Query == "" {
+				return fmt.Errorf("pipeline stage %d: query is required", i+1)
+			}
+		}
+	} else if pr.TransformationMode == TransformationModeJMESPath {
+		if pr.JMESPathQuery == "" {
+			return fmt.Errorf("jmespath_query is required")
+		}
+		if pr.JQQuery != "" {
+			return fmt.Errorf("jq_query is not used in jmespath mode; use jmespath_query")
+		}
+	} else if pr.TransformationMode == TransformationModeTemplate {
+		if pr.Template == "" {
+			return fmt.Errorf("template is required")
+		}
+		if pr.JQQuery != "" {
+			return fmt.Errorf("jq_query is not used in template mode; use template")
+		}
+	} else if len(pr.Exists) > 0 {
+		if pr.JQQuery != "" {
+			return fmt.Errorf("exists and jq_query are mutually exclusive")
+		}
+	} else if pr.JQQuery == "" {
+		// Validate jq query is provided
 		return fmt.Errorf("jq_query is required")
 	}
 
+	if len(pr.Exists) > 0 && (len(pr.Pipeline) > 0 || pr.TransformationMode != TransformationModeJQ) {
+		return fmt.Errorf("exists cannot be combined with a pipeline or another transformation mode")
+	}
+
+	if len(pr.Exists) > 0 && pr.Stream {
+		return fmt.Errorf("stream and exists are mutually exclusive")
+	}
+
+	if (pr.JQQueryHeader == "") != (len(pr.JQQueryByHeader) == 0) {
+		return fmt.Errorf("jq_query_header and jq_query_by_header must be set together")
+	}
+
+	if pr.JQQueryHeader != "" && (pr.TransformationMode != TransformationModeJQ || pr.JQQuery == "") {
+		return fmt.Errorf("jq_query_by_header requires a jq_query to fall back to")
+	}
+
+	if pr.PathPattern != "" {
+		if _, err := pathPatternSegments(pr.PathPattern); err != nil {
+			return fmt.Errorf("invalid path_pattern: %w", err)
+		}
+	}
+
+	if pr.NormalizeErrors && pr.Stream {
+		return fmt.Errorf("stream and normalize_errors are mutually exclusive")
+	}
+
+	if len(pr.Fallbacks) > 0 && pr.Stream {
+		return fmt.Errorf("stream and fallbacks are mutually exclusive")
+	}
+
+	if len(pr.Fallbacks) > 0 && len(pr.Exists) > 0 {
+		return fmt.Errorf("exists and fallbacks are mutually exclusive")
+	}
+
+	if pr.JMESPathQuery != "" && pr.TransformationMode != TransformationModeJMESPath {
+		return fmt.Errorf("jmespath_query requires transformation_mode 'jmespath'")
+	}
+
+	if pr.Template != "" && pr.TransformationMode != TransformationModeTemplate {
+		return fmt.Errorf("template requires transformation_mode 'template'")
+	}
+
+	if pr.ContentType != "" {
+		if _, _, err := mime.ParseMediaType(pr.ContentType); err != nil {
+			return fmt.Errorf("invalid content_type: %w", err)
+		}
+	}
+
+	if pr.ResponseContentType != "" {
+		mediaType, _, err := mime.ParseMediaType(pr.ResponseContentType)
+		if err != nil {
+			return fmt.Errorf("invalid response_content_type: %w", err)
+		}
+		if !strings.Contains(mediaType, "/") {
+			return fmt.Errorf("invalid response_content_type: %s is not a type/subtype media type", mediaType)
+		}
+	}
+
+	if pr.Stream && pr.TextContentType(http.StatusOK) != "" {
+		return fmt.Errorf("stream is not supported when the final stage renders a template")
+	}
+
+	if pr.MaxResultBytes < 0 {
+		return fmt.Errorf("max_result_bytes must be non-negative")
+	}
+
+	for name := range pr.Query {
+		if name == "" {
+			return fmt.Errorf("query parameter names must not be empty")
+		}
+	}
+
+	if pr.Stream && pr.StatusJQQuery != "" {
+		return fmt.Errorf("stream and status_jq_query are mutually exclusive")
+	}
+
+	switch pr.OnError {
+	case "", OnErrorFail, OnErrorNull, OnErrorEmpty:
+	default:
+		return fmt.Errorf("on_error must be '%s', '%s' or '%s'", OnErrorFail, OnErrorNull, OnErrorEmpty)
+	}
+
 	return nil
 }
 
+// validHTTPMethod reports whether method, in any case, is an HTTP method
+// requests may be forwarded with
+func validHTTPMethod(method string) bool {
+	switch strings.ToUpper(method) {
+	case "GET", "POST", "PUT", "DELETE", "PATCH", "HEAD", "OPTIONS":
+		return true
+	default:
+		return false
+	}
+}
+
+// validTransformationMode reports whether mode is well-formed. Modes are
+// pluggable, so whether a transformer is registered for it is checked by the
+// transformer when the request is served.
+func validTransformationMode(mode TransformationMode) bool {
+	return transformationModeName.MatchString(string(mode))
+}
+
 // Validate validates the ProxyConfig
 func (pc *ProxyConfig) Validate() error {
 	if len(pc.Endpoints) == 0 {
@@ -134,10 +940,29 @@ func (pc *ProxyConfig) Validate() error {
 		}
 	}
 
+	patterns, err := compileEndpointPatterns(pc.Endpoints)
+	if err != nil {
+		return err
+	}
+	pc.patterns = patterns
+
+	aliases, err := compileEndpointAliases(pc.Endpoints)
+	if err != nil {
+		return err
+	}
+	pc.aliases = aliases
+
 	if err := pc.Server.Validate(); err != nil {
 		return fmt.Errorf("invalid server configuration: %w", err)
 	}
 
+	// The default endpoint may be given by name, alias or a name matching a pattern
+	if pc.Server.DefaultEndpoint != "" {
+		if _, exists := pc.FindEndpoint(pc.Server.DefaultEndpoint); !exists {
+			return fmt.Errorf("invalid server configuration: default endpoint %s is not configured", pc.Server.DefaultEndpoint)
+		}
+	}
+
 	return nil
 }
 
@@ -147,18 +972,109 @@ func (e *Endpoint) Validate() error {
 		return fmt.Errorf("endpoint name is required")
 	}
 
-	if e.Target == "" {
-		return fmt.Errorf("endpoint target is required")
+	if e.Target != "" && len(e.Targets) > 0 {
+		return fmt.Errorf("endpoint target and targets are mutually exclusive")
 	}
 
-	// Basic URL validation
-	if !strings.HasPrefix(e.Target, "http://") && !strings.HasPrefix(e.Target, "https://") {
-		return fmt.Errorf("endpoint target must be a valid HTTP/HTTPS URL")
+	for _, target := range e.Targets {
+		if target.Weight <= 0 {
+			return fmt.Errorf("target %s: weight must be positive", target.URL)
+		}
+	}
+
+	for _, target := range e.TargetURLs() {
+		if target == "" {
+			return fmt.Errorf("endpoint target is required")
+		}
+
+		// Basic URL validation
+		if !strings.HasPrefix(target, "http://") && !strings.HasPrefix(target, "https://") {
+			return fmt.Errorf("endpoint target must be a valid HTTP/HTTPS URL")
+		}
+	}
+
+	for i, route := range e.Routes {
+		if route.Header == "" || route.Value == "" {
+			return fmt.Errorf("route %d: header and value are required", i+1)
+		}
+		if !strings.HasPrefix(route.Target, "http://") && !strings.HasPrefix(route.Target, "https://") {
+			return fmt.Errorf("route %d: target must be a valid HTTP/HTTPS URL", i+1)
+		}
+	}
+
+	if err := e.validateTargetVariables(); err != nil {
+		return err
 	}
 
+	for name := range e.JQVariables {
+		if !jqVariableName.MatchString(name) {
+			return fmt.Errorf("jq variable name %s is not a valid jq variable name", name)
+		}
+		if reservedJQVariables[name] {
+			return fmt.Errorf("jq variable name %s is reserved", name)
+		}
+	}
+
+	for header, param := range e.HeaderQueryParams {
+		if !isHeaderName(header) {
+			return fmt.Errorf("header_query_params: invalid header name %q", header)
+		}
+		if param == "" {
+			return fmt.Errorf("header_query_params: header %s needs a query parameter name", header)
+		}
+	}
+
+	if e.UpstreamMethod != "" && !validHTTPMethod(e.UpstreamMethod) {
+		return fmt.Errorf("invalid upstream method: %s", e.UpstreamMethod)
+	}
+
+	if e.CacheTTL < 0 {
+		return fmt.Errorf("cache TTL must be non-negative")
+	}
+
+	if e.RateLimit < 0 {
+		return fmt.Errorf("rate limit must be non-negative")
+	}
+
+	if e.IdempotencyTTL < 0 {
+		return fmt.Errorf("idempotency TTL must be non-negative")
+	}
+
+	if e.Transport != nil {
+		if err := e.Transport.Validate(); err != nil {
+			return fmt.Errorf("invalid transport: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Validate validates the TransportConfig
+func (tc *TransportConfig) Validate() error {
+	if tc.Timeout < 0 || tc.MaxIdleConns < 0 || tc.MaxIdleConnsPerHost < 0 ||
+		tc.IdleConnTimeout < 0 || tc.KeepAlive < 0 || tc.DialTimeout < 0 || tc.ResponseHeaderTimeout < 0 {
+		return fmt.Errorf("transport settings must be non-negative")
+	}
+	if tc.Proxy != "" {
+		return validateProxyURL(tc.Proxy)
+	}
 	return nil
 }
 
+// validateProxyURL checks that a proxy URL is absolute with a supported scheme
+func validateProxyURL(proxyURL string) error {
+	parsed, err := url.Parse(proxyURL)
+	if err != nil || parsed.Host == "" {
+		return fmt.Errorf("proxy must be a valid URL")
+	}
+	switch parsed.Scheme {
+	case "http", "https", "socks5":
+		return nil
+	default:
+		return fmt.Errorf("proxy scheme must be http, https or socks5")
+	}
+}
+
 // Validate validates the ServerConfig
 func (sc *ServerConfig) Validate() error {
 	if sc.Port <= 0 || sc.Port > 65535 {
@@ -173,9 +1089,162 @@ func (sc *ServerConfig) Validate() error {
 		return fmt.Errorf("write timeout must be non-negative")
 	}
 
+	if sc.DialTimeout < 0 {
+		return fmt.Errorf("dial timeout must be non-negative")
+	}
+
+	if sc.ResponseHeaderTimeout < 0 {
+		return fmt.Errorf("response header timeout must be non-negative")
+	}
+
+	if sc.MaxTransformTime < 0 {
+		return fmt.Errorf("max transform time must be non-negative")
+	}
+
+	if sc.MaxQueryComplexity < 0 {
+		return fmt.Errorf("max query complexity must be non-negative")
+	}
+
+	for _, name := range sc.JQEnv {
+		if name == "" || strings.Contains(name, "=") {
+			return fmt.Errorf("jq_env: invalid environment variable name %q", name)
+		}
+	}
+
+	if sc.MaxConcurrentUpstream < 0 {
+		return fmt.Errorf("max concurrent upstream must be non-negative")
+	}
+
+	if sc.MaxConcurrentTransforms < 0 {
+		return fmt.Errorf("max concurrent transforms must be non-negative")
+	}
+
+	if sc.MaxResultBytes < 0 {
+		return fmt.Errorf("max result bytes must be non-negative")
+	}
+
+	if sc.MaxResultMemory < 0 {
+		return fmt.Errorf("max result memory must be non-negative")
+	}
+
+	if sc.ResponseBufferBytes < 0 {
+		return fmt.Errorf("response buffer bytes must be non-negative")
+	}
+
+	if sc.TransformCacheSize < 0 {
+		return fmt.Errorf("transform cache size must be non-negative")
+	}
+
+	if sc.MaxPathLength < 0 {
+		return fmt.Errorf("max path length must be non-negative")
+	}
+
+	if sc.UpstreamProxy != "" {
+		if err := validateProxyURL(sc.UpstreamProxy); err != nil {
+			return fmt.Errorf("upstream %w", err)
+		}
+	}
+
+	if sc.MaxRedirects < 0 {
+		return fmt.Errorf("max redirects must be non-negative")
+	}
+
+	if err := sc.Tracing.Validate(); err != nil {
+		return fmt.Errorf("invalid tracing configuration: %w", err)
+	}
+
+	if sc.Logging.Format != "" && sc.Logging.Format != "json" && sc.Logging.Format != "text" {
+		return fmt.Errorf("log format must be 'json' or 'text'")
+	}
+
+	switch sc.ErrorFormat {
+	case "", ErrorFormatDefault, ErrorFormatProblem:
+	default:
+		return fmt.Errorf("error format must be '%s' or '%s'", ErrorFormatDefault, ErrorFormatProblem)
+	}
+
+	if _, err := ipfilter.New(sc.IPAllowlist, sc.IPDenylist); err != nil {
+		return err
+	}
+
+	if _, err := ipfilter.ParseCIDRs(sc.TrustedProxies); err != nil {
+		return fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+
+	if sc.Logging.BodyPreviewBytes < 0 {
+		return fmt.Errorf("body preview bytes must be non-negative")
+	}
+
+	if sc.Metrics.SampleSize < 0 {
+		return fmt.Errorf("metrics sample size must be non-negative")
+	}
+
+	for _, bound := range sc.Metrics.ResponseBuckets {
+		if bound <= 0 {
+			return fmt.Errorf("metrics response buckets must be positive")
+		}
+	}
+
+	if sc.AdminPort < 0 || sc.AdminPort > 65535 {
+		return fmt.Errorf("admin port must be between 1 and 65535")
+	}
+
+	if sc.AdminPort != 0 && sc.AdminPort == sc.Port {
+		return fmt.Errorf("admin port must differ from port")
+	}
+
+	if err := validateAdminPrefix(sc.AdminPrefix); err != nil {
+		return err
+	}
+
+	if sc.RequestIDHeader != "" && !isHeaderName(sc.RequestIDHeader) {
+		return fmt.Errorf("request ID header %q is not a valid header name", sc.RequestIDHeader)
+	}
+
+	return nil
+}
+
+// validateAdminPrefix checks that an admin route prefix is a rooted path that
+// does not shadow the proxy routes
+func validateAdminPrefix(prefix string) error {
+	if prefix == "" {
+		return nil
+	}
+	if !strings.HasPrefix(prefix, "/") || strings.HasSuffix(prefix, "/") {
+		return fmt.Errorf("admin prefix must start with '/' and must not end with '/'")
+	}
+	for _, reserved := range []string{"/proxy", "/p", "/upload"} {
+		if prefix == reserved || strings.HasPrefix(prefix, reserved+"/") {
+			return fmt.Errorf("admin prefix must not be under %s", reserved)
+		}
+	}
 	return nil
 }
 
+// isHeaderName reports whether name is a valid HTTP header field name
+func isHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		isAlnum := c < 0x80 && (c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9')
+		if !isAlnum && !strings.ContainsRune("!#$%&'*+-.^_`|~", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate validates the TracingConfig
+func (tc *TracingConfig) Validate() error {
+	switch tc.Exporter {
+	case "", TracingExporterNone, TracingExporterStdout:
+		return nil
+	default:
+		return fmt.Errorf("unsupported tracing exporter: %s", tc.Exporter)
+	}
+}
+
 // ParseProxyRequest parses JSON data into a ProxyRequest
 func ParseProxyRequest(data []byte) (*ProxyRequest, error) {
 	var req ProxyRequest
@@ -190,6 +1259,94 @@ func ParseProxyRequest(data []byte) (*ProxyRequest, error) {
 	return &req, nil
 }
 
+// ParseBatchRequest parses a batch request body into its items. Items are not
+// validated here so that an invalid item fails on its own rather than failing
+// the whole batch.
+func ParseBatchRequest(data []byte) ([]BatchItem, error) {
+	var items []BatchItem
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	if len(items) == 0 {
+		return nil, fmt.Errorf("batch must contain at least one item")
+	}
+
+	if len(items) > MaxBatchItems {
+		return nil, fmt.Errorf("batch must contain at most %d items", MaxBatchItems)
+	}
+
+	return items, nil
+}
+
+// Validate validates the BatchItem
+func (bi *BatchItem) Validate() error {
+	if bi.Endpoint == "" {
+		return fmt.Errorf("endpoint is required")
+	}
+	return bi.ProxyRequest.Validate()
+}
+
+// Envelope fields taken as single values from form-encoded proxy requests;
+// "body" is handled on its own
+var formEnvelopeFields = []string{
+	"method",
+	"transformation_mode",
+	"jq_query",
+	"jmespath_query",
+	"template",
+	"content_type",
+	"response_content_type",
+	"error_jq_query",
+	"status_jq_query",
+	"request_jq_query",
+}
+
+// ParseProxyRequestForm converts form-encoded data into a ProxyRequest.
+// The envelope fields (method, transformation_mode and the query fields)
+// are taken as single values. A "body" field is decoded as JSON when possible
+// and used as a string otherwise; without it, all remaining fields form the body object,
+// with repeated keys becoming arrays.
+func ParseProxyRequestForm(values url.Values) (*ProxyRequest, error) {
+	envelope := make(map[string]interface{})
+	for _, field := range formEnvelopeFields {
+		if values.Has(field) {
+			envelope[field] = values.Get(field)
+		}
+	}
+
+	if values.Has("body") {
+		var body interface{}
+		raw := values.Get("body")
+		if err := json.Unmarshal([]byte(raw), &body); err != nil {
+			body = raw
+		}
+		envelope["body"] = body
+	} else {
+		body := make(map[string]interface{})
+		for key, fieldValues := range values {
+			if slices.Contains(formEnvelopeFields, key) {
+				continue
+			}
+			if len(fieldValues) == 1 {
+				body[key] = fieldValues[0]
+			} else {
+				body[key] = fieldValues
+			}
+		}
+		if len(body) > 0 {
+			envelope["body"] = body
+		}
+	}
+
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		return nil, fmt.Errorf("invalid form data: %w", err)
+	}
+
+	return ParseProxyRequest(data)
+}
+
 // ParseProxyConfig parses JSON data into a ProxyConfig
 func ParseProxyConfig(data []byte) (*ProxyConfig, error) {
 	var config ProxyConfig
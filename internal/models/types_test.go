@@ -1,6 +1,9 @@
 package models
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/url"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -74,6 +77,110 @@ func TestProxyRequest_Validate(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "negative max_result_bytes",
+			request: ProxyRequest{
+				Method:         "GET",
+				JQQuery:        ".",
+				MaxResultBytes: -1,
+			},
+			wantErr: true,
+			errMsg:  "max_result_bytes must be non-negative",
+		},
+		{
+			name: "pipeline without jq_query",
+			request: ProxyRequest{
+				Method:   "GET",
+				Pipeline: []TransformationStage{{Query: ".data"}, {Mode: TransformationModeJQ, Query: ".name"}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "pipeline with jq_query",
+			request: ProxyRequest{
+				Method:   "GET",
+				JQQuery:  ".",
+				Pipeline: []TransformationStage{{Query: ".data"}},
+			},
+			wantErr: true,
+			errMsg:  "jq_query and pipeline are mutually exclusive",
+		},
+		{
+			name: "stream with status query",
+			request: ProxyRequest{
+				Method:        "GET",
+				JQQuery:       ".items",
+				StatusJQQuery: ".status",
+				Stream:        true,
+			},
+			wantErr: true,
+			errMsg:  "stream and status_jq_query are mutually exclusive",
+		},
+		{
+			name: "on_error null",
+			request: ProxyRequest{
+				Method:  "GET",
+				JQQuery: ".items",
+				OnError: OnErrorNull,
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid on_error",
+			request: ProxyRequest{
+				Method:  "GET",
+				JQQuery: ".items",
+				OnError: "ignore",
+			},
+			wantErr: true,
+			errMsg:  "on_error must be 'fail', 'null' or 'empty'",
+		},
+		{
+			name: "exists without jq query",
+			request: ProxyRequest{
+				Method: "GET",
+				Exists: []string{"$.a.b"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "exists with jq query",
+			request: ProxyRequest{
+				Method:  "GET",
+				JQQuery: ".",
+				Exists:  []string{"$.a.b"},
+			},
+			wantErr: true,
+			errMsg:  "exists and jq_query are mutually exclusive",
+		},
+		{
+			name: "exists with stream",
+			request: ProxyRequest{
+				Method: "GET",
+				Exists: []string{"$.a.b"},
+				Stream: true,
+			},
+			wantErr: true,
+			errMsg:  "stream and exists are mutually exclusive",
+		},
+		{
+			name: "pipeline stage without query",
+			request: ProxyRequest{
+				Method:   "GET",
+				Pipeline: []TransformationStage{{Query: ".data"}, {}},
+			},
+			wantErr: true,
+			errMsg:  "pipeline stage 2: query is required",
+		},
+		{
+			name: "pipeline stage with invalid mode",
+			request: ProxyRequest{
+				Method:   "GET",
+				Pipeline: []TransformationStage{{Mode: "JSON Path", Query: "$.data"}},
+			},
+			wantErr: true,
+			errMsg:  "pipeline stage 1: invalid transformation mode: JSON Path",
+		},
 		{
 			name: "jq mode without query",
 			request: ProxyRequest{
@@ -84,16 +191,166 @@ func TestProxyRequest_Validate(t *testing.T) {
 			wantErr: true,
 			errMsg:  "jq_query is required",
 		},
+		{
+			name: "jmespath mode",
+			request: ProxyRequest{
+				Method:             "GET",
+				TransformationMode: TransformationModeJMESPath,
+				JMESPathQuery:      "data.users[*].name",
+			},
+		},
+		{
+			name: "jmespath mode without query",
+			request: ProxyRequest{
+				Method:             "GET",
+				TransformationMode: TransformationModeJMESPath,
+				JQQuery:            ".data",
+			},
+			wantErr: true,
+			errMsg:  "jmespath_query is required",
+		},
+		{
+			name: "jmespath query in jq mode",
+			request: ProxyRequest{
+				Method:        "GET",
+				JQQuery:       ".data",
+				JMESPathQuery: "data",
+			},
+			wantErr: true,
+			errMsg:  "jmespath_query requires transformation_mode 'jmespath'",
+		},
+		{
+			name: "template mode",
+			request: ProxyRequest{
+				Method:             "GET",
+				TransformationMode: TransformationModeTemplate,
+				Template:           "{{.title}}",
+				ContentType:        "text/markdown",
+			},
+		},
+		{
+			name: "template mode without template",
+			request: ProxyRequest{
+				Method:             "GET",
+				TransformationMode: TransformationModeTemplate,
+			},
+			wantErr: true,
+			errMsg:  "template is required",
+		},
+		{
+			name: "template with invalid content type",
+			request: ProxyRequest{
+				Method:             "GET",
+				TransformationMode: TransformationModeTemplate,
+				Template:           "{{.title}}",
+				ContentType:        "text/",
+			},
+			wantErr: true,
+			errMsg:  "invalid content_type",
+		},
+		{
+			name: "streamed fallbacks",
+			request: ProxyRequest{
+				Method:    "GET",
+				JQQuery:   ".",
+				Stream:    true,
+				Fallbacks: map[string]interface{}{"name": "unknown"},
+			},
+			wantErr: true,
+			errMsg:  "stream and fallbacks are mutually exclusive",
+		},
+		{
+			name: "fallbacks with exists",
+			request: ProxyRequest{
+				Method:    "GET",
+				Exists:    []string{"$.name"},
+				Fallbacks: map[string]interface{}{"name": "unknown"},
+			},
+			wantErr: true,
+			errMsg:  "exists and fallbacks are mutually exclusive",
+		},
+		{
+			name: "jq_query_by_header without a header",
+			request: ProxyRequest{
+				Method:          "GET",
+				JQQuery:         ".",
+				JQQueryByHeader: map[string]string{"2": ".result"},
+			},
+			wantErr: true,
+			errMsg:  "jq_query_header and jq_query_by_header must be set together",
+		},
+		{
+			name: "jq_query_by_header without a default query",
+			request: ProxyRequest{
+				Method:          "GET",
+				Pipeline:        []TransformationStage{{Query: "."}},
+				JQQueryHeader:   "X-API-Version",
+				JQQueryByHeader: map[string]string{"2": ".result"},
+			},
+			wantErr: true,
+			errMsg:  "jq_query_by_header requires a jq_query to fall back to",
+		},
+		{
+			name: "streamed normalize_errors",
+			request: ProxyRequest{
+				Method:          "GET",
+				JQQuery:         ".",
+				Stream:          true,
+				NormalizeErrors: true,
+			},
+			wantErr: true,
+			errMsg:  "stream and normalize_errors are mutually exclusive",
+		},
+		{
+			name: "invalid response content type",
+			request: ProxyRequest{
+				Method:              "GET",
+				JQQuery:             ".",
+				ResponseContentType: "json",
+			},
+			wantErr: true,
+			errMsg:  "invalid response_content_type",
+		},
+		{
+			name: "malformed response content type",
+			request: ProxyRequest{
+				Method:              "GET",
+				JQQuery:             ".",
+				ResponseContentType: "text/plain; charset",
+			},
+			wantErr: true,
+			errMsg:  "invalid response_content_type",
+		},
+		{
+			name: "streamed template",
+			request: ProxyRequest{
+				Method:             "GET",
+				TransformationMode: TransformationModeTemplate,
+				Template:           "{{.title}}",
+				Stream:             true,
+			},
+			wantErr: true,
+			errMsg:  "stream is not supported when the final stage renders a template",
+		},
 		{
 			name: "invalid transformation mode",
 			request: ProxyRequest{
 				Method:             "GET",
 				Body:               nil,
-				TransformationMode: "invalid",
+				TransformationMode: "not a mode",
 				JQQuery:            "{result: .data}",
 			},
 			wantErr: true,
-			errMsg:  "invalid transformation mode: invalid. Must be 'jq'",
+			errMsg:  "invalid transformation mode: not a mode",
+		},
+		{
+			name: "transformation mode of a registered transformer",
+			request: ProxyRequest{
+				Method:             "GET",
+				TransformationMode: "upper",
+				JQQuery:            "name",
+			},
+			wantErr: false,
 		},
 	}
 
@@ -141,6 +398,171 @@ func TestEndpoint_Validate(t *testing.T) {
 			wantErr: true,
 			errMsg:  "endpoint target is required",
 		},
+		{
+			name: "valid header route",
+			endpoint: Endpoint{
+				Name:   "test-service",
+				Target: "https://api.example.com",
+				Routes: []HeaderRoute{{Header: "X-Tenant", Value: "acme", Target: "https://acme.example.com"}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "upstream method override",
+			endpoint: Endpoint{
+				Name:           "test-service",
+				Target:         "https://api.example.com",
+				UpstreamMethod: "post",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid upstream method",
+			endpoint: Endpoint{
+				Name:           "test-service",
+				Target:         "https://api.example.com",
+				UpstreamMethod: "FETCH",
+			},
+			wantErr: true,
+			errMsg:  "invalid upstream method: FETCH",
+		},
+		{
+			name: "negative idempotency TTL",
+			endpoint: Endpoint{
+				Name:           "test-service",
+				Target:         "https://api.example.com",
+				IdempotencyTTL: -1,
+			},
+			wantErr: true,
+			errMsg:  "idempotency TTL must be non-negative",
+		},
+		{
+			name: "valid jq variables",
+			endpoint: Endpoint{
+				Name:        "test-service",
+				Target:      "https://api.example.com",
+				JQVariables: map[string]interface{}{"tenant_id": "acme", "limits": map[string]interface{}{"max": float64(10)}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid jq variable name",
+			endpoint: Endpoint{
+				Name:        "test-service",
+				Target:      "https://api.example.com",
+				JQVariables: map[string]interface{}{"tenant-id": "acme"},
+			},
+			wantErr: true,
+			errMsg:  "jq variable name tenant-id is not a valid jq variable name",
+		},
+		{
+			name: "reserved jq variable name",
+			endpoint: Endpoint{
+				Name:        "test-service",
+				Target:      "https://api.example.com",
+				JQVariables: map[string]interface{}{"ENV": "prod"},
+			},
+			wantErr: true,
+			errMsg:  "jq variable name ENV is reserved",
+		},
+		{
+			name: "invalid header query param header",
+			endpoint: Endpoint{
+				Name:              "test-service",
+				Target:            "https://api.example.com",
+				HeaderQueryParams: map[string]string{"X Tenant": "tenant"},
+			},
+			wantErr: true,
+			errMsg:  `header_query_params: invalid header name "X Tenant"`,
+		},
+		{
+			name: "header query param without parameter name",
+			endpoint: Endpoint{
+				Name:              "test-service",
+				Target:            "https://api.example.com",
+				HeaderQueryParams: map[string]string{"X-Tenant": ""},
+			},
+			wantErr: true,
+			errMsg:  "header_query_params: header X-Tenant needs a query parameter name",
+		},
+		{
+			name: "target placeholder without source",
+			endpoint: Endpoint{
+				Name:   "test-service",
+				Target: "https://api.example.com/{region}",
+			},
+			wantErr: true,
+			errMsg:  "target https://api.example.com/{region}: placeholder {region} has no source in target_variables",
+		},
+		{
+			name: "route target placeholder without source",
+			endpoint: Endpoint{
+				Name:            "test-service",
+				Target:          "https://api.example.com/{region}",
+				TargetVariables: map[string]string{"region": "header:X-Region"},
+				Routes:          []HeaderRoute{{Header: "X-Beta", Value: "1", Target: "https://{env}.example.com"}},
+			},
+			wantErr: true,
+			errMsg:  "target https://{env}.example.com: placeholder {env} has no source in target_variables",
+		},
+		{
+			name: "invalid target variable source",
+			endpoint: Endpoint{
+				Name:            "test-service",
+				Target:          "https://api.example.com/{region}",
+				TargetVariables: map[string]string{"region": "query:region"},
+			},
+			wantErr: true,
+			errMsg:  "target_variables: variable region source must be header:<name> or path:<name>",
+		},
+		{
+			name: "invalid target variable header",
+			endpoint: Endpoint{
+				Name:            "test-service",
+				Target:          "https://api.example.com/{region}",
+				TargetVariables: map[string]string{"region": "header:X Region"},
+			},
+			wantErr: true,
+			errMsg:  `target_variables: variable region has invalid header name "X Region"`,
+		},
+		{
+			name: "target variables from header and path",
+			endpoint: Endpoint{
+				Name:            "test-service",
+				Target:          "https://{region}.example.com/tenants/{tenant}",
+				TargetVariables: map[string]string{"region": "header:X-Region", "tenant": "path:tenant"},
+			},
+		},
+		{
+			name: "jq variable bound to the response",
+			endpoint: Endpoint{
+				Name:        "test-service",
+				Target:      "https://api.example.com",
+				JQVariables: map[string]interface{}{"status": "active"},
+			},
+			wantErr: true,
+			errMsg:  "jq variable name status is reserved",
+		},
+		{
+			name: "header route without value",
+			endpoint: Endpoint{
+				Name:   "test-service",
+				Target: "https://api.example.com",
+				Routes: []HeaderRoute{{Header: "X-Tenant", Target: "https://acme.example.com"}},
+			},
+			wantErr: true,
+			errMsg:  "route 1: header and value are required",
+		},
+		{
+			name: "header route with invalid target",
+			endpoint: Endpoint{
+				Name:   "test-service",
+				Target: "https://api.example.com",
+				Routes: []HeaderRoute{{Header: "X-Tenant", Value: "acme", Target: "acme.example.com"}},
+			},
+			wantErr: true,
+			errMsg:  "route 1: target must be a valid HTTP/HTTPS URL",
+		},
 		{
 			name: "invalid target URL",
 			endpoint: Endpoint{
@@ -150,6 +572,45 @@ func TestEndpoint_Validate(t *testing.T) {
 			wantErr: true,
 			errMsg:  "endpoint target must be a valid HTTP/HTTPS URL",
 		},
+		{
+			name: "negative rate limit",
+			endpoint: Endpoint{
+				Name:      "test-service",
+				Target:    "https://api.example.com",
+				RateLimit: -1,
+			},
+			wantErr: true,
+			errMsg:  "rate limit must be non-negative",
+		},
+		{
+			name: "negative transport setting",
+			endpoint: Endpoint{
+				Name:      "test-service",
+				Target:    "https://api.example.com",
+				Transport: &TransportConfig{MaxIdleConnsPerHost: -1},
+			},
+			wantErr: true,
+			errMsg:  "invalid transport: transport settings must be non-negative",
+		},
+		{
+			name: "transport proxy",
+			endpoint: Endpoint{
+				Name:      "test-service",
+				Target:    "https://api.example.com",
+				Transport: &TransportConfig{Proxy: "http://proxy.internal:3128"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid transport proxy scheme",
+			endpoint: Endpoint{
+				Name:      "test-service",
+				Target:    "https://api.example.com",
+				Transport: &TransportConfig{Proxy: "ftp://proxy.internal"},
+			},
+			wantErr: true,
+			errMsg:  "invalid transport: proxy scheme must be http, https or socks5",
+		},
 		{
 			name: "http target",
 			endpoint: Endpoint{
@@ -158,6 +619,50 @@ func TestEndpoint_Validate(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "multiple targets",
+			endpoint: Endpoint{
+				Name:    "test-service",
+				Targets: []WeightedTarget{{URL: "https://a.example.com", Weight: 1}, {URL: "https://b.example.com", Weight: 1}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "weighted targets",
+			endpoint: Endpoint{
+				Name:    "test-service",
+				Targets: []WeightedTarget{{URL: "https://a.example.com", Weight: 9}, {URL: "https://b.example.com", Weight: 1}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "zero target weight",
+			endpoint: Endpoint{
+				Name:    "test-service",
+				Targets: []WeightedTarget{{URL: "https://a.example.com", Weight: 1}, {URL: "https://b.example.com", Weight: 0}},
+			},
+			wantErr: true,
+			errMsg:  "target https://b.example.com: weight must be positive",
+		},
+		{
+			name: "target and targets",
+			endpoint: Endpoint{
+				Name:    "test-service",
+				Target:  "https://api.example.com",
+				Targets: []WeightedTarget{{URL: "https://a.example.com", Weight: 1}},
+			},
+			wantErr: true,
+			errMsg:  "endpoint target and targets are mutually exclusive",
+		},
+		{
+			name: "invalid URL in targets",
+			endpoint: Endpoint{
+				Name:    "test-service",
+				Targets: []WeightedTarget{{URL: "https://a.example.com", Weight: 1}, {URL: "invalid-url", Weight: 1}},
+			},
+			wantErr: true,
+			errMsg:  "endpoint target must be a valid HTTP/HTTPS URL",
+		},
 	}
 
 	for _, tt := range tests {
@@ -173,6 +678,153 @@ func TestEndpoint_Validate(t *testing.T) {
 	}
 }
 
+func TestWeightedTarget_JSON(t *testing.T) {
+	var endpoint Endpoint
+	err := json.Unmarshal([]byte(`{
+		"name": "api",
+		"targets": [
+			"https://a.example.com",
+			{"url": "https://b.example.com", "weight": 4},
+			{"url": "https://c.example.com"},
+			{"url": "https://d.example.com", "weight": 0}
+		]
+	}`), &endpoint)
+	require.NoError(t, err)
+	assert.Equal(t, []WeightedTarget{
+		{URL: "https://a.example.com", Weight: 1},
+		{URL: "https://b.example.com", Weight: 4},
+		{URL: "https://c.example.com", Weight: 1},
+		{URL: "https://d.example.com", Weight: 0},
+	}, endpoint.Targets)
+	assert.Equal(t, []int{1, 4, 1, 0}, endpoint.TargetWeights())
+
+	data, err := json.Marshal(endpoint.Targets[:2])
+	require.NoError(t, err)
+	assert.JSONEq(t, `["https://a.example.com", {"url": "https://b.example.com", "weight": 4}]`, string(data))
+
+	err = json.Unmarshal([]byte(`{"name": "api", "targets": [42]}`), &endpoint)
+	assert.Error(t, err)
+}
+
+func TestEndpoint_MergeDefaultBody(t *testing.T) {
+	endpoint := &Endpoint{
+		Name:   "events",
+		Target: "https://events.example.com",
+		DefaultBody: map[string]interface{}{
+			"source":  "proxy",
+			"version": float64(1),
+			"meta":    map[string]interface{}{"region": "eu", "tags": []interface{}{"default"}},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		body   interface{}
+		want   interface{}
+	}{
+		{
+			name:   "client fields are kept alongside defaults",
+			method: "POST",
+			body:   map[string]interface{}{"event": "signup"},
+			want: map[string]interface{}{
+				"event":   "signup",
+				"source":  "proxy",
+				"version": float64(1),
+				"meta":    map[string]interface{}{"region": "eu", "tags": []interface{}{"default"}},
+			},
+		},
+		{
+			name:   "client fields override defaults",
+			method: "POST",
+			body: map[string]interface{}{
+				"source": "mobile",
+				"meta":   map[string]interface{}{"region": "us", "tags": []interface{}{"beta"}},
+			},
+			want: map[string]interface{}{
+				"source":  "mobile",
+				"version": float64(1),
+				"meta":    map[string]interface{}{"region": "us", "tags": []interface{}{"beta"}},
+			},
+		},
+		{
+			name:   "nested objects are merged",
+			method: "POST",
+			body:   map[string]interface{}{"meta": map[string]interface{}{"user": "42"}},
+			want: map[string]interface{}{
+				"source":  "proxy",
+				"version": float64(1),
+				"meta":    map[string]interface{}{"region": "eu", "user": "42", "tags": []interface{}{"default"}},
+			},
+		},
+		{
+			name:   "a non-object client value replaces a default object",
+			method: "POST",
+			body:   map[string]interface{}{"meta": nil},
+			want:   map[string]interface{}{"source": "proxy", "version": float64(1), "meta": nil},
+		},
+		{
+			name:   "missing body becomes the defaults",
+			method: "put",
+			body:   nil,
+			want:   endpoint.DefaultBody,
+		},
+		{
+			name:   "missing body stays missing for GET",
+			method: "GET",
+			body:   nil,
+			want:   nil,
+		},
+		{
+			name:   "bodies that are not objects are unchanged",
+			method: "POST",
+			body:   []interface{}{"a", "b"},
+			want:   []interface{}{"a", "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, endpoint.MergeDefaultBody(tt.method, tt.body))
+		})
+	}
+
+	// Merging never modifies the defaults or the client's body
+	body := map[string]interface{}{"meta": map[string]interface{}{"user": "42"}}
+	merged := endpoint.MergeDefaultBody("POST", body).(map[string]interface{})
+	merged["meta"].(map[string]interface{})["region"] = "changed"
+	assert.Equal(t, "eu", endpoint.DefaultBody["meta"].(map[string]interface{})["region"])
+	assert.Equal(t, map[string]interface{}{"meta": map[string]interface{}{"user": "42"}}, body)
+
+	assert.Equal(t, "unchanged", (&Endpoint{}).MergeDefaultBody("POST", "unchanged"))
+}
+
+func TestEndpoint_MergeJQVariables(t *testing.T) {
+	endpoint := &Endpoint{JQVariables: map[string]interface{}{"tenant": "acme", "region": "eu"}}
+
+	assert.Equal(t, endpoint.JQVariables, endpoint.MergeJQVariables(nil))
+	assert.Equal(t, map[string]interface{}{"tenant": "acme", "region": "us", "id": "42"},
+		endpoint.MergeJQVariables(map[string]interface{}{"region": "us", "id": "42"}))
+	assert.Equal(t, "eu", endpoint.JQVariables["region"], "defaults are not modified")
+
+	vars := map[string]interface{}{"id": "42"}
+	assert.Equal(t, vars, (&Endpoint{}).MergeJQVariables(vars))
+}
+
+func TestEndpoint_MergeHeaderQuery(t *testing.T) {
+	endpoint := &Endpoint{HeaderQueryParams: map[string]string{"X-Tenant": "tenant", "X-Api-Key": "api_key"}}
+	query := url.Values{"page": {"2"}, "tenant": {"spoofed"}}
+
+	merged := endpoint.MergeHeaderQuery(query, http.Header{"X-Tenant": {"acme"}})
+	assert.Equal(t, url.Values{"page": {"2"}, "tenant": {"acme"}}, merged)
+	assert.Equal(t, []string{"spoofed"}, query["tenant"], "the original query is not modified")
+
+	assert.Equal(t, query, endpoint.MergeHeaderQuery(query, http.Header{"X-Other": {"value"}}))
+	assert.Equal(t, query, (&Endpoint{}).MergeHeaderQuery(query, http.Header{"X-Tenant": {"acme"}}))
+	assert.Equal(t, url.Values{"api_key": {"k1", "k2"}},
+		endpoint.MergeHeaderQuery(nil, http.Header{"X-Api-Key": {"k1", "k2"}}))
+}
+
 func TestServerConfig_Validate(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -229,6 +881,210 @@ func TestServerConfig_Validate(t *testing.T) {
 			wantErr: true,
 			errMsg:  "write timeout must be non-negative",
 		},
+		{
+			name: "negative max transform time",
+			config: ServerConfig{
+				Port:             8080,
+				ReadTimeout:      30,
+				WriteTimeout:     30,
+				MaxTransformTime: -1,
+			},
+			wantErr: true,
+			errMsg:  "max transform time must be non-negative",
+		},
+		{
+			name: "invalid jq env name",
+			config: ServerConfig{
+				Port:         8080,
+				ReadTimeout:  30,
+				WriteTimeout: 30,
+				JQEnv:        []string{"REGION", "A=B"},
+			},
+			wantErr: true,
+			errMsg:  "jq_env: invalid environment variable name",
+		},
+		{
+			name: "negative max query complexity",
+			config: ServerConfig{
+				Port:               8080,
+				ReadTimeout:        30,
+				WriteTimeout:       30,
+				MaxQueryComplexity: -1,
+			},
+			wantErr: true,
+			errMsg:  "max query complexity must be non-negative",
+		},
+		{
+			name: "negative max concurrent upstream",
+			config: ServerConfig{
+				Port:                  8080,
+				ReadTimeout:           30,
+				WriteTimeout:          30,
+				MaxConcurrentUpstream: -1,
+			},
+			wantErr: true,
+			errMsg:  "max concurrent upstream must be non-negative",
+		},
+		{
+			name: "negative max result bytes",
+			config: ServerConfig{
+				Port:           8080,
+				ReadTimeout:    30,
+				WriteTimeout:   30,
+				MaxResultBytes: -1,
+			},
+			wantErr: true,
+			errMsg:  "max result bytes must be non-negative",
+		},
+		{
+			name: "invalid IP allow list",
+			config: ServerConfig{
+				Port:        8080,
+				IPAllowlist: []string{"10.0.0.0/99"},
+			},
+			wantErr: true,
+			errMsg:  "invalid allow list",
+		},
+		{
+			name: "invalid trusted proxy",
+			config: ServerConfig{
+				Port:           8080,
+				TrustedProxies: []string{"proxy.internal"},
+			},
+			wantErr: true,
+			errMsg:  "invalid trusted proxies",
+		},
+		{
+			name: "unknown error format",
+			config: ServerConfig{
+				Port:         8080,
+				ReadTimeout:  30,
+				WriteTimeout: 30,
+				ErrorFormat:  "xml",
+			},
+			wantErr: true,
+			errMsg:  "error format must be 'default' or 'problem'",
+		},
+		{
+			name: "negative response header timeout",
+			config: ServerConfig{
+				Port:                  8080,
+				ReadTimeout:           30,
+				WriteTimeout:          30,
+				ResponseHeaderTimeout: -1,
+			},
+			wantErr: true,
+			errMsg:  "response header timeout must be non-negative",
+		},
+		{
+			name: "invalid upstream proxy",
+			config: ServerConfig{
+				Port:          8080,
+				ReadTimeout:   30,
+				WriteTimeout:  30,
+				UpstreamProxy: "proxy.internal:3128",
+			},
+			wantErr: true,
+			errMsg:  "upstream proxy must be a valid URL",
+		},
+		{
+			name: "negative max redirects",
+			config: ServerConfig{
+				Port:         8080,
+				ReadTimeout:  30,
+				WriteTimeout: 30,
+				MaxRedirects: -1,
+			},
+			wantErr: true,
+			errMsg:  "max redirects must be non-negative",
+		},
+		{
+			name: "negative metrics sample size",
+			config: ServerConfig{
+				Port:    8080,
+				Metrics: MetricsConfig{SampleSize: -1},
+			},
+			wantErr: true,
+			errMsg:  "metrics sample size must be non-negative",
+		},
+		{
+			name: "valid metrics response buckets",
+			config: ServerConfig{
+				Port:    8080,
+				Metrics: MetricsConfig{SampleSize: 500, ResponseBuckets: []int{10, 100, 1000}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "zero metrics response bucket",
+			config: ServerConfig{
+				Port:    8080,
+				Metrics: MetricsConfig{ResponseBuckets: []int{0, 100}},
+			},
+			wantErr: true,
+			errMsg:  "metrics response buckets must be positive",
+		},
+		{
+			name: "admin port same as port",
+			config: ServerConfig{
+				Port:      8080,
+				AdminPort: 8080,
+			},
+			wantErr: true,
+			errMsg:  "admin port must differ from port",
+		},
+		{
+			name: "valid admin prefix",
+			config: ServerConfig{
+				Port:        8080,
+				AdminPrefix: "/_admin",
+			},
+			wantErr: false,
+		},
+		{
+			name: "admin prefix with trailing slash",
+			config: ServerConfig{
+				Port:        8080,
+				AdminPrefix: "/_admin/",
+			},
+			wantErr: true,
+			errMsg:  "admin prefix must start with '/' and must not end with '/'",
+		},
+		{
+			name: "admin prefix under proxy routes",
+			config: ServerConfig{
+				Port:        8080,
+				AdminPrefix: "/proxy/admin",
+			},
+			wantErr: true,
+			errMsg:  "admin prefix must not be under /proxy",
+		},
+		{
+			name: "admin prefix is the upload route",
+			config: ServerConfig{
+				Port:        8080,
+				AdminPrefix: "/upload",
+			},
+			wantErr: true,
+			errMsg:  "admin prefix must not be under /upload",
+		},
+		{
+			name: "valid request ID header",
+			config: ServerConfig{
+				Port:            8080,
+				RequestIDHeader: "X-Correlation-ID",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid request ID header",
+			config: ServerConfig{
+				Port:            8080,
+				RequestIDHeader: "X Correlation: ID",
+			},
+			wantErr: true,
+			errMsg:  "request ID header \"X Correlation: ID\" is not a valid header name",
+		},
 	}
 
 	for _, tt := range tests {
@@ -317,6 +1173,39 @@ func TestProxyConfig_Validate(t *testing.T) {
 			wantErr: true,
 			errMsg:  "invalid endpoint service1",
 		},
+		{
+			name: "default endpoint",
+			config: ProxyConfig{
+				Server: ServerConfig{
+					Port:            8080,
+					DefaultEndpoint: "service1",
+				},
+				Endpoints: map[string]*Endpoint{
+					"service1": {
+						Name:   "service1",
+						Target: "https://api1.example.com",
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "unknown default endpoint",
+			config: ProxyConfig{
+				Server: ServerConfig{
+					Port:            8080,
+					DefaultEndpoint: "service2",
+				},
+				Endpoints: map[string]*Endpoint{
+					"service1": {
+						Name:   "service1",
+						Target: "https://api1.example.com",
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  "default endpoint service2 is not configured",
+		},
 	}
 
 	for _, tt := range tests {
@@ -349,6 +1238,17 @@ func TestParseProxyRequest(t *testing.T) {
 			}`,
 			wantErr: false,
 		},
+		{
+			name:    "query parameters",
+			data:    `{"method": "GET", "jq_query": ".", "query": {"page": "2", "tag": ["a", "b"]}}`,
+			wantErr: false,
+		},
+		{
+			name:    "invalid query parameter value",
+			data:    `{"method": "GET", "jq_query": ".", "query": {"page": 2}}`,
+			wantErr: true,
+			errMsg:  "query parameter page must be a string or an array of strings",
+		},
 		{
 			name:    "invalid JSON",
 			data:    `{"method": "GET", "body":}`,
@@ -383,6 +1283,75 @@ func TestParseProxyRequest(t *testing.T) {
 	}
 }
 
+func TestParseProxyRequestForm(t *testing.T) {
+	tests := []struct {
+		name         string
+		form         string
+		expectedBody interface{}
+		wantErr      bool
+		errMsg       string
+	}{
+		{
+			name:         "no body fields",
+			form:         "method=GET&jq_query=.",
+			expectedBody: nil,
+		},
+		{
+			name: "remaining fields become body with repeated keys as arrays",
+			form: "method=POST&jq_query=.id&title=Hello&tag=a&tag=b",
+			expectedBody: map[string]interface{}{
+				"title": "Hello",
+				"tag":   []interface{}{"a", "b"},
+			},
+		},
+		{
+			name:         "response content type is not part of the body",
+			form:         "method=POST&jq_query=.id&response_content_type=text%2Fplain&title=Hello",
+			expectedBody: map[string]interface{}{"title": "Hello"},
+		},
+		{
+			name:         "error and status queries are not part of the body",
+			form:         "method=POST&jq_query=.id&error_jq_query=.message&status_jq_query=.code&title=Hello",
+			expectedBody: map[string]interface{}{"title": "Hello"},
+		},
+		{
+			name:         "explicit JSON body",
+			form:         "method=POST&jq_query=.&body=" + url.QueryEscape(`{"count":2}`),
+			expectedBody: map[string]interface{}{"count": float64(2)},
+		},
+		{
+			name:         "explicit non-JSON body used as string",
+			form:         "method=POST&jq_query=.&body=plain+text",
+			expectedBody: "plain text",
+		},
+		{
+			name:    "validation failure",
+			form:    "jq_query=.",
+			wantErr: true,
+			errMsg:  "method is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.form)
+			require.NoError(t, err)
+
+			req, err := ParseProxyRequestForm(values)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errMsg)
+				assert.Nil(t, req)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, TransformationModeJQ, req.TransformationMode)
+			assert.Equal(t, tt.expectedBody, req.Body)
+		})
+	}
+}
+
 func TestParseProxyConfig(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -449,3 +1418,41 @@ func TestParseProxyConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestProxyRequest_ForResponseHeaders(t *testing.T) {
+	req := &ProxyRequest{
+		Method:          "GET",
+		JQQuery:         ".data",
+		JQQueryHeader:   "X-API-Version",
+		JQQueryByHeader: map[string]string{"2": ".result"},
+	}
+
+	selected := req.ForResponseHeaders(http.Header{"X-Api-Version": {" 2 "}})
+	assert.Equal(t, ".result", selected.JQQuery)
+	assert.Equal(t, ".data", req.JQQuery, "the original request is not modified")
+
+	assert.Same(t, req, req.ForResponseHeaders(http.Header{"X-Api-Version": {"1"}}))
+	assert.Same(t, req, req.ForResponseHeaders(http.Header{}))
+}
+
+func TestProxyRequest_ForResponse(t *testing.T) {
+	req := &ProxyRequest{
+		Method:    "GET",
+		JQQuery:   ".",
+		Variables: map[string]interface{}{"tenant": "acme"},
+	}
+
+	withResponse := req.ForResponse(404, http.Header{
+		"Content-Type": {"application/json"},
+		"Vary":         {"Accept", "Origin"},
+	})
+	assert.Equal(t, map[string]interface{}{
+		"tenant": "acme",
+		"status": 404,
+		"headers": map[string]interface{}{
+			"content-type": "application/json",
+			"vary":         "Accept, Origin",
+		},
+	}, withResponse.Variables)
+	assert.Equal(t, map[string]interface{}{"tenant": "acme"}, req.Variables, "the original request is not modified")
+}
@@ -0,0 +1,109 @@
+// Package proxy implements the HTTP proxy service with request handling and routing.
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"jq-proxy-service/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxBatchConcurrency bounds how many items of a batch run at once
+const maxBatchConcurrency = 8
+
+// handleBatchRequest runs each item of a batch through the proxy service and
+// returns their results in order. A failing item is reported in its own
+// result and does not fail the batch.
+func (h *Handler) handleBatchRequest(w http.ResponseWriter, r *http.Request) {
+	// Handle OPTIONS requests for CORS
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	body, ok := h.readRequestBody(w, r)
+	if !ok {
+		return
+	}
+
+	items, err := models.ParseBatchRequest(body)
+	if err != nil {
+		h.logger.WithContext(r.Context()).WithError(err).Error("Failed to parse batch request")
+		h.writeErrorResponse(w, r, http.StatusBadRequest, "INVALID_REQUEST", fmt.Sprintf("Invalid request format: %v", err), nil)
+		return
+	}
+
+	h.logger.WithContext(r.Context()).WithField("items", len(items)).Debug("Processing batch request")
+
+	results := make([]models.BatchResult, len(items))
+	slots := make(chan struct{}, maxBatchConcurrency)
+	var wg sync.WaitGroup
+	for i := range items {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			results[i] = h.runBatchItem(r, &items[i])
+		}(i)
+	}
+	wg.Wait()
+
+	h.writeJSONResponse(w, http.StatusOK, results)
+}
+
+// runBatchItem runs a single batch item and converts its outcome to a result
+func (h *Handler) runBatchItem(r *http.Request, item *models.BatchItem) models.BatchResult {
+	if err := item.Validate(); err != nil {
+		return models.BatchResult{
+			Status: http.StatusBadRequest,
+			Error: &models.ErrorDetail{
+				Code:    "INVALID_REQUEST",
+				Message: fmt.Sprintf("Invalid request format: validation failed: %v", err),
+			},
+		}
+	}
+
+	// The item path may carry its own query string
+	target, err := url.Parse(item.Path)
+	if err != nil {
+		return models.BatchResult{
+			Status: http.StatusBadRequest,
+			Error: &models.ErrorDetail{
+				Code:    "INVALID_REQUEST",
+				Message: fmt.Sprintf("Invalid request format: invalid path: %v", err),
+			},
+		}
+	}
+	path := target.Path
+	if path != "" && !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	// Item results are buffered into the batch response, so streaming does not apply
+	item.Stream = false
+
+	response, err := h.proxyService.HandleRequest(
+		r.Context(),
+		item.Endpoint,
+		path,
+		target.Query(),
+		r.Header,
+		&item.ProxyRequest,
+	)
+	if err != nil {
+		statusCode, detail := h.proxyErrorDetail(r, err)
+		h.logger.WithContext(r.Context()).WithFields(logrus.Fields{
+			"endpoint":    item.Endpoint,
+			"status_code": statusCode,
+		}).Debug("Batch item failed")
+		return models.BatchResult{Status: statusCode, Error: &detail}
+	}
+
+	return models.BatchResult{Status: response.Status, Data: response.Data}
+}
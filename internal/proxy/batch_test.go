@@ -0,0 +1,123 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"jq-proxy-service/internal/models"
+)
+
+func TestHandler_HandleBatchRequest_MixedResults(t *testing.T) {
+	mockService := &MockProxyService{}
+	handler := NewHandler(mockService, createTestLogger())
+	router := handler.SetupRoutes()
+
+	mockService.On("HandleRequest",
+		mock.Anything,
+		"user-service",
+		"/users/1",
+		url.Values{},
+		mock.AnythingOfType("http.Header"),
+		mock.MatchedBy(func(req *models.ProxyRequest) bool { return req.JQQuery == ".name" }),
+	).Return(&models.ProxyResponse{Data: "John", Status: http.StatusOK}, nil)
+
+	mockService.On("HandleRequest",
+		mock.Anything,
+		"post-service",
+		"/posts",
+		url.Values{"limit": []string{"2"}},
+		mock.AnythingOfType("http.Header"),
+		mock.MatchedBy(func(req *models.ProxyRequest) bool { return req.Method == "POST" }),
+	).Return(&models.ProxyResponse{Data: map[string]interface{}{"id": float64(7)}, Status: http.StatusCreated}, nil)
+
+	mockService.On("HandleRequest",
+		mock.Anything,
+		"missing-service",
+		"/",
+		url.Values{},
+		mock.AnythingOfType("http.Header"),
+		mock.AnythingOfType("*models.ProxyRequest"),
+	).Return(nil, &EndpointNotFoundError{EndpointName: "missing-service"})
+
+	batch := `[
+		{"endpoint": "user-service", "path": "/users/1", "method": "GET", "jq_query": ".name"},
+		{"endpoint": "post-service", "path": "posts?limit=2", "method": "POST", "body": {"title": "x"}, "jq_query": "{id}"},
+		{"endpoint": "missing-service", "path": "/", "method": "GET", "jq_query": "."},
+		{"endpoint": "user-service", "path": "/users/1", "jq_query": "."},
+		{"path": "/users/1", "method": "GET", "jq_query": "."}
+	]`
+
+	req := httptest.NewRequest("POST", "/batch", strings.NewReader(batch))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	// Partial failures don't fail the batch
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var results []models.BatchResult
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &results))
+	require.Len(t, results, 5)
+
+	assert.Equal(t, http.StatusOK, results[0].Status)
+	assert.Equal(t, "John", results[0].Data)
+	assert.Nil(t, results[0].Error)
+
+	assert.Equal(t, http.StatusCreated, results[1].Status)
+	assert.Equal(t, map[string]interface{}{"id": float64(7)}, results[1].Data)
+
+	assert.Equal(t, http.StatusNotFound, results[2].Status)
+	require.NotNil(t, results[2].Error)
+	assert.Equal(t, "ENDPOINT_NOT_FOUND", results[2].Error.Code)
+
+	assert.Equal(t, http.StatusBadRequest, results[3].Status)
+	require.NotNil(t, results[3].Error)
+	assert.Contains(t, results[3].Error.Message, "method is required")
+
+	assert.Equal(t, http.StatusBadRequest, results[4].Status)
+	require.NotNil(t, results[4].Error)
+	assert.Contains(t, results[4].Error.Message, "endpoint is required")
+
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_HandleBatchRequest_InvalidBatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		errMsg string
+	}{
+		{name: "not an array", body: `{"endpoint": "user-service"}`, errMsg: "invalid JSON"},
+		{name: "empty batch", body: `[]`, errMsg: "at least one item"},
+		{name: "too many items", body: "[" + strings.TrimSuffix(strings.Repeat(`{"endpoint": "a"},`, models.MaxBatchItems+1), ",") + "]", errMsg: "at most 100 items"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockProxyService{}
+			handler := NewHandler(mockService, createTestLogger())
+			router := handler.SetupRoutes()
+
+			req := httptest.NewRequest("POST", "/batch", bytes.NewReader([]byte(tt.body)))
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+			var errorResponse models.ErrorResponse
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &errorResponse))
+			assert.Equal(t, "INVALID_REQUEST", errorResponse.Error.Code)
+			assert.Contains(t, errorResponse.Error.Message, tt.errMsg)
+			mockService.AssertNotCalled(t, "HandleRequest")
+		})
+	}
+}
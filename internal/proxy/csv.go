@@ -0,0 +1,134 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// formatParam asks for the response as CSV when set to "csv"; other values
+// are left for the target
+const formatParam = "format"
+
+// csvContentType is the media type of CSV responses
+const csvContentType = "text/csv"
+
+// errNotTabular is returned for results that cannot be written as CSV
+var errNotTabular = errors.New("result must be an array of objects with scalar values to be returned as CSV")
+
+// wantsCSV reports whether the client asked for CSV, either with format=csv
+// in the query string or in its Accept header. A format=csv parameter is
+// consumed so it is not forwarded to the target.
+func wantsCSV(r *http.Request, queryParams url.Values) bool {
+	if strings.EqualFold(queryParams.Get(formatParam), "csv") {
+		queryParams.Del(formatParam)
+		return true
+	}
+	for _, accept := range r.Header.Values("Accept") {
+		for _, mediaRange := range strings.Split(accept, ",") {
+			mediaType, _, err := mime.ParseMediaType(mediaRange)
+			if err == nil && mediaType == csvContentType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// encodeCSV writes an array of flat objects as CSV. The header row holds the
+// union of the objects' keys in sorted order; missing keys and null values
+// are written as empty fields.
+func encodeCSV(data interface{}) ([]byte, error) {
+	rows, ok := data.([]interface{})
+	if !ok {
+		return nil, errNotTabular
+	}
+
+	records := make([]map[string]interface{}, len(rows))
+	keys := make(map[string]struct{})
+	for i, row := range rows {
+		record, ok := row.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%w: element %d is not an object", errNotTabular, i)
+		}
+		for key := range record {
+			keys[key] = struct{}{}
+		}
+		records[i] = record
+	}
+
+	header := make([]string, 0, len(keys))
+	for key := range keys {
+		header = append(header, key)
+	}
+	sort.Strings(header)
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	if err := writer.Write(header); err != nil {
+		return nil, err
+	}
+	fields := make([]string, len(header))
+	for i, record := range records {
+		for j, key := range header {
+			field, ok := csvField(record[key])
+			if !ok {
+				return nil, fmt.Errorf("%w: element %d field %q is not a scalar", errNotTabular, i, key)
+			}
+			fields[j] = field
+		}
+		if err := writer.Write(fields); err != nil {
+			return nil, err
+		}
+	}
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
+
+// csvField formats a scalar JSON value as a CSV field, reporting false for
+// objects and arrays
+func csvField(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case nil:
+		return "", true
+	case string:
+		return v, true
+	case bool:
+		return strconv.FormatBool(v), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case int:
+		return strconv.Itoa(v), true
+	case json.Number:
+		return v.String(), true
+	case *big.Int:
+		return v.String(), true
+	default:
+		return "", false
+	}
+}
+
+// writeCSVResponse writes data as CSV with the given content type, or a
+// NOT_ACCEPTABLE error when the data is not tabular
+func (h *Handler) writeCSVResponse(w http.ResponseWriter, r *http.Request, statusCode int, data interface{}, contentType string) {
+	body, err := encodeCSV(data)
+	if err != nil {
+		h.writeErrorResponse(w, r, http.StatusNotAcceptable, "NOT_ACCEPTABLE", err.Error(), nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(statusCode)
+	if _, err := w.Write(body); err != nil {
+		h.logger.WithError(err).Error("Failed to write CSV response")
+	}
+}
@@ -2,55 +2,269 @@
 package proxy
 
 import (
+	"compress/gzip"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"mime"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
+	"jq-proxy-service/internal/ipfilter"
 	"jq-proxy-service/internal/logging"
 	"jq-proxy-service/internal/models"
+	"jq-proxy-service/internal/version"
 
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 )
 
+// Query parameters consumed by the proxy rather than forwarded to the target
+const (
+	// envelopeParam asks for the response to be wrapped as
+	// {"status": <upstream status>, "data": <transformed data>}
+	envelopeParam = "envelope"
+
+	// rawTextParam asks for string results to be returned as text/plain without JSON quoting
+	rawTextParam = "raw_text"
+)
+
+// processStart is used to report uptime in health checks
+var processStart = time.Now()
+
+// upstreamDurationHeader reports how long the upstream call took, in milliseconds
+const upstreamDurationHeader = "X-Upstream-Duration-Ms"
+
+// idempotentReplayedHeader marks a response replayed for a duplicate Idempotency-Key
+const idempotentReplayedHeader = "Idempotent-Replayed"
+
+// defaultEndpointRoute names the routes served by the default endpoint
+const defaultEndpointRoute = "default-endpoint"
+
+// maxRequestBodyBytes bounds the size of request bodies, and the size a
+// compressed body may expand to, so neither a large body nor a small gzip
+// bomb can exhaust memory
+const maxRequestBodyBytes = 10 << 20
+
+// defaultMaxPathLength bounds proxied paths when no limit is configured. It is
+// well above what legitimate APIs use.
+const defaultMaxPathLength = 8192
+
+// problemTypePrefix prefixes error codes to form problem+json type URIs
+const problemTypePrefix = "urn:jq-proxy:error:"
+
 // Handler handles HTTP requests for the proxy service
 type Handler struct {
 	proxyService models.ProxyService
 	logger       *logging.Logger
+	errorFormat  string
+
+	// ipFilter rejects callers by client IP; nil allows everyone
+	ipFilter *ipfilter.Filter
+
+	// trustedProxies may set X-Forwarded-For and X-Real-IP for the client IP
+	trustedProxies []*net.IPNet
+
+	// adminPrefix is prepended to the health, metrics, config, version and cache routes
+	adminPrefix string
+
+	// maxPathLength rejects proxied paths longer than this many bytes
+	maxPathLength int
+
+	// debugEndpoints serves the /debug admin routes
+	debugEndpoints bool
+
+	// requestIDHeader carries request IDs; empty means X-Request-ID
+	requestIDHeader string
+
+	// responseBufferBytes is the estimated result size above which JSON
+	// results are encoded incrementally; zero always encodes them whole
+	responseBufferBytes int
+}
+
+// HandlerOption configures optional Handler behaviour
+type HandlerOption func(*Handler)
+
+// WithErrorFormat selects the error body format, models.ErrorFormatDefault or
+// models.ErrorFormatProblem. An empty format keeps the default.
+func WithErrorFormat(format string) HandlerOption {
+	return func(h *Handler) {
+		if format != "" {
+			h.errorFormat = format
+		}
+	}
+}
+
+// WithIPFilter rejects requests whose client IP the filter does not allow
+func WithIPFilter(filter *ipfilter.Filter) HandlerOption {
+	return func(h *Handler) {
+		if filter != nil && filter.Enabled() {
+			h.ipFilter = filter
+		}
+	}
+}
+
+// WithTrustedProxies honors X-Forwarded-For and X-Real-IP on connections from
+// these networks when resolving client IPs for logging and IP filtering
+func WithTrustedProxies(trustedProxies []*net.IPNet) HandlerOption {
+	return func(h *Handler) {
+		h.trustedProxies = trustedProxies
+	}
+}
+
+// WithAdminPrefix serves the health, metrics, config, version and cache routes
+// under the given path prefix, e.g. "/_admin"
+func WithAdminPrefix(prefix string) HandlerOption {
+	return func(h *Handler) {
+		h.adminPrefix = strings.TrimSuffix(prefix, "/")
+	}
+}
+
+// WithMaxPathLength rejects proxied paths longer than maxBytes. Zero keeps the
+// default of 8192 bytes.
+func WithMaxPathLength(maxBytes int) HandlerOption {
+	return func(h *Handler) {
+		if maxBytes > 0 {
+			h.maxPathLength = maxBytes
+		}
+	}
+}
+
+// WithDebugEndpoints serves the /debug admin routes, which show clients how
+// their requests are interpreted
+func WithDebugEndpoints(enabled bool) HandlerOption {
+	return func(h *Handler) {
+		h.debugEndpoints = enabled
+	}
+}
+
+// WithRequestIDHeader reads and returns request IDs in the named header
+// instead of X-Request-ID
+func WithRequestIDHeader(name string) HandlerOption {
+	return func(h *Handler) {
+		h.requestIDHeader = name
+	}
+}
+
+// WithResponseBufferBytes encodes JSON results estimated to take more than
+// limit bytes one array element or object member at a time, so the encoding
+// is written out as it is produced rather than held whole in memory. Zero
+// keeps encoding every result whole.
+func WithResponseBufferBytes(limit int) HandlerOption {
+	return func(h *Handler) {
+		if limit > 0 {
+			h.responseBufferBytes = limit
+		}
+	}
 }
 
 // NewHandler creates a new HTTP handler
-func NewHandler(proxyService models.ProxyService, logger *logging.Logger) *Handler {
-	return &Handler{
-		proxyService: proxyService,
-		logger:       logger,
+func NewHandler(proxyService models.ProxyService, logger *logging.Logger, opts ...HandlerOption) *Handler {
+	h := &Handler{
+		proxyService:  proxyService,
+		logger:        logger,
+		errorFormat:   models.ErrorFormatDefault,
+		maxPathLength: defaultMaxPathLength,
 	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 // SetupRoutes configures the HTTP routes
 func (h *Handler) SetupRoutes() *mux.Router {
 	router := mux.NewRouter()
+	h.addAdminRoutes(router)
+	h.addProxyRoutes(router)
+	h.addMiddleware(router)
+	return router
+}
+
+// SetupProxyRoutes configures a router with only the proxy and batch routes,
+// for use alongside SetupAdminRoutes on a separate port
+func (h *Handler) SetupProxyRoutes() *mux.Router {
+	router := mux.NewRouter()
+	h.addProxyRoutes(router)
+	h.addMiddleware(router)
+	return router
+}
+
+// SetupAdminRoutes configures a router with only the health, metrics,
+// config, version and cache routes
+func (h *Handler) SetupAdminRoutes() *mux.Router {
+	router := mux.NewRouter()
+	h.addAdminRoutes(router)
+	h.addMiddleware(router)
+	return router
+}
+
+// addAdminRoutes registers the admin routes, optionally under a prefix
+func (h *Handler) addAdminRoutes(router *mux.Router) {
+	admin := h.adminPrefix
 
 	// Health check endpoint
-	router.HandleFunc("/health", h.healthCheck).Methods("GET", "HEAD")
+	router.HandleFunc(admin+"/health", h.healthCheck).Methods("GET", "HEAD")
 
 	// Metrics endpoint
-	router.HandleFunc("/metrics", h.metricsHandler).Methods("GET")
+	router.HandleFunc(admin+"/metrics", h.metricsHandler).Methods("GET")
 
 	// Config endpoint
-	router.HandleFunc("/config", h.configHandler).Methods("GET")
+	router.HandleFunc(admin+"/config", h.configHandler).Methods("GET")
+	router.HandleFunc(admin+"/config/reload", h.configReloadHandler).Methods("POST")
+
+	// Version endpoint
+	router.HandleFunc(admin+"/version", h.versionHandler).Methods("GET")
+
+	// Response cache endpoint
+	router.HandleFunc(admin+"/cache", h.cachePurgeHandler).Methods("DELETE")
+
+	// Endpoint health endpoint
+	router.HandleFunc(admin+"/endpoints/status", h.endpointStatusHandler).Methods("GET")
+
+	// Debug endpoints, only when enabled
+	if h.debugEndpoints {
+		router.HandleFunc(admin+"/debug/echo", h.debugEchoHandler).Methods("POST")
+	}
+}
+
+// addProxyRoutes registers the routes that forward requests to endpoints
+func (h *Handler) addProxyRoutes(router *mux.Router) {
+	// Batch endpoint - runs several proxy requests in one round trip
+	router.HandleFunc("/batch", h.handleBatchRequest).Methods("POST", "OPTIONS")
+
+	// WebSocket upgrades would otherwise fail with an opaque 405
+	upgrade := func(r *http.Request, _ *mux.RouteMatch) bool { return isWebSocketUpgrade(r) }
+	router.PathPrefix("/proxy/").MatcherFunc(upgrade).HandlerFunc(h.rejectWebSocket)
+	router.PathPrefix("/p/").MatcherFunc(upgrade).HandlerFunc(h.rejectWebSocket)
+	router.Path("/p").MatcherFunc(upgrade).HandlerFunc(h.rejectWebSocket)
 
 	// Main proxy endpoint - captures endpoint name and remaining path
 	router.HandleFunc("/proxy/{endpoint}/{path:.*}", h.handleProxyRequest).Methods("POST", "OPTIONS")
 	router.HandleFunc("/proxy/{endpoint}", h.handleProxyRequest).Methods("POST", "OPTIONS")
 
-	// Add middleware
-	router.Use(logging.RequestLoggingMiddleware(h.logger))
-	router.Use(h.corsMiddleware)
+	// Default endpoint shortcut - the endpoint name comes from server.default_endpoint
+	router.HandleFunc("/p/{path:.*}", h.handleProxyRequest).Methods("POST", "OPTIONS").Name(defaultEndpointRoute)
+	router.HandleFunc("/p", h.handleProxyRequest).Methods("POST", "OPTIONS").Name(defaultEndpointRoute)
 
-	return router
+	// Upload endpoint - forwards the raw request body, with the transformation in jpx- headers
+	router.HandleFunc("/upload/{endpoint}/{path:.*}", h.handleUploadRequest).Methods(uploadMethods...)
+	router.HandleFunc("/upload/{endpoint}", h.handleUploadRequest).Methods(uploadMethods...)
+}
+
+// addMiddleware installs the middleware shared by every router
+func (h *Handler) addMiddleware(router *mux.Router) {
+	router.Use(logging.InFlightMiddleware(h.logger.GetMetrics()))
+	router.Use(logging.RequestLoggingMiddleware(h.logger, h.trustedProxies, h.minimalRequestLog, h.requestIDHeader))
+	if h.ipFilter != nil {
+		router.Use(h.ipFilterMiddleware)
+	}
+	router.Use(h.corsMiddleware)
 }
 
 // handleProxyRequest handles the main proxy requests
@@ -61,36 +275,75 @@ func (h *Handler) handleProxyRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Extract endpoint name from URL
-	vars := mux.Vars(r)
-	endpointName := vars["endpoint"]
-	path := vars["path"]
+	endpointName, path, ok := h.proxyTarget(w, r)
+	if !ok {
+		return
+	}
+
+	// Read request body, decompressing it if needed
+	body, ok := h.readRequestBody(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse proxy request from JSON or form-encoded data
+	proxyReq, err := parseProxyRequest(r.Header.Get("Content-Type"), body)
+	if err != nil {
+		h.logger.WithContext(r.Context()).WithError(err).Error("Failed to parse proxy request")
+		h.writeErrorResponse(w, r, http.StatusBadRequest, "INVALID_REQUEST", fmt.Sprintf("Invalid request format: %v", err), nil)
+		return
+	}
+
+	h.serveProxyRequest(w, r, endpointName, path, proxyReq)
+}
+
+// proxyTarget returns the endpoint name and upstream path a proxy route
+// addresses. On failure it writes the error response and reports false.
+func (h *Handler) proxyTarget(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	// Extract endpoint name from URL; the /p routes use the default endpoint
+	endpointName, ok := h.routeEndpoint(r)
+	if !ok {
+		h.writeErrorResponse(w, r, http.StatusNotFound, "ENDPOINT_NOT_FOUND", "No default endpoint is configured", nil)
+		return "", "", false
+	}
+	path := mux.Vars(r)["path"]
+	if len(path) > h.maxPathLength {
+		h.writeErrorResponse(w, r, http.StatusRequestURITooLong, "INVALID_REQUEST",
+			fmt.Sprintf("Path exceeds the maximum length of %d bytes", h.maxPathLength), nil)
+		return "", "", false
+	}
 
 	// Add leading slash to path if it doesn't have one
 	if path != "" && !strings.HasPrefix(path, "/") {
 		path = "/" + path
 	}
 
-	h.logger.WithContext(r.Context()).WithFields(logrus.Fields{
+	logFields := logrus.Fields{
 		"endpoint": endpointName,
-		"path":     path,
 		"method":   r.Method,
-	}).Debug("Processing proxy request")
-
-	// Parse request body
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		h.logger.WithContext(r.Context()).WithError(err).Error("Failed to read request body")
-		h.writeErrorResponse(w, http.StatusBadRequest, "INVALID_REQUEST", "Failed to read request body", nil)
-		return
 	}
+	if !logging.GetAccessInfo(r.Context()).Minimal() {
+		logFields["path"] = path
+	}
+	h.logger.WithContext(r.Context()).WithFields(logFields).Debug("Processing proxy request")
+	return endpointName, path, true
+}
 
-	// Parse proxy request
-	proxyReq, err := models.ParseProxyRequest(body)
-	if err != nil {
-		h.logger.WithContext(r.Context()).WithError(err).Error("Failed to parse proxy request")
-		h.writeErrorResponse(w, http.StatusBadRequest, "INVALID_REQUEST", fmt.Sprintf("Invalid request format: %v", err), nil)
-		return
+// serveProxyRequest runs a parsed proxy request and writes its result in the
+// format the client asked for
+func (h *Handler) serveProxyRequest(w http.ResponseWriter, r *http.Request, endpointName, path string, proxyReq *models.ProxyRequest) {
+	// Response format parameters are consumed by the proxy rather than forwarded
+	queryParams := r.URL.Query()
+	envelope := consumeBoolParam(queryParams, envelopeParam)
+	rawText := consumeBoolParam(queryParams, rawTextParam)
+	asCSV := wantsCSV(r, queryParams)
+
+	// NDJSON lists every value the query yields, so they are always collected
+	asNDJSON := !envelope && !asCSV && wantsNDJSON(r)
+	if asNDJSON && !proxyReq.JQCollect {
+		collected := *proxyReq
+		collected.JQCollect = true
+		proxyReq = &collected
 	}
 
 	// Process the proxy request
@@ -98,25 +351,239 @@ func (h *Handler) handleProxyRequest(w http.ResponseWriter, r *http.Request) {
 		r.Context(),
 		endpointName,
 		path,
-		r.URL.Query(),
+		queryParams,
 		r.Header,
 		proxyReq,
 	)
 
 	if err != nil {
-		h.handleProxyError(w, err)
+		h.handleProxyError(w, r, err)
+		return
+	}
+
+	// Report the upstream call's share of the response time
+	if response.UpstreamDuration > 0 {
+		w.Header().Set(upstreamDurationHeader, strconv.FormatInt(response.UpstreamDuration.Milliseconds(), 10))
+	}
+
+	// Mark responses returned again for a duplicate idempotency key
+	if response.Replayed {
+		w.Header().Set(idempotentReplayedHeader, "true")
+	}
+
+	// Tell rate-limited clients how long the target asked them to wait
+	if response.RetryAfter != "" {
+		w.Header().Set("Retry-After", response.RetryAfter)
+	}
+
+	// Pass on upstream cookies for endpoints that forward them
+	for _, cookie := range response.Cookies {
+		w.Header().Add("Set-Cookie", cookie)
+	}
+
+	// HEAD requests return the upstream status and headers without a body
+	if response.HeadersOnly {
+		for name, values := range response.Headers {
+			for _, value := range values {
+				w.Header().Add(name, value)
+			}
+		}
+		w.WriteHeader(response.Status)
+		return
+	}
+
+	// A 204 No Content is passed on without a body
+	if response.Status == http.StatusNoContent && response.Stream == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	// The client may choose the Content-Type the result is sent with
+	contentType := proxyReq.ResponseContentType
+
+	// Streamed results are transformed as they are written
+	if response.Stream != nil && asCSV {
+		h.writeErrorResponse(w, r, http.StatusNotAcceptable, "NOT_ACCEPTABLE", "CSV output is not available for streamed responses", nil)
+		return
+	}
+	if response.Stream != nil && asNDJSON {
+		h.writeStreamResponse(w, r, response, streamNDJSON, resultContentType(contentType, ndjsonContentType))
+		return
+	}
+	if response.Stream != nil {
+		format := streamJSON
+		if envelope {
+			format = streamEnvelope
+		}
+		h.writeStreamResponse(w, r, response, format, resultContentType(contentType, "application/json"))
+		return
+	}
+
+	// Write successful response, wrapped with the upstream status if requested
+	if envelope {
+		h.writeJSON(w, response.Status, resultContentType(contentType, "application/json"), response)
+		return
+	}
+	if asNDJSON {
+		h.writeNDJSONResponse(w, response.Status, response.Data, resultContentType(contentType, ndjsonContentType))
+		return
+	}
+	if asCSV {
+		h.writeCSVResponse(w, r, response.Status, response.Data, resultContentType(contentType, csvContentType+"; charset=utf-8"))
+		return
+	}
+	if text, ok := response.Data.(string); ok && response.ContentType != "" {
+		h.writeText(w, response.Status, resultContentType(contentType, response.ContentType), text)
+		return
+	}
+	if text, ok := response.Data.(string); ok && rawText {
+		h.writeText(w, response.Status, resultContentType(contentType, "text/plain; charset=utf-8"), text)
 		return
 	}
+	h.writeJSON(w, response.Status, resultContentType(contentType, "application/json"), response.Data)
+}
+
+// resultContentType returns the client's requested content type, or the
+// format's own when none was requested
+func resultContentType(requested, format string) string {
+	if requested != "" {
+		return requested
+	}
+	return format
+}
+
+// readRequestBody reads the request body, decompressing gzip-encoded bodies.
+// On failure it writes the error response and reports false.
+func (h *Handler) readRequestBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)
+	var reader io.Reader = r.Body
+	encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+	switch encoding {
+	case "", "identity":
+	case "gzip":
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			h.logger.WithContext(r.Context()).WithError(err).Error("Failed to decompress request body")
+			h.writeErrorResponse(w, r, http.StatusBadRequest, "INVALID_REQUEST", "Failed to decompress request body", nil)
+			return nil, false
+		}
+		defer gz.Close()
+		// Read one byte past the limit to detect oversized bodies
+		reader = io.LimitReader(gz, maxRequestBodyBytes+1)
+		// The encoding applies to the envelope, not the body sent upstream
+		r.Header.Del("Content-Encoding")
+	default:
+		h.writeErrorResponse(w, r, http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE",
+			fmt.Sprintf("Unsupported Content-Encoding: %s", encoding), nil)
+		return nil, false
+	}
+
+	body, err := io.ReadAll(reader)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		h.logger.WithContext(r.Context()).Warn("Request body exceeds size limit")
+		h.writeErrorResponse(w, r, http.StatusRequestEntityTooLarge, "REQUEST_TOO_LARGE",
+			fmt.Sprintf("Request body exceeds the limit of %d bytes", maxRequestBodyBytes), nil)
+		return nil, false
+	}
+	if err != nil {
+		h.logger.WithContext(r.Context()).WithError(err).Error("Failed to read request body")
+		h.writeErrorResponse(w, r, http.StatusBadRequest, "INVALID_REQUEST", "Failed to read request body", nil)
+		return nil, false
+	}
+	if len(body) > maxRequestBodyBytes {
+		h.logger.WithContext(r.Context()).Warn("Decompressed request body exceeds size limit")
+		h.writeErrorResponse(w, r, http.StatusRequestEntityTooLarge, "REQUEST_TOO_LARGE",
+			fmt.Sprintf("Decompressed request body exceeds the limit of %d bytes", maxRequestBodyBytes), nil)
+		return nil, false
+	}
+	return body, true
+}
+
+// routeEndpoint returns the endpoint a proxy route addresses: its {endpoint}
+// path variable, or the configured default endpoint for the /p routes
+func (h *Handler) routeEndpoint(r *http.Request) (string, bool) {
+	if name, ok := mux.Vars(r)["endpoint"]; ok {
+		return name, true
+	}
+	if route := mux.CurrentRoute(r); route == nil || route.GetName() != defaultEndpointRoute {
+		return "", false
+	}
+	config := h.proxyService.GetConfig()
+	if config == nil || config.Server.DefaultEndpoint == "" {
+		return "", false
+	}
+	return config.Server.DefaultEndpoint, true
+}
+
+// minimalRequestLog reports whether a request addresses an endpoint with
+// request logging disabled, so its path is left out of the request logs
+func (h *Handler) minimalRequestLog(r *http.Request) bool {
+	resolver, ok := h.proxyService.(EndpointResolver)
+	if !ok {
+		return false
+	}
+	name, ok := h.routeEndpoint(r)
+	if !ok {
+		return false
+	}
+	endpoint, exists := resolver.ResolveEndpoint(name)
+	return exists && !endpoint.ShouldLogRequests()
+}
 
-	// Write successful response
-	h.writeJSONResponse(w, response.Status, response.Data)
+// consumeBoolParam removes a boolean query parameter and reports whether it was set to true
+func consumeBoolParam(queryParams url.Values, name string) bool {
+	if !queryParams.Has(name) {
+		return false
+	}
+	value, _ := strconv.ParseBool(queryParams.Get(name))
+	queryParams.Del(name)
+	return value
+}
+
+// rejectWebSocket explains that WebSocket connections cannot be proxied
+func (h *Handler) rejectWebSocket(w http.ResponseWriter, r *http.Request) {
+	h.writeErrorResponse(w, r, http.StatusNotImplemented, "UNSUPPORTED_PROTOCOL",
+		"WebSocket connections are not proxied; send a JSON proxy request over plain HTTP instead", nil)
+}
+
+// isWebSocketUpgrade reports whether the request asks to switch to the
+// WebSocket protocol
+func isWebSocketUpgrade(r *http.Request) bool {
+	for _, upgrade := range r.Header.Values("Upgrade") {
+		for _, protocol := range strings.Split(upgrade, ",") {
+			if strings.EqualFold(strings.TrimSpace(protocol), "websocket") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseProxyRequest parses the request envelope according to its content type
+func parseProxyRequest(contentType string, body []byte) (*models.ProxyRequest, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType != "application/x-www-form-urlencoded" {
+		return models.ParseProxyRequest(body)
+	}
+
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, fmt.Errorf("invalid form data: %w", err)
+	}
+	return models.ParseProxyRequestForm(values)
 }
 
 // healthCheck provides a simple health check endpoint
 func (h *Handler) healthCheck(w http.ResponseWriter, r *http.Request) {
 	response := map[string]interface{}{
-		"status":  "healthy",
-		"service": "jq-proxy-service",
+		"status":         "healthy",
+		"service":        "jq-proxy-service",
+		"version":        version.Version,
+		"uptime_seconds": int64(time.Since(processStart).Seconds()),
+	}
+	if config := h.proxyService.GetConfig(); config != nil {
+		response["endpoints"] = len(config.Endpoints)
 	}
 	h.writeJSONResponse(w, http.StatusOK, response)
 }
@@ -127,12 +594,68 @@ func (h *Handler) metricsHandler(w http.ResponseWriter, r *http.Request) {
 	h.writeJSONResponse(w, http.StatusOK, metrics)
 }
 
+// versionHandler provides build information endpoint
+func (h *Handler) versionHandler(w http.ResponseWriter, r *http.Request) {
+	h.writeJSONResponse(w, http.StatusOK, version.Get())
+}
+
+// debugEchoHandler parses a request envelope the way the proxy routes do and
+// returns the result, with defaults applied, without forwarding anything
+func (h *Handler) debugEchoHandler(w http.ResponseWriter, r *http.Request) {
+	body, ok := h.readRequestBody(w, r)
+	if !ok {
+		return
+	}
+
+	proxyReq, err := parseProxyRequest(r.Header.Get("Content-Type"), body)
+	if err != nil {
+		h.writeErrorResponse(w, r, http.StatusBadRequest, "INVALID_REQUEST", fmt.Sprintf("Invalid request format: %v", err), nil)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, proxyReq)
+}
+
+// cachePurgeHandler purges cached responses, optionally limited to one endpoint via ?endpoint=
+func (h *Handler) cachePurgeHandler(w http.ResponseWriter, r *http.Request) {
+	purger, ok := h.proxyService.(CachePurger)
+	if !ok {
+		h.writeErrorResponse(w, r, http.StatusNotImplemented, "NOT_IMPLEMENTED", "Response caching is not supported", nil)
+		return
+	}
+
+	endpointName := r.URL.Query().Get("endpoint")
+	purged := purger.PurgeCache(endpointName)
+
+	h.logger.WithContext(r.Context()).WithFields(logrus.Fields{
+		"endpoint": endpointName,
+		"purged":   purged,
+	}).Info("Response cache purged")
+
+	h.writeJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"purged": purged,
+	})
+}
+
+// endpointStatusHandler reports each endpoint's recent upstream error rate
+func (h *Handler) endpointStatusHandler(w http.ResponseWriter, r *http.Request) {
+	reporter, ok := h.proxyService.(EndpointStatusReporter)
+	if !ok {
+		h.writeErrorResponse(w, r, http.StatusNotImplemented, "NOT_IMPLEMENTED", "Endpoint status is not supported", nil)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"endpoints": reporter.EndpointStatuses(),
+	})
+}
+
 // configHandler provides current configuration endpoint
 func (h *Handler) configHandler(w http.ResponseWriter, r *http.Request) {
 	// Get the service's config provider
 	config := h.proxyService.GetConfig()
 	if config == nil {
-		h.writeErrorResponse(w, http.StatusInternalServerError, "CONFIG_ERROR", "Configuration not available", nil)
+		h.writeErrorResponse(w, r, http.StatusInternalServerError, "CONFIG_ERROR", "Configuration not available", nil)
 		return
 	}
 
@@ -145,66 +668,186 @@ func (h *Handler) configHandler(w http.ResponseWriter, r *http.Request) {
 		},
 		"endpoints": make(map[string]interface{}),
 	}
+	if config.Server.DefaultEndpoint != "" {
+		response["server"].(map[string]interface{})["default_endpoint"] = config.Server.DefaultEndpoint
+	}
+	if config.Server.AdminPrefix != "" {
+		response["server"].(map[string]interface{})["admin_prefix"] = config.Server.AdminPrefix
+	}
 
 	// Add endpoint information
 	for name, endpoint := range config.Endpoints {
-		response["endpoints"].(map[string]interface{})[name] = map[string]interface{}{
-			"name":   endpoint.Name,
-			"target": endpoint.Target,
+		info := map[string]interface{}{
+			"name":      endpoint.Name,
+			"target":    endpoint.Target,
+			"cache_ttl": endpoint.CacheTTL,
+		}
+		if len(endpoint.Targets) > 0 {
+			info["targets"] = endpoint.Targets
 		}
+		if !endpoint.IsEnabled() {
+			info["enabled"] = false
+		}
+		if endpoint.RateLimit > 0 {
+			info["rate_limit"] = endpoint.RateLimit
+		}
+		if len(endpoint.Aliases) > 0 {
+			info["aliases"] = endpoint.Aliases
+		}
+		response["endpoints"].(map[string]interface{})[name] = info
 	}
 
 	h.writeJSONResponse(w, http.StatusOK, response)
 }
 
+// configReloadHandler reloads the configuration from its source and reports
+// the new endpoint count. An invalid configuration is rejected with 422 and
+// the previous configuration stays in effect.
+func (h *Handler) configReloadHandler(w http.ResponseWriter, r *http.Request) {
+	reloader, ok := h.proxyService.(ConfigReloader)
+	if !ok {
+		h.writeErrorResponse(w, r, http.StatusNotImplemented, "NOT_IMPLEMENTED", "Configuration reload is not supported", nil)
+		return
+	}
+
+	endpoints, err := reloader.ReloadConfig()
+	if err != nil {
+		h.logger.WithContext(r.Context()).WithError(err).Error("Configuration reload failed")
+		h.writeErrorResponse(w, r, http.StatusUnprocessableEntity, "CONFIG_ERROR", "Configuration reload failed", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	h.logger.WithContext(r.Context()).WithField("endpoints", endpoints).Info("Configuration reloaded")
+	h.writeJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"reloaded":  true,
+		"endpoints": endpoints,
+	})
+}
+
+// CachePurger is implemented by proxy services that cache responses
+type CachePurger interface {
+	PurgeCache(endpointName string) int
+}
+
+// EndpointStatusReporter is implemented by proxy services that track the
+// outcome of recent upstream calls per endpoint
+type EndpointStatusReporter interface {
+	EndpointStatuses() []models.EndpointStatus
+}
+
+// EndpointResolver is implemented by proxy services that can look up an
+// endpoint by name, alias or pattern
+type EndpointResolver interface {
+	ResolveEndpoint(name string) (*models.Endpoint, bool)
+}
+
+// ConfigReloader is implemented by proxy services that can reload their
+// configuration; ReloadConfig returns the number of configured endpoints
+type ConfigReloader interface {
+	ReloadConfig() (int, error)
+}
+
 // handleProxyError handles different types of proxy errors
-func (h *Handler) handleProxyError(w http.ResponseWriter, err error) {
+func (h *Handler) handleProxyError(w http.ResponseWriter, r *http.Request, err error) {
+	statusCode, detail := h.proxyErrorDetail(r, err)
+	h.writeErrorResponse(w, r, statusCode, detail.Code, detail.Message, detail.Details)
+}
+
+// proxyErrorDetail maps an error returned by the proxy service to its status code and error detail
+func (h *Handler) proxyErrorDetail(r *http.Request, err error) (int, models.ErrorDetail) {
 	if proxyErr, ok := err.(ProxyError); ok {
-		h.writeErrorResponse(
-			w,
-			proxyErr.HTTPStatusCode(),
-			proxyErr.ErrorCode(),
-			proxyErr.Error(),
-			proxyErr.ErrorDetails(),
-		)
-	} else {
-		// Generic error
-		h.logger.WithFields(logrus.Fields{
-			"error": err.Error(),
-		}).Error("Unexpected error in proxy request")
-		h.writeErrorResponse(
-			w,
-			http.StatusInternalServerError,
-			"INTERNAL_ERROR",
-			"An unexpected error occurred",
-			nil,
-		)
+		return proxyErr.HTTPStatusCode(), models.ErrorDetail{
+			Code:    proxyErr.ErrorCode(),
+			Message: proxyErr.Error(),
+			Details: proxyErr.ErrorDetails(),
+		}
+	}
+
+	// Generic error
+	h.logger.WithContext(r.Context()).WithFields(logrus.Fields{
+		"error": err.Error(),
+	}).Error("Unexpected error in proxy request")
+	return http.StatusInternalServerError, models.ErrorDetail{
+		Code:    "INTERNAL_ERROR",
+		Message: "An unexpected error occurred",
 	}
 }
 
 // writeJSONResponse writes a JSON response
 func (h *Handler) writeJSONResponse(w http.ResponseWriter, statusCode int, data interface{}) {
-	w.Header().Set("Content-Type", "application/json")
+	h.writeJSON(w, statusCode, "application/json", data)
+}
+
+// writeJSON writes data as JSON with the given content type
+func (h *Handler) writeJSON(w http.ResponseWriter, statusCode int, contentType string, data interface{}) {
+	w.Header().Set("Content-Type", contentType)
 	w.WriteHeader(statusCode)
 
+	if h.responseBufferBytes > 0 && resultMemory(data) > h.responseBufferBytes {
+		if err := encodeIncrementally(w, data); err != nil {
+			h.logger.WithError(err).Error("Failed to encode JSON response")
+		}
+		return
+	}
+
 	if err := json.NewEncoder(w).Encode(data); err != nil {
 		h.logger.WithError(err).Error("Failed to encode JSON response")
 	}
 }
 
-// writeErrorResponse writes a standardized error response
-func (h *Handler) writeErrorResponse(w http.ResponseWriter, statusCode int, code, message string, details interface{}) {
+// writeText writes text with the given content type
+func (h *Handler) writeText(w http.ResponseWriter, statusCode int, contentType, text string) {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(statusCode)
+
+	if _, err := io.WriteString(w, text); err != nil {
+		h.logger.WithError(err).Error("Failed to write text response")
+	}
+}
+
+// writeErrorResponse writes a standardized error response in the configured format
+func (h *Handler) writeErrorResponse(w http.ResponseWriter, r *http.Request, statusCode int, code, message string, details interface{}) {
+	if h.errorFormat == models.ErrorFormatProblem {
+		h.writeJSON(w, statusCode, "application/problem+json", models.ProblemDetail{
+			Type:      problemTypePrefix + code,
+			Title:     http.StatusText(statusCode),
+			Status:    statusCode,
+			Detail:    message,
+			Instance:  r.URL.RequestURI(),
+			Code:      code,
+			Details:   details,
+			RequestID: logging.RequestIDFromContext(r.Context()),
+		})
+		return
+	}
+
 	errorResponse := models.ErrorResponse{
 		Error: models.ErrorDetail{
-			Code:    code,
-			Message: message,
-			Details: details,
+			Code:      code,
+			Message:   message,
+			Details:   details,
+			RequestID: logging.RequestIDFromContext(r.Context()),
 		},
 	}
 
 	h.writeJSONResponse(w, statusCode, errorResponse)
 }
 
+// ipFilterMiddleware rejects requests from client IPs that are not allowed
+func (h *Handler) ipFilterMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientIP := logging.ClientIP(r, h.trustedProxies)
+		if !h.ipFilter.Allowed(clientIP) {
+			h.logger.WithContext(r.Context()).WithField("client_ip", clientIP).Warn("Request from disallowed IP rejected")
+			h.writeErrorResponse(w, r, http.StatusForbidden, "FORBIDDEN", "Client IP is not allowed", nil)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 // corsMiddleware adds CORS headers
 func (h *Handler) corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -2,19 +2,30 @@ package proxy
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
+	"jq-proxy-service/internal/client"
+	"jq-proxy-service/internal/ipfilter"
 	"jq-proxy-service/internal/logging"
 	"jq-proxy-service/internal/models"
+	"jq-proxy-service/internal/transform"
+	"jq-proxy-service/internal/version"
 )
 
 // MockProxyService for testing
@@ -103,6 +114,406 @@ func TestHandler_HandleProxyRequest_Success(t *testing.T) {
 	mockService.AssertExpectations(t)
 }
 
+func TestHandler_HandleProxyRequest_Envelope(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		expectEnvelope bool
+	}{
+		{name: "bare data by default", query: "?limit=10", expectEnvelope: false},
+		{name: "envelope requested", query: "?limit=10&envelope=true", expectEnvelope: true},
+		{name: "envelope disabled", query: "?limit=10&envelope=false", expectEnvelope: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockProxyService{}
+			handler := NewHandler(mockService, createTestLogger())
+			router := handler.SetupRoutes()
+
+			proxyResponse := &models.ProxyResponse{
+				Data:   map[string]interface{}{"error": "not found"},
+				Status: http.StatusNotFound,
+			}
+
+			// The envelope parameter is not forwarded to the target
+			mockService.On("HandleRequest",
+				mock.Anything,
+				"user-service",
+				"/users/999",
+				url.Values{"limit": []string{"10"}},
+				mock.AnythingOfType("http.Header"),
+				mock.AnythingOfType("*models.ProxyRequest"),
+			).Return(proxyResponse, nil)
+
+			req := httptest.NewRequest("POST", "/proxy/user-service/users/999"+tt.query,
+				bytes.NewReader([]byte(`{"method": "GET", "jq_query": "."}`)))
+			req.Header.Set("Content-Type", "application/json")
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusNotFound, rr.Code)
+
+			var response map[string]interface{}
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+			if tt.expectEnvelope {
+				assert.Equal(t, map[string]interface{}{
+					"status": float64(http.StatusNotFound),
+					"data":   map[string]interface{}{"error": "not found"},
+				}, response)
+			} else {
+				assert.Equal(t, map[string]interface{}{"error": "not found"}, response)
+			}
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_HandleProxyRequest_RawText(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		data        interface{}
+		contentType string
+		body        string
+	}{
+		{name: "string as JSON by default", query: "", data: "Hello World", contentType: "application/json", body: "\"Hello World\"\n"},
+		{name: "string as raw text", query: "?raw_text=true", data: "Hello World", contentType: "text/plain; charset=utf-8", body: "Hello World"},
+		{name: "object stays JSON", query: "?raw_text=true", data: map[string]interface{}{"title": "Hello"}, contentType: "application/json", body: "{\"title\":\"Hello\"}\n"},
+		{name: "number stays JSON", query: "?raw_text=true", data: float64(42), contentType: "application/json", body: "42\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockProxyService{}
+			handler := NewHandler(mockService, createTestLogger())
+			router := handler.SetupRoutes()
+
+			// The raw_text parameter is not forwarded to the target
+			mockService.On("HandleRequest",
+				mock.Anything,
+				"posts-service",
+				"/posts/1",
+				url.Values{},
+				mock.AnythingOfType("http.Header"),
+				mock.AnythingOfType("*models.ProxyRequest"),
+			).Return(&models.ProxyResponse{Data: tt.data, Status: http.StatusOK}, nil)
+
+			req := httptest.NewRequest("POST", "/proxy/posts-service/posts/1"+tt.query,
+				bytes.NewReader([]byte(`{"method": "GET", "jq_query": ".title"}`)))
+			req.Header.Set("Content-Type", "application/json")
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, tt.contentType, rr.Header().Get("Content-Type"))
+			assert.Equal(t, tt.body, rr.Body.String())
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_HandleProxyRequest_CSV(t *testing.T) {
+	users := []interface{}{
+		map[string]interface{}{"id": float64(1), "name": "Doe, Jane", "active": true},
+		map[string]interface{}{"id": float64(2), "name": "John", "email": "john@example.com", "manager": nil},
+	}
+
+	tests := []struct {
+		name   string
+		query  string
+		accept string
+		data   interface{}
+		status int
+		body   string
+	}{
+		{name: "format parameter", query: "?format=csv", data: users, status: http.StatusOK,
+			body: "active,email,id,manager,name\ntrue,,1,,\"Doe, Jane\"\n,john@example.com,2,,John\n"},
+		{name: "accept header", accept: "application/json;q=0.5, text/csv", data: users, status: http.StatusOK,
+			body: "active,email,id,manager,name\ntrue,,1,,\"Doe, Jane\"\n,john@example.com,2,,John\n"},
+		{name: "empty array", query: "?format=csv", data: []interface{}{}, status: http.StatusOK, body: "\n"},
+		{name: "object is not tabular", query: "?format=csv", data: map[string]interface{}{"id": float64(1)}, status: http.StatusNotAcceptable},
+		{name: "nested value is not tabular", query: "?format=csv",
+			data: []interface{}{map[string]interface{}{"tags": []interface{}{"a"}}}, status: http.StatusNotAcceptable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockProxyService{}
+			handler := NewHandler(mockService, createTestLogger())
+			router := handler.SetupRoutes()
+
+			// The format parameter is not forwarded to the target
+			mockService.On("HandleRequest",
+				mock.Anything,
+				"user-service",
+				"/users",
+				url.Values{},
+				mock.AnythingOfType("http.Header"),
+				mock.AnythingOfType("*models.ProxyRequest"),
+			).Return(&models.ProxyResponse{Data: tt.data, Status: http.StatusOK}, nil)
+
+			req := httptest.NewRequest("POST", "/proxy/user-service/users"+tt.query,
+				bytes.NewReader([]byte(`{"method": "GET", "jq_query": ".users"}`)))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.status, rr.Code)
+			if tt.status != http.StatusOK {
+				var response models.ErrorResponse
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+				assert.Equal(t, "NOT_ACCEPTABLE", response.Error.Code)
+				return
+			}
+			assert.Equal(t, "text/csv; charset=utf-8", rr.Header().Get("Content-Type"))
+			assert.Equal(t, tt.body, rr.Body.String())
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_HandleProxyRequest_NDJSON(t *testing.T) {
+	mockConfig := &MockConfigProvider{}
+	mockClient := &MockHTTPClient{}
+	endpoint := &models.Endpoint{Name: "user-service", Target: "https://api.example.com"}
+	mockConfig.On("GetEndpoint", "user-service").Return(endpoint, true)
+	mockClient.On("ForwardRequest", mock.Anything, "GET", "https://api.example.com", "/users", mock.Anything, mock.Anything, mock.Anything).
+		Return(&client.Response{
+			StatusCode: http.StatusOK,
+			Headers:    http.Header{"Content-Type": []string{"application/json"}},
+			Body:       []byte(`[{"name": "John", "age": 30}, {"name": "Jane", "age": 25}]`),
+		}, nil)
+
+	service := NewService(mockConfig, mockClient, transform.NewUnifiedTransformer(), createTestLogger())
+	router := NewHandler(service, createTestLogger()).SetupRoutes()
+
+	send := func(envelope, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/proxy/user-service/users", bytes.NewReader([]byte(envelope)))
+		req.Header.Set("Content-Type", "application/json")
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		return rr
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		body  string
+	}{
+		{name: "several values", query: ".[]", body: "{\"age\":30,\"name\":\"John\"}\n{\"age\":25,\"name\":\"Jane\"}\n"},
+		{name: "several scalars", query: ".[].name", body: "\"John\"\n\"Jane\"\n"},
+		{name: "a single array stays one value", query: "map(.age)", body: "[30,25]\n"},
+		{name: "no values", query: "empty", body: ""},
+	}
+
+	for _, tt := range tests {
+		for _, stream := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s stream=%t", tt.name, stream), func(t *testing.T) {
+				envelope := fmt.Sprintf(`{"method": "GET", "jq_query": %q, "stream": %t}`, tt.query, stream)
+				rr := send(envelope, "application/json;q=0.5, application/x-ndjson")
+				assert.Equal(t, "application/x-ndjson", rr.Header().Get("Content-Type"))
+				assert.Equal(t, tt.body, rr.Body.String())
+
+				// Each line holds one of the values jq_collect returns
+				var collected []interface{}
+				require.NoError(t, json.Unmarshal(send(fmt.Sprintf(`{"method": "GET", "jq_query": %q, "jq_collect": true}`, tt.query), "").Body.Bytes(), &collected))
+				lines := []interface{}{}
+				for _, line := range strings.Split(strings.TrimSuffix(rr.Body.String(), "\n"), "\n") {
+					if line == "" {
+						continue
+					}
+					var value interface{}
+					require.NoError(t, json.Unmarshal([]byte(line), &value))
+					lines = append(lines, value)
+				}
+				assert.Equal(t, collected, lines)
+			})
+		}
+	}
+
+	// Without the Accept header several values are returned as an array
+	rr := send(`{"method": "GET", "jq_query": ".[].name"}`, "")
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	assert.Equal(t, "[\"John\",\"Jane\"]\n", rr.Body.String())
+}
+
+func TestHandler_HandleProxyRequest_FormData(t *testing.T) {
+	// Setup
+	mockService := &MockProxyService{}
+	logger := createTestLogger()
+
+	handler := NewHandler(mockService, logger)
+	router := handler.SetupRoutes()
+
+	// Setup expectations
+	mockService.On("HandleRequest",
+		mock.Anything,
+		"user-service",
+		"/api/users",
+		url.Values{},
+		mock.AnythingOfType("http.Header"),
+		mock.MatchedBy(func(req *models.ProxyRequest) bool {
+			body, ok := req.Body.(map[string]interface{})
+			return ok &&
+				req.Method == "POST" &&
+				req.JQQuery == "{id: .id}" &&
+				req.TransformationMode == models.TransformationModeJQ &&
+				body["name"] == "John" &&
+				assert.ObjectsAreEqual([]interface{}{"admin", "dev"}, body["role"])
+		}),
+	).Return(&models.ProxyResponse{Data: map[string]interface{}{"id": float64(7)}, Status: 201}, nil)
+
+	// Create form-encoded request
+	form := url.Values{
+		"method":   []string{"POST"},
+		"jq_query": []string{"{id: .id}"},
+		"name":     []string{"John"},
+		"role":     []string{"admin", "dev"},
+	}
+	req := httptest.NewRequest("POST", "/proxy/user-service/api/users", bytes.NewReader([]byte(form.Encode())))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+
+	// Execute
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	var response map[string]interface{}
+	err := json.Unmarshal(rr.Body.Bytes(), &response)
+	require.NoError(t, err)
+	assert.Equal(t, float64(7), response["id"])
+
+	mockService.AssertExpectations(t)
+}
+
+// gzipBytes compresses data for gzip-encoded request tests
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func TestHandler_HandleProxyRequest_GzipBody(t *testing.T) {
+	mockService := &MockProxyService{}
+	router := NewHandler(mockService, createTestLogger()).SetupRoutes()
+
+	mockService.On("HandleRequest",
+		mock.Anything,
+		"user-service",
+		"/api/users",
+		url.Values{},
+		// The envelope's encoding is not forwarded with the decompressed body
+		mock.MatchedBy(func(headers http.Header) bool { return headers.Get("Content-Encoding") == "" }),
+		mock.MatchedBy(func(req *models.ProxyRequest) bool {
+			body, ok := req.Body.(map[string]interface{})
+			return ok && req.Method == "POST" && req.JQQuery == "{id}" && body["name"] == "John"
+		}),
+	).Return(&models.ProxyResponse{Data: map[string]interface{}{"id": float64(7)}, Status: 201}, nil)
+
+	envelope := []byte(`{"method": "POST", "body": {"name": "John"}, "jq_query": "{id}"}`)
+	req := httptest.NewRequest("POST", "/proxy/user-service/api/users", bytes.NewReader(gzipBytes(t, envelope)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.JSONEq(t, `{"id": 7}`, rr.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_HandleProxyRequest_BodyErrors(t *testing.T) {
+	// Highly compressible padding that expands past the decompressed size limit
+	bomb := append([]byte(`{"method": "GET", "jq_query": ".", "body": "`), bytes.Repeat([]byte("a"), maxRequestBodyBytes)...)
+	bomb = append(bomb, []byte(`"}`)...)
+
+	tests := []struct {
+		name           string
+		encoding       string
+		body           []byte
+		expectedStatus int
+		expectedCode   string
+	}{
+		{name: "exceeds decompressed limit", encoding: "gzip", body: gzipBytes(t, bomb), expectedStatus: http.StatusRequestEntityTooLarge, expectedCode: "REQUEST_TOO_LARGE"},
+		{name: "exceeds uncompressed limit", encoding: "", body: bomb, expectedStatus: http.StatusRequestEntityTooLarge, expectedCode: "REQUEST_TOO_LARGE"},
+		{name: "not gzip data", encoding: "gzip", body: []byte(`{"method": "GET"}`), expectedStatus: http.StatusBadRequest, expectedCode: "INVALID_REQUEST"},
+		{name: "unsupported encoding", encoding: "br", body: []byte(`{}`), expectedStatus: http.StatusUnsupportedMediaType, expectedCode: "UNSUPPORTED_MEDIA_TYPE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockProxyService{}
+			router := NewHandler(mockService, createTestLogger()).SetupRoutes()
+
+			req := httptest.NewRequest("POST", "/proxy/user-service/api/users", bytes.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Content-Encoding", tt.encoding)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			var errorResponse models.ErrorResponse
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &errorResponse))
+			assert.Equal(t, tt.expectedCode, errorResponse.Error.Code)
+			mockService.AssertNotCalled(t, "HandleRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestHandler_HandleProxyRequest_PathTooLong(t *testing.T) {
+	mockService := &MockProxyService{}
+	router := NewHandler(mockService, createTestLogger(), WithMaxPathLength(16)).SetupRoutes()
+
+	req := httptest.NewRequest("POST", "/proxy/user-service/"+strings.Repeat("a", 17), bytes.NewReader([]byte(`{"method": "GET", "jq_query": "."}`)))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusRequestURITooLong, rr.Code)
+	var errorResponse models.ErrorResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &errorResponse))
+	assert.Equal(t, "INVALID_REQUEST", errorResponse.Error.Code)
+	mockService.AssertNotCalled(t, "HandleRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHandler_HandleProxyRequest_WebSocketUpgrade(t *testing.T) {
+	mockService := &MockProxyService{}
+	router := NewHandler(mockService, createTestLogger()).SetupRoutes()
+
+	req := httptest.NewRequest("GET", "/proxy/user-service/events", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "WebSocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotImplemented, rr.Code)
+	var errorResponse models.ErrorResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &errorResponse))
+	assert.Equal(t, "UNSUPPORTED_PROTOCOL", errorResponse.Error.Code)
+	assert.Contains(t, errorResponse.Error.Message, "WebSocket connections are not proxied")
+	mockService.AssertNotCalled(t, "HandleRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
 func TestHandler_HandleProxyRequest_EndpointNotFound(t *testing.T) {
 	// Setup
 	mockService := &MockProxyService{}
@@ -162,6 +573,64 @@ func TestHandler_HandleProxyRequest_EndpointNotFound(t *testing.T) {
 	mockService.AssertExpectations(t)
 }
 
+func TestHandler_HandleProxyRequest_DefaultEndpoint(t *testing.T) {
+	tests := []struct {
+		name         string
+		target       string
+		expectedPath string
+	}{
+		{name: "with path", target: "/p/api/users?limit=10", expectedPath: "/api/users"},
+		{name: "without path", target: "/p?limit=10", expectedPath: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockProxyService{}
+			router := NewHandler(mockService, createTestLogger()).SetupRoutes()
+
+			mockService.On("GetConfig").Return(&models.ProxyConfig{
+				Server: models.ServerConfig{DefaultEndpoint: "user-service"},
+			})
+			mockService.On("HandleRequest",
+				mock.Anything,
+				"user-service",
+				tt.expectedPath,
+				url.Values{"limit": []string{"10"}},
+				mock.AnythingOfType("http.Header"),
+				mock.AnythingOfType("*models.ProxyRequest"),
+			).Return(&models.ProxyResponse{Data: "ok", Status: http.StatusOK}, nil)
+
+			req := httptest.NewRequest("POST", tt.target, bytes.NewReader([]byte(`{"method": "GET", "jq_query": "."}`)))
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusOK, rr.Code)
+			assert.JSONEq(t, `"ok"`, rr.Body.String())
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_HandleProxyRequest_DefaultEndpointNotConfigured(t *testing.T) {
+	mockService := &MockProxyService{}
+	router := NewHandler(mockService, createTestLogger()).SetupRoutes()
+
+	mockService.On("GetConfig").Return(&models.ProxyConfig{})
+
+	req := httptest.NewRequest("POST", "/p/api/users", bytes.NewReader([]byte(`{"method": "GET", "jq_query": "."}`)))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	var errorResponse models.ErrorResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &errorResponse))
+	assert.Equal(t, "ENDPOINT_NOT_FOUND", errorResponse.Error.Code)
+	mockService.AssertNotCalled(t, "HandleRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
 func TestHandler_HandleProxyRequest_InvalidJSON(t *testing.T) {
 	// Setup
 	mockService := &MockProxyService{}
@@ -173,6 +642,7 @@ func TestHandler_HandleProxyRequest_InvalidJSON(t *testing.T) {
 	// Create request with invalid JSON
 	req := httptest.NewRequest("POST", "/proxy/user-service/api/users", bytes.NewReader([]byte(`{"method": "GET", "body":}`)))
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Request-ID", "client-request-1")
 
 	// Execute
 	rr := httptest.NewRecorder()
@@ -188,6 +658,10 @@ func TestHandler_HandleProxyRequest_InvalidJSON(t *testing.T) {
 	assert.Equal(t, "INVALID_REQUEST", errorResponse.Error.Code)
 	assert.Contains(t, errorResponse.Error.Message, "Invalid request format")
 
+	// Request ID is echoed in the header and error body
+	assert.Equal(t, "client-request-1", rr.Header().Get("X-Request-ID"))
+	assert.Equal(t, "client-request-1", errorResponse.Error.RequestID)
+
 	// Should not call the service
 	mockService.AssertNotCalled(t, "HandleRequest")
 }
@@ -280,6 +754,142 @@ func TestHandler_HandleProxyRequest_TransformationError(t *testing.T) {
 	mockService.AssertExpectations(t)
 }
 
+func TestHandler_IPFilter(t *testing.T) {
+	filter, err := ipfilter.New([]string{"10.0.0.0/8"}, []string{"10.0.0.66"})
+	require.NoError(t, err)
+
+	trustedProxies, err := ipfilter.ParseCIDRs([]string{"192.0.2.10"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor string
+		wantStatus   int
+	}{
+		{name: "allowed IP", remoteAddr: "10.1.2.3:5000", wantStatus: http.StatusOK},
+		{name: "IP outside allow list", remoteAddr: "192.0.2.1:5000", wantStatus: http.StatusForbidden},
+		{name: "denied IP inside allow list", remoteAddr: "10.0.0.66:5000", wantStatus: http.StatusForbidden},
+		{name: "spoofed header from untrusted source", remoteAddr: "192.0.2.1:5000", forwardedFor: "10.1.2.3", wantStatus: http.StatusForbidden},
+		{name: "allowed client behind trusted proxy", remoteAddr: "192.0.2.10:5000", forwardedFor: "10.1.2.3", wantStatus: http.StatusOK},
+		{name: "blocked client behind trusted proxy", remoteAddr: "192.0.2.10:5000", forwardedFor: "10.1.2.3, 10.0.0.66", wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockProxyService{}
+			mockService.On("GetConfig").Return(nil)
+			router := NewHandler(mockService, createTestLogger(), WithIPFilter(filter), WithTrustedProxies(trustedProxies)).SetupRoutes()
+
+			req := httptest.NewRequest("GET", "/health", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			if tt.wantStatus == http.StatusForbidden {
+				var errorResponse models.ErrorResponse
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &errorResponse))
+				assert.Equal(t, "FORBIDDEN", errorResponse.Error.Code)
+			}
+		})
+	}
+}
+
+func TestHandler_ErrorFormat(t *testing.T) {
+	notFound := &EndpointNotFoundError{
+		EndpointName:       "missing-service",
+		AvailableEndpoints: []string{"user-service"},
+	}
+
+	t.Run("default format", func(t *testing.T) {
+		mockService := &MockProxyService{}
+		router := NewHandler(mockService, createTestLogger()).SetupRoutes()
+		mockService.On("HandleRequest", mock.Anything, "missing-service", "/users", url.Values{}, mock.AnythingOfType("http.Header"), mock.Anything).
+			Return(nil, notFound)
+
+		req := httptest.NewRequest("POST", "/proxy/missing-service/users", bytes.NewReader([]byte(`{"method": "GET", "jq_query": "."}`)))
+		req.Header.Set(logging.RequestIDHeader, "req-1")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+		var errorResponse models.ErrorResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &errorResponse))
+		assert.Equal(t, "ENDPOINT_NOT_FOUND", errorResponse.Error.Code)
+		assert.Equal(t, "endpoint 'missing-service' not found", errorResponse.Error.Message)
+		assert.Equal(t, "req-1", errorResponse.Error.RequestID)
+	})
+
+	t.Run("custom request ID header", func(t *testing.T) {
+		mockService := &MockProxyService{}
+		router := NewHandler(mockService, createTestLogger(), WithRequestIDHeader("X-Correlation-ID")).SetupRoutes()
+		mockService.On("HandleRequest", mock.Anything, "missing-service", "/users", url.Values{}, mock.AnythingOfType("http.Header"), mock.Anything).
+			Return(nil, notFound)
+
+		req := httptest.NewRequest("POST", "/proxy/missing-service/users", bytes.NewReader([]byte(`{"method": "GET", "jq_query": "."}`)))
+		req.Header.Set("X-Correlation-ID", "corr-1")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		var errorResponse models.ErrorResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &errorResponse))
+		assert.Equal(t, "corr-1", errorResponse.Error.RequestID)
+		assert.Equal(t, "corr-1", rr.Header().Get("X-Correlation-ID"))
+		assert.Empty(t, rr.Header().Get(logging.RequestIDHeader))
+	})
+
+	t.Run("problem+json format", func(t *testing.T) {
+		mockService := &MockProxyService{}
+		router := NewHandler(mockService, createTestLogger(), WithErrorFormat(models.ErrorFormatProblem)).SetupRoutes()
+		mockService.On("HandleRequest", mock.Anything, "missing-service", "/users", url.Values{"page": []string{"2"}}, mock.AnythingOfType("http.Header"), mock.Anything).
+			Return(nil, notFound)
+
+		req := httptest.NewRequest("POST", "/proxy/missing-service/users?page=2", bytes.NewReader([]byte(`{"method": "GET", "jq_query": "."}`)))
+		req.Header.Set(logging.RequestIDHeader, "req-1")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"))
+
+		var problem map[string]interface{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+		assert.Equal(t, "urn:jq-proxy:error:ENDPOINT_NOT_FOUND", problem["type"])
+		assert.Equal(t, "Not Found", problem["title"])
+		assert.Equal(t, float64(http.StatusNotFound), problem["status"])
+		assert.Equal(t, "endpoint 'missing-service' not found", problem["detail"])
+		assert.Equal(t, "/proxy/missing-service/users?page=2", problem["instance"])
+		assert.Equal(t, "ENDPOINT_NOT_FOUND", problem["code"])
+		assert.Equal(t, "req-1", problem["request_id"])
+		assert.Equal(t, map[string]interface{}{"available_endpoints": []interface{}{"user-service"}}, problem["details"])
+		assert.NotContains(t, problem, "error")
+	})
+
+	t.Run("problem+json for handler errors", func(t *testing.T) {
+		mockService := &MockProxyService{}
+		router := NewHandler(mockService, createTestLogger(), WithErrorFormat(models.ErrorFormatProblem)).SetupRoutes()
+
+		req := httptest.NewRequest("POST", "/proxy/user-service", bytes.NewReader([]byte(`{invalid`)))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"))
+
+		var problem models.ProblemDetail
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+		assert.Equal(t, "INVALID_REQUEST", problem.Code)
+		assert.Equal(t, "Bad Request", problem.Title)
+		assert.Contains(t, problem.Detail, "Invalid request format")
+	})
+}
+
 func TestHandler_HandleProxyRequest_UpstreamError(t *testing.T) {
 	// Setup
 	mockService := &MockProxyService{}
@@ -336,8 +946,9 @@ func TestHandler_HandleProxyRequest_UpstreamError(t *testing.T) {
 }
 
 func TestHandler_HealthCheck(t *testing.T) {
-	// Setup
+	// Setup - health stays healthy even when configuration is unavailable
 	mockService := &MockProxyService{}
+	mockService.On("GetConfig").Return(nil)
 	logger := createTestLogger()
 
 	handler := NewHandler(mockService, logger)
@@ -361,6 +972,838 @@ func TestHandler_HealthCheck(t *testing.T) {
 	assert.Equal(t, "jq-proxy-service", response["service"])
 }
 
+func TestHandler_HealthCheck_Details(t *testing.T) {
+	origVersion := version.Version
+	version.Version = "v1.2.3"
+	defer func() { version.Version = origVersion }()
+
+	mockService := &MockProxyService{}
+	mockService.On("GetConfig").Return(&models.ProxyConfig{
+		Endpoints: map[string]*models.Endpoint{
+			"user-service":  {Name: "user-service", Target: "https://users.example.com"},
+			"posts-service": {Name: "posts-service", Target: "https://posts.example.com"},
+		},
+	})
+
+	handler := NewHandler(mockService, createTestLogger())
+	router := handler.SetupRoutes()
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/health", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, "healthy", response["status"])
+	assert.Equal(t, "jq-proxy-service", response["service"])
+	assert.Equal(t, "v1.2.3", response["version"])
+	assert.Equal(t, float64(2), response["endpoints"])
+	uptime, ok := response["uptime_seconds"].(float64)
+	require.True(t, ok)
+	assert.GreaterOrEqual(t, uptime, float64(0))
+
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_AdminPrefix(t *testing.T) {
+	mockService := &MockProxyService{}
+	mockService.On("GetConfig").Return(nil)
+
+	router := NewHandler(mockService, createTestLogger(), WithAdminPrefix("/_admin")).SetupRoutes()
+
+	for _, path := range []string{"/health", "/metrics", "/version"} {
+		t.Run(path, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest("GET", "/_admin"+path, nil))
+			assert.Equal(t, http.StatusOK, rr.Code)
+
+			rr = httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+			assert.Equal(t, http.StatusNotFound, rr.Code)
+		})
+	}
+}
+
+func TestHandler_DebugEcho(t *testing.T) {
+	mockService := &MockProxyService{}
+
+	// The route only exists when debug endpoints are enabled
+	router := NewHandler(mockService, createTestLogger()).SetupRoutes()
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("POST", "/debug/echo", strings.NewReader(`{"method": "get", "jq_query": "."}`)))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	router = NewHandler(mockService, createTestLogger(), WithDebugEndpoints(true)).SetupRoutes()
+
+	t.Run("defaults are applied", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/debug/echo", strings.NewReader(`{"method": "GET", "jq_query": ".items"}`))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var echoed map[string]interface{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &echoed))
+		assert.Equal(t, "GET", echoed["method"])
+		assert.Equal(t, "jq", echoed["transformation_mode"])
+		assert.Equal(t, ".items", echoed["jq_query"])
+	})
+
+	t.Run("form-encoded envelope", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/debug/echo", strings.NewReader("method=POST&jq_query=.id&body=%7B%22a%22%3A1%7D"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var echoed map[string]interface{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &echoed))
+		assert.Equal(t, "POST", echoed["method"])
+		assert.Equal(t, "jq", echoed["transformation_mode"])
+		assert.Equal(t, map[string]interface{}{"a": float64(1)}, echoed["body"])
+	})
+
+	t.Run("invalid envelope", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/debug/echo", strings.NewReader(`{"method": "GET"}`))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Contains(t, rr.Body.String(), "INVALID_REQUEST")
+	})
+
+	// Nothing is forwarded
+	mockService.AssertNotCalled(t, "HandleRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHandler_SplitAdminRoutes(t *testing.T) {
+	mockService := &MockProxyService{}
+	mockService.On("GetConfig").Return(nil)
+	handler := NewHandler(mockService, createTestLogger())
+
+	publicRouter := handler.SetupProxyRoutes()
+	adminRouter := handler.SetupAdminRoutes()
+
+	for _, path := range []string{"/health", "/metrics", "/config", "/version"} {
+		t.Run(path, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			publicRouter.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+			assert.Equal(t, http.StatusNotFound, rr.Code)
+
+			rr = httptest.NewRecorder()
+			adminRouter.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+			assert.NotEqual(t, http.StatusNotFound, rr.Code)
+		})
+	}
+
+	// Proxy traffic is only accepted on the public router
+	rr := httptest.NewRecorder()
+	adminRouter.ServeHTTP(rr, httptest.NewRequest("OPTIONS", "/proxy/test-service/users", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr = httptest.NewRecorder()
+	publicRouter.ServeHTTP(rr, httptest.NewRequest("OPTIONS", "/proxy/test-service/users", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestHandler_Version(t *testing.T) {
+	// Inject build information as -ldflags would
+	origVersion, origCommit, origDate := version.Version, version.GitCommit, version.BuildDate
+	version.Version, version.GitCommit, version.BuildDate = "v1.2.3", "abc1234", "2024-01-02T03:04:05Z"
+	defer func() {
+		version.Version, version.GitCommit, version.BuildDate = origVersion, origCommit, origDate
+	}()
+
+	// Setup
+	mockService := &MockProxyService{}
+	logger := createTestLogger()
+
+	handler := NewHandler(mockService, logger)
+	router := handler.SetupRoutes()
+
+	// Create request
+	req := httptest.NewRequest("GET", "/version", nil)
+
+	// Execute
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+	var response map[string]interface{}
+	err := json.Unmarshal(rr.Body.Bytes(), &response)
+	require.NoError(t, err)
+	assert.Equal(t, "v1.2.3", response["version"])
+	assert.Equal(t, "abc1234", response["git_commit"])
+	assert.Equal(t, "2024-01-02T03:04:05Z", response["build_date"])
+}
+
+// MockCachingProxyService is a MockProxyService that also supports cache purging
+type MockCachingProxyService struct {
+	MockProxyService
+}
+
+func (m *MockCachingProxyService) PurgeCache(endpointName string) int {
+	args := m.Called(endpointName)
+	return args.Int(0)
+}
+
+func TestHandler_CachePurge(t *testing.T) {
+	tests := []struct {
+		name             string
+		url              string
+		expectedEndpoint string
+	}{
+		{name: "all endpoints", url: "/cache", expectedEndpoint: ""},
+		{name: "single endpoint", url: "/cache?endpoint=user-service", expectedEndpoint: "user-service"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockCachingProxyService{}
+			mockService.On("PurgeCache", tt.expectedEndpoint).Return(3)
+
+			handler := NewHandler(mockService, createTestLogger())
+			router := handler.SetupRoutes()
+
+			req := httptest.NewRequest("DELETE", tt.url, nil)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusOK, rr.Code)
+
+			var response map[string]interface{}
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+			assert.Equal(t, float64(3), response["purged"])
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_CachePurge_NotSupported(t *testing.T) {
+	handler := NewHandler(&MockProxyService{}, createTestLogger())
+	router := handler.SetupRoutes()
+
+	req := httptest.NewRequest("DELETE", "/cache", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotImplemented, rr.Code)
+}
+
+func TestHandler_ConfigReload(t *testing.T) {
+	newRouter := func(reloadErr error) (http.Handler, *MockConfigProvider) {
+		mockConfig := &MockConfigProvider{}
+		mockConfig.On("Reload").Return(reloadErr)
+		mockConfig.On("LoadConfig").Return(&models.ProxyConfig{
+			Endpoints: map[string]*models.Endpoint{
+				"user-service":  {Name: "user-service", Target: "https://users.example.com"},
+				"order-service": {Name: "order-service", Target: "https://orders.example.com"},
+			},
+		}, nil)
+		service := NewService(mockConfig, &MockHTTPClient{}, transform.NewUnifiedTransformer(), createTestLogger())
+		return NewHandler(service, createTestLogger()).SetupRoutes(), mockConfig
+	}
+
+	t.Run("success", func(t *testing.T) {
+		router, mockConfig := newRouter(nil)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("POST", "/config/reload", nil))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"reloaded": true, "endpoints": 2}`, rr.Body.String())
+		mockConfig.AssertCalled(t, "Reload")
+	})
+
+	t.Run("invalid configuration", func(t *testing.T) {
+		router, _ := newRouter(errors.New("failed to parse configuration: at least one endpoint must be configured"))
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("POST", "/config/reload", nil))
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+		var errorResponse models.ErrorResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &errorResponse))
+		assert.Equal(t, "CONFIG_ERROR", errorResponse.Error.Code)
+		details, ok := errorResponse.Error.Details.(map[string]interface{})
+		require.True(t, ok)
+		assert.Contains(t, details["error"], "at least one endpoint must be configured")
+	})
+
+	t.Run("not supported", func(t *testing.T) {
+		router := NewHandler(&MockProxyService{}, createTestLogger()).SetupRoutes()
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("POST", "/config/reload", nil))
+
+		assert.Equal(t, http.StatusNotImplemented, rr.Code)
+	})
+}
+
+func TestHandler_EndpointRequestLogging(t *testing.T) {
+	logRequests := false
+	tests := []struct {
+		name       string
+		endpoint   *models.Endpoint
+		expectPath bool
+	}{
+		{name: "logged by default", endpoint: &models.Endpoint{Name: "records", Target: "https://api.example.com"}, expectPath: true},
+		{name: "logging disabled", endpoint: &models.Endpoint{Name: "records", Target: "https://api.example.com", LogRequests: &logRequests}, expectPath: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockConfig := &MockConfigProvider{}
+			mockClient := &MockHTTPClient{}
+			mockConfig.On("GetEndpoint", "records").Return(tt.endpoint, true)
+			mockClient.On("ForwardRequest", mock.Anything, "GET", "https://api.example.com", "/patients/42", mock.Anything, mock.Anything, nil).
+				Return(&client.Response{
+					StatusCode: http.StatusOK,
+					Headers:    http.Header{"Content-Type": []string{"application/json"}},
+					Body:       []byte(`{"name": "x"}`),
+				}, nil)
+
+			logger, _ := logging.NewLogger("debug")
+			var logs bytes.Buffer
+			logger.SetOutput(&logs)
+			service := NewService(mockConfig, mockClient, transform.NewUnifiedTransformer(), logger)
+			router := NewHandler(service, logger).SetupRoutes()
+
+			req := httptest.NewRequest("POST", "/proxy/records/patients/42?ssn=123", bytes.NewReader([]byte(`{"method": "GET", "jq_query": ".name"}`)))
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			output := logs.String()
+			assert.Contains(t, output, "Request completed")
+			assert.Contains(t, output, `"endpoint":"records"`)
+			if tt.expectPath {
+				assert.Contains(t, output, "/patients/42")
+				assert.Contains(t, output, "ssn=123")
+			} else {
+				assert.NotContains(t, output, "patients/42")
+				assert.NotContains(t, output, "ssn=123")
+			}
+		})
+	}
+}
+
+func TestHandler_UpstreamDurationHeader(t *testing.T) {
+	mockConfig := &MockConfigProvider{}
+	mockClient := &MockHTTPClient{}
+	endpoint := &models.Endpoint{Name: "slow-service", Target: "https://api.example.com"}
+	mockConfig.On("GetEndpoint", "slow-service").Return(endpoint, true)
+	mockClient.On("ForwardRequest", mock.Anything, "GET", "https://api.example.com", "/items", mock.Anything, mock.Anything, nil).
+		After(20*time.Millisecond).
+		Return(&client.Response{
+			StatusCode: http.StatusOK,
+			Headers:    http.Header{"Content-Type": []string{"application/json"}},
+			Body:       []byte(`{"items": []}`),
+		}, nil)
+
+	service := NewService(mockConfig, mockClient, transform.NewUnifiedTransformer(), createTestLogger())
+	router := NewHandler(service, createTestLogger()).SetupRoutes()
+
+	req := httptest.NewRequest("POST", "/proxy/slow-service/items", bytes.NewReader([]byte(`{"method": "GET", "jq_query": ".items"}`)))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	start := time.Now()
+	router.ServeHTTP(rr, req)
+	elapsed := time.Since(start)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	header := rr.Header().Get("X-Upstream-Duration-Ms")
+	require.NotEmpty(t, header)
+	ms, err := strconv.ParseInt(header, 10, 64)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, ms, int64(20))
+	assert.LessOrEqual(t, ms, elapsed.Milliseconds())
+}
+
+func TestHandler_ForwardCookies(t *testing.T) {
+	tests := []struct {
+		name          string
+		forward       bool
+		expectCookies []string
+	}{
+		{name: "dropped by default", forward: false, expectCookies: nil},
+		{name: "forwarded when enabled", forward: true, expectCookies: []string{"session=abc; HttpOnly", "theme=dark"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockConfig := &MockConfigProvider{}
+			mockClient := &MockHTTPClient{}
+			endpoint := &models.Endpoint{Name: "session-service", Target: "https://api.example.com", ForwardCookies: tt.forward}
+			mockConfig.On("GetEndpoint", "session-service").Return(endpoint, true)
+			mockClient.On("ForwardRequest", mock.Anything, "POST", "https://api.example.com", "/login", mock.Anything, mock.Anything, mock.Anything).
+				Return(&client.Response{
+					StatusCode: http.StatusOK,
+					Headers: http.Header{
+						"Content-Type": []string{"application/json"},
+						"Set-Cookie":   []string{"session=abc; HttpOnly", "theme=dark"},
+					},
+					Body: []byte(`{"ok": true}`),
+				}, nil)
+
+			service := NewService(mockConfig, mockClient, transform.NewUnifiedTransformer(), createTestLogger())
+			router := NewHandler(service, createTestLogger()).SetupRoutes()
+
+			req := httptest.NewRequest("POST", "/proxy/session-service/login", bytes.NewReader([]byte(`{"method": "POST", "body": {"user": "a"}, "jq_query": ".ok"}`)))
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, tt.expectCookies, rr.Header().Values("Set-Cookie"))
+		})
+	}
+}
+
+func TestHandler_RetryAfter(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		retryAfter string
+	}{
+		{name: "propagated from 429", status: http.StatusTooManyRequests, retryAfter: "30"},
+		{name: "ignored on other statuses", status: http.StatusServiceUnavailable, retryAfter: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockConfig := &MockConfigProvider{}
+			mockClient := &MockHTTPClient{}
+			endpoint := &models.Endpoint{Name: "quota-service", Target: "https://api.example.com"}
+			mockConfig.On("GetEndpoint", "quota-service").Return(endpoint, true)
+			mockClient.On("ForwardRequest", mock.Anything, "GET", "https://api.example.com", "/items", mock.Anything, mock.Anything, mock.Anything).
+				Return(&client.Response{
+					StatusCode: tt.status,
+					Headers: http.Header{
+						"Content-Type": []string{"application/json"},
+						"Retry-After":  []string{"30"},
+					},
+					Body: []byte(`{"error": "slow down"}`),
+				}, nil)
+
+			service := NewService(mockConfig, mockClient, transform.NewUnifiedTransformer(), createTestLogger())
+			router := NewHandler(service, createTestLogger()).SetupRoutes()
+
+			req := httptest.NewRequest("POST", "/proxy/quota-service/items", bytes.NewReader([]byte(`{"method": "GET", "jq_query": ".error"}`)))
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.status, rr.Code)
+			assert.Equal(t, tt.retryAfter, rr.Header().Get("Retry-After"))
+			assert.Equal(t, "\"slow down\"\n", rr.Body.String())
+		})
+	}
+}
+
+func TestHandler_IdempotencyKey(t *testing.T) {
+	mockConfig := &MockConfigProvider{}
+	mockClient := &MockHTTPClient{}
+	endpoint := &models.Endpoint{Name: "orders", Target: "https://api.example.com", IdempotencyTTL: 60}
+	mockConfig.On("GetEndpoint", "orders").Return(endpoint, true)
+	mockClient.On("ForwardRequest", mock.Anything, "POST", "https://api.example.com", "/orders", mock.Anything, mock.Anything, mock.Anything).
+		Return(&client.Response{
+			StatusCode: http.StatusCreated,
+			Headers:    http.Header{"Content-Type": []string{"application/json"}},
+			Body:       []byte(`{"order": 1}`),
+		}, nil).Once()
+
+	service := NewService(mockConfig, mockClient, transform.NewUnifiedTransformer(), createTestLogger())
+	router := NewHandler(service, createTestLogger()).SetupRoutes()
+
+	for i, replayed := range []string{"", "true"} {
+		req := httptest.NewRequest("POST", "/proxy/orders/orders", bytes.NewReader([]byte(`{"method": "POST", "body": {"item": "book"}, "jq_query": ".order"}`)))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", "abc")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusCreated, rr.Code, "request %d", i+1)
+		assert.Equal(t, "1\n", rr.Body.String(), "request %d", i+1)
+		assert.Equal(t, replayed, rr.Header().Get("Idempotent-Replayed"), "request %d", i+1)
+	}
+	mockClient.AssertExpectations(t)
+}
+
+func TestHandler_EmptyUpstreamBody(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		expectedBody string
+	}{
+		{name: "204 has no body", status: http.StatusNoContent, expectedBody: ""},
+		{name: "empty 200 returns null", status: http.StatusOK, expectedBody: "null\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockConfig := &MockConfigProvider{}
+			mockClient := &MockHTTPClient{}
+			endpoint := &models.Endpoint{Name: "test-service", Target: "https://api.example.com", ErrorOnNull: true}
+			mockConfig.On("GetEndpoint", "test-service").Return(endpoint, true)
+			mockClient.On("ForwardRequest", mock.Anything, "DELETE", "https://api.example.com", "/items/1", mock.Anything, mock.Anything, mock.Anything).
+				Return(&client.Response{StatusCode: tt.status, Headers: http.Header{}}, nil)
+
+			service := NewService(mockConfig, mockClient, transform.NewUnifiedTransformer(), createTestLogger())
+			router := NewHandler(service, createTestLogger()).SetupRoutes()
+
+			// The query would fail on the empty body if it were run
+			req := httptest.NewRequest("POST", "/proxy/test-service/items/1", bytes.NewReader([]byte(`{"method": "DELETE", "jq_query": ".items[]"}`)))
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.status, rr.Code)
+			assert.Equal(t, tt.expectedBody, rr.Body.String())
+		})
+	}
+}
+
+func TestHandler_HeadRequest(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+	}{
+		{name: "found", status: http.StatusOK},
+		{name: "not found", status: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockConfig := &MockConfigProvider{}
+			mockClient := &MockHTTPClient{}
+			endpoint := &models.Endpoint{Name: "test-service", Target: "https://api.example.com", ErrorOnNull: true}
+			mockConfig.On("GetEndpoint", "test-service").Return(endpoint, true)
+			mockClient.On("ForwardRequest", mock.Anything, "HEAD", "https://api.example.com", "/files/1", mock.Anything, mock.Anything, mock.Anything).
+				Return(&client.Response{StatusCode: tt.status, Headers: http.Header{
+					"Content-Type":   []string{"application/pdf"},
+					"Content-Length": []string{"1234"},
+					"Etag":           []string{`"v1"`},
+					"Last-Modified":  []string{"Wed, 14 Oct 2026 10:00:00 GMT"},
+					"X-Internal":     []string{"secret"},
+				}}, nil)
+
+			service := NewService(mockConfig, mockClient, transform.NewUnifiedTransformer(), createTestLogger())
+			router := NewHandler(service, createTestLogger()).SetupRoutes()
+
+			// The query would fail if it were run
+			req := httptest.NewRequest("POST", "/proxy/test-service/files/1", bytes.NewReader([]byte(`{"method": "HEAD", "jq_query": "error(\"ran\")"}`)))
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.status, rr.Code)
+			assert.Empty(t, rr.Body.String())
+			assert.Equal(t, "application/pdf", rr.Header().Get("Content-Type"))
+			assert.Equal(t, `"v1"`, rr.Header().Get("ETag"))
+			assert.Equal(t, "Wed, 14 Oct 2026 10:00:00 GMT", rr.Header().Get("Last-Modified"))
+			assert.Empty(t, rr.Header().Get("Content-Length"), "the upstream length describes a body that is not sent")
+			assert.Empty(t, rr.Header().Get("X-Internal"))
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_UploadRequest(t *testing.T) {
+	// Bytes that are not valid UTF-8 or JSON, as in an image
+	image := []byte{0x89, 'P', 'N', 'G', 0x0d, 0x0a, 0x1a, 0x0a, 0x00, 0xff, 0xfe, 0x80}
+
+	var received struct {
+		method, path, contentType, jqHeader string
+		body                                []byte
+	}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.method = r.Method
+		received.path = r.URL.Path
+		received.contentType = r.Header.Get("Content-Type")
+		received.jqHeader = r.Header.Get("Jpx-Jq-Query")
+		received.body, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"id": "img-1", "size": %d, "internal": true}`, len(received.body))
+	}))
+	defer upstream.Close()
+
+	mockConfig := &MockConfigProvider{}
+	mockConfig.On("GetEndpoint", "images").Return(&models.Endpoint{Name: "images", Target: upstream.URL}, true)
+	service := NewService(mockConfig, client.NewClient(5*time.Second), transform.NewUnifiedTransformer(), createTestLogger())
+	router := NewHandler(service, createTestLogger()).SetupRoutes()
+
+	t.Run("forwards the body byte for byte", func(t *testing.T) {
+		req := httptest.NewRequest("PUT", "/upload/images/avatars/1", bytes.NewReader(image))
+		req.Header.Set("Content-Type", "image/png")
+		req.Header.Set("Jpx-Jq-Query", "{id, size}")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"id": "img-1", "size": 12}`, rr.Body.String())
+		assert.Equal(t, "PUT", received.method)
+		assert.Equal(t, "/avatars/1", received.path)
+		assert.Equal(t, "image/png", received.contentType)
+		assert.Equal(t, image, received.body)
+		assert.Empty(t, received.jqHeader, "transformation headers are not forwarded")
+	})
+
+	t.Run("untyped bodies are sent as octet streams", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/upload/images", bytes.NewReader(image))
+		req.Header.Set("Jpx-Jq-Query", ".id")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `"img-1"`, rr.Body.String())
+		assert.Equal(t, "application/octet-stream", received.contentType)
+		assert.Equal(t, image, received.body)
+	})
+
+	t.Run("missing query", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/upload/images", bytes.NewReader(image))
+		req.Header.Set("Content-Type", "image/png")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Contains(t, rr.Body.String(), "jq_query is required")
+	})
+}
+
+func TestHandler_ResponseBufferBytes(t *testing.T) {
+	items := make([]interface{}, 2000)
+	for i := range items {
+		items[i] = map[string]interface{}{"id": float64(i), "name": fmt.Sprintf("User %d", i)}
+	}
+	expected, err := json.Marshal(items)
+	require.NoError(t, err)
+
+	for _, limit := range []int{0, 1024} {
+		t.Run(fmt.Sprintf("limit %d", limit), func(t *testing.T) {
+			mockService := &MockProxyService{}
+			mockService.On("HandleRequest", mock.Anything, "test-service", "/users", mock.Anything, mock.Anything, mock.Anything).
+				Return(&models.ProxyResponse{Data: items, Status: http.StatusOK}, nil)
+			router := NewHandler(mockService, createTestLogger(), WithResponseBufferBytes(limit)).SetupRoutes()
+
+			req := httptest.NewRequest("POST", "/proxy/test-service/users", bytes.NewReader([]byte(`{"method": "GET", "jq_query": "."}`)))
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			// Incremental encoding does not change the response
+			assert.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+			assert.Equal(t, string(expected)+"\n", rr.Body.String())
+		})
+	}
+}
+
+func TestHandler_TemplateOutput(t *testing.T) {
+	tests := []struct {
+		name        string
+		envelope    string
+		contentType string
+		body        string
+	}{
+		{
+			name:        "default content type",
+			envelope:    `{"method": "GET", "transformation_mode": "template", "template": "{{range .}}* {{.name}}\n{{end}}"}`,
+			contentType: "text/plain; charset=utf-8",
+			body:        "* John\n* Jane\n",
+		},
+		{
+			name:        "configured content type",
+			envelope:    `{"method": "GET", "transformation_mode": "template", "template": "# Users\n{{range .}}- {{.name}}\n{{end}}", "content_type": "text/markdown; charset=utf-8"}`,
+			contentType: "text/markdown; charset=utf-8",
+			body:        "# Users\n- John\n- Jane\n",
+		},
+		{
+			name:        "jq output stays JSON",
+			envelope:    `{"method": "GET", "jq_query": ".[0].name", "content_type": "text/markdown"}`,
+			contentType: "application/json",
+			body:        "\"John\"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockConfig := &MockConfigProvider{}
+			mockClient := &MockHTTPClient{}
+			endpoint := &models.Endpoint{Name: "user-service", Target: "https://api.example.com"}
+			mockConfig.On("GetEndpoint", "user-service").Return(endpoint, true)
+			mockClient.On("ForwardRequest", mock.Anything, "GET", "https://api.example.com", "/users", mock.Anything, mock.Anything, mock.Anything).
+				Return(&client.Response{
+					StatusCode: http.StatusOK,
+					Headers:    http.Header{"Content-Type": []string{"application/json"}},
+					Body:       []byte(`[{"name": "John"}, {"name": "Jane"}]`),
+				}, nil)
+
+			service := NewService(mockConfig, mockClient, transform.NewUnifiedTransformer(), createTestLogger())
+			router := NewHandler(service, createTestLogger()).SetupRoutes()
+
+			req := httptest.NewRequest("POST", "/proxy/user-service/users", bytes.NewReader([]byte(tt.envelope)))
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+			assert.Equal(t, tt.contentType, rr.Header().Get("Content-Type"))
+			assert.Equal(t, tt.body, rr.Body.String())
+		})
+	}
+}
+
+func TestHandler_NormalizeErrors(t *testing.T) {
+	tests := []struct {
+		name           string
+		upstreamStatus int
+		upstreamBody   string
+		normalize      bool
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "upstream error passed through by default",
+			upstreamStatus: http.StatusServiceUnavailable,
+			upstreamBody:   `{"message": "maintenance"}`,
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   `{"message": "maintenance"}`,
+		},
+		{
+			name:           "upstream error wrapped when normalized",
+			upstreamStatus: http.StatusServiceUnavailable,
+			upstreamBody:   `{"message": "maintenance"}`,
+			normalize:      true,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"error": {"message": "maintenance"}, "status": 503}`,
+		},
+		{
+			name:           "client error wrapped when normalized",
+			upstreamStatus: http.StatusNotFound,
+			upstreamBody:   `{"message": "no such user"}`,
+			normalize:      true,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"error": {"message": "no such user"}, "status": 404}`,
+		},
+		{
+			name:           "successful response unchanged when normalized",
+			upstreamStatus: http.StatusOK,
+			upstreamBody:   `{"message": "hello"}`,
+			normalize:      true,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"message": "hello"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockConfig := &MockConfigProvider{}
+			mockClient := &MockHTTPClient{}
+			endpoint := &models.Endpoint{Name: "user-service", Target: "https://api.example.com"}
+			mockConfig.On("GetEndpoint", "user-service").Return(endpoint, true)
+			mockClient.On("ForwardRequest", mock.Anything, "GET", "https://api.example.com", "/users", mock.Anything, mock.Anything, mock.Anything).
+				Return(&client.Response{
+					StatusCode: tt.upstreamStatus,
+					Headers:    http.Header{"Content-Type": []string{"application/json"}},
+					Body:       []byte(tt.upstreamBody),
+				}, nil)
+
+			service := NewService(mockConfig, mockClient, transform.NewUnifiedTransformer(), createTestLogger())
+			router := NewHandler(service, createTestLogger()).SetupRoutes()
+
+			envelope, _ := json.Marshal(map[string]interface{}{
+				"method":           "GET",
+				"jq_query":         ".",
+				"normalize_errors": tt.normalize,
+			})
+			req := httptest.NewRequest("POST", "/proxy/user-service/users", bytes.NewReader(envelope))
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.JSONEq(t, tt.expectedBody, rr.Body.String())
+		})
+	}
+}
+
+func TestHandler_ResponseContentType(t *testing.T) {
+	tests := []struct {
+		name        string
+		url         string
+		envelope    string
+		contentType string
+		body        string
+	}{
+		{
+			name:        "JSON result",
+			url:         "/proxy/user-service/users",
+			envelope:    `{"method": "GET", "jq_query": ".[0]", "response_content_type": "application/vnd.api+json"}`,
+			contentType: "application/vnd.api+json",
+			body:        "{\"name\":\"John\"}\n",
+		},
+		{
+			name:        "template result",
+			url:         "/proxy/user-service/users",
+			envelope:    `{"method": "GET", "transformation_mode": "template", "template": "{{range .}}<li>{{.name}}</li>{{end}}", "response_content_type": "text/html; charset=utf-8"}`,
+			contentType: "text/html; charset=utf-8",
+			body:        "<li>John</li><li>Jane</li>",
+		},
+		{
+			name:        "CSV result",
+			url:         "/proxy/user-service/users?format=csv",
+			envelope:    `{"method": "GET", "jq_query": ".", "response_content_type": "text/plain"}`,
+			contentType: "text/plain",
+			body:        "name\nJohn\nJane\n",
+		},
+		{
+			name:        "streamed result",
+			url:         "/proxy/user-service/users",
+			envelope:    `{"method": "GET", "jq_query": ".[]", "stream": true, "response_content_type": "application/x-ndjson"}`,
+			contentType: "application/x-ndjson",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockConfig := &MockConfigProvider{}
+			mockClient := &MockHTTPClient{}
+			endpoint := &models.Endpoint{Name: "user-service", Target: "https://api.example.com"}
+			mockConfig.On("GetEndpoint", "user-service").Return(endpoint, true)
+			mockClient.On("ForwardRequest", mock.Anything, "GET", "https://api.example.com", "/users", mock.Anything, mock.Anything, mock.Anything).
+				Return(&client.Response{
+					StatusCode: http.StatusOK,
+					Headers:    http.Header{"Content-Type": []string{"application/json"}},
+					Body:       []byte(`[{"name": "John"}, {"name": "Jane"}]`),
+				}, nil)
+
+			service := NewService(mockConfig, mockClient, transform.NewUnifiedTransformer(), createTestLogger())
+			router := NewHandler(service, createTestLogger()).SetupRoutes()
+
+			req := httptest.NewRequest("POST", tt.url, bytes.NewReader([]byte(tt.envelope)))
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+			assert.Equal(t, tt.contentType, rr.Header().Get("Content-Type"))
+			if tt.body != "" {
+				assert.Equal(t, tt.body, rr.Body.String())
+			}
+		})
+	}
+}
+
 func TestHandler_CORS(t *testing.T) {
 	// Setup
 	mockService := &MockProxyService{}
@@ -0,0 +1,113 @@
+package proxy
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"jq-proxy-service/internal/models"
+)
+
+// healthWindowSize is the number of recent upstream calls an endpoint's
+// status is computed over
+const healthWindowSize = 100
+
+// healthTracker keeps a rolling window of upstream call outcomes per endpoint
+type healthTracker struct {
+	mu        sync.Mutex
+	endpoints map[string]*endpointHealth
+	now       func() time.Time
+}
+
+// endpointHealth is the outcome window of a single endpoint. failed is a ring
+// buffer; next is the slot the next outcome overwrites.
+type endpointHealth struct {
+	failed      [healthWindowSize]bool
+	next        int
+	count       int
+	failures    int
+	lastFailure time.Time
+	lastError   string
+}
+
+// newHealthTracker creates an empty health tracker
+func newHealthTracker() *healthTracker {
+	return &healthTracker{
+		endpoints: make(map[string]*endpointHealth),
+		now:       time.Now,
+	}
+}
+
+// record adds the outcome of an upstream call to the endpoint's window. A
+// call fails when it returned an error or a 5xx response.
+func (t *healthTracker) record(endpointName string, statusCode int, err error) {
+	failure := ""
+	switch {
+	case err != nil:
+		failure = err.Error()
+	case statusCode >= 500:
+		failure = fmt.Sprintf("upstream responded with status %d", statusCode)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	h, ok := t.endpoints[endpointName]
+	if !ok {
+		h = &endpointHealth{}
+		t.endpoints[endpointName] = h
+	}
+
+	// Drop the outcome leaving the window once it is full
+	if h.count == healthWindowSize {
+		if h.failed[h.next] {
+			h.failures--
+		}
+	} else {
+		h.count++
+	}
+
+	h.failed[h.next] = failure != ""
+	h.next = (h.next + 1) % healthWindowSize
+	if failure != "" {
+		h.failures++
+		h.lastFailure = t.now()
+		h.lastError = failure
+	}
+}
+
+// statuses returns the status of the given endpoints and of every endpoint
+// with recorded calls, sorted by name
+func (t *healthTracker) statuses(endpointNames []string) []models.EndpointStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	names := make(map[string]struct{}, len(endpointNames)+len(t.endpoints))
+	for _, name := range endpointNames {
+		names[name] = struct{}{}
+	}
+	for name := range t.endpoints {
+		names[name] = struct{}{}
+	}
+
+	statuses := make([]models.EndpointStatus, 0, len(names))
+	for name := range names {
+		status := models.EndpointStatus{Endpoint: name}
+		if h, ok := t.endpoints[name]; ok {
+			status.Requests = h.count
+			status.Failures = h.failures
+			if h.count > 0 {
+				status.ErrorRate = float64(h.failures) / float64(h.count)
+			}
+			if !h.lastFailure.IsZero() {
+				lastFailure := h.lastFailure
+				status.LastFailure = &lastFailure
+				status.LastError = h.lastError
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Endpoint < statuses[j].Endpoint })
+	return statuses
+}
@@ -0,0 +1,111 @@
+package proxy
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"jq-proxy-service/internal/client"
+	"jq-proxy-service/internal/models"
+	"jq-proxy-service/internal/transform"
+)
+
+func TestHealthTracker(t *testing.T) {
+	tracker := newHealthTracker()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tracker.now = func() time.Time { return now }
+
+	tracker.record("users", 200, nil)
+	tracker.record("users", 503, nil)
+	tracker.record("users", 404, nil)
+	now = now.Add(time.Minute)
+	tracker.record("users", 0, errors.New("connection refused"))
+
+	statuses := tracker.statuses([]string{"orders", "users"})
+	require.Len(t, statuses, 2)
+
+	// Configured endpoints without calls are reported as healthy
+	assert.Equal(t, models.EndpointStatus{Endpoint: "orders"}, statuses[0])
+
+	users := statuses[1]
+	assert.Equal(t, "users", users.Endpoint)
+	assert.Equal(t, 4, users.Requests)
+	assert.Equal(t, 2, users.Failures)
+	assert.Equal(t, 0.5, users.ErrorRate)
+	require.NotNil(t, users.LastFailure)
+	assert.Equal(t, now, *users.LastFailure)
+	assert.Equal(t, "connection refused", users.LastError)
+}
+
+func TestHealthTracker_RollingWindow(t *testing.T) {
+	tracker := newHealthTracker()
+
+	// A burst of failures leaves the window as successes replace it
+	for i := 0; i < healthWindowSize; i++ {
+		tracker.record("users", 500, nil)
+	}
+	for i := 0; i < healthWindowSize-10; i++ {
+		tracker.record("users", 200, nil)
+	}
+
+	status := tracker.statuses(nil)[0]
+	assert.Equal(t, healthWindowSize, status.Requests)
+	assert.Equal(t, 10, status.Failures)
+	assert.InDelta(t, 0.1, status.ErrorRate, 1e-9)
+	assert.Equal(t, "upstream responded with status 500", status.LastError)
+}
+
+func TestHandler_EndpointStatus(t *testing.T) {
+	mockConfig := &MockConfigProvider{}
+	mockClient := &MockHTTPClient{}
+	endpoint := &models.Endpoint{Name: "flaky-service", Target: "https://flaky.example.com"}
+	mockConfig.On("GetEndpoint", "flaky-service").Return(endpoint, true)
+	mockConfig.On("LoadConfig").Return(&models.ProxyConfig{
+		Endpoints: map[string]*models.Endpoint{
+			"flaky-service":  endpoint,
+			"stable-service": {Name: "stable-service", Target: "https://stable.example.com"},
+		},
+	}, nil)
+
+	ok := &client.Response{StatusCode: http.StatusOK, Headers: http.Header{"Content-Type": []string{"application/json"}}, Body: []byte(`{}`)}
+	mockClient.On("ForwardRequest", mock.Anything, "GET", "https://flaky.example.com", "/ok", mock.Anything, mock.Anything, mock.Anything).Return(ok, nil)
+	mockClient.On("ForwardRequest", mock.Anything, "GET", "https://flaky.example.com", "/down", mock.Anything, mock.Anything, mock.Anything).
+		Return(nil, errors.New("connection refused"))
+
+	service := NewService(mockConfig, mockClient, transform.NewUnifiedTransformer(), createTestLogger())
+	router := NewHandler(service, createTestLogger()).SetupRoutes()
+
+	for _, path := range []string{"/ok", "/down", "/ok", "/down"} {
+		req := httptest.NewRequest("POST", "/proxy/flaky-service"+path, strings.NewReader(`{"method": "GET", "jq_query": "."}`))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/endpoints/status", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var response struct {
+		Endpoints []models.EndpointStatus `json:"endpoints"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	require.Len(t, response.Endpoints, 2)
+
+	flaky := response.Endpoints[0]
+	assert.Equal(t, "flaky-service", flaky.Endpoint)
+	assert.Equal(t, 4, flaky.Requests)
+	assert.Equal(t, 2, flaky.Failures)
+	assert.Equal(t, 0.5, flaky.ErrorRate)
+	assert.NotNil(t, flaky.LastFailure)
+	assert.Equal(t, "Failed to connect to target endpoint", flaky.LastError)
+
+	assert.Equal(t, models.EndpointStatus{Endpoint: "stable-service"}, response.Endpoints[1])
+}
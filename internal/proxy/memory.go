@@ -0,0 +1,119 @@
+package proxy
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"slices"
+)
+
+// Approximate in-memory sizes of decoded JSON values on 64-bit platforms
+const (
+	interfaceSize = 16
+	sliceSize     = 24
+	mapSize       = 48
+	stringSize    = 16
+	numberSize    = 8
+)
+
+// encodeBufferSize bounds what an incremental encoding holds before writing
+// it to the client
+const encodeBufferSize = 32 << 10
+
+// resultMemory estimates the memory a transformation result occupies,
+// counting the headers of maps, slices and strings along with their contents.
+// It is meant for enforcing limits rather than exact accounting.
+func resultMemory(data interface{}) int {
+	switch v := data.(type) {
+	case nil, bool:
+		return interfaceSize
+	case string:
+		return interfaceSize + stringSize + len(v)
+	case json.Number:
+		return interfaceSize + stringSize + len(v)
+	case []interface{}:
+		size := interfaceSize + sliceSize
+		for _, element := range v {
+			size += resultMemory(element)
+		}
+		return size
+	case map[string]interface{}:
+		size := interfaceSize + mapSize
+		for key, value := range v {
+			size += stringSize + len(key) + resultMemory(value)
+		}
+		return size
+	default:
+		return interfaceSize + numberSize
+	}
+}
+
+// encodeIncrementally writes data as JSON the way json.Encoder does, but
+// encodes arrays and objects one member at a time through a bounded buffer
+// instead of holding the whole encoding in memory
+func encodeIncrementally(w io.Writer, data interface{}) error {
+	bw := bufio.NewWriterSize(w, encodeBufferSize)
+	if err := encodeValue(bw, data); err != nil {
+		return err
+	}
+	if err := bw.WriteByte('\n'); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// encodeValue writes one JSON value, recursing into arrays and objects
+func encodeValue(w *bufio.Writer, data interface{}) error {
+	switch v := data.(type) {
+	case []interface{}:
+		if err := w.WriteByte('['); err != nil {
+			return err
+		}
+		for i, element := range v {
+			if i > 0 {
+				if err := w.WriteByte(','); err != nil {
+					return err
+				}
+			}
+			if err := encodeValue(w, element); err != nil {
+				return err
+			}
+		}
+		return w.WriteByte(']')
+	case map[string]interface{}:
+		// Keys are sorted, as encoding/json writes them
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+
+		if err := w.WriteByte('{'); err != nil {
+			return err
+		}
+		for i, key := range keys {
+			if i > 0 {
+				if err := w.WriteByte(','); err != nil {
+					return err
+				}
+			}
+			if err := encodeValue(w, key); err != nil {
+				return err
+			}
+			if err := w.WriteByte(':'); err != nil {
+				return err
+			}
+			if err := encodeValue(w, v[key]); err != nil {
+				return err
+			}
+		}
+		return w.WriteByte('}')
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(encoded)
+		return err
+	}
+}
@@ -0,0 +1,59 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResultMemory(t *testing.T) {
+	small := map[string]interface{}{"id": float64(1)}
+	large := map[string]interface{}{"id": float64(1), "name": "a much longer value than the small one"}
+
+	assert.Greater(t, resultMemory(large), resultMemory(small))
+	assert.Greater(t, resultMemory([]interface{}{small, small}), 2*resultMemory(small))
+	assert.Equal(t, resultMemory("abc")+10, resultMemory("abcdefghijklm"))
+	assert.Positive(t, resultMemory(nil))
+}
+
+// chunkWriter records the largest single write it receives
+type chunkWriter struct {
+	bytes.Buffer
+	largest int
+}
+
+func (c *chunkWriter) Write(p []byte) (int, error) {
+	c.largest = max(c.largest, len(p))
+	return c.Buffer.Write(p)
+}
+
+func TestEncodeIncrementally(t *testing.T) {
+	items := make([]interface{}, 5000)
+	for i := range items {
+		items[i] = map[string]interface{}{
+			"id":    i,
+			"name":  fmt.Sprintf("User <%d> & co", i),
+			"tags":  []interface{}{"a", "b"},
+			"score": 1.5,
+			"admin": i%2 == 0,
+			"group": nil,
+		}
+	}
+	data := map[string]interface{}{"items": items, "total": len(items)}
+
+	var expected bytes.Buffer
+	require.NoError(t, json.NewEncoder(&expected).Encode(data))
+
+	var w chunkWriter
+	require.NoError(t, encodeIncrementally(&w, data))
+
+	// The output matches json.Encoder byte for byte, but is written in
+	// bounded chunks
+	assert.Equal(t, expected.String(), w.String())
+	assert.Greater(t, w.Len(), encodeBufferSize)
+	assert.LessOrEqual(t, w.largest, encodeBufferSize)
+}
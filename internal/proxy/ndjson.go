@@ -0,0 +1,46 @@
+package proxy
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// ndjsonContentType is the media type of newline-delimited JSON responses
+const ndjsonContentType = "application/x-ndjson"
+
+// wantsNDJSON reports whether the client's Accept header asks for
+// newline-delimited JSON
+func wantsNDJSON(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, mediaRange := range strings.Split(accept, ",") {
+			mediaType, _, err := mime.ParseMediaType(mediaRange)
+			if err == nil && mediaType == ndjsonContentType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// writeNDJSONResponse writes each value the query yielded on its own line,
+// like the jq command line does. Requests asking for NDJSON are collected, so
+// data holds every value; any other result is written as a single line.
+func (h *Handler) writeNDJSONResponse(w http.ResponseWriter, statusCode int, data interface{}, contentType string) {
+	values, ok := data.([]interface{})
+	if !ok {
+		values = []interface{}{data}
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(statusCode)
+
+	encoder := json.NewEncoder(w)
+	for _, value := range values {
+		if err := encoder.Encode(value); err != nil {
+			h.logger.WithError(err).Error("Failed to encode NDJSON response")
+			return
+		}
+	}
+}
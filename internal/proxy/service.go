@@ -3,14 +3,28 @@ package proxy
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"net/http"
 	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"jq-proxy-service/internal/balancer"
+	"jq-proxy-service/internal/cache"
 	"jq-proxy-service/internal/client"
 	"jq-proxy-service/internal/logging"
 	"jq-proxy-service/internal/models"
+	"jq-proxy-service/internal/ratelimit"
+	"jq-proxy-service/internal/singleflight"
+	"jq-proxy-service/internal/tracing"
 	"jq-proxy-service/internal/transform"
 
 	"github.com/sirupsen/logrus"
@@ -20,23 +34,172 @@ import (
 type Service struct {
 	configProvider models.ConfigProvider
 	httpClient     client.HTTPClient
-	transformer    *transform.UnifiedTransformer
+	transformer    transform.Engine
 	logger         *logging.Logger
+	responseCache  *cache.Cache
+	balancer       *balancer.Balancer
+
+	// upstreamSlots bounds concurrent upstream requests; nil means unlimited
+	upstreamSlots chan struct{}
+
+	// transformSlots bounds concurrent response transformations; nil means unlimited
+	transformSlots chan struct{}
+
+	// maxResultBytes caps the serialized transformation result; zero means unlimited
+	maxResultBytes int
+
+	// maxResultMemory caps the estimated in-memory size of transformation
+	// results; zero means unlimited
+	maxResultMemory int
+
+	// bodyPreviewBytes bounds the upstream body logged on failures; zero disables it
+	bodyPreviewBytes int
+
+	// transformCache holds transformation results by upstream body and
+	// query; nil disables it
+	transformCache *cache.Cache
+
+	// rateLimiters paces outbound requests per endpoint name
+	rateLimitersMu sync.Mutex
+	rateLimiters   map[string]*ratelimit.Limiter
+
+	// endpointClients holds the clients of endpoints with their own transport settings
+	endpointClientsMu sync.Mutex
+	endpointClients   map[string]*endpointClient
+	newEndpointClient func(client.Settings) client.HTTPClient
+
+	// inflightReads shares one upstream call between identical concurrent GETs
+	inflightReads singleflight.Group
+
+	// idempotentResponses keeps responses to mutating calls by idempotency
+	// key; inflightIdempotent holds concurrent duplicates until the first
+	// call completes
+	idempotentResponses *cache.Cache
+	inflightIdempotent  singleflight.Group
+
+	// clientSettings are the defaults endpoint transport settings are applied over
+	clientSettings client.Settings
+
+	// health tracks recent upstream call outcomes per endpoint
+	health *healthTracker
+}
+
+// endpointClient is an HTTP client built from an endpoint's transport settings
+type endpointClient struct {
+	settings client.Settings
+	client   client.HTTPClient
+}
+
+// upstreamTimeout bounds each request forwarded to a target when the client
+// settings set no timeout
+const upstreamTimeout = 30 * time.Second
+
+// Option configures optional Service behaviour
+type Option func(*Service)
+
+// WithMaxConcurrentUpstream caps the number of upstream requests in flight at
+// once across all endpoints. Requests beyond the limit wait for a free slot
+// until their deadline. A non-positive limit means unlimited.
+func WithMaxConcurrentUpstream(limit int) Option {
+	return func(s *Service) {
+		if limit > 0 {
+			s.upstreamSlots = make(chan struct{}, limit)
+		}
+	}
+}
+
+// WithMaxConcurrentTransforms caps the number of response transformations
+// running at once, independently of the upstream limit. Requests beyond the
+// limit wait for a free slot until their context is done. A non-positive
+// limit means unlimited.
+func WithMaxConcurrentTransforms(limit int) Option {
+	return func(s *Service) {
+		if limit > 0 {
+			s.transformSlots = make(chan struct{}, limit)
+		}
+	}
+}
+
+// WithMaxResultBytes caps the serialized size of transformation results.
+// Requests may lower the cap further but never raise it. A non-positive
+// limit means unlimited.
+func WithMaxResultBytes(limit int) Option {
+	return func(s *Service) {
+		if limit > 0 {
+			s.maxResultBytes = limit
+		}
+	}
+}
+
+// WithBodyPreviewBytes logs a redacted preview of up to limit bytes of the
+// upstream body, at debug level, when it fails to parse or transform. A
+// non-positive limit disables the preview.
+func WithBodyPreviewBytes(limit int) Option {
+	return func(s *Service) {
+		if limit > 0 {
+			s.bodyPreviewBytes = limit
+		}
+	}
+}
+
+// WithMaxResultMemory caps the estimated memory a transformation result may
+// occupy while it is held for writing. Larger results are rejected with 413
+// instead of being sent. Zero disables the limit.
+func WithMaxResultMemory(limit int) Option {
+	return func(s *Service) {
+		if limit > 0 {
+			s.maxResultMemory = limit
+		}
+	}
+}
+
+// WithTransformCacheSize keeps up to entries transformation results, keyed by
+// the upstream body and the transformation, so the same query over the same
+// upstream data is not run again. A non-positive size disables the cache.
+func WithTransformCacheSize(entries int) Option {
+	return func(s *Service) {
+		if entries > 0 {
+			s.transformCache = cache.New(entries)
+		}
+	}
+}
+
+// WithClientSettings sets the defaults that endpoints with their own transport
+// settings start from, so they share the server's timeout and redirect policy.
+func WithClientSettings(settings client.Settings) Option {
+	return func(s *Service) {
+		s.clientSettings = settings
+	}
 }
 
 // NewService creates a new proxy service instance
 func NewService(
 	configProvider models.ConfigProvider,
 	httpClient client.HTTPClient,
-	transformer *transform.UnifiedTransformer,
+	transformer transform.Engine,
 	logger *logging.Logger,
+	opts ...Option,
 ) models.ProxyService {
-	return &Service{
-		configProvider: configProvider,
-		httpClient:     httpClient,
-		transformer:    transformer,
-		logger:         logger,
+	s := &Service{
+		configProvider:      configProvider,
+		httpClient:          httpClient,
+		transformer:         transformer,
+		logger:              logger,
+		responseCache:       cache.New(cache.DefaultMaxEntries),
+		idempotentResponses: cache.New(cache.DefaultMaxEntries),
+		balancer:            balancer.New(balancer.DefaultFailureCooldown),
+		rateLimiters:        make(map[string]*ratelimit.Limiter),
+		endpointClients:     make(map[string]*endpointClient),
+		newEndpointClient: func(settings client.Settings) client.HTTPClient {
+			return client.NewClientWithSettings(settings)
+		},
+		clientSettings: client.DefaultSettings(upstreamTimeout),
+		health:         newHealthTracker(),
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // HandleRequest processes a proxy request and returns the transformed response
@@ -49,16 +212,24 @@ func (s *Service) HandleRequest(
 ) (*models.ProxyResponse, error) {
 	// Record start time for metrics
 	startTime := time.Now()
+	accessInfo := logging.GetAccessInfo(ctx)
 
-	// Log the incoming request with request ID
-	s.logger.WithContext(ctx).WithFields(logrus.Fields{
+	// Resolve endpoint
+	endpoint, exists := s.configProvider.GetEndpoint(endpointName)
+
+	// Log the incoming request with request ID, leaving out the path for
+	// endpoints that keep their requests out of the logs
+	logFields := logrus.Fields{
 		"endpoint": endpointName,
-		"path":     path,
 		"method":   proxyReq.Method,
-	}).Info("Processing proxy request")
+	}
+	if exists && !endpoint.ShouldLogRequests() {
+		accessInfo.SetMinimal()
+	} else {
+		logFields["path"] = path
+	}
+	s.logger.WithContext(ctx).WithFields(logFields).Info("Processing proxy request")
 
-	// Resolve endpoint
-	endpoint, exists := s.configProvider.GetEndpoint(endpointName)
 	if !exists {
 		s.logger.WithContext(ctx).WithField("endpoint", endpointName).Warn("Endpoint not found")
 		s.logger.GetMetrics().RecordError(endpointName)
@@ -68,33 +239,187 @@ func (s *Service) HandleRequest(
 		}
 	}
 
+	if !endpoint.IsEnabled() {
+		s.logger.WithContext(ctx).WithField("endpoint", endpointName).Warn("Endpoint is disabled")
+		s.logger.GetMetrics().RecordError(endpointName)
+		return nil, &EndpointDisabledError{EndpointName: endpointName}
+	}
+
+	accessInfo.SetEndpoint(endpointName)
+
+	// Endpoints may require a fixed method regardless of the envelope's
+	if endpoint.UpstreamMethod != "" {
+		overridden := *proxyReq
+		overridden.Method = strings.ToUpper(endpoint.UpstreamMethod)
+		proxyReq = &overridden
+	}
+
+	// Duplicate mutating calls with the same Idempotency-Key get the first
+	// call's response instead of being forwarded again
+	if key := idempotencyKey(endpoint, endpointName, path, queryParams, headers, proxyReq); key != "" {
+		return s.handleIdempotent(ctx, endpoint, endpointName, key, startTime, func() (*models.ProxyResponse, error) {
+			return s.handleEndpointRequest(ctx, endpoint, endpointName, path, queryParams, headers, proxyReq, startTime)
+		})
+	}
+	return s.handleEndpointRequest(ctx, endpoint, endpointName, path, queryParams, headers, proxyReq, startTime)
+}
+
+// handleEndpointRequest forwards a request to a resolved, enabled endpoint
+// and transforms the response
+func (s *Service) handleEndpointRequest(
+	ctx context.Context,
+	endpoint *models.Endpoint,
+	endpointName, path string,
+	queryParams url.Values,
+	headers http.Header,
+	proxyReq *models.ProxyRequest,
+	startTime time.Time,
+) (*models.ProxyResponse, error) {
+	accessInfo := logging.GetAccessInfo(ctx)
+
+	// Fill in the endpoint's default body fields the client left out
+	if len(endpoint.DefaultBody) > 0 {
+		withDefaults := *proxyReq
+		withDefaults.Body = endpoint.MergeDefaultBody(proxyReq.Method, proxyReq.Body)
+		proxyReq = &withDefaults
+	}
+
+	// Envelope query parameters take precedence over the URL's, and the
+	// endpoint's mapped headers over both
+	queryParams = proxyReq.MergeQuery(queryParams)
+	queryParams = endpoint.MergeHeaderQuery(queryParams, headers)
+
 	// Validate transformation before making the request
 	if err := s.validateTransformation(proxyReq); err != nil {
 		s.logger.WithContext(ctx).WithError(err).Error("Invalid transformation")
 		s.logger.GetMetrics().RecordError(endpointName)
+		accessInfo.SetTransformError()
 		return nil, &TransformationError{
 			Message: fmt.Sprintf("Invalid transformation: %v", err),
-			Details: map[string]interface{}{
+			Details: withSyntaxErrorDetails(map[string]interface{}{
 				"transformation_mode": proxyReq.TransformationMode,
 				"jq_query":            proxyReq.JQQuery,
-			},
+			}, err),
 		}
 	}
 
-	// Forward request to target endpoint
-	response, err := s.forwardRequest(ctx, endpoint, path, queryParams, headers, proxyReq)
+	// The endpoint's variables and named path segments are made available to
+	// the jq queries, path segments winning over endpoint variables
+	var params map[string]interface{}
+	if proxyReq.PathPattern != "" {
+		var ok bool
+		params, ok = proxyReq.PathParams(path)
+		if !ok {
+			s.logger.WithContext(ctx).WithField("path_pattern", proxyReq.PathPattern).Error("Path does not match path pattern")
+			s.logger.GetMetrics().RecordError(endpointName)
+			accessInfo.SetTransformError()
+			return nil, &TransformationError{
+				Message: fmt.Sprintf("Path %s does not match path_pattern %s", path, proxyReq.PathPattern),
+				Details: map[string]interface{}{
+					"path":         path,
+					"path_pattern": proxyReq.PathPattern,
+				},
+			}
+		}
+	}
+	if variables := endpoint.MergeJQVariables(params); len(variables) > 0 {
+		withVariables := *proxyReq
+		withVariables.Variables = variables
+		proxyReq = &withVariables
+	}
+
+	// Serve from the response cache when enabled for this endpoint. Expired
+	// entries with an upstream ETag are revalidated instead of refetched.
+	cacheable := isCacheable(endpoint, proxyReq) && !proxyReq.Stream
+	var cacheKey string
+	var stale *cachedResponse
+	if cacheable {
+		cacheKey = responseCacheKey(endpointName, endpoint.RouteKey(headers), path, queryParams, headers, proxyReq)
+		if value, fresh, found := s.responseCache.Lookup(cacheKey); found {
+			cached := value.(*cachedResponse)
+			if fresh {
+				duration := time.Since(startTime)
+				s.logger.GetMetrics().RecordCacheHit(endpointName)
+				s.logger.GetMetrics().RecordRequest(endpointName, duration)
+				s.logger.WithContext(ctx).WithField("endpoint", endpointName).Debug("Serving response from cache")
+				result := *cached.response
+				return &result, nil
+			}
+			if cached.etag != "" {
+				stale = cached
+			}
+		}
+	}
+
+	// Rewrite the outgoing body when the request provides a request query
+	upstreamReq := proxyReq
+	if proxyReq.RequestJQQuery != "" {
+		body, err := s.transformer.TransformRequestBody(proxyReq)
+		if err != nil {
+			s.logger.WithContext(ctx).WithError(err).Error("Failed to transform request body")
+			s.logger.GetMetrics().RecordError(endpointName)
+			accessInfo.SetTransformError()
+			return nil, &TransformationError{
+				Message: fmt.Sprintf("Failed to transform request body: %v", err),
+				Details: map[string]interface{}{
+					"request_jq_query": proxyReq.RequestJQQuery,
+					"error":            err.Error(),
+				},
+			}
+		}
+		rewritten := *proxyReq
+		rewritten.Body = body
+		upstreamReq = &rewritten
+	}
+
+	// Forward request to target endpoint, sharing the call with identical
+	// concurrent reads. Revalidations are conditional and never shared.
+	var response *client.Response
+	var err error
+	if stale != nil {
+		conditional := headers.Clone()
+		if conditional == nil {
+			conditional = make(http.Header)
+		}
+		conditional.Set("If-None-Match", stale.etag)
+		response, err = s.forwardRequest(ctx, endpoint, path, queryParams, conditional, upstreamReq)
+	} else {
+		response, err = s.forwardShared(ctx, endpoint, path, queryParams, headers, upstreamReq)
+	}
+	// A revalidated entry the upstream confirms is unchanged counts as a hit
+	if cacheable && (err != nil || stale == nil || response.StatusCode != http.StatusNotModified) {
+		s.logger.GetMetrics().RecordCacheMiss(endpointName)
+	}
 	if err != nil {
+		// A request missing a target variable says nothing about the upstream
+		var targetErr *TargetVariableError
+		if !errors.As(err, &targetErr) {
+			s.health.record(endpointName, 0, err)
+		}
 		s.logger.WithContext(ctx).WithError(err).Error("Failed to forward request")
 		s.logger.GetMetrics().RecordError(endpointName)
 		return nil, err
 	}
+	s.health.record(endpointName, response.StatusCode, nil)
+	accessInfo.SetUpstream(response.StatusCode, len(response.Body))
+
+	// An unchanged upstream resource renews the cached response
+	if stale != nil && response.StatusCode == http.StatusNotModified {
+		return s.renewCachedResponse(ctx, endpoint, endpointName, cacheKey, stale, response, startTime), nil
+	}
+
+	// A HEAD response has no body to transform, only a status and headers
+	if strings.EqualFold(upstreamReq.Method, http.MethodHead) {
+		return s.headResponse(ctx, endpoint, endpointName, response, startTime), nil
+	}
 
 	// Parse response body if it's JSON
 	var responseData interface{}
 	if response.IsJSONResponse() {
-		responseData, err = response.ParseJSONBody()
+		responseData, err = parseJSON(response, preciseNumbers(endpoint, proxyReq))
 		if err != nil {
 			s.logger.WithContext(ctx).WithError(err).Error("Failed to parse JSON response")
+			s.logBodyPreview(ctx, endpoint, endpointName, response, "Unparseable upstream response body")
 			s.logger.GetMetrics().RecordError(endpointName)
 			return nil, &UpstreamError{
 				Message:    "Failed to parse response from target endpoint",
@@ -105,24 +430,84 @@ func (s *Service) HandleRequest(
 				},
 			}
 		}
+	} else if parsed, ok := detectJSON(endpoint, response, preciseNumbers(endpoint, proxyReq)); ok {
+		responseData = parsed
 	} else {
 		// For non-JSON responses, use the raw body as string
 		responseData = string(response.Body)
 	}
 
-	// Apply transformation using the unified transformer
-	transformedData, err := s.transformer.TransformRequest(responseData, proxyReq)
+	// A response header may select the jq query for this response, and the
+	// response queries may read its status and headers
+	proxyReq = proxyReq.ForResponseHeaders(response.Headers).ForResponse(response.StatusCode, response.Headers)
 
-	if err != nil {
-		s.logger.WithContext(ctx).WithError(err).Error("Failed to transform response")
+	// An empty body, such as that of a 204 No Content, has nothing to
+	// transform and is returned as null
+	empty := len(response.Body) == 0
+
+	// Streamed results are transformed as the client response is written
+	if proxyReq.Stream && !empty {
+		return s.streamResponse(ctx, endpoint, endpointName, response, responseData, proxyReq, startTime), nil
+	}
+
+	// Apply transformation using the unified transformer; error responses use
+	// the request's error query when one is provided
+	var transformedData interface{}
+	if !empty {
+		release, err := s.acquireTransformSlot(ctx, endpointName)
+		if err != nil {
+			s.logger.GetMetrics().RecordError(endpointName)
+			return nil, err
+		}
+		transformStart := time.Now()
+		transformedData, err = s.transformResponse(ctx, endpoint, response, responseData, proxyReq)
+		s.logger.GetMetrics().RecordTransform(endpointName, time.Since(transformStart))
+		release()
+
+		if err != nil {
+			s.logger.WithContext(ctx).WithError(err).Error("Failed to transform response")
+			s.logBodyPreview(ctx, endpoint, endpointName, response, "Untransformable upstream response body")
+			s.logger.GetMetrics().RecordError(endpointName)
+			accessInfo.SetTransformError()
+			return nil, transformFailure(err, proxyReq, response.StatusCode)
+		}
+	} else {
+		s.logger.WithContext(ctx).WithField("endpoint", endpointName).Debug("Upstream response body is empty, skipping transformation")
+	}
+
+	// Treat a null result as a failure when the request or endpoint asks for it
+	if transformedData == nil && !empty && errorOnNull(endpoint, proxyReq) {
+		s.logger.WithContext(ctx).WithField("endpoint", endpointName).Warn("Transformation produced a null result")
 		s.logger.GetMetrics().RecordError(endpointName)
-		return nil, &TransformationError{
-			Message: fmt.Sprintf("Failed to transform response: %v", err),
-			Details: map[string]interface{}{
-				"transformation_mode": proxyReq.TransformationMode,
-				"jq_query":            proxyReq.JQQuery,
-				"error":               err.Error(),
-			},
+		accessInfo.SetTransformError()
+		return nil, transformFailure(errNullResult, proxyReq, response.StatusCode)
+	}
+
+	// Reject results that would take too much memory to hold for writing
+	if s.maxResultMemory > 0 {
+		if size := resultMemory(transformedData); size > s.maxResultMemory {
+			s.logger.WithContext(ctx).WithFields(logrus.Fields{
+				"endpoint":      endpointName,
+				"result_memory": size,
+				"limit":         s.maxResultMemory,
+			}).Warn("Transformation result exceeds memory limit")
+			s.logger.GetMetrics().RecordError(endpointName)
+			accessInfo.SetTransformError()
+			return nil, resultTooLargeInMemory(s.maxResultMemory, proxyReq, response.StatusCode)
+		}
+	}
+
+	// Reject results larger than the effective size limit
+	if limit := s.resultLimit(proxyReq); limit > 0 {
+		if size, err := resultSize(transformedData); err != nil || size > limit {
+			s.logger.WithContext(ctx).WithFields(logrus.Fields{
+				"endpoint":    endpointName,
+				"result_size": size,
+				"limit":       limit,
+			}).Warn("Transformation result exceeds size limit")
+			s.logger.GetMetrics().RecordError(endpointName)
+			accessInfo.SetTransformError()
+			return nil, resultTooLarge(limit, proxyReq, response.StatusCode)
 		}
 	}
 
@@ -136,13 +521,768 @@ func (s *Service) HandleRequest(
 		"duration_ms": duration.Milliseconds(),
 	}).Info("Successfully processed proxy request")
 
+	result := &models.ProxyResponse{
+		Data:             transformedData,
+		Status:           response.StatusCode,
+		UpstreamDuration: response.Duration,
+		Cookies:          forwardedCookies(endpoint, response),
+		RetryAfter:       retryAfter(response),
+	}
+
+	// Text rendered by a template is sent as-is rather than as a JSON string
+	if _, ok := transformedData.(string); ok {
+		result.ContentType = proxyReq.TextContentType(response.StatusCode)
+	}
+
+	// Let the status query override the upstream status when it yields a valid code
+	if proxyReq.StatusJQQuery != "" {
+		if status, ok := s.transformer.ResponseStatus(transformedData, proxyReq); ok {
+			result.Status = status
+		} else {
+			s.logger.WithContext(ctx).WithField("status_jq_query", proxyReq.StatusJQQuery).
+				Debug("Status query did not yield a valid status code, using upstream status")
+		}
+	}
+
+	// Clients whose HTTP libraries throw on error statuses can have them
+	// wrapped in a successful response instead
+	if proxyReq.NormalizeErrors && result.Status >= 400 {
+		result.Data = map[string]interface{}{
+			"error":  result.Data,
+			"status": result.Status,
+		}
+		result.Status = http.StatusOK
+		result.ContentType = ""
+	}
+
+	// Only successful responses are cached, without the upstream timing and
+	// cookies that only apply to this request
+	if cacheable && response.StatusCode >= 200 && response.StatusCode < 300 {
+		cached := *result
+		cached.UpstreamDuration = 0
+		cached.Cookies = nil
+		s.responseCache.Set(cacheKey, &cachedResponse{
+			response: &cached,
+			etag:     response.Headers.Get("ETag"),
+		}, time.Duration(endpoint.CacheTTL)*time.Second)
+	}
+
+	return result, nil
+}
+
+// parseJSON parses a JSON response body, keeping numbers exact when precise
+func parseJSON(response *client.Response, precise bool) (interface{}, error) {
+	if precise {
+		return response.ParseJSONBodyWithNumbers()
+	}
+	return response.ParseJSONBody()
+}
+
+// preciseNumbers reports whether the upstream JSON is decoded with exact
+// numbers: the endpoint asks for it and only jq, which handles them, reads
+// the data. JMESPath and templates compare numbers as float64.
+func preciseNumbers(endpoint *models.Endpoint, proxyReq *models.ProxyRequest) bool {
+	if !endpoint.PreciseNumbers {
+		return false
+	}
+	for _, stage := range proxyReq.Pipeline {
+		if stage.Mode != models.TransformationModeJQ {
+			return false
+		}
+	}
+	return len(proxyReq.Pipeline) > 0 || proxyReq.TransformationMode == models.TransformationModeJQ
+}
+
+// detectJSON parses a body without a specific Content-Type as JSON for
+// endpoints that opt in, reporting false when it is not valid JSON
+func detectJSON(endpoint *models.Endpoint, response *client.Response, precise bool) (interface{}, bool) {
+	if !endpoint.DetectJSON || len(response.Body) == 0 || !response.HasUnspecificContentType() {
+		return nil, false
+	}
+	parsed, err := parseJSON(response, precise)
+	if err != nil {
+		return nil, false
+	}
+	return parsed, true
+}
+
+// idempotencyKeyHeader lets clients mark retries of a mutating call
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// idempotencyKey returns the key a request's response is kept under for
+// replays, or an empty string when the request does not qualify: the endpoint
+// must set idempotency_ttl, and the request must be a mutating, non-streamed
+// call carrying an Idempotency-Key. The key includes the caller's credentials
+// so one caller never receives another's response, and the method, path,
+// query and body so a key reused for a different request is forwarded rather
+// than answered with the first request's response.
+func idempotencyKey(endpoint *models.Endpoint, endpointName, path string, queryParams url.Values, headers http.Header, proxyReq *models.ProxyRequest) string {
+	clientKey := headers.Get(idempotencyKeyHeader)
+	if endpoint.IdempotencyTTL <= 0 || clientKey == "" || proxyReq.Stream {
+		return ""
+	}
+	switch strings.ToUpper(proxyReq.Method) {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return ""
+	}
+
+	body, err := json.Marshal(proxyReq.Body)
+	if err != nil {
+		return ""
+	}
+	bodyDigest := sha256.Sum256(body)
+
+	hash := sha256.New()
+	for _, part := range []string{
+		clientKey,
+		headers.Get("Authorization"),
+		headers.Get("Cookie"),
+		strings.ToUpper(proxyReq.Method),
+		path,
+		queryParams.Encode(),
+		url.Values(proxyReq.Query).Encode(),
+		hex.EncodeToString(bodyDigest[:]),
+	} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return endpointName + "|" + hex.EncodeToString(hash.Sum(nil))
+}
+
+// handleIdempotent returns the kept response for key when there is one.
+// Otherwise it runs handle, sharing the call with concurrent duplicates, and
+// keeps a successful response for the endpoint's idempotency TTL. Failed calls,
+// including those the upstream answers with a status outside 2xx, are not
+// kept, so they can be retried with the same key.
+func (s *Service) handleIdempotent(
+	ctx context.Context,
+	endpoint *models.Endpoint,
+	endpointName, key string,
+	startTime time.Time,
+	handle func() (*models.ProxyResponse, error),
+) (*models.ProxyResponse, error) {
+	if value, found := s.idempotentResponses.Get(key); found {
+		s.logger.GetMetrics().RecordRequest(endpointName, time.Since(startTime))
+		s.logger.WithContext(ctx).WithField("endpoint", endpointName).Info("Replaying response for duplicate idempotency key")
+		// The kept response is copied so the caller cannot modify it
+		replay := *value.(*models.ProxyResponse)
+		return &replay, nil
+	}
+
+	leader := false
+	value, err, _ := s.inflightIdempotent.Do(key, func() (interface{}, error) {
+		leader = true
+		response, err := handle()
+		if err != nil {
+			return nil, err
+		}
+		if response.Status >= 200 && response.Status < 300 {
+			s.idempotentResponses.Set(key, replayed(response), time.Duration(endpoint.IdempotencyTTL)*time.Second)
+		}
+		return response, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := value.(*models.ProxyResponse)
+	if !leader {
+		// A concurrent duplicate waited for the first call to finish
+		s.logger.WithContext(ctx).WithField("endpoint", endpointName).Info("Replaying response for duplicate idempotency key")
+		return replayed(response), nil
+	}
+	return response, nil
+}
+
+// replayed copies a response for returning again to a duplicate call, without
+// the upstream timing that only applied to the first call
+func replayed(response *models.ProxyResponse) *models.ProxyResponse {
+	replay := *response
+	replay.UpstreamDuration = 0
+	replay.Replayed = true
+	return &replay
+}
+
+// transformCacheTTL bounds how long unused transformation results are kept
+const transformCacheTTL = 10 * time.Minute
+
+// transformResponse transforms the parsed upstream response, reusing the
+// result of an identical transformation of an identical body when the
+// transformation cache is enabled. Failed transformations are not kept.
+func (s *Service) transformResponse(
+	ctx context.Context,
+	endpoint *models.Endpoint,
+	response *client.Response,
+	responseData interface{},
+	proxyReq *models.ProxyRequest,
+) (interface{}, error) {
+	if s.transformCache == nil {
+		return s.transformer.TransformResponse(responseData, proxyReq, response.StatusCode)
+	}
+
+	key := transformCacheKey(endpoint, response, proxyReq)
+	if result, found := s.transformCache.Get(key); found {
+		s.logger.WithContext(ctx).WithField("endpoint", endpoint.Name).Debug("Reusing cached transformation result")
+		return result, nil
+	}
+
+	result, err := s.transformer.TransformResponse(responseData, proxyReq, response.StatusCode)
+	if err != nil {
+		return nil, err
+	}
+	s.transformCache.Set(key, result, transformCacheTTL)
+	return result, nil
+}
+
+// transformCacheKey identifies a transformation result by the upstream body,
+// everything that decides how it is parsed, and every request field the
+// transformation reads
+func transformCacheKey(endpoint *models.Endpoint, response *client.Response, proxyReq *models.ProxyRequest) string {
+	exists, _ := json.Marshal(proxyReq.Exists)
+	fallbacks, _ := json.Marshal(proxyReq.Fallbacks)
+
+	// Headers such as Date differ between otherwise identical responses, so
+	// they only key the results of queries that read them
+	pipeline := pipelineCacheKey(proxyReq.Pipeline)
+	vars := proxyReq.Variables
+	if _, bound := vars[models.HeadersVariable]; bound {
+		reference := "$" + models.HeadersVariable
+		if !strings.Contains(proxyReq.JQQuery, reference) && !strings.Contains(proxyReq.ErrorJQQuery, reference) &&
+			!strings.Contains(pipeline, reference) {
+			vars = maps.Clone(vars)
+			delete(vars, models.HeadersVariable)
+		}
+	}
+	variables, _ := json.Marshal(vars)
+
+	hash := sha256.New()
+	hash.Write(response.Body)
+	hash.Write([]byte{0})
+	for _, part := range []string{
+		response.Headers.Get("Content-Type"),
+		strconv.FormatBool(endpoint.DetectJSON),
+		strconv.FormatBool(preciseNumbers(endpoint, proxyReq)),
+		strconv.Itoa(response.StatusCode),
+		string(proxyReq.TransformationMode),
+		proxyReq.JQQuery,
+		proxyReq.JMESPathQuery,
+		proxyReq.Template,
+		proxyReq.ErrorJQQuery,
+		pipeline,
+		strconv.FormatBool(proxyReq.JQCollect),
+		proxyReq.OnError,
+		string(exists),
+		string(fallbacks),
+		string(variables),
+	} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// cachedResponse is a response cache entry: the transformed response and the
+// upstream ETag it was produced from, if any
+type cachedResponse struct {
+	response *models.ProxyResponse
+	etag     string
+}
+
+// acquireTransformSlot waits for a free transformation slot when concurrent
+// transformations are limited. The returned function releases the slot.
+func (s *Service) acquireTransformSlot(ctx context.Context, endpointName string) (func(), error) {
+	if s.transformSlots == nil {
+		return func() {}, nil
+	}
+	select {
+	case s.transformSlots <- struct{}{}:
+		return func() { <-s.transformSlots }, nil
+	case <-ctx.Done():
+		s.logger.WithContext(ctx).WithField("endpoint", endpointName).Warn("Transformation concurrency limit reached")
+		return nil, &TransformBusyError{
+			EndpointName: endpointName,
+			Limit:        cap(s.transformSlots),
+		}
+	}
+}
+
+// renewCachedResponse serves a stale cache entry the upstream confirmed is
+// unchanged with a 304, keeping it for another TTL
+func (s *Service) renewCachedResponse(
+	ctx context.Context,
+	endpoint *models.Endpoint,
+	endpointName, cacheKey string,
+	stale *cachedResponse,
+	response *client.Response,
+	startTime time.Time,
+) *models.ProxyResponse {
+	renewed := &cachedResponse{response: stale.response, etag: stale.etag}
+	if etag := response.Headers.Get("ETag"); etag != "" {
+		renewed.etag = etag
+	}
+	s.responseCache.Set(cacheKey, renewed, time.Duration(endpoint.CacheTTL)*time.Second)
+
+	duration := time.Since(startTime)
+	s.logger.GetMetrics().RecordCacheHit(endpointName)
+	s.logger.GetMetrics().RecordRequest(endpointName, duration)
+	s.logger.WithContext(ctx).WithField("endpoint", endpointName).Debug("Upstream resource not modified, serving revalidated response from cache")
+
+	result := *stale.response
+	result.UpstreamDuration = response.Duration
+	return &result
+}
+
+// streamResponse returns a response whose transformation runs when the handler
+// streams it to the client. The result size limit is enforced as elements are
+// produced, so an oversized result fails part way through.
+func (s *Service) streamResponse(
+	ctx context.Context,
+	endpoint *models.Endpoint,
+	endpointName string,
+	response *client.Response,
+	responseData interface{},
+	proxyReq *models.ProxyRequest,
+	startTime time.Time,
+) *models.ProxyResponse {
+	stream := func(sink models.ResultSink) error {
+		limit := s.resultLimit(proxyReq)
+		if limit > 0 {
+			sink = &limitedSink{ResultSink: sink, limit: limit}
+		}
+		if errorOnNull(endpoint, proxyReq) {
+			sink = nonNullSink{sink}
+		}
+
+		release, err := s.acquireTransformSlot(ctx, endpointName)
+		if err != nil {
+			s.logger.GetMetrics().RecordError(endpointName)
+			return err
+		}
+		err = s.transformer.StreamResponse(responseData, proxyReq, response.StatusCode, sink)
+		release()
+		if err != nil {
+			s.logger.GetMetrics().RecordError(endpointName)
+			logging.GetAccessInfo(ctx).SetTransformError()
+			if errors.Is(err, errResultTooLarge) {
+				s.logger.WithContext(ctx).WithFields(logrus.Fields{
+					"endpoint": endpointName,
+					"limit":    limit,
+				}).Warn("Streamed transformation result exceeds size limit")
+				return resultTooLarge(limit, proxyReq, response.StatusCode)
+			}
+			if errors.Is(err, errNullResult) {
+				s.logger.WithContext(ctx).WithField("endpoint", endpointName).Warn("Transformation produced a null result")
+				return transformFailure(err, proxyReq, response.StatusCode)
+			}
+			s.logger.WithContext(ctx).WithError(err).Error("Failed to stream transformed response")
+			s.logBodyPreview(ctx, endpoint, endpointName, response, "Untransformable upstream response body")
+			return transformFailure(err, proxyReq, response.StatusCode)
+		}
+
+		duration := time.Since(startTime)
+		s.logger.GetMetrics().RecordRequest(endpointName, duration)
+		s.logger.WithContext(ctx).WithFields(logrus.Fields{
+			"endpoint":    endpointName,
+			"status_code": response.StatusCode,
+			"duration_ms": duration.Milliseconds(),
+		}).Info("Successfully streamed proxy request")
+		return nil
+	}
+
+	return &models.ProxyResponse{
+		Status:           response.StatusCode,
+		Stream:           stream,
+		UpstreamDuration: response.Duration,
+		Cookies:          forwardedCookies(endpoint, response),
+		RetryAfter:       retryAfter(response),
+	}
+}
+
+// forwardedCookies returns the upstream's Set-Cookie values when the endpoint
+// forwards them to the client
+func forwardedCookies(endpoint *models.Endpoint, response *client.Response) []string {
+	if !endpoint.ForwardCookies {
+		return nil
+	}
+	return response.Headers.Values("Set-Cookie")
+}
+
+// headResponseHeaders are the upstream headers passed on for HEAD requests.
+// Content-Length and Content-Encoding are left out since they would describe
+// a body the client does not receive.
+var headResponseHeaders = []string{
+	"Accept-Ranges",
+	"Cache-Control",
+	"Content-Language",
+	"Content-Type",
+	"ETag",
+	"Expires",
+	"Last-Modified",
+}
+
+// headResponse returns the upstream status and allowlisted headers of a HEAD
+// request without running any transformation
+func (s *Service) headResponse(ctx context.Context, endpoint *models.Endpoint, endpointName string, response *client.Response, startTime time.Time) *models.ProxyResponse {
+	headers := make(http.Header)
+	for _, name := range headResponseHeaders {
+		for _, value := range response.Headers.Values(name) {
+			headers.Add(name, value)
+		}
+	}
+
+	duration := time.Since(startTime)
+	s.logger.GetMetrics().RecordRequest(endpointName, duration)
+	s.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"endpoint":    endpointName,
+		"status_code": response.StatusCode,
+		"duration_ms": duration.Milliseconds(),
+	}).Info("Successfully processed HEAD request")
+
 	return &models.ProxyResponse{
-		Data:   transformedData,
-		Status: response.StatusCode,
-	}, nil
+		Status:           response.StatusCode,
+		UpstreamDuration: response.Duration,
+		Cookies:          forwardedCookies(endpoint, response),
+		RetryAfter:       retryAfter(response),
+		HeadersOnly:      true,
+		Headers:          headers,
+	}
+}
+
+// retryAfter returns the upstream's Retry-After header when it rate limited
+// the request, so clients can back off for as long as the target asked
+func retryAfter(response *client.Response) string {
+	if response.StatusCode != http.StatusTooManyRequests {
+		return ""
+	}
+	return response.Headers.Get("Retry-After")
+}
+
+// transformFailure converts a response transformation error to a TransformationError
+func transformFailure(err error, proxyReq *models.ProxyRequest, statusCode int) *TransformationError {
+	code := ""
+	if errors.Is(err, transform.ErrTransformTimeout) {
+		code = "TRANSFORM_TIMEOUT"
+	}
+	return &TransformationError{
+		Code:    code,
+		Message: fmt.Sprintf("Failed to transform response: %v", err),
+		Details: withSyntaxErrorDetails(map[string]interface{}{
+			"transformation_mode": proxyReq.TransformationMode,
+			"jq_query":            proxyReq.QueryForStatus(statusCode),
+			"error":               err.Error(),
+		}, err),
+	}
+}
+
+// withSyntaxErrorDetails adds the location of a jq syntax error, when err
+// is one, to the error details
+func withSyntaxErrorDetails(details map[string]interface{}, err error) map[string]interface{} {
+	var syntaxErr *transform.QuerySyntaxError
+	if errors.As(err, &syntaxErr) {
+		details["line"] = syntaxErr.Line
+		details["column"] = syntaxErr.Column
+		details["offset"] = syntaxErr.Offset
+		details["snippet"] = syntaxErr.Snippet
+	}
+	return details
+}
+
+// resultTooLarge builds the error returned when a result exceeds the size limit
+func resultTooLarge(limit int, proxyReq *models.ProxyRequest, statusCode int) *TransformationError {
+	return &TransformationError{
+		Code:       "RESULT_TOO_LARGE",
+		StatusCode: http.StatusRequestEntityTooLarge,
+		Message:    fmt.Sprintf("Transformation result exceeds the limit of %d bytes", limit),
+		Details: map[string]interface{}{
+			"jq_query":         proxyReq.QueryForStatus(statusCode),
+			"max_result_bytes": limit,
+		},
+	}
 }
 
-// forwardRequest forwards the request to the target endpoint
+// resultTooLargeInMemory reports a transformation result whose estimated
+// memory exceeds the server's limit
+func resultTooLargeInMemory(limit int, proxyReq *models.ProxyRequest, statusCode int) *TransformationError {
+	return &TransformationError{
+		Code:       "RESULT_TOO_LARGE",
+		StatusCode: http.StatusRequestEntityTooLarge,
+		Message:    fmt.Sprintf("Transformation result exceeds the memory limit of %d bytes", limit),
+		Details: map[string]interface{}{
+			"jq_query":          proxyReq.QueryForStatus(statusCode),
+			"max_result_memory": limit,
+		},
+	}
+}
+
+// errNullResult fails a transformation whose result is null when null results
+// are not accepted
+var errNullResult = errors.New("transformation produced a null result")
+
+// errorOnNull reports whether a null result should fail the request
+func errorOnNull(endpoint *models.Endpoint, proxyReq *models.ProxyRequest) bool {
+	return proxyReq.ErrorOnNull || endpoint.ErrorOnNull
+}
+
+// nonNullSink fails a streamed result that is a single null value
+type nonNullSink struct {
+	models.ResultSink
+}
+
+func (ns nonNullSink) Value(v interface{}) error {
+	if v == nil {
+		return errNullResult
+	}
+	return ns.ResultSink.Value(v)
+}
+
+// errResultTooLarge aborts a streamed result that has grown past its size limit
+var errResultTooLarge = errors.New("result exceeds size limit")
+
+// limitedSink tracks the serialized size of a streamed result and fails once
+// it exceeds the limit
+type limitedSink struct {
+	models.ResultSink
+	limit    int
+	size     int
+	elements int
+}
+
+func (ls *limitedSink) Value(v interface{}) error {
+	if err := ls.add(v, 0); err != nil {
+		return err
+	}
+	return ls.ResultSink.Value(v)
+}
+
+func (ls *limitedSink) BeginArray() error {
+	// Account for the brackets up front
+	ls.size += 2
+	if ls.size > ls.limit {
+		return errResultTooLarge
+	}
+	return ls.ResultSink.BeginArray()
+}
+
+func (ls *limitedSink) Element(v interface{}) error {
+	separator := 0
+	if ls.elements > 0 {
+		separator = 1
+	}
+	ls.elements++
+	if err := ls.add(v, separator); err != nil {
+		return err
+	}
+	return ls.ResultSink.Element(v)
+}
+
+// add counts a value's serialized size plus any separator
+func (ls *limitedSink) add(v interface{}, separator int) error {
+	size, err := resultSize(v)
+	if err != nil {
+		return err
+	}
+	ls.size += size + separator
+	if ls.size > ls.limit {
+		return errResultTooLarge
+	}
+	return nil
+}
+
+// resultLimit returns the effective result size limit for a request: the
+// server limit, lowered by the request's own limit if it sets one
+func (s *Service) resultLimit(proxyReq *models.ProxyRequest) int {
+	limit := s.maxResultBytes
+	if proxyReq.MaxResultBytes > 0 && (limit == 0 || proxyReq.MaxResultBytes < limit) {
+		limit = proxyReq.MaxResultBytes
+	}
+	return limit
+}
+
+// logBodyPreview logs a truncated, redacted preview of the upstream body when
+// previews are enabled, debug logging is on and the endpoint allows request logging
+func (s *Service) logBodyPreview(ctx context.Context, endpoint *models.Endpoint, endpointName string, response *client.Response, msg string) {
+	if s.bodyPreviewBytes <= 0 || !s.logger.IsLevelEnabled(logrus.DebugLevel) || !endpoint.ShouldLogRequests() {
+		return
+	}
+	s.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"endpoint":     endpointName,
+		"status_code":  response.StatusCode,
+		"content_type": response.Headers.Get("Content-Type"),
+		"body_size":    len(response.Body),
+		"body_preview": logging.BodyPreview(response.Body, s.bodyPreviewBytes),
+	}).Debug(msg)
+}
+
+// resultSize returns the serialized size of a transformation result
+func resultSize(data interface{}) (int, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return 0, err
+	}
+	return len(encoded), nil
+}
+
+// isCacheable reports whether responses for the request may be cached
+func isCacheable(endpoint *models.Endpoint, proxyReq *models.ProxyRequest) bool {
+	return endpoint.CacheTTL > 0 && strings.EqualFold(proxyReq.Method, http.MethodGet)
+}
+
+// responseCacheKey builds the response cache key for a request. The key is
+// prefixed with the endpoint name so an endpoint's entries can be purged
+// together, and includes the caller's credentials and header route target so
+// cached data is never served to a different caller or from a different
+// upstream. The body is included for upstreams that accept a GET body, such
+// as search APIs.
+func responseCacheKey(
+	endpointName, route, path string,
+	queryParams url.Values,
+	headers http.Header,
+	proxyReq *models.ProxyRequest,
+) string {
+	// Maps encode with sorted keys, so equal bodies give equal keys
+	body, _ := json.Marshal(proxyReq.Body)
+	fallbacks, _ := json.Marshal(proxyReq.Fallbacks)
+	queriesByHeader, _ := json.Marshal(proxyReq.JQQueryByHeader)
+
+	hash := sha256.New()
+	for _, part := range []string{
+		route,
+		path,
+		queryParams.Encode(),
+		string(body),
+		string(proxyReq.TransformationMode),
+		proxyReq.RequestJQQuery,
+		proxyReq.JQQuery,
+		proxyReq.JQQueryHeader,
+		string(queriesByHeader),
+		proxyReq.JMESPathQuery,
+		proxyReq.Template,
+		proxyReq.ContentType,
+		strings.Join(proxyReq.Exists, "\x00"),
+		pipelineCacheKey(proxyReq.Pipeline),
+		strconv.FormatBool(proxyReq.JQCollect),
+		strconv.FormatBool(proxyReq.ErrorOnNull),
+		strconv.FormatBool(proxyReq.NormalizeErrors),
+		proxyReq.OnError,
+		string(fallbacks),
+		proxyReq.PathPattern,
+		proxyReq.StatusJQQuery,
+		strconv.Itoa(proxyReq.MaxResultBytes),
+		headers.Get("Authorization"),
+		headers.Get("Cookie"),
+	} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return endpointName + "|" + hex.EncodeToString(hash.Sum(nil))
+}
+
+// pipelineCacheKey serializes pipeline stages for use in a response cache key
+func pipelineCacheKey(stages []models.TransformationStage) string {
+	var key strings.Builder
+	for _, stage := range stages {
+		key.WriteString(string(stage.Mode))
+		key.WriteByte(0)
+		key.WriteString(stage.Query)
+		key.WriteByte(0)
+	}
+	return key.String()
+}
+
+// PurgeCache removes cached responses for the named endpoint, or for all
+// endpoints when the name is empty, and returns the number of entries removed
+func (s *Service) PurgeCache(endpointName string) int {
+	prefix := ""
+	if endpointName != "" {
+		prefix = endpointName + "|"
+	}
+	return s.responseCache.PurgePrefix(prefix)
+}
+
+// forwardShared forwards the request, collapsing identical concurrent GET
+// requests into a single upstream call. Each caller still transforms the
+// shared response with its own query. The shared call is not tied to the
+// caller that started it: a client going away does not fail the others, and
+// the call is only cancelled once none of them waits for it. forwardRequest
+// still bounds it with the endpoint's request timeout.
+func (s *Service) forwardShared(
+	ctx context.Context,
+	endpoint *models.Endpoint,
+	path string,
+	queryParams url.Values,
+	headers http.Header,
+	proxyReq *models.ProxyRequest,
+) (*client.Response, error) {
+	if !strings.EqualFold(proxyReq.Method, http.MethodGet) || proxyReq.Body != nil {
+		return s.forwardRequest(ctx, endpoint, path, queryParams, headers, proxyReq)
+	}
+
+	key := upstreamReadKey(endpoint.Name, endpoint.RouteKey(headers), path, proxyReq.PathPattern, queryParams, headers)
+	leader := false
+	value, err, shared := s.inflightReads.DoContext(ctx, key, func(callCtx context.Context) (interface{}, error) {
+		leader = true
+		return s.forwardRequest(callCtx, endpoint, path, queryParams, headers, proxyReq)
+	})
+	// A caller that stopped waiting may have done so before the call ran,
+	// so leader is only read once the call has completed
+	if err != nil && ctx.Err() != nil {
+		return nil, err
+	}
+	if shared {
+		s.logger.WithContext(ctx).WithField("endpoint", endpoint.Name).Debug("Shared upstream response with concurrent requests")
+	}
+	if !leader {
+		s.logger.GetMetrics().RecordCoalesced(endpoint.Name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Each caller gets its own copy of the shared response
+	response := *value.(*client.Response)
+	return &response, nil
+}
+
+// upstreamReadKey identifies upstream GET requests that may share a response.
+// Only requests the upstream cannot tell apart share one: the key includes
+// every header that is forwarded, so callers with different credentials or
+// different Accept or Accept-Language headers never share, nor do requests
+// sent to different header route targets. The path pattern is included since
+// it can fill target variables.
+func upstreamReadKey(endpointName, route, path, pathPattern string, queryParams url.Values, headers http.Header) string {
+	hash := sha256.New()
+	for _, part := range []string{
+		http.MethodGet,
+		route,
+		path,
+		pathPattern,
+		queryParams.Encode(),
+	} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+
+	// Headers in a stable order, leaving out the jpx- headers the client drops
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		if !strings.HasPrefix(strings.ToLower(name), "jpx-") {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		hash.Write([]byte(http.CanonicalHeaderKey(name)))
+		hash.Write([]byte{0})
+		for _, value := range headers[name] {
+			hash.Write([]byte(value))
+			hash.Write([]byte{0})
+		}
+		hash.Write([]byte{0})
+	}
+	return endpointName + "|" + hex.EncodeToString(hash.Sum(nil))
+}
+
+// forwardRequest forwards the request to the target endpoint. Endpoints with
+// several targets are load-balanced; targets that fail to connect or return a
+// 5xx status are skipped by subsequent requests until their cooldown expires.
 func (s *Service) forwardRequest(
 	ctx context.Context,
 	endpoint *models.Endpoint,
@@ -152,14 +1292,83 @@ func (s *Service) forwardRequest(
 	proxyReq *models.ProxyRequest,
 ) (*client.Response, error) {
 	// Create a timeout context for the request
-	requestCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	requestCtx, cancel := context.WithTimeout(ctx, s.requestTimeout(endpoint))
 	defer cancel()
 
-	// Forward the request
-	response, err := s.httpClient.ForwardRequest(
+	// Wait for the endpoint's outbound rate limit
+	if endpoint.RateLimit > 0 {
+		wait, err := s.rateLimiter(endpoint).Wait(requestCtx)
+		if err != nil {
+			s.logger.WithContext(ctx).WithField("endpoint", endpoint.Name).Warn("Upstream rate limit wait exceeded deadline")
+			return nil, &UpstreamThrottledError{
+				EndpointName: endpoint.Name,
+				RateLimit:    endpoint.RateLimit,
+			}
+		}
+		if wait > 0 {
+			s.logger.GetMetrics().RecordThrottleWait(endpoint.Name, wait)
+		}
+	}
+
+	// Wait for a free upstream slot when concurrency is limited
+	if s.upstreamSlots != nil {
+		select {
+		case s.upstreamSlots <- struct{}{}:
+			defer func() { <-s.upstreamSlots }()
+		case <-requestCtx.Done():
+			s.logger.WithContext(ctx).WithField("endpoint", endpoint.Name).Warn("Upstream concurrency limit reached")
+			return nil, &UpstreamBusyError{
+				EndpointName: endpoint.Name,
+				Limit:        cap(s.upstreamSlots),
+			}
+		}
+	}
+
+	// Pick the upstream target for this request: a matching header route,
+	// otherwise the load-balanced endpoint targets, at random by weight when
+	// their weights differ
+	target := endpoint.RouteTarget(headers)
+	if target == "" {
+		if weights := endpoint.TargetWeights(); weights != nil {
+			target = s.balancer.NextWeighted(endpoint.TargetURLs(), weights)
+		} else {
+			target = s.balancer.Next(endpoint.Name, endpoint.TargetURLs())
+		}
+	}
+
+	// Fill the target's placeholders from the request; the balancer keeps
+	// tracking the target as configured
+	upstream := target
+	if len(endpoint.TargetVariables) > 0 {
+		var pathParams map[string]interface{}
+		if proxyReq.PathPattern != "" {
+			pathParams, _ = proxyReq.PathParams(path)
+		}
+		resolved, err := endpoint.ResolveTarget(target, headers, pathParams)
+		if err != nil {
+			return nil, &TargetVariableError{EndpointName: endpoint.Name, Message: err.Error()}
+		}
+		upstream = resolved
+	}
+
+	// Apply the endpoint's User-Agent unless the caller sent its own
+	if endpoint.UserAgent != "" && headers.Get("User-Agent") == "" {
+		headers = headers.Clone()
+		if headers == nil {
+			headers = make(http.Header)
+		}
+		headers.Set("User-Agent", endpoint.UserAgent)
+	}
+
+	// Start a client span and propagate its trace context to the target
+	requestCtx, span, headers := tracing.StartUpstreamSpan(requestCtx, endpoint.Name, headers)
+
+	// Forward the request, timing the upstream call on its own
+	upstreamStart := time.Now()
+	response, err := s.clientFor(endpoint).ForwardRequest(
 		requestCtx,
 		proxyReq.Method,
-		endpoint.Target,
+		upstream,
 		path,
 		queryParams,
 		headers,
@@ -167,17 +1376,31 @@ func (s *Service) forwardRequest(
 	)
 
 	if err != nil {
+		tracing.EndUpstreamSpan(span, 0, err)
+		s.balancer.ReportFailure(target)
 		return nil, &UpstreamError{
 			Message:    "Failed to connect to target endpoint",
 			StatusCode: http.StatusBadGateway,
 			Details: map[string]interface{}{
 				"endpoint": endpoint.Name,
-				"target":   endpoint.Target,
+				"target":   upstream,
 				"error":    err.Error(),
 			},
 		}
 	}
 
+	// The client's response is copied rather than modified, since it may
+	// already be in use by other callers
+	timed := *response
+	timed.Duration = time.Since(upstreamStart)
+	response = &timed
+	tracing.EndUpstreamSpan(span, response.StatusCode, nil)
+	if response.StatusCode >= 500 {
+		s.balancer.ReportFailure(target)
+	} else {
+		s.balancer.ReportSuccess(target)
+	}
+
 	// Check for HTTP error status codes
 	if response.StatusCode >= 400 {
 		s.logger.WithContext(ctx).WithFields(logrus.Fields{
@@ -191,13 +1414,100 @@ func (s *Service) forwardRequest(
 
 	s.logger.WithContext(ctx).WithFields(logrus.Fields{
 		"endpoint":    endpoint.Name,
-		"target":      endpoint.Target,
+		"target":      upstream,
 		"status_code": response.StatusCode,
 	}).Debug("Request forwarded successfully")
 
 	return response, nil
 }
 
+// rateLimiter returns the outbound rate limiter for an endpoint, replacing it
+// if the endpoint's configured rate has changed
+func (s *Service) rateLimiter(endpoint *models.Endpoint) *ratelimit.Limiter {
+	s.rateLimitersMu.Lock()
+	defer s.rateLimitersMu.Unlock()
+
+	limiter, exists := s.rateLimiters[endpoint.Name]
+	if !exists || limiter.Rate() != endpoint.RateLimit {
+		limiter = ratelimit.New(endpoint.RateLimit)
+		s.rateLimiters[endpoint.Name] = limiter
+	}
+	return limiter
+}
+
+// clientFor returns the HTTP client for an endpoint. Endpoints without transport
+// settings share the default client; the others get a client of their own,
+// rebuilt if their settings change.
+func (s *Service) clientFor(endpoint *models.Endpoint) client.HTTPClient {
+	if endpoint.Transport == nil {
+		return s.httpClient
+	}
+
+	settings := transportSettings(s.clientSettings, endpoint.Transport)
+
+	s.endpointClientsMu.Lock()
+	defer s.endpointClientsMu.Unlock()
+
+	cached, exists := s.endpointClients[endpoint.Name]
+	if !exists || cached.settings != settings {
+		if exists {
+			closeIdleConnections(cached.client)
+		}
+		cached = &endpointClient{settings: settings, client: s.newEndpointClient(settings)}
+		s.endpointClients[endpoint.Name] = cached
+	}
+	return cached.client
+}
+
+// requestTimeout returns how long a request forwarded to the endpoint may take:
+// the timeout of the client settings it uses, or upstreamTimeout if unset
+func (s *Service) requestTimeout(endpoint *models.Endpoint) time.Duration {
+	settings := s.clientSettings
+	if endpoint.Transport != nil {
+		settings = transportSettings(settings, endpoint.Transport)
+	}
+	if settings.Timeout > 0 {
+		return settings.Timeout
+	}
+	return upstreamTimeout
+}
+
+// transportSettings applies endpoint transport configuration over the default client settings
+func transportSettings(settings client.Settings, tc *models.TransportConfig) client.Settings {
+	if tc.Timeout > 0 {
+		settings.Timeout = time.Duration(tc.Timeout) * time.Second
+	}
+	if tc.MaxIdleConns > 0 {
+		settings.MaxIdleConns = tc.MaxIdleConns
+	}
+	if tc.MaxIdleConnsPerHost > 0 {
+		settings.MaxIdleConnsPerHost = tc.MaxIdleConnsPerHost
+	}
+	if tc.IdleConnTimeout > 0 {
+		settings.IdleConnTimeout = time.Duration(tc.IdleConnTimeout) * time.Second
+	}
+	if tc.KeepAlive > 0 {
+		settings.KeepAlive = time.Duration(tc.KeepAlive) * time.Second
+	}
+	if tc.DialTimeout > 0 {
+		settings.DialTimeout = time.Duration(tc.DialTimeout) * time.Second
+	}
+	if tc.ResponseHeaderTimeout > 0 {
+		settings.ResponseHeaderTimeout = time.Duration(tc.ResponseHeaderTimeout) * time.Second
+	}
+	if tc.Proxy != "" {
+		settings.ProxyURL = tc.Proxy
+	}
+	return settings
+}
+
+// closeIdleConnections releases the idle connections of a replaced client
+func closeIdleConnections(c client.HTTPClient) {
+	if closer, ok := c.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
 // validateTransformation validates the transformation rules
 func (s *Service) validateTransformation(req *models.ProxyRequest) error {
 	return s.transformer.ValidateTransformation(req)
@@ -218,6 +1528,39 @@ func (s *Service) getAvailableEndpoints() []string {
 	return endpoints
 }
 
+// ResolveEndpoint looks up an endpoint by name, alias or pattern
+func (s *Service) ResolveEndpoint(name string) (*models.Endpoint, bool) {
+	return s.configProvider.GetEndpoint(name)
+}
+
+// ReloadConfig reloads the configuration provider and returns the number of
+// endpoints in the new configuration
+func (s *Service) ReloadConfig() (int, error) {
+	if err := s.configProvider.Reload(); err != nil {
+		return 0, err
+	}
+	config := s.GetConfig()
+	if config == nil {
+		return 0, nil
+	}
+	return len(config.Endpoints), nil
+}
+
+// EndpointStatuses reports the recent upstream error rate of every configured
+// endpoint and of every endpoint that has been called. Wildcard endpoints are
+// reported under the names they were called by.
+func (s *Service) EndpointStatuses() []models.EndpointStatus {
+	var names []string
+	if config := s.GetConfig(); config != nil {
+		for name := range config.Endpoints {
+			if !models.IsEndpointPattern(name) {
+				names = append(names, name)
+			}
+		}
+	}
+	return s.health.statuses(names)
+}
+
 // GetConfig returns the current configuration
 func (s *Service) GetConfig() *models.ProxyConfig {
 	config, err := s.configProvider.LoadConfig()
@@ -254,10 +1597,38 @@ func (e *EndpointNotFoundError) ErrorDetails() interface{} {
 	}
 }
 
+// EndpointDisabledError represents a request to an endpoint that is
+// configured but disabled
+type EndpointDisabledError struct {
+	EndpointName string
+}
+
+func (e *EndpointDisabledError) Error() string {
+	return fmt.Sprintf("endpoint '%s' is disabled", e.EndpointName)
+}
+
+func (e *EndpointDisabledError) HTTPStatusCode() int {
+	return http.StatusServiceUnavailable
+}
+
+func (e *EndpointDisabledError) ErrorCode() string {
+	return "ENDPOINT_DISABLED"
+}
+
+func (e *EndpointDisabledError) ErrorDetails() interface{} {
+	return map[string]interface{}{
+		"endpoint": e.EndpointName,
+	}
+}
+
 // TransformationError represents an error during response transformation
 type TransformationError struct {
-	Message string
-	Details map[string]interface{}
+	// Code overrides the default TRANSFORMATION_ERROR code, e.g. TRANSFORM_TIMEOUT
+	Code string
+	// StatusCode overrides the default 422 status
+	StatusCode int
+	Message    string
+	Details    map[string]interface{}
 }
 
 func (e *TransformationError) Error() string {
@@ -265,10 +1636,16 @@ func (e *TransformationError) Error() string {
 }
 
 func (e *TransformationError) HTTPStatusCode() int {
+	if e.StatusCode != 0 {
+		return e.StatusCode
+	}
 	return http.StatusUnprocessableEntity
 }
 
 func (e *TransformationError) ErrorCode() string {
+	if e.Code != "" {
+		return e.Code
+	}
 	return "TRANSFORMATION_ERROR"
 }
 
@@ -302,6 +1679,110 @@ func (e *UpstreamError) ErrorDetails() interface{} {
 	return e.Details
 }
 
+// UpstreamBusyError represents a request that could not acquire an upstream
+// slot before its deadline because the concurrency limit was reached
+type UpstreamBusyError struct {
+	EndpointName string
+	Limit        int
+}
+
+func (e *UpstreamBusyError) Error() string {
+	return "Too many concurrent upstream requests"
+}
+
+func (e *UpstreamBusyError) HTTPStatusCode() int {
+	return http.StatusServiceUnavailable
+}
+
+func (e *UpstreamBusyError) ErrorCode() string {
+	return "UPSTREAM_BUSY"
+}
+
+func (e *UpstreamBusyError) ErrorDetails() interface{} {
+	return map[string]interface{}{
+		"endpoint":                e.EndpointName,
+		"max_concurrent_upstream": e.Limit,
+	}
+}
+
+// TransformBusyError represents a request that could not acquire a
+// transformation slot before its context was done because the concurrency
+// limit was reached
+type TransformBusyError struct {
+	EndpointName string
+	Limit        int
+}
+
+func (e *TransformBusyError) Error() string {
+	return "Too many concurrent transformations"
+}
+
+func (e *TransformBusyError) HTTPStatusCode() int {
+	return http.StatusServiceUnavailable
+}
+
+func (e *TransformBusyError) ErrorCode() string {
+	return "TRANSFORM_BUSY"
+}
+
+func (e *TransformBusyError) ErrorDetails() interface{} {
+	return map[string]interface{}{
+		"endpoint":                  e.EndpointName,
+		"max_concurrent_transforms": e.Limit,
+	}
+}
+
+// UpstreamThrottledError represents a request that could not be sent within
+// its deadline because of the endpoint's outbound rate limit
+type UpstreamThrottledError struct {
+	EndpointName string
+	RateLimit    float64
+}
+
+func (e *UpstreamThrottledError) Error() string {
+	return "Upstream rate limit exceeded"
+}
+
+func (e *UpstreamThrottledError) HTTPStatusCode() int {
+	return http.StatusServiceUnavailable
+}
+
+func (e *UpstreamThrottledError) ErrorCode() string {
+	return "UPSTREAM_THROTTLED"
+}
+
+func (e *UpstreamThrottledError) ErrorDetails() interface{} {
+	return map[string]interface{}{
+		"endpoint":   e.EndpointName,
+		"rate_limit": e.RateLimit,
+	}
+}
+
+// TargetVariableError represents a request that does not supply a value for
+// one of the placeholders in the endpoint's target
+type TargetVariableError struct {
+	EndpointName string
+	Message      string
+}
+
+func (e *TargetVariableError) Error() string {
+	return e.Message
+}
+
+func (e *TargetVariableError) HTTPStatusCode() int {
+	return http.StatusBadRequest
+}
+
+func (e *TargetVariableError) ErrorCode() string {
+	return "MISSING_TARGET_VARIABLE"
+}
+
+func (e *TargetVariableError) ErrorDetails() interface{} {
+	return map[string]interface{}{
+		"endpoint": e.EndpointName,
+	}
+}
+
 // ProxyError interface for structured error handling
 type ProxyError interface {
 	error
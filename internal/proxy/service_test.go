@@ -2,15 +2,25 @@ package proxy
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
+	"jq-proxy-service/internal/cache"
 	"jq-proxy-service/internal/client"
 	"jq-proxy-service/internal/logging"
 	"jq-proxy-service/internal/models"
@@ -61,6 +71,10 @@ func (m *MockHTTPClient) ForwardRequest(ctx context.Context, method, baseURL, pa
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
+	// A function builds a fresh response for each call
+	if build, ok := args.Get(0).(func() *client.Response); ok {
+		return build(), args.Error(1)
+	}
 	return args.Get(0).(*client.Response), args.Error(1)
 }
 
@@ -113,6 +127,11 @@ func TestService_HandleRequest_Success(t *testing.T) {
 	assert.Equal(t, 200, result.Status)
 	assert.Equal(t, transformedData, result.Data)
 
+	// The transformation is timed separately from the whole request
+	metrics := logger.GetMetrics().GetMetrics()
+	assert.Equal(t, int64(1), metrics.Transform.Count)
+	assert.Equal(t, int64(1), metrics.Endpoints["test-service"].TransformCount)
+
 	// Verify all expectations were met
 	mockConfig.AssertExpectations(t)
 	mockClient.AssertExpectations(t)
@@ -162,6 +181,40 @@ func TestService_HandleRequest_EndpointNotFound(t *testing.T) {
 	mockConfig.AssertExpectations(t)
 }
 
+func TestService_HandleRequest_EndpointDisabled(t *testing.T) {
+	mockConfig := &MockConfigProvider{}
+	mockClient := &MockHTTPClient{}
+	transformer := transform.NewUnifiedTransformer()
+	logger, _ := logging.NewLogger("error")
+
+	service := NewService(mockConfig, mockClient, transformer, logger)
+
+	disabled := false
+	endpoint := &models.Endpoint{
+		Name:    "test-service",
+		Target:  "https://api.example.com",
+		Enabled: &disabled,
+	}
+	mockConfig.On("GetEndpoint", "test-service").Return(endpoint, true)
+
+	proxyReq := &models.ProxyRequest{
+		Method:             "GET",
+		TransformationMode: models.TransformationModeJQ,
+		JQQuery:            ".",
+	}
+
+	result, err := service.HandleRequest(context.Background(), "test-service", "/users", nil, nil, proxyReq)
+	assert.Nil(t, result)
+	disabledErr, ok := err.(*EndpointDisabledError)
+	require.True(t, ok, "expected EndpointDisabledError, got %v", err)
+	assert.Equal(t, http.StatusServiceUnavailable, disabledErr.HTTPStatusCode())
+	assert.Equal(t, "ENDPOINT_DISABLED", disabledErr.ErrorCode())
+	assert.Equal(t, "endpoint 'test-service' is disabled", disabledErr.Error())
+
+	// The target is never called
+	mockClient.AssertNotCalled(t, "ForwardRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
 func TestService_HandleRequest_InvalidTransformation(t *testing.T) {
 	// Setup mocks
 	mockConfig := &MockConfigProvider{}
@@ -200,9 +253,122 @@ func TestService_HandleRequest_InvalidTransformation(t *testing.T) {
 	assert.Equal(t, http.StatusUnprocessableEntity, transformErr.HTTPStatusCode())
 	assert.Equal(t, "TRANSFORMATION_ERROR", transformErr.ErrorCode())
 
+	// The syntax error is located within the query
+	assert.Equal(t, 1, transformErr.Details["line"])
+	assert.Equal(t, 10, transformErr.Details["column"])
+	assert.Equal(t, 9, transformErr.Details["offset"])
+	assert.Equal(t, ".invalid[", transformErr.Details["snippet"])
+
 	mockConfig.AssertExpectations(t)
 }
 
+func TestService_HandleRequest_UnsupportedModeSkipsUpstream(t *testing.T) {
+	mockConfig := &MockConfigProvider{}
+	mockClient := &MockHTTPClient{}
+	transformer := transform.NewUnifiedTransformer()
+	logger, _ := logging.NewLogger("error")
+
+	service := NewService(mockConfig, mockClient, transformer, logger)
+
+	endpoint := &models.Endpoint{
+		Name:   "test-service",
+		Target: "https://api.example.com",
+	}
+	mockConfig.On("GetEndpoint", "test-service").Return(endpoint, true)
+
+	tests := []struct {
+		name     string
+		proxyReq *models.ProxyRequest
+		errMsg   string
+	}{
+		{
+			name: "jsonpath mode",
+			proxyReq: &models.ProxyRequest{
+				Method:             "GET",
+				TransformationMode: "jsonpath",
+				JQQuery:            "$.data",
+			},
+			errMsg: "unsupported transformation mode: jsonpath",
+		},
+		{
+			name: "jsonpath pipeline stage",
+			proxyReq: &models.ProxyRequest{
+				Method:             "GET",
+				TransformationMode: models.TransformationModeJQ,
+				Pipeline:           []models.TransformationStage{{Mode: "jq", Query: ".data"}, {Mode: "jsonpath", Query: "$.id"}},
+			},
+			errMsg: "pipeline stage 2: unsupported transformation mode: jsonpath",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.HandleRequest(context.Background(), "test-service", "/users", nil, nil, tt.proxyReq)
+
+			transformErr, ok := err.(*TransformationError)
+			require.True(t, ok, "expected TransformationError, got %v", err)
+			assert.Contains(t, transformErr.Message, tt.errMsg)
+		})
+	}
+
+	// Validation fails before the upstream is called
+	mockClient.AssertNotCalled(t, "ForwardRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+// upperTransformer is a custom transformation mode that upper-cases the
+// string field named by the query
+type upperTransformer struct{}
+
+func (upperTransformer) Transform(data any, req *models.ProxyRequest) (any, error) {
+	object, ok := data.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("input must be an object")
+	}
+	value, _ := object[req.JQQuery].(string)
+	return strings.ToUpper(value), nil
+}
+
+func (upperTransformer) Validate(req *models.ProxyRequest) error {
+	if req.JQQuery == "" {
+		return errors.New("query must name a field")
+	}
+	return nil
+}
+
+func TestService_HandleRequest_RegisteredMode(t *testing.T) {
+	mockConfig := &MockConfigProvider{}
+	mockClient := &MockHTTPClient{}
+	transformer := transform.NewUnifiedTransformer()
+	transformer.Register("upper", upperTransformer{})
+
+	service := NewService(mockConfig, mockClient, transformer, createTestLogger())
+
+	endpoint := &models.Endpoint{Name: "users", Target: "https://api.example.com"}
+	mockConfig.On("GetEndpoint", "users").Return(endpoint, true)
+	mockClient.On("ForwardRequest", mock.Anything, "GET", "https://api.example.com", "/users/1", mock.Anything, mock.Anything, mock.Anything).
+		Return(&client.Response{
+			StatusCode: http.StatusOK,
+			Headers:    http.Header{"Content-Type": []string{"application/json"}},
+			Body:       []byte(`{"name": "jane"}`),
+		}, nil)
+
+	// The request is parsed and validated as the handler does
+	proxyReq, err := models.ParseProxyRequest([]byte(`{"method": "GET", "transformation_mode": "upper", "jq_query": "name"}`))
+	require.NoError(t, err)
+
+	result, err := service.HandleRequest(context.Background(), "users", "/users/1", nil, nil, proxyReq)
+	require.NoError(t, err)
+	assert.Equal(t, "JANE", result.Data)
+
+	// A pipeline can hand jq output to the custom mode
+	proxyReq, err = models.ParseProxyRequest([]byte(`{"method": "GET", "pipeline": [{"query": "{name: (.name + \" doe\")}"}, {"mode": "upper", "query": "name"}]}`))
+	require.NoError(t, err)
+
+	result, err = service.HandleRequest(context.Background(), "users", "/users/1", nil, nil, proxyReq)
+	require.NoError(t, err)
+	assert.Equal(t, "JANE DOE", result.Data)
+}
+
 func TestService_HandleRequest_UpstreamError(t *testing.T) {
 	// Setup mocks
 	mockConfig := &MockConfigProvider{}
@@ -286,6 +452,162 @@ func TestService_HandleRequest_TransformationFailure(t *testing.T) {
 	mockConfig.AssertExpectations(t)
 }
 
+func TestService_HandleRequest_TransformTimeout(t *testing.T) {
+	mockConfig := &MockConfigProvider{}
+	mockClient := &MockHTTPClient{}
+	transformer := transform.NewUnifiedTransformer()
+	transformer.SetMaxTransformTime(50 * time.Millisecond)
+	logger, _ := logging.NewLogger("error")
+
+	service := NewService(mockConfig, mockClient, transformer, logger)
+
+	endpoint := &models.Endpoint{
+		Name:   "test-service",
+		Target: "https://api.example.com",
+	}
+
+	proxyReq := &models.ProxyRequest{
+		Method:             "GET",
+		TransformationMode: models.TransformationModeJQ,
+		JQQuery:            "last(range(1e12))",
+	}
+
+	httpResponse := &client.Response{
+		StatusCode: 200,
+		Headers:    http.Header{"Content-Type": []string{"application/json"}},
+		Body:       []byte(`{}`),
+	}
+
+	mockConfig.On("GetEndpoint", "test-service").Return(endpoint, true)
+	mockClient.On("ForwardRequest", mock.Anything, "GET", "https://api.example.com", "/users", url.Values(nil), http.Header(nil), nil).Return(httpResponse, nil)
+
+	result, err := service.HandleRequest(context.Background(), "test-service", "/users", nil, nil, proxyReq)
+
+	assert.Nil(t, result)
+	transformErr, ok := err.(*TransformationError)
+	require.True(t, ok)
+	assert.Equal(t, "TRANSFORM_TIMEOUT", transformErr.ErrorCode())
+	assert.Equal(t, http.StatusUnprocessableEntity, transformErr.HTTPStatusCode())
+}
+
+func TestService_HandleRequest_LoadBalancedTargets(t *testing.T) {
+	mockConfig := &MockConfigProvider{}
+	mockClient := &MockHTTPClient{}
+	transformer := transform.NewUnifiedTransformer()
+	logger, _ := logging.NewLogger("error")
+
+	service := NewService(mockConfig, mockClient, transformer, logger)
+
+	endpoint := &models.Endpoint{
+		Name:    "test-service",
+		Targets: []models.WeightedTarget{{URL: "https://a.example.com", Weight: 1}, {URL: "https://b.example.com", Weight: 1}},
+	}
+
+	proxyReq := &models.ProxyRequest{
+		Method:             "GET",
+		TransformationMode: models.TransformationModeJQ,
+		JQQuery:            ".",
+	}
+
+	okResponse := &client.Response{
+		StatusCode: 200,
+		Headers:    http.Header{"Content-Type": []string{"application/json"}},
+		Body:       []byte(`{}`),
+	}
+
+	mockConfig.On("GetEndpoint", "test-service").Return(endpoint, true)
+	mockClient.On("ForwardRequest", mock.Anything, "GET", "https://a.example.com", "/users", url.Values(nil), http.Header(nil), nil).
+		Return(okResponse, nil).Once()
+	mockClient.On("ForwardRequest", mock.Anything, "GET", "https://b.example.com", "/users", url.Values(nil), http.Header(nil), nil).
+		Return(okResponse, nil).Once()
+	mockClient.On("ForwardRequest", mock.Anything, "GET", "https://a.example.com", "/users", url.Values(nil), http.Header(nil), nil).
+		Return(nil, errors.New("connection refused")).Once()
+
+	ctx := context.Background()
+
+	// Requests alternate between targets
+	for i := 0; i < 2; i++ {
+		_, err := service.HandleRequest(ctx, "test-service", "/users", nil, nil, proxyReq)
+		require.NoError(t, err)
+	}
+
+	// A failing target is reported with the target that was tried
+	_, err := service.HandleRequest(ctx, "test-service", "/users", nil, nil, proxyReq)
+	upstreamErr, ok := err.(*UpstreamError)
+	require.True(t, ok)
+	assert.Equal(t, "https://a.example.com", upstreamErr.Details["target"])
+
+	// Subsequent requests skip the failed target
+	mockClient.On("ForwardRequest", mock.Anything, "GET", "https://b.example.com", "/users", url.Values(nil), http.Header(nil), nil).
+		Return(okResponse, nil).Twice()
+	for i := 0; i < 2; i++ {
+		_, err := service.HandleRequest(ctx, "test-service", "/users", nil, nil, proxyReq)
+		require.NoError(t, err)
+	}
+
+	mockClient.AssertExpectations(t)
+}
+
+func TestService_HandleRequest_BodyPreviewLogging(t *testing.T) {
+	body := `{"password": "hunter2", "items": "` + strings.Repeat("x", 200) + `"}`
+
+	tests := []struct {
+		name        string
+		level       string
+		previewSize int
+		wantPreview bool
+	}{
+		{name: "debug level with preview enabled", level: "debug", previewSize: 40, wantPreview: true},
+		{name: "info level with preview enabled", level: "info", previewSize: 40, wantPreview: false},
+		{name: "debug level with preview disabled", level: "debug", previewSize: 0, wantPreview: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockConfig := &MockConfigProvider{}
+			mockClient := &MockHTTPClient{}
+			transformer := transform.NewUnifiedTransformer()
+			logger, _ := logging.NewLogger(tt.level)
+			var logs strings.Builder
+			logger.SetOutput(&logs)
+
+			service := NewService(mockConfig, mockClient, transformer, logger, WithBodyPreviewBytes(tt.previewSize))
+
+			endpoint := &models.Endpoint{
+				Name:   "test-service",
+				Target: "https://api.example.com",
+			}
+			mockConfig.On("GetEndpoint", "test-service").Return(endpoint, true)
+			mockClient.On("ForwardRequest", mock.Anything, "GET", "https://api.example.com", "/users", url.Values(nil), http.Header(nil), nil).
+				Return(&client.Response{
+					StatusCode: 200,
+					Headers:    http.Header{"Content-Type": []string{"application/json"}},
+					Body:       []byte(body),
+				}, nil)
+
+			// Adding a number to a string fails at transform time
+			proxyReq := &models.ProxyRequest{
+				Method:             "GET",
+				TransformationMode: models.TransformationModeJQ,
+				JQQuery:            ".items + 1",
+			}
+			_, err := service.HandleRequest(context.Background(), "test-service", "/users", nil, nil, proxyReq)
+			require.Error(t, err)
+
+			output := logs.String()
+			assert.NotContains(t, output, "hunter2")
+			if tt.wantPreview {
+				assert.Contains(t, output, "Untransformable upstream response body")
+				assert.Contains(t, output, `[REDACTED]`)
+				assert.Contains(t, output, "bytes truncated")
+				assert.NotContains(t, output, strings.Repeat("x", 100))
+			} else {
+				assert.NotContains(t, output, "body_preview")
+			}
+		})
+	}
+}
+
 func TestService_HandleRequest_NonJSONResponse(t *testing.T) {
 	// Setup mocks
 	mockConfig := &MockConfigProvider{}
@@ -384,8 +706,7 @@ func TestService_HandleRequest_HTTPErrorStatus(t *testing.T) {
 	mockClient.AssertExpectations(t)
 }
 
-func TestService_HandleRequest_WithQueryParamsAndHeaders(t *testing.T) {
-	// Setup mocks
+func TestService_HandleRequest_ErrorQuery(t *testing.T) {
 	mockConfig := &MockConfigProvider{}
 	mockClient := &MockHTTPClient{}
 	transformer := transform.NewUnifiedTransformer()
@@ -398,47 +719,2052 @@ func TestService_HandleRequest_WithQueryParamsAndHeaders(t *testing.T) {
 		Target: "https://api.example.com",
 	}
 
+	proxyReq := &models.ProxyRequest{
+		Method:             "GET",
+		TransformationMode: models.TransformationModeJQ,
+		JQQuery:            "{name: .user.name}",
+		ErrorJQQuery:       "{error: .errors[0].detail}",
+	}
+
+	errorResponse := &client.Response{
+		StatusCode: 500,
+		Headers:    http.Header{"Content-Type": []string{"application/json"}},
+		Body:       []byte(`{"errors":[{"detail":"database unavailable"}]}`),
+	}
+	okResponse := &client.Response{
+		StatusCode: 200,
+		Headers:    http.Header{"Content-Type": []string{"application/json"}},
+		Body:       []byte(`{"user":{"name":"John"}}`),
+	}
+
+	mockConfig.On("GetEndpoint", "test-service").Return(endpoint, true)
+	mockClient.On("ForwardRequest", mock.Anything, "GET", "https://api.example.com", "/users/1", url.Values(nil), http.Header(nil), nil).
+		Return(errorResponse, nil).Once()
+	mockClient.On("ForwardRequest", mock.Anything, "GET", "https://api.example.com", "/users/1", url.Values(nil), http.Header(nil), nil).
+		Return(okResponse, nil).Once()
+
+	ctx := context.Background()
+
+	// Error responses use the error query
+	result, err := service.HandleRequest(ctx, "test-service", "/users/1", nil, nil, proxyReq)
+	require.NoError(t, err)
+	assert.Equal(t, 500, result.Status)
+	assert.Equal(t, map[string]interface{}{"error": "database unavailable"}, result.Data)
+
+	// Successful responses still use the main query
+	result, err = service.HandleRequest(ctx, "test-service", "/users/1", nil, nil, proxyReq)
+	require.NoError(t, err)
+	assert.Equal(t, 200, result.Status)
+	assert.Equal(t, map[string]interface{}{"name": "John"}, result.Data)
+
+	mockClient.AssertExpectations(t)
+}
+
+func TestService_HandleRequest_ConcurrencyLimit(t *testing.T) {
+	mockConfig := &MockConfigProvider{}
+	mockClient := &MockHTTPClient{}
+	transformer := transform.NewUnifiedTransformer()
+	logger, _ := logging.NewLogger("error")
+
+	service := NewService(mockConfig, mockClient, transformer, logger, WithMaxConcurrentUpstream(1))
+
+	endpoint := &models.Endpoint{
+		Name:   "test-service",
+		Target: "https://api.example.com",
+	}
+
+	proxyReq := &models.ProxyRequest{
+		Method:             "GET",
+		TransformationMode: models.TransformationModeJQ,
+		JQQuery:            ".",
+	}
+
+	okResponse := func() *client.Response {
+		return &client.Response{
+			StatusCode: 200,
+			Headers:    http.Header{"Content-Type": []string{"application/json"}},
+			Body:       []byte(`{}`),
+		}
+	}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	mockConfig.On("GetEndpoint", "test-service").Return(endpoint, true)
+	mockClient.On("ForwardRequest", mock.Anything, "GET", "https://api.example.com", "/slow", url.Values(nil), http.Header(nil), nil).
+		Run(func(args mock.Arguments) {
+			close(started)
+			<-release
+		}).
+		Return(okResponse, nil).Once()
+	mockClient.On("ForwardRequest", mock.Anything, "GET", "https://api.example.com", "/fast", url.Values(nil), http.Header(nil), nil).
+		Return(okResponse, nil)
+
+	// Occupy the only upstream slot
+	slowDone := make(chan error, 1)
+	go func() {
+		_, err := service.HandleRequest(context.Background(), "test-service", "/slow", nil, nil, proxyReq)
+		slowDone <- err
+	}()
+	<-started
+
+	// A request whose deadline passes while waiting is rejected
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := service.HandleRequest(ctx, "test-service", "/fast", nil, nil, proxyReq)
+	busyErr, ok := err.(*UpstreamBusyError)
+	require.True(t, ok, "expected UpstreamBusyError, got %v", err)
+	assert.Equal(t, http.StatusServiceUnavailable, busyErr.HTTPStatusCode())
+	assert.Equal(t, "UPSTREAM_BUSY", busyErr.ErrorCode())
+
+	// A request with a longer deadline queues until the slot is released
+	queuedDone := make(chan error, 1)
+	go func() {
+		_, err := service.HandleRequest(context.Background(), "test-service", "/fast", nil, nil, proxyReq)
+		queuedDone <- err
+	}()
+
+	select {
+	case err := <-queuedDone:
+		t.Fatalf("queued request completed before the slot was released: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	require.NoError(t, <-slowDone)
+	require.NoError(t, <-queuedDone)
+	mockClient.AssertExpectations(t)
+}
+
+// busyTransformer stands in for a CPU-heavy transformation, recording the
+// highest number of transformations that ran at once
+type busyTransformer struct {
+	duration time.Duration
+	mu       sync.Mutex
+	running  int
+	peak     int
+}
+
+func (b *busyTransformer) Transform(data any, req *models.ProxyRequest) (any, error) {
+	b.mu.Lock()
+	b.running++
+	b.peak = max(b.peak, b.running)
+	b.mu.Unlock()
+
+	time.Sleep(b.duration)
+
+	b.mu.Lock()
+	b.running--
+	b.mu.Unlock()
+	return data, nil
+}
+
+func (b *busyTransformer) Validate(req *models.ProxyRequest) error {
+	return nil
+}
+
+func TestService_HandleRequest_TransformConcurrencyLimit(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	}))
+	defer upstream.Close()
+
+	mockConfig := &MockConfigProvider{}
+	busy := &busyTransformer{duration: 20 * time.Millisecond}
+	transformer := transform.NewUnifiedTransformer()
+	transformer.Register(models.TransformationModeJQ, busy)
+	logger, _ := logging.NewLogger("error")
+
+	service := NewService(mockConfig, client.NewClient(5*time.Second), transformer, logger, WithMaxConcurrentTransforms(2))
+
+	endpoint := &models.Endpoint{
+		Name:   "test-service",
+		Target: upstream.URL,
+	}
+	mockConfig.On("GetEndpoint", "test-service").Return(endpoint, true)
+
 	proxyReq := &models.ProxyRequest{
 		Method:             "POST",
-		Body:               map[string]interface{}{"name": "John"},
+		Body:               map[string]interface{}{},
 		TransformationMode: models.TransformationModeJQ,
-		JQQuery:            "{id: .id}",
+		JQQuery:            ".",
 	}
 
-	queryParams := url.Values{
-		"limit": []string{"10"},
-		"page":  []string{"1"},
+	// Many concurrent requests never run more transformations than the limit
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = service.HandleRequest(context.Background(), "test-service", "/compute", nil, nil, proxyReq)
+		}(i)
 	}
+	wg.Wait()
 
-	headers := http.Header{
-		"Authorization": []string{"Bearer token"},
-		"Content-Type":  []string{"application/json"},
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, busy.peak)
+
+	// A request whose context ends while waiting for a slot is rejected
+	busy.duration = 200 * time.Millisecond
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = service.HandleRequest(context.Background(), "test-service", "/compute", nil, nil, proxyReq)
+		}()
 	}
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := service.HandleRequest(ctx, "test-service", "/compute", nil, nil, proxyReq)
+	busyErr, ok := err.(*TransformBusyError)
+	require.True(t, ok, "expected TransformBusyError, got %v", err)
+	assert.Equal(t, http.StatusServiceUnavailable, busyErr.HTTPStatusCode())
+	assert.Equal(t, "TRANSFORM_BUSY", busyErr.ErrorCode())
+	wg.Wait()
+}
 
-	transformedData := map[string]interface{}{
-		"id": float64(123),
+func TestService_HandleRequest_ResultLimit(t *testing.T) {
+	endpoint := &models.Endpoint{
+		Name:   "test-service",
+		Target: "https://api.example.com",
 	}
 
 	httpResponse := &client.Response{
-		StatusCode: 201,
+		StatusCode: 200,
 		Headers:    http.Header{"Content-Type": []string{"application/json"}},
-		Body:       []byte(`{"id":123,"name":"John"}`),
+		Body:       []byte(`{"count": 1000}`),
 	}
 
-	// Setup expectations
-	mockConfig.On("GetEndpoint", "test-service").Return(endpoint, true)
-	mockClient.On("ForwardRequest", mock.Anything, "POST", "https://api.example.com", "/users", queryParams, headers, map[string]interface{}{"name": "John"}).Return(httpResponse, nil)
+	tests := []struct {
+		name         string
+		serverLimit  int
+		requestLimit int
+		expectLimit  int
+	}{
+		{name: "within server limit", serverLimit: 10000},
+		{name: "exceeds server limit", serverLimit: 100, expectLimit: 100},
+		{name: "request lowers limit", serverLimit: 10000, requestLimit: 50, expectLimit: 50},
+		{name: "request cannot raise limit", serverLimit: 100, requestLimit: 10000, expectLimit: 100},
+		{name: "request limit without server limit", requestLimit: 50, expectLimit: 50},
+	}
 
-	// Execute
-	ctx := context.Background()
-	result, err := service.HandleRequest(ctx, "test-service", "/users", queryParams, headers, proxyReq)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockConfig := &MockConfigProvider{}
+			mockClient := &MockHTTPClient{}
+			logger, _ := logging.NewLogger("error")
+			service := NewService(mockConfig, mockClient, transform.NewUnifiedTransformer(), logger,
+				WithMaxResultBytes(tt.serverLimit))
+
+			mockConfig.On("GetEndpoint", "test-service").Return(endpoint, true)
+			mockClient.On("ForwardRequest", mock.Anything, "GET", "https://api.example.com", "/items", url.Values(nil), http.Header(nil), nil).
+				Return(httpResponse, nil)
+
+			// Produces a JSON array of roughly 4KB
+			proxyReq := &models.ProxyRequest{
+				Method:             "GET",
+				TransformationMode: models.TransformationModeJQ,
+				JQQuery:            "[range(.count)]",
+				MaxResultBytes:     tt.requestLimit,
+			}
+
+			result, err := service.HandleRequest(context.Background(), "test-service", "/items", nil, nil, proxyReq)
+
+			if tt.expectLimit == 0 {
+				require.NoError(t, err)
+				assert.Len(t, result.Data, 1000)
+				return
+			}
+
+			assert.Nil(t, result)
+			transformErr, ok := err.(*TransformationError)
+			require.True(t, ok)
+			assert.Equal(t, "RESULT_TOO_LARGE", transformErr.ErrorCode())
+			assert.Equal(t, http.StatusRequestEntityTooLarge, transformErr.HTTPStatusCode())
+			assert.Equal(t, tt.expectLimit, transformErr.Details["max_result_bytes"])
+		})
+	}
+}
 
-	// Assert
-	require.NoError(t, err)
-	assert.NotNil(t, result)
-	assert.Equal(t, 201, result.Status)
-	assert.Equal(t, transformedData, result.Data)
+func TestService_HandleRequest_ResultMemoryLimit(t *testing.T) {
+	endpoint := &models.Endpoint{
+		Name:   "test-service",
+		Target: "https://api.example.com",
+	}
 
-	mockConfig.AssertExpectations(t)
-	mockClient.AssertExpectations(t)
+	tests := []struct {
+		name        string
+		limit       int
+		expectError bool
+	}{
+		{name: "no limit"},
+		{name: "within limit", limit: 100000},
+		{name: "exceeds limit", limit: 10000, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockConfig := &MockConfigProvider{}
+			mockClient := &MockHTTPClient{}
+			service := NewService(mockConfig, mockClient, transform.NewUnifiedTransformer(), createTestLogger(),
+				WithMaxResultMemory(tt.limit))
+
+			mockConfig.On("GetEndpoint", "test-service").Return(endpoint, true)
+			mockClient.On("ForwardRequest", mock.Anything, "GET", "https://api.example.com", "/items", url.Values(nil), http.Header(nil), nil).
+				Return(&client.Response{
+					StatusCode: 200,
+					Headers:    http.Header{"Content-Type": []string{"application/json"}},
+					Body:       []byte(`{"count": 1000}`),
+				}, nil)
+
+			// Produces an array estimated at roughly 24KB in memory
+			proxyReq := &models.ProxyRequest{
+				Method:             "GET",
+				TransformationMode: models.TransformationModeJQ,
+				JQQuery:            "[range(.count)]",
+			}
+
+			result, err := service.HandleRequest(context.Background(), "test-service", "/items", nil, nil, proxyReq)
+
+			if !tt.expectError {
+				require.NoError(t, err)
+				assert.Len(t, result.Data, 1000)
+				return
+			}
+
+			assert.Nil(t, result)
+			transformErr, ok := err.(*TransformationError)
+			require.True(t, ok)
+			assert.Equal(t, "RESULT_TOO_LARGE", transformErr.ErrorCode())
+			assert.Equal(t, http.StatusRequestEntityTooLarge, transformErr.HTTPStatusCode())
+			assert.Equal(t, tt.limit, transformErr.Details["max_result_memory"])
+		})
+	}
+}
+
+func TestService_HandleRequest_HeaderRoutes(t *testing.T) {
+	mockConfig := &MockConfigProvider{}
+	mockClient := &MockHTTPClient{}
+	logger, _ := logging.NewLogger("error")
+	service := NewService(mockConfig, mockClient, transform.NewUnifiedTransformer(), logger)
+
+	endpoint := &models.Endpoint{
+		Name:   "test-service",
+		Target: "https://api.example.com",
+		Routes: []models.HeaderRoute{
+			{Header: "X-Tenant", Value: "acme", Target: "https://acme.example.com"},
+			{Header: "X-Tenant", Value: "globex", Target: "https://globex.example.com"},
+		},
+	}
+	mockConfig.On("GetEndpoint", "test-service").Return(endpoint, true)
+
+	okResponse := &client.Response{
+		StatusCode: 200,
+		Headers:    http.Header{"Content-Type": []string{"application/json"}},
+		Body:       []byte(`{}`),
+	}
+	for _, target := range []string{"https://api.example.com", "https://acme.example.com", "https://globex.example.com"} {
+		mockClient.On("ForwardRequest", mock.Anything, "GET", target, "/users", url.Values(nil), mock.Anything, nil).Return(okResponse, nil)
+	}
+
+	tests := []struct {
+		name         string
+		headers      http.Header
+		expectTarget string
+	}{
+		{name: "matching tenant", headers: http.Header{"X-Tenant": []string{"acme"}}, expectTarget: "https://acme.example.com"},
+		{name: "second route", headers: http.Header{"X-Tenant": []string{"globex"}}, expectTarget: "https://globex.example.com"},
+		{name: "unknown tenant falls back", headers: http.Header{"X-Tenant": []string{"initech"}}, expectTarget: "https://api.example.com"},
+		{name: "no header falls back", headers: nil, expectTarget: "https://api.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxyReq := &models.ProxyRequest{
+				Method:             "GET",
+				TransformationMode: models.TransformationModeJQ,
+				JQQuery:            ".",
+			}
+
+			_, err := service.HandleRequest(context.Background(), "test-service", "/users", nil, tt.headers, proxyReq)
+			require.NoError(t, err)
+
+			lastCall := mockClient.Calls[len(mockClient.Calls)-1]
+			assert.Equal(t, tt.expectTarget, lastCall.Arguments.String(2))
+		})
+	}
+}
+
+func TestService_HandleRequest_StatusQuery(t *testing.T) {
+	mockConfig := &MockConfigProvider{}
+	mockClient := &MockHTTPClient{}
+	transformer := transform.NewUnifiedTransformer()
+	logger, _ := logging.NewLogger("error")
+
+	service := NewService(mockConfig, mockClient, transformer, logger)
+
+	endpoint := &models.Endpoint{
+		Name:   "test-service",
+		Target: "https://api.example.com",
+	}
+
+	httpResponse := &client.Response{
+		StatusCode: 200,
+		Headers:    http.Header{"Content-Type": []string{"application/json"}},
+		Body:       []byte(`{"users":[{"name":"John"}]}`),
+	}
+
+	mockConfig.On("GetEndpoint", "test-service").Return(endpoint, true)
+	mockClient.On("ForwardRequest", mock.Anything, "GET", "https://api.example.com", "/users", url.Values(nil), http.Header(nil), nil).
+		Return(httpResponse, nil)
+
+	tests := []struct {
+		name         string
+		jqQuery      string
+		statusQuery  string
+		expectStatus int
+	}{
+		{name: "404 on empty lookup", jqQuery: "[.users[] | select(.name == \"Jane\")]", statusQuery: "if length == 0 then 404 else 200 end", expectStatus: 404},
+		{name: "200 on match", jqQuery: "[.users[] | select(.name == \"John\")]", statusQuery: "if length == 0 then 404 else 200 end", expectStatus: 200},
+		{name: "invalid status falls back to upstream", jqQuery: ".users", statusQuery: "\"not a status\"", expectStatus: 200},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxyReq := &models.ProxyRequest{
+				Method:             "GET",
+				TransformationMode: models.TransformationModeJQ,
+				JQQuery:            tt.jqQuery,
+				StatusJQQuery:      tt.statusQuery,
+			}
+
+			result, err := service.HandleRequest(context.Background(), "test-service", "/users", nil, nil, proxyReq)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectStatus, result.Status)
+		})
+	}
+}
+
+func TestService_HandleRequest_ErrorOnNull(t *testing.T) {
+	httpResponse := &client.Response{
+		StatusCode: 200,
+		Headers:    http.Header{"Content-Type": []string{"application/json"}},
+		Body:       []byte(`{"user":{"name":"John"}}`),
+	}
+
+	tests := []struct {
+		name          string
+		jqQuery       string
+		requestFlag   bool
+		endpointFlag  bool
+		expectFailure bool
+	}{
+		{name: "null returned when off", jqQuery: ".user.email", expectFailure: false},
+		{name: "null fails with request flag", jqQuery: ".user.email", requestFlag: true, expectFailure: true},
+		{name: "null fails with endpoint flag", jqQuery: ".user.email", endpointFlag: true, expectFailure: true},
+		{name: "non-null passes with flag", jqQuery: ".user.name", requestFlag: true, expectFailure: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockConfig := &MockConfigProvider{}
+			mockClient := &MockHTTPClient{}
+			logger, _ := logging.NewLogger("error")
+			service := NewService(mockConfig, mockClient, transform.NewUnifiedTransformer(), logger)
+
+			endpoint := &models.Endpoint{
+				Name:        "test-service",
+				Target:      "https://api.example.com",
+				ErrorOnNull: tt.endpointFlag,
+			}
+			mockConfig.On("GetEndpoint", "test-service").Return(endpoint, true)
+			mockClient.On("ForwardRequest", mock.Anything, "GET", "https://api.example.com", "/user", url.Values(nil), http.Header(nil), nil).
+				Return(httpResponse, nil)
+
+			proxyReq := &models.ProxyRequest{
+				Method:             "GET",
+				TransformationMode: models.TransformationModeJQ,
+				JQQuery:            tt.jqQuery,
+				ErrorOnNull:        tt.requestFlag,
+			}
+
+			result, err := service.HandleRequest(context.Background(), "test-service", "/user", nil, nil, proxyReq)
+			if !tt.expectFailure {
+				require.NoError(t, err)
+				assert.Equal(t, 200, result.Status)
+				return
+			}
+
+			require.Error(t, err)
+			var transformErr *TransformationError
+			require.ErrorAs(t, err, &transformErr)
+			assert.Equal(t, "TRANSFORMATION_ERROR", transformErr.ErrorCode())
+			assert.Equal(t, http.StatusUnprocessableEntity, transformErr.HTTPStatusCode())
+		})
+	}
+}
+
+func TestService_HandleRequest_RateLimit(t *testing.T) {
+	mockConfig := &MockConfigProvider{}
+	mockClient := &MockHTTPClient{}
+	transformer := transform.NewUnifiedTransformer()
+	logger, _ := logging.NewLogger("error")
+
+	service := NewService(mockConfig, mockClient, transformer, logger)
+
+	endpoint := &models.Endpoint{
+		Name:      "test-service",
+		Target:    "https://api.example.com",
+		RateLimit: 20, // one call every 50ms
+	}
+
+	proxyReq := &models.ProxyRequest{
+		Method:             "GET",
+		TransformationMode: models.TransformationModeJQ,
+		JQQuery:            ".",
+	}
+
+	var callTimes []time.Time
+	mockConfig.On("GetEndpoint", "test-service").Return(endpoint, true)
+	mockClient.On("ForwardRequest", mock.Anything, "GET", "https://api.example.com", "/users", url.Values(nil), http.Header(nil), nil).
+		Run(func(args mock.Arguments) { callTimes = append(callTimes, time.Now()) }).
+		Return(&client.Response{StatusCode: 200, Body: []byte(`ok`)}, nil)
+
+	for i := 0; i < 4; i++ {
+		_, err := service.HandleRequest(context.Background(), "test-service", "/users", nil, nil, proxyReq)
+		require.NoError(t, err)
+	}
+
+	// Calls to the upstream are paced at the endpoint's rate
+	require.Len(t, callTimes, 4)
+	for i := 1; i < len(callTimes); i++ {
+		assert.GreaterOrEqual(t, callTimes[i].Sub(callTimes[i-1]), 40*time.Millisecond)
+	}
+
+	endpointMetrics := logger.GetMetrics().GetMetrics().Endpoints["test-service"]
+	assert.Equal(t, int64(3), endpointMetrics.ThrottledRequests)
+	assert.Greater(t, endpointMetrics.ThrottleWaitTime, 100*time.Millisecond)
+
+	// A request whose deadline falls before its slot is rejected
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := service.HandleRequest(ctx, "test-service", "/users", nil, nil, proxyReq)
+	throttledErr, ok := err.(*UpstreamThrottledError)
+	require.True(t, ok, "expected UpstreamThrottledError, got %v", err)
+	assert.Equal(t, http.StatusServiceUnavailable, throttledErr.HTTPStatusCode())
+	assert.Equal(t, "UPSTREAM_THROTTLED", throttledErr.ErrorCode())
+}
+
+func TestService_HandleRequest_EndpointTransport(t *testing.T) {
+	mockConfig := &MockConfigProvider{}
+	sharedClient := &MockHTTPClient{}
+	endpointClient := &MockHTTPClient{}
+	transformer := transform.NewUnifiedTransformer()
+	logger, _ := logging.NewLogger("error")
+
+	service := NewService(mockConfig, sharedClient, transformer, logger).(*Service)
+	var built []client.Settings
+	service.newEndpointClient = func(settings client.Settings) client.HTTPClient {
+		built = append(built, settings)
+		return endpointClient
+	}
+
+	tuned := &models.Endpoint{
+		Name:   "tuned-service",
+		Target: "https://tuned.example.com",
+		Transport: &models.TransportConfig{
+			Timeout:             5,
+			MaxIdleConnsPerHost: 50,
+			IdleConnTimeout:     30,
+			DialTimeout:         2,
+			Proxy:               "http://proxy.internal:3128",
+		},
+	}
+	plain := &models.Endpoint{
+		Name:   "plain-service",
+		Target: "https://plain.example.com",
+	}
+
+	proxyReq := &models.ProxyRequest{
+		Method:             "GET",
+		TransformationMode: models.TransformationModeJQ,
+		JQQuery:            ".",
+	}
+	response := &client.Response{StatusCode: 200, Body: []byte(`{}`)}
+
+	mockConfig.On("GetEndpoint", "tuned-service").Return(tuned, true)
+	mockConfig.On("GetEndpoint", "plain-service").Return(plain, true)
+	endpointClient.On("ForwardRequest", mock.Anything, "GET", "https://tuned.example.com", "/", url.Values(nil), http.Header(nil), nil).
+		Return(response, nil).Twice()
+	sharedClient.On("ForwardRequest", mock.Anything, "GET", "https://plain.example.com", "/", url.Values(nil), http.Header(nil), nil).
+		Return(response, nil).Once()
+
+	for _, name := range []string{"tuned-service", "tuned-service", "plain-service"} {
+		_, err := service.HandleRequest(context.Background(), name, "/", nil, nil, proxyReq)
+		require.NoError(t, err)
+	}
+
+	// The tuned endpoint's client is built once with its settings merged over the defaults
+	require.Len(t, built, 1)
+	assert.Equal(t, 5*time.Second, built[0].Timeout)
+	assert.Equal(t, 50, built[0].MaxIdleConnsPerHost)
+	assert.Equal(t, 30*time.Second, built[0].IdleConnTimeout)
+	assert.Equal(t, 2*time.Second, built[0].DialTimeout)
+	assert.Equal(t, "http://proxy.internal:3128", built[0].ProxyURL)
+	assert.Equal(t, client.DefaultSettings(0).MaxIdleConns, built[0].MaxIdleConns)
+	assert.Equal(t, client.DefaultSettings(0).KeepAlive, built[0].KeepAlive)
+
+	// Changing the settings rebuilds the client
+	tuned.Transport = &models.TransportConfig{MaxIdleConnsPerHost: 5}
+	endpointClient.On("ForwardRequest", mock.Anything, "GET", "https://tuned.example.com", "/", url.Values(nil), http.Header(nil), nil).
+		Return(response, nil).Once()
+	_, err := service.HandleRequest(context.Background(), "tuned-service", "/", nil, nil, proxyReq)
+	require.NoError(t, err)
+	require.Len(t, built, 2)
+	assert.Equal(t, 5, built[1].MaxIdleConnsPerHost)
+
+	sharedClient.AssertExpectations(t)
+	endpointClient.AssertExpectations(t)
+}
+
+func TestService_HandleRequest_EndpointTimeout(t *testing.T) {
+	mockConfig := &MockConfigProvider{}
+	sharedClient := &MockHTTPClient{}
+	endpointClient := &MockHTTPClient{}
+	service := NewService(mockConfig, sharedClient, transform.NewUnifiedTransformer(), createTestLogger()).(*Service)
+	service.newEndpointClient = func(client.Settings) client.HTTPClient { return endpointClient }
+
+	slow := &models.Endpoint{
+		Name:      "slow-service",
+		Target:    "https://slow.example.com",
+		Transport: &models.TransportConfig{Timeout: 120},
+	}
+	plain := &models.Endpoint{Name: "plain-service", Target: "https://plain.example.com"}
+	mockConfig.On("GetEndpoint", "slow-service").Return(slow, true)
+	mockConfig.On("GetEndpoint", "plain-service").Return(plain, true)
+
+	// Record how long each upstream call was given
+	var budgets []time.Duration
+	recordBudget := func(args mock.Arguments) {
+		deadline, ok := args.Get(0).(context.Context).Deadline()
+		require.True(t, ok)
+		budgets = append(budgets, time.Until(deadline))
+	}
+	response := &client.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}
+	endpointClient.On("ForwardRequest", mock.Anything, "GET", "https://slow.example.com", "/", url.Values(nil), http.Header(nil), nil).
+		Run(recordBudget).Return(response, nil)
+	sharedClient.On("ForwardRequest", mock.Anything, "GET", "https://plain.example.com", "/", url.Values(nil), http.Header(nil), nil).
+		Run(recordBudget).Return(response, nil)
+
+	proxyReq := &models.ProxyRequest{Method: "GET", TransformationMode: models.TransformationModeJQ, JQQuery: "."}
+	for _, name := range []string{"slow-service", "plain-service"} {
+		_, err := service.HandleRequest(context.Background(), name, "/", nil, nil, proxyReq)
+		require.NoError(t, err)
+	}
+
+	// The endpoint's timeout is longer than the default, which still applies elsewhere
+	require.Len(t, budgets, 2)
+	assert.InDelta(t, float64(120*time.Second), float64(budgets[0]), float64(time.Second))
+	assert.InDelta(t, float64(upstreamTimeout), float64(budgets[1]), float64(time.Second))
+}
+
+func TestService_HandleRequest_EndpointUserAgent(t *testing.T) {
+	mockConfig := &MockConfigProvider{}
+	mockClient := &MockHTTPClient{}
+	transformer := transform.NewUnifiedTransformer()
+	logger, _ := logging.NewLogger("error")
+
+	service := NewService(mockConfig, mockClient, transformer, logger)
+
+	endpoint := &models.Endpoint{
+		Name:      "test-service",
+		Target:    "https://api.example.com",
+		UserAgent: "partner-integration/2.0",
+	}
+	mockConfig.On("GetEndpoint", "test-service").Return(endpoint, true)
+
+	proxyReq := &models.ProxyRequest{
+		Method:             "POST",
+		TransformationMode: models.TransformationModeJQ,
+		JQQuery:            ".",
+	}
+	response := &client.Response{StatusCode: 200, Body: []byte(`ok`)}
+
+	// The endpoint's User-Agent fills in when the caller sends none
+	mockClient.On("ForwardRequest", mock.Anything, "POST", "https://api.example.com", "/a", url.Values(nil),
+		http.Header{"User-Agent": []string{"partner-integration/2.0"}}, nil).Return(response, nil).Once()
+	_, err := service.HandleRequest(context.Background(), "test-service", "/a", nil, nil, proxyReq)
+	require.NoError(t, err)
+
+	// The caller's User-Agent is kept
+	callerHeaders := http.Header{"User-Agent": []string{"curl/8.0"}}
+	mockClient.On("ForwardRequest", mock.Anything, "POST", "https://api.example.com", "/b", url.Values(nil),
+		callerHeaders, nil).Return(response, nil).Once()
+	_, err = service.HandleRequest(context.Background(), "test-service", "/b", nil, callerHeaders, proxyReq)
+	require.NoError(t, err)
+
+	mockClient.AssertExpectations(t)
+}
+
+func TestService_HandleRequest_UpstreamMethod(t *testing.T) {
+	mockConfig := &MockConfigProvider{}
+	mockClient := &MockHTTPClient{}
+	transformer := transform.NewUnifiedTransformer()
+	logger, _ := logging.NewLogger("error")
+
+	service := NewService(mockConfig, mockClient, transformer, logger)
+
+	endpoint := &models.Endpoint{
+		Name:           "search-service",
+		Target:         "https://api.example.com",
+		UpstreamMethod: "post",
+		CacheTTL:       60,
+	}
+	mockConfig.On("GetEndpoint", "search-service").Return(endpoint, true)
+
+	proxyReq := &models.ProxyRequest{
+		Method:             "GET",
+		Body:               map[string]interface{}{"term": "jq"},
+		TransformationMode: models.TransformationModeJQ,
+		JQQuery:            ".hits",
+	}
+	response := &client.Response{
+		StatusCode: 200,
+		Headers:    http.Header{"Content-Type": []string{"application/json"}},
+		Body:       []byte(`{"hits": 3}`),
+	}
+
+	// The endpoint's method replaces the envelope's, and the forwarded POST
+	// is neither cached nor shared like a GET would be
+	mockClient.On("ForwardRequest", mock.Anything, "POST", "https://api.example.com", "/search", url.Values(nil),
+		mock.Anything, map[string]interface{}{"term": "jq"}).Return(response, nil).Twice()
+	for i := 0; i < 2; i++ {
+		result, err := service.HandleRequest(context.Background(), "search-service", "/search", nil, nil, proxyReq)
+		require.NoError(t, err)
+		assert.Equal(t, float64(3), result.Data)
+	}
+
+	assert.Equal(t, "GET", proxyReq.Method, "the caller's request is not modified")
+	mockClient.AssertExpectations(t)
+}
+
+func TestService_HandleRequest_DefaultBody(t *testing.T) {
+	mockConfig := &MockConfigProvider{}
+	mockClient := &MockHTTPClient{}
+	transformer := transform.NewUnifiedTransformer()
+	logger, _ := logging.NewLogger("error")
+
+	service := NewService(mockConfig, mockClient, transformer, logger)
+
+	endpoint := &models.Endpoint{
+		Name:        "events",
+		Target:      "https://api.example.com",
+		DefaultBody: map[string]interface{}{"source": "proxy", "priority": "low"},
+	}
+	mockConfig.On("GetEndpoint", "events").Return(endpoint, true)
+
+	proxyReq := &models.ProxyRequest{
+		Method:             "POST",
+		Body:               map[string]interface{}{"event": "signup", "priority": "high"},
+		TransformationMode: models.TransformationModeJQ,
+		JQQuery:            ".",
+	}
+	response := &client.Response{
+		StatusCode: 200,
+		Headers:    http.Header{"Content-Type": []string{"application/json"}},
+		Body:       []byte(`{"ok": true}`),
+	}
+
+	mockClient.On("ForwardRequest", mock.Anything, "POST", "https://api.example.com", "/events", url.Values(nil),
+		mock.Anything, map[string]interface{}{"event": "signup", "priority": "high", "source": "proxy"}).Return(response, nil).Once()
+
+	_, err := service.HandleRequest(context.Background(), "events", "/events", nil, nil, proxyReq)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{"event": "signup", "priority": "high"}, proxyReq.Body, "the caller's request is not modified")
+	mockClient.AssertExpectations(t)
+}
+
+func TestService_HandleRequest_IdempotencyKey(t *testing.T) {
+	var calls atomic.Int64
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"order": %d}`, calls.Add(1))
+	}))
+	defer upstream.Close()
+
+	mockConfig := &MockConfigProvider{}
+	service := NewService(mockConfig, client.NewClient(5*time.Second), transform.NewUnifiedTransformer(), createTestLogger())
+
+	endpoint := &models.Endpoint{Name: "orders", Target: upstream.URL, IdempotencyTTL: 60}
+	mockConfig.On("GetEndpoint", "orders").Return(endpoint, true)
+
+	item := "book"
+	post := func(method string, headers http.Header) *models.ProxyResponse {
+		proxyReq := &models.ProxyRequest{
+			Method:             method,
+			Body:               map[string]interface{}{"item": item},
+			TransformationMode: models.TransformationModeJQ,
+			JQQuery:            ".order",
+		}
+		response, err := service.HandleRequest(context.Background(), "orders", "/orders", nil, headers, proxyReq)
+		require.NoError(t, err)
+		return response
+	}
+
+	first := post("POST", http.Header{"Idempotency-Key": []string{"abc"}})
+	assert.Equal(t, float64(1), first.Data)
+	assert.False(t, first.Replayed)
+
+	// A duplicate key returns the first response without forwarding
+	duplicate := post("POST", http.Header{"Idempotency-Key": []string{"abc"}})
+	assert.Equal(t, float64(1), duplicate.Data)
+	assert.Equal(t, http.StatusCreated, duplicate.Status)
+	assert.True(t, duplicate.Replayed)
+	assert.Zero(t, duplicate.UpstreamDuration)
+	assert.Equal(t, int64(1), calls.Load())
+
+	// Other keys, other callers, requests without a key and reads are forwarded
+	assert.Equal(t, float64(2), post("POST", http.Header{"Idempotency-Key": []string{"def"}}).Data)
+	assert.Equal(t, float64(3), post("POST", http.Header{"Idempotency-Key": []string{"abc"}, "Authorization": []string{"Bearer other"}}).Data)
+	assert.Equal(t, float64(4), post("POST", nil).Data)
+	assert.Equal(t, float64(5), post("GET", http.Header{"Idempotency-Key": []string{"abc"}}).Data)
+	assert.Equal(t, float64(6), post("GET", http.Header{"Idempotency-Key": []string{"abc"}}).Data)
+
+	// A key reused for another method or body is a different request
+	assert.Equal(t, float64(7), post("PUT", http.Header{"Idempotency-Key": []string{"abc"}}).Data)
+	item = "pen"
+	assert.Equal(t, float64(8), post("POST", http.Header{"Idempotency-Key": []string{"abc"}}).Data)
+	assert.Equal(t, float64(8), post("POST", http.Header{"Idempotency-Key": []string{"abc"}}).Data)
+	item = "book"
+	assert.Equal(t, float64(1), post("POST", http.Header{"Idempotency-Key": []string{"abc"}}).Data)
+
+	// Endpoints without an idempotency TTL forward every call
+	endpoint.IdempotencyTTL = 0
+	assert.Equal(t, float64(9), post("PUT", http.Header{"Idempotency-Key": []string{"ghi"}}).Data)
+	assert.Equal(t, float64(10), post("PUT", http.Header{"Idempotency-Key": []string{"ghi"}}).Data)
+}
+
+func TestService_HandleRequest_IdempotencyKeyFailures(t *testing.T) {
+	mockConfig := &MockConfigProvider{}
+	mockClient := &MockHTTPClient{}
+	service := NewService(mockConfig, mockClient, transform.NewUnifiedTransformer(), createTestLogger())
+
+	endpoint := &models.Endpoint{Name: "orders", Target: "https://api.example.com", IdempotencyTTL: 60}
+	mockConfig.On("GetEndpoint", "orders").Return(endpoint, true)
+	mockClient.On("ForwardRequest", mock.Anything, "POST", "https://api.example.com", "/orders", mock.Anything, mock.Anything, mock.Anything).
+		Return(nil, errors.New("connection refused")).Once()
+	mockClient.On("ForwardRequest", mock.Anything, "POST", "https://api.example.com", "/orders", mock.Anything, mock.Anything, mock.Anything).
+		Return(&client.Response{
+			StatusCode: http.StatusCreated,
+			Headers:    http.Header{"Content-Type": []string{"application/json"}},
+			Body:       []byte(`{"order": 1}`),
+		}, nil).Once()
+
+	headers := http.Header{"Idempotency-Key": []string{"abc"}}
+	proxyReq := &models.ProxyRequest{Method: "POST", TransformationMode: models.TransformationModeJQ, JQQuery: ".order"}
+
+	// A failed call is not kept, so the client can retry with the same key
+	_, err := service.HandleRequest(context.Background(), "orders", "/orders", nil, headers, proxyReq)
+	require.Error(t, err)
+	response, err := service.HandleRequest(context.Background(), "orders", "/orders", nil, headers, proxyReq)
+	require.NoError(t, err)
+	assert.Equal(t, float64(1), response.Data)
+	mockClient.AssertExpectations(t)
+}
+
+func TestService_HandleRequest_IdempotencyKeyErrorStatus(t *testing.T) {
+	mockConfig := &MockConfigProvider{}
+	mockClient := &MockHTTPClient{}
+	service := NewService(mockConfig, mockClient, transform.NewUnifiedTransformer(), createTestLogger())
+
+	endpoint := &models.Endpoint{Name: "orders", Target: "https://api.example.com", IdempotencyTTL: 60}
+	mockConfig.On("GetEndpoint", "orders").Return(endpoint, true)
+	mockClient.On("ForwardRequest", mock.Anything, "POST", "https://api.example.com", "/orders", mock.Anything, mock.Anything, mock.Anything).
+		Return(&client.Response{
+			StatusCode: http.StatusServiceUnavailable,
+			Headers:    http.Header{"Content-Type": []string{"application/json"}},
+			Body:       []byte(`{"error": "busy"}`),
+		}, nil).Once()
+	mockClient.On("ForwardRequest", mock.Anything, "POST", "https://api.example.com", "/orders", mock.Anything, mock.Anything, mock.Anything).
+		Return(&client.Response{
+			StatusCode: http.StatusCreated,
+			Headers:    http.Header{"Content-Type": []string{"application/json"}},
+			Body:       []byte(`{"order": 1}`),
+		}, nil).Once()
+
+	headers := http.Header{"Idempotency-Key": []string{"abc"}}
+	proxyReq := &models.ProxyRequest{Method: "POST", TransformationMode: models.TransformationModeJQ, JQQuery: "."}
+
+	// A 5xx response is not kept, so the retry reaches the upstream
+	response, err := service.HandleRequest(context.Background(), "orders", "/orders", nil, headers, proxyReq)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, response.Status)
+
+	response, err = service.HandleRequest(context.Background(), "orders", "/orders", nil, headers, proxyReq)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, response.Status)
+	assert.False(t, response.Replayed)
+
+	// The successful response is kept
+	response, err = service.HandleRequest(context.Background(), "orders", "/orders", nil, headers, proxyReq)
+	require.NoError(t, err)
+	assert.True(t, response.Replayed)
+	mockClient.AssertExpectations(t)
+}
+
+func TestService_HandleRequest_IdempotencyKeyConcurrent(t *testing.T) {
+	mockConfig := &MockConfigProvider{}
+	mockClient := &MockHTTPClient{}
+	service := NewService(mockConfig, mockClient, transform.NewUnifiedTransformer(), createTestLogger())
+
+	endpoint := &models.Endpoint{Name: "orders", Target: "https://api.example.com", IdempotencyTTL: 60}
+	mockConfig.On("GetEndpoint", "orders").Return(endpoint, true)
+
+	release := make(chan struct{})
+	mockClient.On("ForwardRequest", mock.Anything, "POST", "https://api.example.com", "/orders", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { <-release }).
+		Return(&client.Response{
+			StatusCode: http.StatusCreated,
+			Headers:    http.Header{"Content-Type": []string{"application/json"}},
+			Body:       []byte(`{"order": 1}`),
+		}, nil).Once()
+
+	// Double-submits arriving together are forwarded once
+	const submits = 4
+	responses := make([]*models.ProxyResponse, submits)
+	errs := make([]error, submits)
+	var wg sync.WaitGroup
+	for i := 0; i < submits; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			proxyReq := &models.ProxyRequest{Method: "POST", TransformationMode: models.TransformationModeJQ, JQQuery: ".order"}
+			responses[i], errs[i] = service.HandleRequest(context.Background(), "orders", "/orders", nil,
+				http.Header{"Idempotency-Key": []string{"abc"}}, proxyReq)
+		}(i)
+	}
+
+	// Give every request time to join the in-flight call
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	replays := 0
+	for i := 0; i < submits; i++ {
+		require.NoError(t, errs[i])
+		assert.Equal(t, float64(1), responses[i].Data)
+		if responses[i].Replayed {
+			replays++
+		}
+	}
+	assert.Equal(t, submits-1, replays)
+	mockClient.AssertNumberOfCalls(t, "ForwardRequest", 1)
+}
+
+func TestService_HandleRequest_SharesConcurrentReads(t *testing.T) {
+	mockConfig := &MockConfigProvider{}
+	mockClient := &MockHTTPClient{}
+	transformer := transform.NewUnifiedTransformer()
+	logger, _ := logging.NewLogger("error")
+
+	service := NewService(mockConfig, mockClient, transformer, logger)
+
+	endpoint := &models.Endpoint{
+		Name:   "test-service",
+		Target: "https://api.example.com",
+	}
+	mockConfig.On("GetEndpoint", "test-service").Return(endpoint, true)
+
+	release := make(chan struct{})
+	mockClient.On("ForwardRequest", mock.Anything, "GET", "https://api.example.com", "/users/1", url.Values(nil), http.Header(nil), nil).
+		Run(func(args mock.Arguments) { <-release }).
+		Return(&client.Response{
+			StatusCode: 200,
+			Headers:    http.Header{"Content-Type": []string{"application/json"}},
+			Body:       []byte(`{"id": 1, "name": "John"}`),
+		}, nil).Once()
+
+	// Identical reads with different queries share the upstream call but are transformed independently
+	queries := []string{".id", ".name", ".id", ".name", ".id", ".name"}
+	results := make([]interface{}, len(queries))
+	errs := make([]error, len(queries))
+	var wg sync.WaitGroup
+	for i, query := range queries {
+		wg.Add(1)
+		go func(i int, query string) {
+			defer wg.Done()
+			proxyReq := &models.ProxyRequest{
+				Method:             "GET",
+				TransformationMode: models.TransformationModeJQ,
+				JQQuery:            query,
+			}
+			resp, err := service.HandleRequest(context.Background(), "test-service", "/users/1", nil, nil, proxyReq)
+			errs[i] = err
+			if resp != nil {
+				results[i] = resp.Data
+			}
+		}(i, query)
+	}
+
+	// Give every request time to join the in-flight call
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	for i, query := range queries {
+		require.NoError(t, errs[i])
+		if query == ".id" {
+			assert.Equal(t, float64(1), results[i])
+		} else {
+			assert.Equal(t, "John", results[i])
+		}
+	}
+	mockClient.AssertNumberOfCalls(t, "ForwardRequest", 1)
+	assert.Equal(t, int64(len(queries)-1), logger.GetMetrics().GetMetrics().TotalCoalesced)
+}
+
+func TestService_HandleRequest_SharedReadOutlivesFirstCaller(t *testing.T) {
+	mockConfig := &MockConfigProvider{}
+	mockClient := &MockHTTPClient{}
+	transformer := transform.NewUnifiedTransformer()
+	logger, _ := logging.NewLogger("error")
+
+	service := NewService(mockConfig, mockClient, transformer, logger)
+
+	endpoint := &models.Endpoint{
+		Name:   "test-service",
+		Target: "https://api.example.com",
+	}
+	mockConfig.On("GetEndpoint", "test-service").Return(endpoint, true)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	upstreamErr := make(chan error, 1)
+	mockClient.On("ForwardRequest", mock.Anything, "GET", "https://api.example.com", "/users/1", url.Values(nil), http.Header(nil), nil).
+		Run(func(args mock.Arguments) {
+			close(started)
+			<-release
+			upstreamErr <- args.Get(0).(context.Context).Err()
+		}).
+		Return(&client.Response{
+			StatusCode: 200,
+			Headers:    http.Header{"Content-Type": []string{"application/json"}},
+			Body:       []byte(`{"id": 1}`),
+		}, nil).Once()
+
+	proxyReq := &models.ProxyRequest{
+		Method:             "GET",
+		TransformationMode: models.TransformationModeJQ,
+		JQQuery:            ".id",
+	}
+
+	// The first caller starts the upstream call, then goes away
+	firstCtx, cancelFirst := context.WithCancel(context.Background())
+	firstDone := make(chan error, 1)
+	go func() {
+		_, err := service.HandleRequest(firstCtx, "test-service", "/users/1", nil, nil, proxyReq)
+		firstDone <- err
+	}()
+	<-started
+
+	secondDone := make(chan *models.ProxyResponse, 1)
+	go func() {
+		resp, err := service.HandleRequest(context.Background(), "test-service", "/users/1", nil, nil, proxyReq)
+		assert.NoError(t, err)
+		secondDone <- resp
+	}()
+
+	// Give the second caller time to join the in-flight call
+	time.Sleep(50 * time.Millisecond)
+	cancelFirst()
+	assert.ErrorIs(t, <-firstDone, context.Canceled)
+
+	close(release)
+	assert.NoError(t, <-upstreamErr, "the shared call is not cancelled with its first caller")
+	resp := <-secondDone
+	require.NotNil(t, resp)
+	assert.Equal(t, float64(1), resp.Data)
+	mockClient.AssertNumberOfCalls(t, "ForwardRequest", 1)
+}
+
+func TestService_HandleRequest_DoesNotShareWrites(t *testing.T) {
+	mockConfig := &MockConfigProvider{}
+	mockClient := &MockHTTPClient{}
+	transformer := transform.NewUnifiedTransformer()
+	logger, _ := logging.NewLogger("error")
+
+	service := NewService(mockConfig, mockClient, transformer, logger)
+
+	endpoint := &models.Endpoint{
+		Name:   "test-service",
+		Target: "https://api.example.com",
+	}
+	mockConfig.On("GetEndpoint", "test-service").Return(endpoint, true)
+
+	// Each upstream call creates a different user
+	release := make(chan struct{})
+	var created int32
+	mockClient.On("ForwardRequest", mock.Anything, "POST", "https://api.example.com", "/users", url.Values(nil), http.Header(nil), mock.Anything).
+		Run(func(args mock.Arguments) { <-release }).
+		Return(func() *client.Response {
+			id := atomic.AddInt32(&created, 1)
+			return &client.Response{
+				StatusCode: 201,
+				Headers:    http.Header{"Content-Type": []string{"application/json"}},
+				Body:       []byte(fmt.Sprintf(`{"id": %d}`, id)),
+			}
+		}, nil)
+
+	var wg sync.WaitGroup
+	ids := make([]interface{}, 3)
+	for i := range ids {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			proxyReq := &models.ProxyRequest{
+				Method:             "POST",
+				Body:               map[string]interface{}{"name": "John"},
+				TransformationMode: models.TransformationModeJQ,
+				JQQuery:            ".id",
+			}
+			result, err := service.HandleRequest(context.Background(), "test-service", "/users", nil, nil, proxyReq)
+			if assert.NoError(t, err) {
+				ids[i] = result.Data
+			}
+		}(i)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	mockClient.AssertNumberOfCalls(t, "ForwardRequest", 3)
+	assert.ElementsMatch(t, []interface{}{float64(1), float64(2), float64(3)}, ids, "each caller gets its own upstream response")
+}
+
+func TestUpstreamReadKey_IncludesCredentials(t *testing.T) {
+	query := url.Values{"page": []string{"1"}}
+	auth := func(token string) http.Header { return http.Header{"Authorization": []string{"Bearer " + token}} }
+	base := upstreamReadKey("svc", "", "/users", "", query, auth("a"))
+
+	assert.Equal(t, base, upstreamReadKey("svc", "", "/users", "", url.Values{"page": []string{"1"}}, auth("a")))
+	assert.NotEqual(t, base, upstreamReadKey("svc", "", "/users", "", query, auth("b")))
+	assert.NotEqual(t, base, upstreamReadKey("svc", "", "/users", "", url.Values{"page": []string{"2"}}, auth("a")))
+	assert.NotEqual(t, base, upstreamReadKey("other", "", "/users", "", query, auth("a")))
+	assert.NotEqual(t, base, upstreamReadKey("svc", "https://acme.example.com", "/users", "", query, auth("a")))
+	assert.NotEqual(t, base, upstreamReadKey("svc", "", "/users", "{tenant}", query, auth("a")))
+}
+
+func TestUpstreamReadKey_IncludesForwardedHeaders(t *testing.T) {
+	query := url.Values{"page": []string{"1"}}
+	base := upstreamReadKey("svc", "", "/users", "", query, http.Header{"Accept-Language": []string{"en"}})
+
+	assert.NotEqual(t, base, upstreamReadKey("svc", "", "/users", "", query, http.Header{"Accept-Language": []string{"fr"}}))
+	assert.NotEqual(t, base, upstreamReadKey("svc", "", "/users", "", query, http.Header{"Accept-Language": []string{"en"}, "X-Tenant": []string{"acme"}}))
+	assert.NotEqual(t, base, upstreamReadKey("svc", "", "/users", "", query, http.Header{"Accept-Language": []string{"en", "fr"}}))
+
+	// jpx- headers are not forwarded, so they do not keep requests apart
+	assert.Equal(t, base, upstreamReadKey("svc", "", "/users", "", query, http.Header{"Accept-Language": []string{"en"}, "Jpx-Jq-Query": []string{".id"}}))
+}
+
+func TestService_HandleRequest_RequestQuery(t *testing.T) {
+	mockConfig := &MockConfigProvider{}
+	mockClient := &MockHTTPClient{}
+	transformer := transform.NewUnifiedTransformer()
+	logger, _ := logging.NewLogger("error")
+
+	service := NewService(mockConfig, mockClient, transformer, logger)
+
+	endpoint := &models.Endpoint{
+		Name:   "test-service",
+		Target: "https://api.example.com",
+	}
+
+	proxyReq := &models.ProxyRequest{
+		Method:             "POST",
+		Body:               map[string]interface{}{"first": "John", "last": "Doe", "age": float64(30)},
+		TransformationMode: models.TransformationModeJQ,
+		JQQuery:            ".",
+		RequestJQQuery:     "{user: {name: \"\\(.first) \\(.last)\"}, age}",
+	}
+
+	// The upstream receives the rewritten body
+	expectedBody := map[string]interface{}{
+		"user": map[string]interface{}{"name": "John Doe"},
+		"age":  float64(30),
+	}
+
+	mockConfig.On("GetEndpoint", "test-service").Return(endpoint, true)
+	mockClient.On("ForwardRequest", mock.Anything, "POST", "https://api.example.com", "/users", url.Values(nil), http.Header(nil), expectedBody).
+		Return(&client.Response{StatusCode: 201, Body: []byte(`created`)}, nil)
+
+	result, err := service.HandleRequest(context.Background(), "test-service", "/users", nil, nil, proxyReq)
+	require.NoError(t, err)
+	assert.Equal(t, 201, result.Status)
+
+	// The client's request is left untouched
+	assert.Equal(t, "John", proxyReq.Body.(map[string]interface{})["first"])
+	mockClient.AssertExpectations(t)
+}
+
+func TestService_HandleRequest_RequestQueryFailure(t *testing.T) {
+	mockConfig := &MockConfigProvider{}
+	mockClient := &MockHTTPClient{}
+	transformer := transform.NewUnifiedTransformer()
+	logger, _ := logging.NewLogger("error")
+
+	service := NewService(mockConfig, mockClient, transformer, logger)
+
+	mockConfig.On("GetEndpoint", "test-service").Return(&models.Endpoint{
+		Name:   "test-service",
+		Target: "https://api.example.com",
+	}, true)
+
+	proxyReq := &models.ProxyRequest{
+		Method:             "POST",
+		Body:               map[string]interface{}{"name": "John"},
+		TransformationMode: models.TransformationModeJQ,
+		JQQuery:            ".",
+		RequestJQQuery:     ".name | error",
+	}
+
+	_, err := service.HandleRequest(context.Background(), "test-service", "/users", nil, nil, proxyReq)
+	transformErr, ok := err.(*TransformationError)
+	require.True(t, ok)
+	assert.Contains(t, transformErr.Message, "Failed to transform request body")
+
+	// Nothing is sent upstream
+	mockClient.AssertNotCalled(t, "ForwardRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestService_HandleRequest_WithQueryParamsAndHeaders(t *testing.T) {
+	// Setup mocks
+	mockConfig := &MockConfigProvider{}
+	mockClient := &MockHTTPClient{}
+	transformer := transform.NewUnifiedTransformer()
+	logger, _ := logging.NewLogger("error")
+
+	service := NewService(mockConfig, mockClient, transformer, logger)
+
+	endpoint := &models.Endpoint{
+		Name:   "test-service",
+		Target: "https://api.example.com",
+	}
+
+	proxyReq := &models.ProxyRequest{
+		Method:             "POST",
+		Body:               map[string]interface{}{"name": "John"},
+		TransformationMode: models.TransformationModeJQ,
+		JQQuery:            "{id: .id}",
+	}
+
+	queryParams := url.Values{
+		"limit": []string{"10"},
+		"page":  []string{"1"},
+	}
+
+	headers := http.Header{
+		"Authorization": []string{"Bearer token"},
+		"Content-Type":  []string{"application/json"},
+	}
+
+	transformedData := map[string]interface{}{
+		"id": float64(123),
+	}
+
+	httpResponse := &client.Response{
+		StatusCode: 201,
+		Headers:    http.Header{"Content-Type": []string{"application/json"}},
+		Body:       []byte(`{"id":123,"name":"John"}`),
+	}
+
+	// Setup expectations
+	mockConfig.On("GetEndpoint", "test-service").Return(endpoint, true)
+	mockClient.On("ForwardRequest", mock.Anything, "POST", "https://api.example.com", "/users", queryParams, headers, map[string]interface{}{"name": "John"}).Return(httpResponse, nil)
+
+	// Execute
+	ctx := context.Background()
+	result, err := service.HandleRequest(ctx, "test-service", "/users", queryParams, headers, proxyReq)
+
+	// Assert
+	require.NoError(t, err)
+	assert.NotNil(t, result)
+	assert.Equal(t, 201, result.Status)
+	assert.Equal(t, transformedData, result.Data)
+
+	mockConfig.AssertExpectations(t)
+	mockClient.AssertExpectations(t)
+}
+
+func TestService_HandleRequest_EnvelopeQuery(t *testing.T) {
+	mockConfig := &MockConfigProvider{}
+	mockClient := &MockHTTPClient{}
+	logger, _ := logging.NewLogger("error")
+	service := NewService(mockConfig, mockClient, transform.NewUnifiedTransformer(), logger)
+
+	endpoint := &models.Endpoint{
+		Name:   "test-service",
+		Target: "https://api.example.com",
+	}
+
+	proxyReq, err := models.ParseProxyRequest([]byte(`{
+		"method": "GET",
+		"jq_query": ".",
+		"query": {"page": "2", "tag": ["a", "b"]}
+	}`))
+	require.NoError(t, err)
+
+	// Envelope parameters are merged in and replace URL parameters of the same name
+	urlParams := url.Values{"limit": []string{"10"}, "page": []string{"1"}}
+	expectedParams := url.Values{
+		"limit": []string{"10"},
+		"page":  []string{"2"},
+		"tag":   []string{"a", "b"},
+	}
+
+	mockConfig.On("GetEndpoint", "test-service").Return(endpoint, true)
+	mockClient.On("ForwardRequest", mock.Anything, "GET", "https://api.example.com", "/items", expectedParams, http.Header(nil), nil).
+		Return(&client.Response{
+			StatusCode: 200,
+			Headers:    http.Header{"Content-Type": []string{"application/json"}},
+			Body:       []byte(`[]`),
+		}, nil)
+
+	_, err = service.HandleRequest(context.Background(), "test-service", "/items", urlParams, nil, proxyReq)
+	require.NoError(t, err)
+
+	// The caller's URL parameters are left untouched
+	assert.Equal(t, []string{"1"}, urlParams["page"])
+	mockClient.AssertExpectations(t)
+}
+
+func TestService_HandleRequest_ResponseCache(t *testing.T) {
+	// Setup mocks
+	mockConfig := &MockConfigProvider{}
+	mockClient := &MockHTTPClient{}
+	transformer := transform.NewUnifiedTransformer()
+	logger, _ := logging.NewLogger("error")
+
+	service := NewService(mockConfig, mockClient, transformer, logger)
+	svc := service.(*Service)
+
+	// Control the cache clock so expiry can be tested deterministically
+	now := time.Now()
+	svc.responseCache = cache.New(10)
+	svc.responseCache.SetClock(func() time.Time { return now })
+
+	endpoint := &models.Endpoint{
+		Name:     "test-service",
+		Target:   "https://api.example.com",
+		CacheTTL: 60,
+	}
+
+	proxyReq := &models.ProxyRequest{
+		Method:             "GET",
+		TransformationMode: models.TransformationModeJQ,
+		JQQuery:            "{name: .name}",
+	}
+
+	httpResponse := &client.Response{
+		StatusCode: 200,
+		Headers:    http.Header{"Content-Type": []string{"application/json"}},
+		Body:       []byte(`{"name":"John"}`),
+	}
+
+	mockConfig.On("GetEndpoint", "test-service").Return(endpoint, true)
+	mockClient.On("ForwardRequest", mock.Anything, "GET", "https://api.example.com", "/users/1", url.Values(nil), http.Header(nil), nil).Return(httpResponse, nil)
+
+	ctx := context.Background()
+
+	// Miss: forwarded upstream
+	result, err := service.HandleRequest(ctx, "test-service", "/users/1", nil, nil, proxyReq)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"name": "John"}, result.Data)
+	mockClient.AssertNumberOfCalls(t, "ForwardRequest", 1)
+
+	// Hit: served from cache within TTL
+	now = now.Add(59 * time.Second)
+	result, err = service.HandleRequest(ctx, "test-service", "/users/1", nil, nil, proxyReq)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"name": "John"}, result.Data)
+	mockClient.AssertNumberOfCalls(t, "ForwardRequest", 1)
+	assert.Equal(t, int64(1), logger.GetMetrics().GetMetrics().Endpoints["test-service"].CacheHits)
+	assert.Equal(t, int64(1), logger.GetMetrics().GetMetrics().Endpoints["test-service"].CacheMisses)
+
+	// Changes to a served response do not reach the cached entry
+	result.Status = http.StatusTeapot
+	result.Cookies = []string{"session=abc"}
+	result, err = service.HandleRequest(ctx, "test-service", "/users/1", nil, nil, proxyReq)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, result.Status)
+	assert.Nil(t, result.Cookies)
+	mockClient.AssertNumberOfCalls(t, "ForwardRequest", 1)
+
+	// A different query is a different cache entry
+	otherReq := *proxyReq
+	otherReq.JQQuery = ".name"
+	_, err = service.HandleRequest(ctx, "test-service", "/users/1", nil, nil, &otherReq)
+	require.NoError(t, err)
+	mockClient.AssertNumberOfCalls(t, "ForwardRequest", 2)
+
+	// Expiry: forwarded upstream again
+	now = now.Add(2 * time.Second)
+	_, err = service.HandleRequest(ctx, "test-service", "/users/1", nil, nil, proxyReq)
+	require.NoError(t, err)
+	mockClient.AssertNumberOfCalls(t, "ForwardRequest", 3)
+
+	// Purge: forwarded upstream again
+	assert.Equal(t, 2, svc.PurgeCache("test-service"))
+	_, err = service.HandleRequest(ctx, "test-service", "/users/1", nil, nil, proxyReq)
+	require.NoError(t, err)
+	mockClient.AssertNumberOfCalls(t, "ForwardRequest", 4)
+
+	snapshot := logger.GetMetrics().GetMetrics()
+	assert.Equal(t, int64(2), snapshot.TotalCacheHits)
+	assert.Equal(t, int64(4), snapshot.TotalCacheMisses)
+	assert.InDelta(t, 1.0/3, snapshot.CacheHitRatio, 0.0001)
+}
+
+func TestService_HandleRequest_ResponseCacheResultLimit(t *testing.T) {
+	mockConfig := &MockConfigProvider{}
+	mockClient := &MockHTTPClient{}
+	service := NewService(mockConfig, mockClient, transform.NewUnifiedTransformer(), createTestLogger())
+
+	endpoint := &models.Endpoint{Name: "test-service", Target: "https://api.example.com", CacheTTL: 60}
+	mockConfig.On("GetEndpoint", "test-service").Return(endpoint, true)
+	mockClient.On("ForwardRequest", mock.Anything, "GET", "https://api.example.com", "/users/1", url.Values(nil), http.Header(nil), nil).
+		Return(&client.Response{
+			StatusCode: http.StatusOK,
+			Headers:    http.Header{"Content-Type": []string{"application/json"}},
+			Body:       []byte(`{"name":"John"}`),
+		}, nil)
+
+	proxyReq := &models.ProxyRequest{
+		Method:             "GET",
+		TransformationMode: models.TransformationModeJQ,
+		JQQuery:            ".",
+	}
+	_, err := service.HandleRequest(context.Background(), "test-service", "/users/1", nil, nil, proxyReq)
+	require.NoError(t, err)
+
+	// A request with a lower result limit is not served the cached result
+	limited := *proxyReq
+	limited.MaxResultBytes = 5
+	_, err = service.HandleRequest(context.Background(), "test-service", "/users/1", nil, nil, &limited)
+	transformErr, ok := err.(*TransformationError)
+	require.True(t, ok)
+	assert.Equal(t, "RESULT_TOO_LARGE", transformErr.ErrorCode())
+	mockClient.AssertNumberOfCalls(t, "ForwardRequest", 2)
+}
+
+func TestService_HandleRequest_PathPattern(t *testing.T) {
+	mockConfig := &MockConfigProvider{}
+	mockClient := &MockHTTPClient{}
+	service := NewService(mockConfig, mockClient, transform.NewUnifiedTransformer(), createTestLogger())
+
+	endpoint := &models.Endpoint{Name: "users", Target: "https://api.example.com"}
+	mockConfig.On("GetEndpoint", "users").Return(endpoint, true)
+	mockClient.On("ForwardRequest", mock.Anything, "GET", "https://api.example.com", "/42/orders", mock.Anything, mock.Anything, mock.Anything).
+		Return(&client.Response{
+			StatusCode: http.StatusOK,
+			Headers:    http.Header{"Content-Type": []string{"application/json"}},
+			Body:       []byte(`[{"id": "a", "total": 10}, {"id": "b", "total": 5}]`),
+		}, nil)
+
+	proxyReq := &models.ProxyRequest{
+		Method:             "GET",
+		TransformationMode: models.TransformationModeJQ,
+		JQQuery:            `{user_id: $id, order_ids: map(.id)}`,
+		PathPattern:        "{id}/orders",
+	}
+
+	result, err := service.HandleRequest(context.Background(), "users", "/42/orders", nil, nil, proxyReq)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"user_id": "42", "order_ids": []interface{}{"a", "b"}}, result.Data)
+
+	// A path that does not fit the pattern fails before the target is called
+	_, err = service.HandleRequest(context.Background(), "users", "/42/invoices", nil, nil, proxyReq)
+	var transformErr *TransformationError
+	require.ErrorAs(t, err, &transformErr)
+	assert.Contains(t, transformErr.Message, "does not match path_pattern")
+	mockClient.AssertNumberOfCalls(t, "ForwardRequest", 1)
+}
+
+func TestService_HandleRequest_EndpointJQVariables(t *testing.T) {
+	mockConfig := &MockConfigProvider{}
+	mockClient := &MockHTTPClient{}
+	service := NewService(mockConfig, mockClient, transform.NewUnifiedTransformer(), createTestLogger())
+
+	endpoint := &models.Endpoint{
+		Name:        "users",
+		Target:      "https://api.example.com",
+		JQVariables: map[string]interface{}{"tenant": "acme", "id": "default"},
+	}
+	mockConfig.On("GetEndpoint", "users").Return(endpoint, true)
+	mockClient.On("ForwardRequest", mock.Anything, "GET", "https://api.example.com", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(&client.Response{
+			StatusCode: http.StatusOK,
+			Headers:    http.Header{"Content-Type": []string{"application/json"}},
+			Body:       []byte(`{"name": "Jane"}`),
+		}, nil)
+
+	// Endpoint variables are available without a path pattern
+	proxyReq := &models.ProxyRequest{
+		Method:             "GET",
+		TransformationMode: models.TransformationModeJQ,
+		JQQuery:            `{name, tenant: $tenant, id: $id}`,
+	}
+	result, err := service.HandleRequest(context.Background(), "users", "/users/42", nil, nil, proxyReq)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"name": "Jane", "tenant": "acme", "id": "default"}, result.Data)
+
+	// Path parameters override endpoint variables of the same name
+	proxyReq.PathPattern = "users/{id}"
+	result, err = service.HandleRequest(context.Background(), "users", "/users/42", nil, nil, proxyReq)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"name": "Jane", "tenant": "acme", "id": "42"}, result.Data)
+	assert.Nil(t, proxyReq.Variables, "the caller's request is not modified")
+}
+
+func TestService_HandleRequest_HeaderQueryParams(t *testing.T) {
+	mockConfig := &MockConfigProvider{}
+	mockClient := &MockHTTPClient{}
+	service := NewService(mockConfig, mockClient, transform.NewUnifiedTransformer(), createTestLogger())
+
+	endpoint := &models.Endpoint{
+		Name:              "users",
+		Target:            "https://api.example.com",
+		HeaderQueryParams: map[string]string{"X-Tenant": "tenant"},
+	}
+	mockConfig.On("GetEndpoint", "users").Return(endpoint, true)
+	mockClient.On("ForwardRequest", mock.Anything, "GET", "https://api.example.com", "/users",
+		url.Values{"page": {"2"}, "tenant": {"acme"}}, mock.Anything, mock.Anything).
+		Return(&client.Response{
+			StatusCode: http.StatusOK,
+			Headers:    http.Header{"Content-Type": []string{"application/json"}},
+			Body:       []byte(`[]`),
+		}, nil)
+
+	proxyReq := &models.ProxyRequest{
+		Method:             "GET",
+		TransformationMode: models.TransformationModeJQ,
+		JQQuery:            ".",
+	}
+	_, err := service.HandleRequest(context.Background(), "users", "/users",
+		url.Values{"page": {"2"}}, http.Header{"X-Tenant": {"acme"}}, proxyReq)
+	require.NoError(t, err)
+	mockClient.AssertExpectations(t)
+}
+
+func TestService_HandleRequest_TargetVariables(t *testing.T) {
+	mockConfig := &MockConfigProvider{}
+	mockClient := &MockHTTPClient{}
+	service := NewService(mockConfig, mockClient, transform.NewUnifiedTransformer(), createTestLogger())
+
+	endpoint := &models.Endpoint{
+		Name:            "orders",
+		Target:          "https://{region}.example.com/tenants/{tenant}",
+		CacheTTL:        60,
+		TargetVariables: map[string]string{"region": "header:X-Region", "tenant": "path:tenant"},
+	}
+	mockConfig.On("GetEndpoint", "orders").Return(endpoint, true)
+	for _, region := range []string{"eu", "us"} {
+		mockClient.On("ForwardRequest", mock.Anything, "GET", "https://"+region+".example.com/tenants/acme", "/orders/acme",
+			mock.Anything, mock.Anything, mock.Anything).
+			Return(&client.Response{
+				StatusCode: http.StatusOK,
+				Headers:    http.Header{"Content-Type": []string{"application/json"}},
+				Body:       []byte(`{"region": "` + region + `"}`),
+			}, nil).Once()
+	}
+
+	proxyReq := &models.ProxyRequest{
+		Method:             "GET",
+		TransformationMode: models.TransformationModeJQ,
+		JQQuery:            ".region",
+		PathPattern:        "orders/{tenant}",
+	}
+
+	// Each region is fetched and cached on its own
+	for _, region := range []string{"eu", "us", "eu"} {
+		result, err := service.HandleRequest(context.Background(), "orders", "/orders/acme", nil,
+			http.Header{"X-Region": {region}}, proxyReq)
+		require.NoError(t, err)
+		assert.Equal(t, region, result.Data)
+	}
+	mockClient.AssertExpectations(t)
+
+	_, err := service.HandleRequest(context.Background(), "orders", "/orders/acme", nil, http.Header{}, proxyReq)
+	var targetErr *TargetVariableError
+	require.ErrorAs(t, err, &targetErr)
+	assert.Equal(t, http.StatusBadRequest, targetErr.HTTPStatusCode())
+	assert.Equal(t, "MISSING_TARGET_VARIABLE", targetErr.ErrorCode())
+	assert.Equal(t, "target variable region requires a header X-Region", targetErr.Error())
+	mockClient.AssertNumberOfCalls(t, "ForwardRequest", 2)
+}
+
+func TestService_HandleRequest_ResponseVariables(t *testing.T) {
+	mockConfig := &MockConfigProvider{}
+	mockClient := &MockHTTPClient{}
+	service := NewService(mockConfig, mockClient, transform.NewUnifiedTransformer(), createTestLogger())
+
+	endpoint := &models.Endpoint{Name: "users", Target: "https://api.example.com"}
+	mockConfig.On("GetEndpoint", "users").Return(endpoint, true)
+	mockClient.On("ForwardRequest", mock.Anything, "GET", "https://api.example.com", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(&client.Response{
+			StatusCode: http.StatusNotFound,
+			Headers: http.Header{
+				"Content-Type": []string{"application/json"},
+				"X-Request-Id": []string{"abc"},
+			},
+			Body: []byte(`{"message": "no such user"}`),
+		}, nil)
+
+	proxyReq := &models.ProxyRequest{
+		Method:             "GET",
+		TransformationMode: models.TransformationModeJQ,
+		JQQuery:            `{status: $status, found: ($status < 400), request_id: $headers["x-request-id"], message}`,
+	}
+	result, err := service.HandleRequest(context.Background(), "users", "/users/42", nil, nil, proxyReq)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, result.Status)
+	assert.Equal(t, map[string]interface{}{
+		"status":     http.StatusNotFound,
+		"found":      false,
+		"request_id": "abc",
+		"message":    "no such user",
+	}, result.Data)
+	assert.Nil(t, proxyReq.Variables, "the caller's request is not modified")
+}
+
+func TestService_HandleRequest_QueryByResponseHeader(t *testing.T) {
+	// Upstream whose response shape depends on the requested API version
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		version := r.URL.Query().Get("v")
+		w.Header().Set("Content-Type", "application/json")
+		if version != "" {
+			w.Header().Set("X-API-Version", version)
+		}
+		if version == "2" {
+			w.Write([]byte(`{"result": {"users": [{"full_name": "Jane"}]}}`))
+			return
+		}
+		w.Write([]byte(`{"data": [{"name": "John"}]}`))
+	}))
+	defer upstream.Close()
+
+	mockConfig := &MockConfigProvider{}
+	service := NewService(mockConfig, client.NewClient(5*time.Second), transform.NewUnifiedTransformer(), createTestLogger())
+	endpoint := &models.Endpoint{Name: "users", Target: upstream.URL}
+	mockConfig.On("GetEndpoint", "users").Return(endpoint, true)
+
+	tests := []struct {
+		name     string
+		version  string
+		expected interface{}
+	}{
+		{name: "default query without the header", expected: map[string]interface{}{"names": []interface{}{"John"}}},
+		{name: "version 1 query", version: "1", expected: map[string]interface{}{"names": []interface{}{"John"}, "version": "1"}},
+		{name: "version 2 query", version: "2", expected: map[string]interface{}{"names": []interface{}{"Jane"}, "version": "2"}},
+		{name: "default query for an unknown value", version: "3", expected: map[string]interface{}{"names": []interface{}{"John"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxyReq := &models.ProxyRequest{
+				Method:             "GET",
+				TransformationMode: models.TransformationModeJQ,
+				JQQuery:            "{names: [.data[].name]}",
+				JQQueryHeader:      "X-API-Version",
+				JQQueryByHeader: map[string]string{
+					"1": `{names: [.data[].name], version: "1"}`,
+					"2": `{names: [.result.users[].full_name], version: "2"}`,
+				},
+			}
+
+			result, err := service.HandleRequest(context.Background(), "users", "/users", url.Values{"v": {tt.version}}, nil, proxyReq)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result.Data)
+		})
+	}
+}
+
+func TestService_HandleRequest_DetectJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		detectJSON  bool
+		contentType string
+		body        string
+		expected    interface{}
+	}{
+		{
+			name:       "headerless JSON is parsed when detection is on",
+			detectJSON: true,
+			body:       `{"user": {"name": "John"}}`,
+			expected:   "John",
+		},
+		{
+			name:        "JSON sent as text/plain is parsed when detection is on",
+			detectJSON:  true,
+			contentType: "text/plain",
+			body:        `{"user": {"name": "John"}}`,
+			expected:    "John",
+		},
+		{
+			name:       "genuine text stays a string",
+			detectJSON: true,
+			body:       "user John logged in",
+			expected:   "user John logged in",
+		},
+		{
+			name:        "specific content types are not sniffed",
+			detectJSON:  true,
+			contentType: "text/html",
+			body:        `{"user": {"name": "John"}}`,
+			expected:    `{"user": {"name": "John"}}`,
+		},
+		{
+			name:     "headerless JSON stays a string by default",
+			body:     `{"user": {"name": "John"}}`,
+			expected: `{"user": {"name": "John"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockConfig := &MockConfigProvider{}
+			mockClient := &MockHTTPClient{}
+			service := NewService(mockConfig, mockClient, transform.NewUnifiedTransformer(), createTestLogger())
+
+			endpoint := &models.Endpoint{Name: "users", Target: "https://api.example.com", DetectJSON: tt.detectJSON}
+			mockConfig.On("GetEndpoint", "users").Return(endpoint, true)
+			headers := http.Header{}
+			if tt.contentType != "" {
+				headers.Set("Content-Type", tt.contentType)
+			}
+			mockClient.On("ForwardRequest", mock.Anything, "GET", "https://api.example.com", "/users/1", mock.Anything, mock.Anything, mock.Anything).
+				Return(&client.Response{StatusCode: http.StatusOK, Headers: headers, Body: []byte(tt.body)}, nil)
+
+			// Strings pass through the query unchanged, objects have the name extracted
+			proxyReq := &models.ProxyRequest{
+				Method:             "GET",
+				TransformationMode: models.TransformationModeJQ,
+				JQQuery:            "if type == \"object\" then .user.name else . end",
+			}
+
+			result, err := service.HandleRequest(context.Background(), "users", "/users/1", nil, nil, proxyReq)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result.Data)
+		})
+	}
+}
+
+func TestService_HandleRequest_PreciseNumbers(t *testing.T) {
+	body := []byte(`{"id": 1234567890123456789, "big": 123456789012345678901234567890, "price": 9.99}`)
+
+	tests := []struct {
+		name     string
+		precise  bool
+		mode     models.TransformationMode
+		expected string
+	}{
+		{
+			name:     "exact in jq mode",
+			precise:  true,
+			mode:     models.TransformationModeJQ,
+			expected: `{"big":123456789012345678901234567890,"id":1234567890123456789,"next":1234567890123456790,"price":9.99}`,
+		},
+		{
+			name:     "rounded by default",
+			mode:     models.TransformationModeJQ,
+			expected: `{"big":1.2345678901234568e+29,"id":1234567890123456800,"next":1234567890123456800,"price":9.99}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockConfig := &MockConfigProvider{}
+			mockClient := &MockHTTPClient{}
+			service := NewService(mockConfig, mockClient, transform.NewUnifiedTransformer(), createTestLogger())
+
+			endpoint := &models.Endpoint{Name: "orders", Target: "https://api.example.com", PreciseNumbers: tt.precise}
+			mockConfig.On("GetEndpoint", "orders").Return(endpoint, true)
+			mockClient.On("ForwardRequest", mock.Anything, "GET", "https://api.example.com", "/orders/1", mock.Anything, mock.Anything, mock.Anything).
+				Return(&client.Response{StatusCode: http.StatusOK, Headers: http.Header{"Content-Type": []string{"application/json"}}, Body: body}, nil)
+
+			proxyReq := &models.ProxyRequest{
+				Method:             "GET",
+				TransformationMode: tt.mode,
+				JQQuery:            "{id, big, price, next: (.id + 1)}",
+			}
+			result, err := service.HandleRequest(context.Background(), "orders", "/orders/1", nil, nil, proxyReq)
+			require.NoError(t, err)
+
+			encoded, err := json.Marshal(result.Data)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(encoded))
+		})
+	}
+
+	// JMESPath compares numbers as float64, so it gets them as usual
+	mockConfig := &MockConfigProvider{}
+	mockClient := &MockHTTPClient{}
+	service := NewService(mockConfig, mockClient, transform.NewUnifiedTransformer(), createTestLogger())
+	endpoint := &models.Endpoint{Name: "orders", Target: "https://api.example.com", PreciseNumbers: true}
+	mockConfig.On("GetEndpoint", "orders").Return(endpoint, true)
+	mockClient.On("ForwardRequest", mock.Anything, "GET", "https://api.example.com", "/orders", mock.Anything, mock.Anything, mock.Anything).
+		Return(&client.Response{StatusCode: http.StatusOK, Headers: http.Header{"Content-Type": []string{"application/json"}}, Body: []byte(`[{"n": 5}, {"n": 1}]`)}, nil)
+
+	proxyReq := &models.ProxyRequest{
+		Method:             "GET",
+		TransformationMode: models.TransformationModeJMESPath,
+		JMESPathQuery:      "[?n > `2`].n",
+	}
+	result, err := service.HandleRequest(context.Background(), "orders", "/orders", nil, nil, proxyReq)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{float64(5)}, result.Data)
+}
+
+func TestService_HandleRequest_GetWithBody(t *testing.T) {
+	// Search upstream that reads its query from a GET body
+	var received []string
+	var mu sync.Mutex
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, r.Method+" "+string(body))
+		mu.Unlock()
+		var search map[string]interface{}
+		json.Unmarshal(body, &search)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"hits": []interface{}{search["term"]}})
+	}))
+	defer upstream.Close()
+
+	mockConfig := &MockConfigProvider{}
+	logger, _ := logging.NewLogger("error")
+	service := NewService(mockConfig, client.NewClient(5*time.Second), transform.NewUnifiedTransformer(), logger)
+
+	endpoint := &models.Endpoint{Name: "search", Target: upstream.URL, CacheTTL: 60}
+	mockConfig.On("GetEndpoint", "search").Return(endpoint, true)
+
+	search := func(term string) interface{} {
+		proxyReq := &models.ProxyRequest{
+			Method:             "GET",
+			Body:               map[string]interface{}{"term": term},
+			TransformationMode: models.TransformationModeJQ,
+			JQQuery:            ".hits[0]",
+		}
+		result, err := service.HandleRequest(context.Background(), "search", "/_search", nil, nil, proxyReq)
+		require.NoError(t, err)
+		return result.Data
+	}
+
+	// The body reaches the upstream intact, and different bodies are cached apart
+	assert.Equal(t, "jq", search("jq"))
+	assert.Equal(t, "jmespath", search("jmespath"))
+	assert.Equal(t, "jq", search("jq"))
+	assert.Equal(t, []string{`GET {"term":"jq"}`, `GET {"term":"jmespath"}`}, received)
+}
+
+func TestService_HandleRequest_ResponseCacheRevalidation(t *testing.T) {
+	// Upstream that honors If-None-Match for its current ETag
+	var requests, notModified int
+	var lastIfNoneMatch string
+	var mu sync.Mutex
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		lastIfNoneMatch = r.Header.Get("If-None-Match")
+		if lastIfNoneMatch == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(`{"name":"John"}`))
+	}))
+	defer upstream.Close()
+
+	mockConfig := &MockConfigProvider{}
+	logger, _ := logging.NewLogger("error")
+	service := NewService(mockConfig, client.NewClient(5*time.Second), transform.NewUnifiedTransformer(), logger)
+	svc := service.(*Service)
+
+	now := time.Now()
+	svc.responseCache = cache.New(10)
+	svc.responseCache.SetClock(func() time.Time { return now })
+
+	endpoint := &models.Endpoint{Name: "test-service", Target: upstream.URL, CacheTTL: 60}
+	mockConfig.On("GetEndpoint", "test-service").Return(endpoint, true)
+
+	proxyReq := &models.ProxyRequest{
+		Method:             "GET",
+		TransformationMode: models.TransformationModeJQ,
+		JQQuery:            "{name: .name}",
+	}
+	ctx := context.Background()
+
+	// Miss: fetched unconditionally
+	result, err := service.HandleRequest(ctx, "test-service", "/users/1", nil, nil, proxyReq)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"name": "John"}, result.Data)
+	assert.Equal(t, 1, requests)
+	assert.Empty(t, lastIfNoneMatch)
+
+	// Stale: revalidated with the stored ETag and served from the cache on 304
+	now = now.Add(61 * time.Second)
+	result, err = service.HandleRequest(ctx, "test-service", "/users/1", nil, nil, proxyReq)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, result.Status)
+	assert.Equal(t, map[string]interface{}{"name": "John"}, result.Data)
+	assert.Equal(t, 2, requests)
+	assert.Equal(t, 1, notModified)
+	assert.Equal(t, `"v1"`, lastIfNoneMatch)
+
+	// Renewed: fresh again for another TTL
+	now = now.Add(59 * time.Second)
+	_, err = service.HandleRequest(ctx, "test-service", "/users/1", nil, nil, proxyReq)
+	require.NoError(t, err)
+	assert.Equal(t, 2, requests)
+	assert.Equal(t, int64(2), logger.GetMetrics().GetMetrics().Endpoints["test-service"].CacheHits)
+}
+
+func TestService_HandleRequest_ResponseCacheSkipsNonCacheable(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		statusCode int
+		cacheTTL   int
+	}{
+		{name: "caching disabled", method: "GET", statusCode: 200, cacheTTL: 0},
+		{name: "non-GET method", method: "POST", statusCode: 200, cacheTTL: 60},
+		{name: "error status", method: "GET", statusCode: 500, cacheTTL: 60},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockConfig := &MockConfigProvider{}
+			mockClient := &MockHTTPClient{}
+			transformer := transform.NewUnifiedTransformer()
+			logger, _ := logging.NewLogger("error")
+
+			service := NewService(mockConfig, mockClient, transformer, logger)
+
+			endpoint := &models.Endpoint{
+				Name:     "test-service",
+				Target:   "https://api.example.com",
+				CacheTTL: tt.cacheTTL,
+			}
+
+			proxyReq := &models.ProxyRequest{
+				Method:             tt.method,
+				TransformationMode: models.TransformationModeJQ,
+				JQQuery:            ".",
+			}
+
+			httpResponse := &client.Response{
+				StatusCode: tt.statusCode,
+				Headers:    http.Header{"Content-Type": []string{"application/json"}},
+				Body:       []byte(`{}`),
+			}
+
+			mockConfig.On("GetEndpoint", "test-service").Return(endpoint, true)
+			mockClient.On("ForwardRequest", mock.Anything, tt.method, "https://api.example.com", "/data", url.Values(nil), http.Header(nil), nil).Return(httpResponse, nil)
+
+			for i := 0; i < 2; i++ {
+				_, err := service.HandleRequest(context.Background(), "test-service", "/data", nil, nil, proxyReq)
+				require.NoError(t, err)
+			}
+
+			mockClient.AssertNumberOfCalls(t, "ForwardRequest", 2)
+		})
+	}
+}
+
+func TestResponseCacheKey_IncludesCredentials(t *testing.T) {
+	proxyReq := &models.ProxyRequest{Method: "GET", JQQuery: "."}
+
+	keyA := responseCacheKey("svc", "", "/me", nil, http.Header{"Authorization": []string{"Bearer a"}}, proxyReq)
+	keyB := responseCacheKey("svc", "", "/me", nil, http.Header{"Authorization": []string{"Bearer b"}}, proxyReq)
+
+	assert.NotEqual(t, keyA, keyB)
+	assert.True(t, strings.HasPrefix(keyA, "svc|"))
+}
+
+func TestResponseCacheKey_IncludesTransformationMode(t *testing.T) {
+	jq := &models.ProxyRequest{Method: "GET", TransformationMode: models.TransformationModeJQ, JQQuery: "."}
+	custom := *jq
+	custom.TransformationMode = models.TransformationMode("custom")
+
+	assert.NotEqual(t,
+		responseCacheKey("svc", "", "/me", nil, nil, jq),
+		responseCacheKey("svc", "", "/me", nil, nil, &custom))
+}
+
+// countingTransformer wraps a transformer, counting the transformations it runs
+type countingTransformer struct {
+	transform.Transformer
+	calls atomic.Int64
+}
+
+func (c *countingTransformer) Transform(data any, req *models.ProxyRequest) (any, error) {
+	c.calls.Add(1)
+	return c.Transformer.Transform(data, req)
+}
+
+func TestService_HandleRequest_TransformCache(t *testing.T) {
+	// Upstream returning the body named by the request's query, with a
+	// header that differs on every response
+	var served atomic.Int64
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Served", strconv.FormatInt(served.Add(1), 10))
+		fmt.Fprintf(w, `{"items": [{"name": %q}]}`, r.URL.Query().Get("name"))
+	}))
+	defer upstream.Close()
+
+	mockConfig := &MockConfigProvider{}
+	counting := &countingTransformer{Transformer: transform.NewJQTransformer()}
+	transformer := transform.NewUnifiedTransformer()
+	transformer.Register(models.TransformationModeJQ, counting)
+	service := NewService(mockConfig, client.NewClient(5*time.Second), transformer, createTestLogger(), WithTransformCacheSize(10))
+
+	endpoint := &models.Endpoint{Name: "users", Target: upstream.URL}
+	mockConfig.On("GetEndpoint", "users").Return(endpoint, true)
+
+	request := func(name, query string) interface{} {
+		proxyReq := &models.ProxyRequest{
+			Method:             "GET",
+			TransformationMode: models.TransformationModeJQ,
+			JQQuery:            query,
+		}
+		result, err := service.HandleRequest(context.Background(), "users", "/users", url.Values{"name": {name}}, nil, proxyReq)
+		require.NoError(t, err)
+		return result.Data
+	}
+
+	// The same query over the same body runs once, whatever the headers
+	assert.Equal(t, []interface{}{"Jane"}, request("Jane", "[.items[].name]"))
+	assert.Equal(t, []interface{}{"Jane"}, request("Jane", "[.items[].name]"))
+	assert.Equal(t, int64(1), counting.calls.Load())
+
+	// A different body or a different query runs again
+	assert.Equal(t, []interface{}{"John"}, request("John", "[.items[].name]"))
+	assert.Equal(t, "Jane", request("Jane", ".items[0].name"))
+	assert.Equal(t, int64(3), counting.calls.Load())
+
+	// A query reading the headers runs for each response
+	assert.Equal(t, "5", request("Jane", `$headers["x-served"]`))
+	assert.Equal(t, "6", request("Jane", `$headers["x-served"]`))
+	assert.Equal(t, int64(5), counting.calls.Load())
 }
@@ -0,0 +1,177 @@
+package proxy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"jq-proxy-service/internal/models"
+)
+
+// streamFormat selects how a streamed result is written
+type streamFormat int
+
+const (
+	// streamJSON writes the result as a single JSON value
+	streamJSON streamFormat = iota
+	// streamEnvelope wraps the result in {"data": ..., "status": ...}
+	streamEnvelope
+	// streamNDJSON writes each array element on its own line
+	streamNDJSON
+)
+
+// writeStreamResponse runs a streamed transformation and writes its result to
+// the client as it is produced. Errors raised before anything is written are
+// reported as a normal error response; once the response has started the
+// connection is aborted so the client sees a truncated body rather than a
+// complete-looking one.
+func (h *Handler) writeStreamResponse(w http.ResponseWriter, r *http.Request, response *models.ProxyResponse, format streamFormat, contentType string) {
+	sw := &streamWriter{
+		w:           w,
+		controller:  http.NewResponseController(w),
+		status:      response.Status,
+		contentType: contentType,
+		format:      format,
+	}
+
+	err := response.Stream(sw)
+	if err == nil {
+		err = sw.finish()
+	}
+	if err == nil {
+		return
+	}
+
+	if !sw.started {
+		h.handleProxyError(w, r, err)
+		return
+	}
+	h.logger.WithContext(r.Context()).WithError(err).Error("Streamed response failed after it started")
+	panic(http.ErrAbortHandler)
+}
+
+// streamWriter writes a streamed result as JSON, sending the status and
+// headers with the first value and flushing after each array element
+type streamWriter struct {
+	w           http.ResponseWriter
+	controller  *http.ResponseController
+	status      int
+	contentType string
+	format      streamFormat
+	started     bool
+	elements    int
+}
+
+// start writes the headers and, for enveloped responses, the envelope prefix
+func (sw *streamWriter) start() error {
+	if sw.started {
+		return nil
+	}
+	sw.started = true
+	sw.w.Header().Set("Content-Type", sw.contentType)
+	sw.w.WriteHeader(sw.status)
+	if sw.format == streamEnvelope {
+		return sw.write(`{"data":`)
+	}
+	return nil
+}
+
+func (sw *streamWriter) Value(v interface{}) error {
+	if err := sw.start(); err != nil {
+		return err
+	}
+	if sw.format == streamNDJSON {
+		return sw.line(v)
+	}
+	return sw.encode(v)
+}
+
+func (sw *streamWriter) BeginArray() error {
+	if err := sw.start(); err != nil {
+		return err
+	}
+	if sw.format == streamNDJSON {
+		return nil
+	}
+	return sw.write("[")
+}
+
+func (sw *streamWriter) Element(v interface{}) error {
+	if sw.format == streamNDJSON {
+		return sw.line(v)
+	}
+	if sw.elements > 0 {
+		if err := sw.write(","); err != nil {
+			return err
+		}
+	}
+	sw.elements++
+	if err := sw.encode(v); err != nil {
+		return err
+	}
+	return sw.flush()
+}
+
+func (sw *streamWriter) EndArray() error {
+	if sw.format == streamNDJSON {
+		return nil
+	}
+	return sw.write("]")
+}
+
+// line writes a value on its own line of an NDJSON response
+func (sw *streamWriter) line(v interface{}) error {
+	sw.elements++
+	if err := sw.encode(v); err != nil {
+		return err
+	}
+	if err := sw.write("\n"); err != nil {
+		return err
+	}
+	return sw.flush()
+}
+
+// finish closes the envelope and flushes the end of the response
+func (sw *streamWriter) finish() error {
+	if err := sw.start(); err != nil {
+		return err
+	}
+	switch sw.format {
+	case streamEnvelope:
+		if err := sw.write(fmt.Sprintf(`,"status":%d}`, sw.status)); err != nil {
+			return err
+		}
+	case streamNDJSON:
+		// Every value already ended its line
+		return sw.flush()
+	}
+	if err := sw.write("\n"); err != nil {
+		return err
+	}
+	return sw.flush()
+}
+
+func (sw *streamWriter) encode(v interface{}) error {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = sw.w.Write(encoded)
+	return err
+}
+
+func (sw *streamWriter) write(s string) error {
+	_, err := io.WriteString(sw.w, s)
+	return err
+}
+
+// flush sends buffered output to the client; writers that cannot flush are
+// left to send it when the response completes
+func (sw *streamWriter) flush() error {
+	if err := sw.controller.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"jq-proxy-service/internal/client"
+	"jq-proxy-service/internal/logging"
+	"jq-proxy-service/internal/models"
+	"jq-proxy-service/internal/transform"
+)
+
+// newStreamTestRouter routes requests through a real service whose upstream returns body
+func newStreamTestRouter(t *testing.T, body string, opts ...Option) http.Handler {
+	t.Helper()
+	mockConfig := &MockConfigProvider{}
+	mockClient := &MockHTTPClient{}
+	mockConfig.On("GetEndpoint", "test-service").Return(&models.Endpoint{
+		Name:   "test-service",
+		Target: "https://api.example.com",
+	}, true)
+	mockClient.On("ForwardRequest", mock.Anything, "GET", "https://api.example.com", "/items", mock.Anything, mock.Anything, nil).
+		Return(&client.Response{
+			StatusCode: http.StatusOK,
+			Headers:    http.Header{"Content-Type": []string{"application/json"}},
+			Body:       []byte(body),
+		}, nil)
+
+	logger, _ := logging.NewLogger("error")
+	service := NewService(mockConfig, mockClient, transform.NewUnifiedTransformer(), logger, opts...)
+	return NewHandler(service, createTestLogger()).SetupRoutes()
+}
+
+func serveProxy(router http.Handler, target, payload string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", target, strings.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestHandler_StreamMatchesBuffered(t *testing.T) {
+	router := newStreamTestRouter(t, `{"items": [{"id": 1, "name": "a"}, {"id": 2, "name": "b"}, {"id": 3, "name": "c"}]}`)
+
+	tests := []struct {
+		name    string
+		request string
+		query   string
+	}{
+		{name: "multiple outputs", request: `"jq_query": ".items[] | {id}"`},
+		{name: "single array", request: `"jq_query": "[.items[] | .name]"`},
+		{name: "single object", request: `"jq_query": ".items[0]"`},
+		{name: "scalar", request: `"jq_query": ".items | length"`},
+		{name: "no output", request: `"jq_query": "empty"`},
+		{name: "collect", request: `"jq_query": ".items[0].id", "jq_collect": true`},
+		{name: "pipeline", request: `"pipeline": [{"query": ".items"}, {"query": ".[] | .name"}]`},
+		{name: "envelope", request: `"jq_query": ".items[] | .id"`, query: "?envelope=true"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := "/proxy/test-service/items" + tt.query
+			buffered := serveProxy(router, target, `{"method": "GET", `+tt.request+`}`)
+			streamed := serveProxy(router, target, `{"method": "GET", "stream": true, `+tt.request+`}`)
+
+			require.Equal(t, http.StatusOK, buffered.Code)
+			assert.Equal(t, buffered.Code, streamed.Code)
+			assert.Equal(t, "application/json", streamed.Header().Get("Content-Type"))
+			assert.JSONEq(t, buffered.Body.String(), streamed.Body.String())
+		})
+	}
+}
+
+func TestHandler_StreamErrorBeforeOutput(t *testing.T) {
+	router := newStreamTestRouter(t, `{"items": [1, "two"]}`)
+
+	// The second value fails before the array is started, so a normal error is returned
+	rr := serveProxy(router, "/proxy/test-service/items", `{"method": "GET", "stream": true, "jq_query": ".items[] | . + 1"}`)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.Contains(t, rr.Body.String(), "TRANSFORMATION_ERROR")
+}
+
+func TestHandler_StreamAbortsAfterOutput(t *testing.T) {
+	router := newStreamTestRouter(t, `{"count": 1000}`, WithMaxResultBytes(100))
+
+	// The size limit is reached part way through the array, after output has started
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		serveProxy(router, "/proxy/test-service/items", `{"method": "GET", "stream": true, "jq_query": "range(.count)"}`)
+	})
+}
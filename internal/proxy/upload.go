@@ -0,0 +1,81 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"jq-proxy-service/internal/models"
+)
+
+// uploadHeaderPrefix marks the headers that carry an upload's transformation.
+// The client strips jpx- headers, so they never reach the upstream.
+const uploadHeaderPrefix = "Jpx-"
+
+// uploadMethods are the methods accepted by the upload routes
+var uploadMethods = []string{"POST", "PUT", "PATCH", "OPTIONS"}
+
+// uploadFields are the envelope fields an upload may set, each read from a
+// header named after it, e.g. jq_query from Jpx-Jq-Query. Request queries are
+// left out since they only apply to JSON bodies.
+var uploadFields = []string{
+	"transformation_mode", "jq_query", "jmespath_query", "template", "content_type",
+	"response_content_type", "error_jq_query", "status_jq_query", "on_error",
+}
+
+// handleUploadRequest forwards the request body to the upstream as-is, with
+// the request's own method and Content-Type, so clients can send binary data
+// such as images. The upstream response is transformed like any other.
+func (h *Handler) handleUploadRequest(w http.ResponseWriter, r *http.Request) {
+	// Handle OPTIONS requests for CORS
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	endpointName, path, ok := h.proxyTarget(w, r)
+	if !ok {
+		return
+	}
+
+	body, ok := h.readRequestBody(w, r)
+	if !ok {
+		return
+	}
+
+	proxyReq, err := parseUploadRequest(r)
+	if err != nil {
+		h.logger.WithContext(r.Context()).WithError(err).Error("Failed to parse upload request")
+		h.writeErrorResponse(w, r, http.StatusBadRequest, "INVALID_REQUEST", fmt.Sprintf("Invalid request format: %v", err), nil)
+		return
+	}
+	if len(body) > 0 {
+		proxyReq.Body = body
+	}
+
+	// Bodies without a declared type are sent as opaque bytes rather than JSON
+	if len(body) > 0 && r.Header.Get("Content-Type") == "" {
+		r.Header.Set("Content-Type", "application/octet-stream")
+	}
+
+	h.serveProxyRequest(w, r, endpointName, path, proxyReq)
+}
+
+// parseUploadRequest builds the proxy request of an upload from its method
+// and jpx- headers. The body is left for the caller to fill in.
+func parseUploadRequest(r *http.Request) (*models.ProxyRequest, error) {
+	envelope := map[string]interface{}{"method": r.Method}
+	for _, field := range uploadFields {
+		header := uploadHeaderPrefix + strings.ReplaceAll(field, "_", "-")
+		if value := r.Header.Get(header); value != "" {
+			envelope[field] = value
+		}
+	}
+
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		return nil, err
+	}
+	return models.ParseProxyRequest(data)
+}
@@ -0,0 +1,68 @@
+// Package ratelimit paces outbound requests to a fixed rate.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrDeadline is returned when the next free slot is later than the caller's deadline
+var ErrDeadline = errors.New("rate limit wait would exceed deadline")
+
+// Limiter spaces calls evenly so that no more than the configured number
+// start per second. It is safe for concurrent use.
+type Limiter struct {
+	mu       sync.Mutex
+	rate     float64
+	interval time.Duration
+	next     time.Time
+	now      func() time.Time
+}
+
+// New creates a limiter allowing perSecond calls per second
+func New(perSecond float64) *Limiter {
+	return &Limiter{
+		rate:     perSecond,
+		interval: time.Duration(float64(time.Second) / perSecond),
+		now:      time.Now,
+	}
+}
+
+// Rate returns the configured number of calls per second
+func (l *Limiter) Rate() float64 {
+	return l.rate
+}
+
+// Wait blocks until the caller may proceed and returns how long it waited.
+// If the caller's slot lies beyond the context deadline it returns
+// ErrDeadline immediately without consuming the slot.
+func (l *Limiter) Wait(ctx context.Context) (time.Duration, error) {
+	l.mu.Lock()
+	now := l.now()
+	slot := l.next
+	if slot.Before(now) {
+		slot = now
+	}
+	wait := slot.Sub(now)
+	if deadline, ok := ctx.Deadline(); ok && slot.After(deadline) {
+		l.mu.Unlock()
+		return 0, ErrDeadline
+	}
+	l.next = slot.Add(l.interval)
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return 0, nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return wait, nil
+	case <-ctx.Done():
+		return l.now().Sub(now), ctx.Err()
+	}
+}
@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter_PacesCalls(t *testing.T) {
+	l := New(20) // one call every 50ms
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		_, err := l.Wait(context.Background())
+		require.NoError(t, err)
+	}
+
+	// The first call is immediate and the remaining four are spaced 50ms apart
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+	assert.Equal(t, float64(20), l.Rate())
+}
+
+func TestLimiter_ReportsWait(t *testing.T) {
+	l := New(10)
+
+	wait, err := l.Wait(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), wait)
+
+	wait, err = l.Wait(context.Background())
+	require.NoError(t, err)
+	assert.Greater(t, wait, 50*time.Millisecond)
+}
+
+func TestLimiter_Deadline(t *testing.T) {
+	l := New(1)
+
+	_, err := l.Wait(context.Background())
+	require.NoError(t, err)
+
+	// The next slot is a second away, beyond this deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = l.Wait(ctx)
+	assert.ErrorIs(t, err, ErrDeadline)
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+}
+
+func TestLimiter_Concurrent(t *testing.T) {
+	l := New(100)
+	var wg sync.WaitGroup
+
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := l.Wait(context.Background())
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+}
@@ -0,0 +1,137 @@
+// Package singleflight collapses concurrent calls for the same key into one.
+package singleflight
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// call is an in-flight or completed Do call
+type call struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+	dups  int
+
+	// Calls started by DoContext are cancelled once no caller waits for them
+	waiters int
+	cancel  context.CancelFunc
+	// shared is set once the call completes
+	shared bool
+}
+
+// Group runs at most one function per key at a time. Callers that arrive
+// while a call for their key is in flight wait for it and share its result.
+// The zero value is ready to use and it is safe for concurrent use.
+type Group struct {
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+// Do runs fn for key unless a call for key is already in flight, in which
+// case it waits for that call. shared reports whether the result was given
+// to more than one caller.
+func (g *Group) Do(key string, fn func() (interface{}, error)) (value interface{}, err error, shared bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*call)
+	}
+	if c, exists := g.calls[key]; exists {
+		c.dups++
+		g.mu.Unlock()
+		<-c.done
+		return c.value, c.err, true
+	}
+	c := &call{done: make(chan struct{})}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	// Release waiters even if fn panics, giving them an error instead
+	defer func() {
+		r := recover()
+		if r != nil {
+			c.err = fmt.Errorf("singleflight: call for %q panicked: %v", key, r)
+		}
+
+		g.mu.Lock()
+		delete(g.calls, key)
+		shared = c.dups > 0
+		g.mu.Unlock()
+		close(c.done)
+
+		if r != nil {
+			panic(r)
+		}
+	}()
+
+	c.value, c.err = fn()
+	return c.value, c.err, false
+}
+
+// DoContext is like Do, but runs fn on its own goroutine with a context that
+// keeps the values of the first caller's ctx and is cancelled only once every
+// caller has stopped waiting. Each caller stops waiting when its own ctx is
+// done and gets ctx.Err(), except the last one, which cancels the call and
+// returns its result. Callers of Do that join such a call do not keep it
+// from being cancelled. A panic in fn is returned as an error rather than
+// propagated.
+func (g *Group) DoContext(ctx context.Context, key string, fn func(ctx context.Context) (interface{}, error)) (value interface{}, err error, shared bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*call)
+	}
+	c, exists := g.calls[key]
+	if exists {
+		c.dups++
+	} else {
+		c = &call{}
+		var callCtx context.Context
+		callCtx, c.cancel = context.WithCancel(context.WithoutCancel(ctx))
+		c.done = make(chan struct{})
+		g.calls[key] = c
+		go g.run(callCtx, key, c, fn)
+	}
+	c.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.value, c.err, exists || c.shared
+	case <-ctx.Done():
+	}
+
+	g.mu.Lock()
+	c.waiters--
+	last := c.waiters == 0
+	if last && c.cancel != nil && g.calls[key] == c {
+		// Later callers start a new call rather than join a cancelled one
+		delete(g.calls, key)
+	}
+	g.mu.Unlock()
+	if !last || c.cancel == nil {
+		// Calls started by Do are not cancelled
+		return nil, ctx.Err(), true
+	}
+	c.cancel()
+	<-c.done
+	return c.value, c.err, exists || c.shared
+}
+
+// run completes a call started by DoContext
+func (g *Group) run(ctx context.Context, key string, c *call, fn func(ctx context.Context) (interface{}, error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.err = fmt.Errorf("singleflight: call for %q panicked: %v", key, r)
+		}
+		g.mu.Lock()
+		if g.calls[key] == c {
+			delete(g.calls, key)
+		}
+		c.shared = c.dups > 0
+		g.mu.Unlock()
+		c.cancel()
+		close(c.done)
+	}()
+	c.value, c.err = fn(ctx)
+}
@@ -0,0 +1,178 @@
+package singleflight
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroup_CollapsesConcurrentCalls(t *testing.T) {
+	var g Group
+	var calls int32
+	release := make(chan struct{})
+
+	var wg sync.WaitGroup
+	results := make([]interface{}, 10)
+	shared := make([]bool, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _, shared[i] = g.Do("key", func() (interface{}, error) {
+				atomic.AddInt32(&calls, 1)
+				<-release
+				return "value", nil
+			})
+		}(i)
+	}
+
+	// Give every caller time to join the in-flight call
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	for i := range results {
+		assert.Equal(t, "value", results[i])
+		assert.True(t, shared[i])
+	}
+}
+
+func TestGroup_SequentialCallsRunAgain(t *testing.T) {
+	var g Group
+	calls := 0
+	fn := func() (interface{}, error) {
+		calls++
+		return calls, nil
+	}
+
+	v, err, shared := g.Do("key", fn)
+	require.NoError(t, err)
+	assert.Equal(t, 1, v)
+	assert.False(t, shared)
+
+	v, _, _ = g.Do("key", fn)
+	assert.Equal(t, 2, v)
+}
+
+func TestGroup_DistinctKeys(t *testing.T) {
+	var g Group
+	v1, _, _ := g.Do("a", func() (interface{}, error) { return "a", nil })
+	v2, _, _ := g.Do("b", func() (interface{}, error) { return "b", nil })
+	assert.Equal(t, "a", v1)
+	assert.Equal(t, "b", v2)
+}
+
+func TestGroup_SharesErrors(t *testing.T) {
+	var g Group
+	want := errors.New("upstream down")
+	_, err, _ := g.Do("key", func() (interface{}, error) { return nil, want })
+	assert.ErrorIs(t, err, want)
+}
+
+func TestGroup_PanicReleasesWaiters(t *testing.T) {
+	var g Group
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	go func() {
+		defer func() { recover() }()
+		g.Do("key", func() (interface{}, error) {
+			close(started)
+			<-release
+			panic("boom")
+		})
+	}()
+
+	<-started
+	done := make(chan error)
+	go func() {
+		_, err, _ := g.Do("key", func() (interface{}, error) { return nil, nil })
+		done <- err
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+
+	select {
+	case err := <-done:
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "panicked")
+	case <-time.After(time.Second):
+		t.Fatal("waiter was not released")
+	}
+}
+
+func TestGroup_DoContext_CallerLeavesEarly(t *testing.T) {
+	var g Group
+	started := make(chan struct{})
+	release := make(chan struct{})
+	callErr := make(chan error, 1)
+	fn := func(ctx context.Context) (interface{}, error) {
+		close(started)
+		<-release
+		callErr <- ctx.Err()
+		return "value", nil
+	}
+
+	firstCtx, cancelFirst := context.WithCancel(context.Background())
+	firstDone := make(chan error, 1)
+	go func() {
+		_, err, _ := g.DoContext(firstCtx, "key", fn)
+		firstDone <- err
+	}()
+	<-started
+
+	secondDone := make(chan interface{}, 1)
+	go func() {
+		v, err, shared := g.DoContext(context.Background(), "key", fn)
+		assert.NoError(t, err)
+		assert.True(t, shared)
+		secondDone <- v
+	}()
+
+	// Give the second caller time to join the in-flight call
+	time.Sleep(50 * time.Millisecond)
+	cancelFirst()
+	assert.ErrorIs(t, <-firstDone, context.Canceled)
+
+	close(release)
+	assert.NoError(t, <-callErr, "the call outlives the caller that started it")
+	assert.Equal(t, "value", <-secondDone)
+}
+
+func TestGroup_DoContext_LastCallerCancels(t *testing.T) {
+	var g Group
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	want := errors.New("gave up")
+	v, err, shared := g.DoContext(ctx, "key", func(ctx context.Context) (interface{}, error) {
+		<-ctx.Done()
+		return nil, want
+	})
+	assert.Nil(t, v)
+	assert.ErrorIs(t, err, want, "the last caller gets the cancelled call's result")
+	assert.False(t, shared)
+
+	// A later call runs again
+	v, err, _ = g.DoContext(context.Background(), "key", func(ctx context.Context) (interface{}, error) {
+		return "again", nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "again", v)
+}
+
+func TestGroup_DoContext_Panic(t *testing.T) {
+	var g Group
+	_, err, _ := g.DoContext(context.Background(), "key", func(ctx context.Context) (interface{}, error) {
+		panic("boom")
+	})
+	assert.ErrorContains(t, err, "panicked: boom")
+}
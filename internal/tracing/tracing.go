@@ -0,0 +1,151 @@
+// Package tracing provides optional OpenTelemetry instrumentation for the proxy service.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+
+	"jq-proxy-service/internal/models"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// instrumentationName identifies the tracer used by the proxy
+	instrumentationName = "jq-proxy-service"
+
+	// defaultServiceName is reported when no service name is configured
+	defaultServiceName = "jq-proxy-service"
+
+	// EndpointAttribute is the span attribute holding the resolved endpoint name
+	EndpointAttribute = attribute.Key("proxy.endpoint")
+)
+
+// ShutdownFunc flushes and stops the tracer provider
+type ShutdownFunc func(ctx context.Context) error
+
+// Setup installs a global tracer provider and the W3C trace context propagator.
+// It returns a no-op shutdown function when tracing is disabled.
+func Setup(cfg models.TracingConfig) (ShutdownFunc, error) {
+	if !cfg.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = defaultServiceName
+	}
+
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	}
+
+	if cfg.Exporter == models.TracingExporterStdout {
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stderr))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stdout trace exporter: %w", err)
+		}
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	}
+
+	provider := sdktrace.NewTracerProvider(opts...)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the tracer used for proxy spans
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Middleware creates a server span for every request, continuing any trace
+// context received in the incoming W3C traceparent/tracestate headers
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := Tracer().Start(ctx, r.Method+" "+r.URL.Path,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+			),
+		)
+		defer span.End()
+
+		wrapper := &statusRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(wrapper, r.WithContext(ctx))
+
+		span.SetAttributes(semconv.HTTPResponseStatusCode(wrapper.statusCode))
+		if wrapper.statusCode >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(wrapper.statusCode))
+		}
+	})
+}
+
+// StartUpstreamSpan starts a client span for a call to the named endpoint.
+// The returned headers carry the new span's trace context for the upstream request.
+func StartUpstreamSpan(ctx context.Context, endpointName string, headers http.Header) (context.Context, trace.Span, http.Header) {
+	trace.SpanFromContext(ctx).SetAttributes(EndpointAttribute.String(endpointName))
+
+	ctx, span := Tracer().Start(ctx, "upstream "+endpointName,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(EndpointAttribute.String(endpointName)),
+	)
+
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		// No propagator is installed; forward the caller's headers untouched
+		return ctx, span, headers
+	}
+
+	outgoing := headers.Clone()
+	if outgoing == nil {
+		outgoing = make(http.Header)
+	}
+	for key, value := range carrier {
+		outgoing.Set(key, value)
+	}
+
+	return ctx, span, outgoing
+}
+
+// EndUpstreamSpan records the outcome of an upstream call and ends the span
+func EndUpstreamSpan(span trace.Span, statusCode int, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		span.SetAttributes(semconv.HTTPResponseStatusCode(statusCode))
+		if statusCode >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(statusCode))
+		}
+	}
+	span.End()
+}
+
+// statusRecorder wraps http.ResponseWriter to capture the status code
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (sr *statusRecorder) WriteHeader(code int) {
+	sr.statusCode = code
+	sr.ResponseWriter.WriteHeader(code)
+}
@@ -0,0 +1,97 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"jq-proxy-service/internal/models"
+)
+
+// setupTestProvider installs a tracer provider backed by an in-memory span recorder
+func setupTestProvider(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	prevProvider := otel.GetTracerProvider()
+	prevPropagator := otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+
+	return recorder
+}
+
+func TestMiddleware_PropagatesTraceContextUpstream(t *testing.T) {
+	recorder := setupTestProvider(t)
+
+	const incomingTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	const incomingParent = "00f067aa0ba902b7"
+
+	var upstreamHeaders http.Header
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, span, headers := StartUpstreamSpan(r.Context(), "user-service", r.Header)
+		upstreamHeaders = headers
+		EndUpstreamSpan(span, http.StatusOK, nil)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("POST", "/proxy/user-service/users", nil)
+	req.Header.Set("traceparent", "00-"+incomingTraceID+"-"+incomingParent+"-01")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	clientSpan, serverSpan := spans[0], spans[1]
+
+	// Both spans continue the incoming trace
+	assert.Equal(t, incomingTraceID, serverSpan.SpanContext().TraceID().String())
+	assert.Equal(t, incomingParent, serverSpan.Parent().SpanID().String())
+	assert.Equal(t, trace.SpanKindServer, serverSpan.SpanKind())
+
+	// The client span is a child of the server span
+	assert.Equal(t, trace.SpanKindClient, clientSpan.SpanKind())
+	assert.Equal(t, serverSpan.SpanContext().SpanID(), clientSpan.Parent().SpanID())
+
+	// The upstream receives the client span as its parent
+	expected := "00-" + incomingTraceID + "-" + clientSpan.SpanContext().SpanID().String() + "-01"
+	assert.Equal(t, expected, upstreamHeaders.Get("traceparent"))
+
+	// Endpoint name is recorded on both spans
+	assert.Contains(t, clientSpan.Attributes(), EndpointAttribute.String("user-service"))
+	assert.Contains(t, serverSpan.Attributes(), EndpointAttribute.String("user-service"))
+}
+
+func TestStartUpstreamSpan_WithoutPropagator(t *testing.T) {
+	prevPropagator := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+	defer otel.SetTextMapPropagator(prevPropagator)
+
+	headers := http.Header{"Traceparent": []string{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}}
+	_, span, outgoing := StartUpstreamSpan(context.Background(), "svc", headers)
+	EndUpstreamSpan(span, http.StatusOK, nil)
+
+	// Incoming headers pass through untouched when tracing is not configured
+	assert.Equal(t, headers, outgoing)
+}
+
+func TestSetup_Disabled(t *testing.T) {
+	shutdown, err := Setup(models.TracingConfig{Enabled: false})
+	require.NoError(t, err)
+	require.NotNil(t, shutdown)
+	assert.NoError(t, shutdown(context.Background()))
+}
@@ -0,0 +1,336 @@
+package transform
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/itchyny/gojq"
+)
+
+// ErrQueryTooComplex is returned for jq queries whose estimated cost exceeds
+// the complexity budget
+var ErrQueryTooComplex = errors.New("jq query is too complex")
+
+// Estimated number of values produced by constructs whose output depends on
+// the data: iterating an array or object with .[], and walking the whole
+// document with .., recurse, paths or walk
+const (
+	iterateFanout = 10
+	recurseFanout = 100
+)
+
+// complexity is the estimated cost of evaluating a jq expression once and the
+// estimated number of values it produces
+type complexity struct {
+	cost   float64
+	fanout float64
+}
+
+// unbounded is the complexity of an expression that may run forever
+var unbounded = complexity{cost: math.Inf(1), fanout: math.Inf(1)}
+
+// estimator walks a query, tracking the functions in scope so calls can be
+// resolved to the definition they refer to
+type estimator struct {
+	scope []scopedFunc
+}
+
+// scopedFunc is a function or function parameter a call may refer to
+type scopedFunc struct {
+	name     string
+	arity    int
+	defining bool
+}
+
+// EstimateComplexity returns a rough cost estimate for a parsed jq query. Each
+// term costs 1 per value it runs on, so the cost of an expression is
+// multiplied by the number of values feeding it. The estimate assumes .[]
+// yields 10 values, .., recurse, paths and walk visit 100 values, and
+// range yields as many values as its literal bounds allow, or 100 when they
+// are not literals. Nested iteration therefore grows the cost geometrically.
+//
+// Queries that may run without bound are estimated at math.MaxInt32: repeat,
+// while and until, recurse with a generator that does not descend into its
+// input, and recursive function definitions.
+func EstimateComplexity(query *gojq.Query) int {
+	estimate := new(estimator).queryComplexity(query)
+	return int(min(estimate.cost, math.MaxInt32))
+}
+
+// checkComplexity rejects a parsed query whose estimated cost exceeds budget;
+// a non-positive budget disables the check
+func checkComplexity(query *gojq.Query, budget int) error {
+	if budget <= 0 {
+		return nil
+	}
+	estimate := new(estimator).queryComplexity(query)
+	if math.IsInf(estimate.cost, 1) {
+		return fmt.Errorf("%w: it may run without bound", ErrQueryTooComplex)
+	}
+	if cost := int(min(estimate.cost, math.MaxInt32)); cost > budget {
+		return fmt.Errorf("%w: estimated cost %d exceeds the budget of %d", ErrQueryTooComplex, cost, budget)
+	}
+	return nil
+}
+
+func (e *estimator) queryComplexity(q *gojq.Query) complexity {
+	if q == nil {
+		return complexity{fanout: 1}
+	}
+
+	// Definitions are in scope for the rest of the query, and each one for
+	// its own body, where a call to it is recursion
+	defined := len(e.scope)
+	defer func() { e.scope = e.scope[:defined] }()
+	var defs float64
+	for _, fd := range q.FuncDefs {
+		e.scope = append(e.scope, scopedFunc{name: fd.Name, arity: len(fd.Args), defining: true})
+		self := len(e.scope) - 1
+		for _, arg := range fd.Args {
+			e.scope = append(e.scope, scopedFunc{name: arg})
+			if name, ok := strings.CutPrefix(arg, "$"); ok {
+				// A $name parameter is also callable as name
+				e.scope = append(e.scope, scopedFunc{name: name})
+			}
+		}
+		defs += e.queryComplexity(fd.Body).cost
+		e.scope = e.scope[:self+1]
+		e.scope[self].defining = false
+	}
+
+	if q.Term != nil {
+		c := e.termComplexity(q.Term)
+		c.cost += defs
+		return c
+	}
+
+	left, right := e.queryComplexity(q.Left), e.queryComplexity(q.Right)
+	switch q.Op {
+	case gojq.OpComma, gojq.OpAlt:
+		// Both sides run once and their outputs are concatenated
+		return complexity{cost: defs + left.cost + right.cost, fanout: left.fanout + right.fanout}
+	default:
+		// Pipes and binary operators run the right side once per left value
+		return complexity{cost: defs + left.cost + left.fanout*right.cost, fanout: left.fanout * right.fanout}
+	}
+}
+
+func (e *estimator) termComplexity(t *gojq.Term) complexity {
+	c := complexity{cost: 1, fanout: 1}
+
+	switch t.Type {
+	case gojq.TermTypeRecurse:
+		c = complexity{cost: recurseFanout, fanout: recurseFanout}
+	case gojq.TermTypeIndex:
+		c.cost += e.indexComplexity(t.Index)
+	case gojq.TermTypeFunc:
+		c = e.funcComplexity(t.Func)
+	case gojq.TermTypeObject:
+		for _, kv := range t.Object.KeyVals {
+			key := e.queryComplexity(kv.KeyQuery)
+			val := e.queryComplexity(kv.Val)
+			c.cost += key.cost + e.stringComplexity(kv.KeyString) + val.cost
+			c.fanout *= key.fanout * val.fanout
+		}
+	case gojq.TermTypeArray:
+		// Collecting values runs the inner query once and yields one array
+		c.cost += e.queryComplexity(t.Array.Query).cost
+	case gojq.TermTypeUnary:
+		c = e.termComplexity(t.Unary.Term)
+	case gojq.TermTypeFormat, gojq.TermTypeString:
+		c.cost += e.stringComplexity(t.Str)
+	case gojq.TermTypeIf:
+		cond := e.queryComplexity(t.If.Cond)
+		branch := e.queryComplexity(t.If.Then)
+		for _, elif := range t.If.Elif {
+			elifCond := e.queryComplexity(elif.Cond)
+			cond.cost += elifCond.cost
+			branch = maxComplexity(branch, e.queryComplexity(elif.Then))
+		}
+		branch = maxComplexity(branch, e.queryComplexity(t.If.Else))
+		c = complexity{cost: 1 + cond.cost + cond.fanout*branch.cost, fanout: cond.fanout * branch.fanout}
+	case gojq.TermTypeTry:
+		body := e.queryComplexity(t.Try.Body)
+		c = complexity{cost: 1 + body.cost + e.queryComplexity(t.Try.Catch).cost, fanout: body.fanout}
+	case gojq.TermTypeReduce:
+		source := e.queryComplexity(t.Reduce.Query)
+		start := e.queryComplexity(t.Reduce.Start)
+		update := e.queryComplexity(t.Reduce.Update)
+		c = complexity{cost: 1 + source.cost + start.cost + source.fanout*update.cost, fanout: start.fanout}
+	case gojq.TermTypeForeach:
+		source := e.queryComplexity(t.Foreach.Query)
+		start := e.queryComplexity(t.Foreach.Start)
+		update := e.queryComplexity(t.Foreach.Update)
+		extract := e.queryComplexity(t.Foreach.Extract)
+		c = complexity{
+			cost:   1 + source.cost + start.cost + source.fanout*(update.cost+extract.cost),
+			fanout: source.fanout * extract.fanout,
+		}
+	case gojq.TermTypeLabel:
+		c = e.queryComplexity(t.Label.Body)
+	case gojq.TermTypeQuery:
+		c = e.queryComplexity(t.Query)
+	}
+
+	// Each suffix runs once per value produced so far
+	for _, suffix := range t.SuffixList {
+		switch {
+		case suffix.Iter:
+			c.cost += c.fanout
+			c.fanout *= iterateFanout
+		case suffix.Index != nil:
+			c.cost += c.fanout * (1 + e.indexComplexity(suffix.Index))
+		case suffix.Bind != nil:
+			body := e.queryComplexity(suffix.Bind.Body)
+			c.cost += c.fanout * body.cost
+			c.fanout *= body.fanout
+		}
+	}
+	return c
+}
+
+func (e *estimator) funcComplexity(f *gojq.Func) complexity {
+	c := complexity{cost: 1, fanout: 1}
+	for _, arg := range f.Args {
+		c.cost += e.queryComplexity(arg).cost
+	}
+
+	// Calls to user-defined functions cost their arguments, unless they recurse
+	if fn, ok := e.lookup(f.Name, len(f.Args)); ok {
+		if fn.defining {
+			return unbounded
+		}
+		return c
+	}
+
+	switch f.Name {
+	case "repeat", "while", "until":
+		// Loops run until their input or condition says otherwise
+		return unbounded
+	case "recurse", "paths", "leaf_paths", "walk":
+		if f.Name == "recurse" && len(f.Args) > 0 && !descends(f.Args[0]) {
+			return unbounded
+		}
+		// Visiting every value in the document, running any argument on each
+		args := c.cost - 1
+		c.cost = recurseFanout * (1 + args)
+		if f.Name != "walk" {
+			c.fanout = recurseFanout
+		}
+	case "range":
+		c.fanout = rangeFanout(f.Args)
+		c.cost += c.fanout
+	}
+	return c
+}
+
+// lookup returns the innermost function in scope with the given name and arity
+func (e *estimator) lookup(name string, arity int) (scopedFunc, bool) {
+	for i := len(e.scope) - 1; i >= 0; i-- {
+		if fn := e.scope[i]; fn.name == name && fn.arity == arity {
+			return fn, true
+		}
+	}
+	return scopedFunc{}, false
+}
+
+// descends reports whether a recurse generator only moves into its input,
+// such as .children[]? or .left, .right, so the recursion ends with the data
+func descends(q *gojq.Query) bool {
+	if q == nil || len(q.FuncDefs) > 0 {
+		return false
+	}
+	if q.Term == nil {
+		switch q.Op {
+		case gojq.OpPipe, gojq.OpComma, gojq.OpAlt:
+			return descends(q.Left) && descends(q.Right)
+		default:
+			return false
+		}
+	}
+
+	t := q.Term
+	steps := 0
+	switch t.Type {
+	case gojq.TermTypeIndex:
+		steps++
+	case gojq.TermTypeQuery:
+		if !descends(t.Query) {
+			return false
+		}
+		steps++
+	case gojq.TermTypeIdentity:
+	default:
+		return false
+	}
+	for _, suffix := range t.SuffixList {
+		switch {
+		case suffix.Bind != nil:
+			return false
+		case suffix.Iter, suffix.Index != nil:
+			steps++
+		}
+	}
+	return steps > 0
+}
+
+// rangeFanout estimates how many values range yields from its literal
+// bounds, assuming 100 when a bound is not a literal number
+func rangeFanout(args []*gojq.Query) float64 {
+	bounds := make([]float64, len(args))
+	for i, arg := range args {
+		n, ok := literalNumber(arg)
+		if !ok {
+			return recurseFanout
+		}
+		bounds[i] = n
+	}
+
+	switch len(bounds) {
+	case 1:
+		return max(bounds[0], 1)
+	case 2:
+		return max(bounds[1]-bounds[0], 1)
+	case 3:
+		if bounds[2] == 0 {
+			return recurseFanout
+		}
+		return max(math.Abs((bounds[1]-bounds[0])/bounds[2]), 1)
+	default:
+		return recurseFanout
+	}
+}
+
+// literalNumber returns the value of a query that is just a number literal
+func literalNumber(q *gojq.Query) (float64, bool) {
+	if q == nil || q.Term == nil || q.Term.Type != gojq.TermTypeNumber || len(q.Term.SuffixList) > 0 {
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(q.Term.Number, 64)
+	return n, err == nil
+}
+
+func (e *estimator) indexComplexity(index *gojq.Index) float64 {
+	if index == nil {
+		return 0
+	}
+	return e.stringComplexity(index.Str) + e.queryComplexity(index.Start).cost + e.queryComplexity(index.End).cost
+}
+
+func (e *estimator) stringComplexity(s *gojq.String) float64 {
+	if s == nil {
+		return 0
+	}
+	var cost float64
+	for _, q := range s.Queries {
+		cost += e.queryComplexity(q).cost
+	}
+	return cost
+}
+
+func maxComplexity(a, b complexity) complexity {
+	return complexity{cost: max(a.cost, b.cost), fanout: max(a.fanout, b.fanout)}
+}
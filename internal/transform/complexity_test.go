@@ -0,0 +1,90 @@
+package transform
+
+import (
+	"math"
+	"testing"
+
+	"github.com/itchyny/gojq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateComplexity(t *testing.T) {
+	estimate := func(query string) int {
+		parsed, err := gojq.Parse(query)
+		require.NoError(t, err)
+		return EstimateComplexity(parsed)
+	}
+
+	assert.Equal(t, 1, estimate("."))
+	assert.Less(t, estimate("{users: [.data[] | {name, email}]}"), 100)
+	assert.Greater(t, estimate("[.. | ..]"), 10000)
+	assert.Greater(t, estimate("[range(1000000)]"), 1000000)
+	assert.Less(t, estimate("[range(5)]"), 10)
+
+	// Nested iteration grows geometrically
+	assert.Greater(t, estimate("[.[] | .[] | .[]]"), 10*estimate("[.[]]"))
+}
+
+func TestEstimateComplexity_Unbounded(t *testing.T) {
+	estimate := func(query string) int {
+		parsed, err := gojq.Parse(query)
+		require.NoError(t, err)
+		return EstimateComplexity(parsed)
+	}
+
+	// Loops, generators that do not descend and recursive definitions may run forever
+	for _, query := range []string{
+		"repeat(.)",
+		"[limit(5; repeat(. * 2))]",
+		"while(true; .)",
+		"until(. > 100; . * 2)",
+		"recurse(. + 1)",
+		"recurse(.; . < 10)",
+		"recurse(.a | .)",
+		"def f: f; f",
+		"def f: [.[] | f]; f",
+		"def f: def g: f; g; f",
+		"def f(x): x | f(x); f(.)",
+		"{a: (def f: f; f)}",
+	} {
+		assert.Equal(t, math.MaxInt32, estimate(query), query)
+	}
+
+	// Recursion that descends into the input ends with the data
+	for _, query := range []string{
+		"recurse",
+		"[recurse(.children[]?) | .name]",
+		"recurse(.left, .right)",
+		"recurse(.[]?; . != null)",
+		"def f: 1; def g: f; g",
+		"def f: def f: 1; f; f",
+		"def f(f): f; f(.)",
+		"def f($x): x + $x; f(1)",
+		"def repeat(f): f; repeat(.)",
+	} {
+		assert.Less(t, estimate(query), 1000, query)
+	}
+}
+
+func TestJQTransformer_ValidateQuery_Complexity(t *testing.T) {
+	transformer := NewJQTransformer()
+	transformer.SetMaxComplexity(1000)
+
+	assert.NoError(t, transformer.ValidateQuery("{users: [.data[] | {name, email}]}"))
+
+	for _, query := range []string{"[.. | ..]", "[range(1000000)]", "[.[] | .[] | .[] | .[]]"} {
+		err := transformer.ValidateQuery(query)
+		require.Error(t, err, query)
+		assert.ErrorIs(t, err, ErrQueryTooComplex)
+	}
+
+	err := transformer.ValidateQuery("def f: f; f")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrQueryTooComplex)
+	assert.Contains(t, err.Error(), "may run without bound")
+
+	// Without a budget any valid query is accepted
+	transformer.SetMaxComplexity(0)
+	assert.NoError(t, transformer.ValidateQuery("[range(1000000)]"))
+}
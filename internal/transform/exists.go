@@ -0,0 +1,110 @@
+package transform
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// pathSegment is one step of a path: an object member or, when isIndex is
+// set, an array index
+type pathSegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// CheckPaths reports for each path whether it is present in data. Paths use
+// the JSONPath member and index syntax: "$", ".name", "['name']" and "[0]".
+// A member holding null is present.
+func CheckPaths(data interface{}, paths []string) (map[string]interface{}, error) {
+	result := make(map[string]interface{}, len(paths))
+	for _, path := range paths {
+		segments, err := parsePath(path)
+		if err != nil {
+			return nil, err
+		}
+		result[path] = pathExists(data, segments)
+	}
+	return result, nil
+}
+
+// ValidatePaths checks that every path can be parsed
+func ValidatePaths(paths []string) error {
+	for _, path := range paths {
+		if _, err := parsePath(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pathExists walks data along segments, reporting whether every step is present
+func pathExists(data interface{}, segments []pathSegment) bool {
+	current := data
+	for _, segment := range segments {
+		if segment.isIndex {
+			array, ok := current.([]interface{})
+			if !ok || segment.index >= len(array) {
+				return false
+			}
+			current = array[segment.index]
+			continue
+		}
+
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		value, found := object[segment.key]
+		if !found {
+			return false
+		}
+		current = value
+	}
+	return true
+}
+
+// parsePath splits a path such as "$.items[0]['display name']" into segments
+func parsePath(path string) ([]pathSegment, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("invalid path %q: must start with $", path)
+	}
+
+	var segments []pathSegment
+	rest := path[1:]
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			key := rest[1 : end+1]
+			if key == "" {
+				return nil, fmt.Errorf("invalid path %q: empty member name", path)
+			}
+			segments = append(segments, pathSegment{key: key})
+			rest = rest[end+1:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid path %q: unclosed [", path)
+			}
+			inner := rest[1:end]
+			if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+				segments = append(segments, pathSegment{key: inner[1 : len(inner)-1]})
+			} else {
+				index, err := strconv.Atoi(inner)
+				if err != nil || index < 0 {
+					return nil, fmt.Errorf("invalid path %q: [%s] must be a quoted name or a non-negative index", path, inner)
+				}
+				segments = append(segments, pathSegment{index: index, isIndex: true})
+			}
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("invalid path %q: unexpected %q", path, rest[0])
+		}
+	}
+	return segments, nil
+}
@@ -0,0 +1,96 @@
+package transform
+
+import (
+	"net/http"
+	"testing"
+
+	"jq-proxy-service/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckPaths(t *testing.T) {
+	data := map[string]interface{}{
+		"a": map[string]interface{}{
+			"b":       float64(1),
+			"empty":   nil,
+			"tag set": []interface{}{"x", "y"},
+		},
+		"items": []interface{}{
+			map[string]interface{}{"id": float64(1)},
+		},
+	}
+
+	result, err := CheckPaths(data, []string{
+		"$",
+		"$.a.b",
+		"$.a.empty",
+		"$.a['tag set'][1]",
+		"$.items[0].id",
+		"$.c",
+		"$.a.b.c",
+		"$.items[1]",
+		"$.items.id",
+		"$.a[\"missing\"]",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"$":                 true,
+		"$.a.b":             true,
+		"$.a.empty":         true,
+		"$.a['tag set'][1]": true,
+		"$.items[0].id":     true,
+		"$.c":               false,
+		"$.a.b.c":           false,
+		"$.items[1]":        false,
+		"$.items.id":        false,
+		"$.a[\"missing\"]":  false,
+	}, result)
+}
+
+func TestValidatePaths(t *testing.T) {
+	tests := []struct {
+		path   string
+		errMsg string
+	}{
+		{path: "a.b", errMsg: "must start with $"},
+		{path: "$.a..b", errMsg: "empty member name"},
+		{path: "$.a[0", errMsg: "unclosed ["},
+		{path: "$.a[-1]", errMsg: "must be a quoted name or a non-negative index"},
+		{path: "$a", errMsg: "unexpected 'a'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			err := ValidatePaths([]string{"$.ok", tt.path})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
+
+func TestUnifiedTransformer_Exists(t *testing.T) {
+	transformer := NewUnifiedTransformer()
+	data := map[string]interface{}{"user": map[string]interface{}{"name": "John"}}
+
+	req := &models.ProxyRequest{
+		Method:             "GET",
+		TransformationMode: models.TransformationModeJQ,
+		Exists:             []string{"$.user.name", "$.user.email"},
+	}
+	require.NoError(t, transformer.ValidateTransformation(req))
+
+	result, err := transformer.TransformResponse(data, req, http.StatusOK)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"$.user.name": true, "$.user.email": false}, result)
+
+	// An error query still applies to error responses
+	req.ErrorJQQuery = ".user.name"
+	result, err = transformer.TransformResponse(data, req, http.StatusNotFound)
+	require.NoError(t, err)
+	assert.Equal(t, "John", result)
+
+	req.Exists = []string{"user"}
+	assert.Error(t, transformer.ValidateTransformation(req))
+}
@@ -0,0 +1,61 @@
+package transform
+
+import (
+	"fmt"
+
+	"github.com/jmespath/go-jmespath"
+
+	"jq-proxy-service/internal/models"
+)
+
+// JMESPathTransformer implements JMESPath-based transformations
+type JMESPathTransformer struct{}
+
+// NewJMESPathTransformer creates a new JMESPath transformer
+func NewJMESPathTransformer() *JMESPathTransformer {
+	return &JMESPathTransformer{}
+}
+
+// Transform applies the request's JMESPath expression to data. An expression
+// yields a single value, which JQCollect and OnError apply to as described
+// for singleValueResult.
+func (jt *JMESPathTransformer) Transform(data any, req *models.ProxyRequest) (any, error) {
+	result, err := jt.TransformWithQuery(data, req.JQQuery)
+	return singleValueResult(result, err, req)
+}
+
+// Validate checks that the request's JMESPath expression is syntactically correct
+func (jt *JMESPathTransformer) Validate(req *models.ProxyRequest) error {
+	return jt.ValidateQuery(req.JQQuery)
+}
+
+// TransformWithQuery applies a JMESPath expression to the input data. An
+// empty expression returns the data unchanged.
+func (jt *JMESPathTransformer) TransformWithQuery(data any, query string) (any, error) {
+	if query == "" {
+		return data, nil
+	}
+
+	expression, err := jmespath.Compile(query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid jmespath query: %w", err)
+	}
+
+	result, err := expression.Search(data)
+	if err != nil {
+		return nil, fmt.Errorf("jmespath query execution failed: %w", err)
+	}
+	return result, nil
+}
+
+// ValidateQuery validates that a JMESPath expression is syntactically correct
+func (jt *JMESPathTransformer) ValidateQuery(query string) error {
+	if query == "" {
+		return nil
+	}
+
+	if _, err := jmespath.Compile(query); err != nil {
+		return fmt.Errorf("invalid jmespath query: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,254 @@
+package transform
+
+import (
+	"testing"
+
+	"jq-proxy-service/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJMESPathTransformer_TransformWithQuery(t *testing.T) {
+	transformer := NewJMESPathTransformer()
+
+	// Same data as the jq tests, with numbers as decoded from JSON
+	sampleData := map[string]interface{}{
+		"users": []interface{}{
+			map[string]interface{}{
+				"id":    float64(1),
+				"name":  "John Doe",
+				"email": "john@example.com",
+				"profile": map[string]interface{}{
+					"age":  float64(30),
+					"city": "New York",
+				},
+			},
+			map[string]interface{}{
+				"id":    float64(2),
+				"name":  "Jane Smith",
+				"email": "jane@example.com",
+				"profile": map[string]interface{}{
+					"age":  float64(25),
+					"city": "Los Angeles",
+				},
+			},
+		},
+		"total": float64(2),
+		"page":  float64(1),
+	}
+
+	tests := []struct {
+		name        string
+		data        interface{}
+		query       string
+		expected    interface{}
+		expectError bool
+		errorMsg    string
+	}{
+		{
+			name:     "simple field extraction",
+			data:     sampleData,
+			query:    "total",
+			expected: float64(2),
+		},
+		{
+			name:     "array length",
+			data:     sampleData,
+			query:    "length(users)",
+			expected: float64(2),
+		},
+		{
+			name:     "map over array",
+			data:     sampleData,
+			query:    "users[*].name",
+			expected: []interface{}{"John Doe", "Jane Smith"},
+		},
+		{
+			name:  "select and transform",
+			data:  sampleData,
+			query: "users[*].{id: id, name: name}",
+			expected: []interface{}{
+				map[string]interface{}{"id": float64(1), "name": "John Doe"},
+				map[string]interface{}{"id": float64(2), "name": "Jane Smith"},
+			},
+		},
+		{
+			name:     "filter and map",
+			data:     sampleData,
+			query:    "users[?profile.age > `25`].name",
+			expected: []interface{}{"John Doe"},
+		},
+		{
+			name:     "nested field extraction",
+			data:     sampleData,
+			query:    "users[0].profile.city",
+			expected: "New York",
+		},
+		{
+			name:  "complex transformation",
+			data:  sampleData,
+			query: "{total_users: total, user_names: users[*].name, avg_age: avg(users[*].profile.age)}",
+			expected: map[string]interface{}{
+				"total_users": float64(2),
+				"user_names":  []interface{}{"John Doe", "Jane Smith"},
+				"avg_age":     27.5,
+			},
+		},
+		{
+			name:     "identity query",
+			data:     sampleData,
+			query:    "@",
+			expected: sampleData,
+		},
+		{
+			name:     "empty query",
+			data:     sampleData,
+			query:    "",
+			expected: sampleData,
+		},
+		{
+			name:        "invalid query",
+			data:        sampleData,
+			query:       "invalid_function(users)",
+			expectError: true,
+			errorMsg:    "jmespath query execution failed",
+		},
+		{
+			name:        "syntax error",
+			data:        sampleData,
+			query:       "users[",
+			expectError: true,
+			errorMsg:    "invalid jmespath query",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := transformer.TransformWithQuery(tt.data, tt.query)
+
+			if tt.expectError {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+				assert.Nil(t, result)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestJMESPathTransformer_ValidateQuery(t *testing.T) {
+	transformer := NewJMESPathTransformer()
+
+	tests := []struct {
+		name        string
+		query       string
+		expectError bool
+	}{
+		{name: "valid simple query", query: "name"},
+		{name: "valid complex query", query: "users[?age > `25`].{name: name, age: age}"},
+		{name: "empty query", query: ""},
+		{name: "invalid syntax", query: "users[", expectError: true},
+		{name: "unterminated literal", query: "users[?age > `25]", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := transformer.ValidateQuery(tt.query)
+
+			if tt.expectError {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), "invalid jmespath query")
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestJMESPathTransformer_Transform(t *testing.T) {
+	transformer := NewJMESPathTransformer()
+	data := map[string]interface{}{"items": []interface{}{"a", "b"}, "count": float64(2)}
+
+	tests := []struct {
+		name     string
+		query    string
+		collect  bool
+		onError  string
+		expected interface{}
+		wantErr  bool
+	}{
+		{name: "single value", query: "count", expected: float64(2)},
+		{name: "collected value", query: "count", collect: true, expected: []interface{}{float64(2)}},
+		{name: "collected array is wrapped", query: "items", collect: true, expected: []interface{}{[]interface{}{"a", "b"}}},
+		{name: "collected null is empty", query: "missing", collect: true, expected: []interface{}{}},
+		{name: "runtime error fails by default", query: "length(count)", wantErr: true},
+		{name: "runtime error as null", query: "length(count)", onError: models.OnErrorNull, expected: nil},
+		{name: "runtime error as empty", query: "length(count)", onError: models.OnErrorEmpty, collect: true, expected: []interface{}{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &models.ProxyRequest{
+				TransformationMode: models.TransformationModeJMESPath,
+				JQQuery:            tt.query,
+				JQCollect:          tt.collect,
+				OnError:            tt.onError,
+			}
+			result, err := transformer.Transform(data, req)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestUnifiedTransformer_TransformResponse_JMESPath(t *testing.T) {
+	transformer := NewUnifiedTransformer()
+	data := map[string]interface{}{
+		"data": map[string]interface{}{
+			"users": []interface{}{
+				map[string]interface{}{"name": "John", "active": true},
+				map[string]interface{}{"name": "Jane", "active": false},
+			},
+		},
+	}
+
+	req := &models.ProxyRequest{
+		Method:             "GET",
+		TransformationMode: models.TransformationModeJMESPath,
+		JMESPathQuery:      "data.users[?active].name",
+	}
+	require.NoError(t, req.Validate())
+	require.NoError(t, transformer.ValidateTransformation(req))
+	result, err := transformer.TransformResponse(data, req, 200)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"John"}, result)
+
+	// JMESPath and jq stages can be mixed in a pipeline
+	pipeline := &models.ProxyRequest{
+		Method: "GET",
+		Pipeline: []models.TransformationStage{
+			{Mode: models.TransformationModeJMESPath, Query: "data.users[*].name"},
+			{Mode: models.TransformationModeJQ, Query: "join(\", \")"},
+		},
+	}
+	require.NoError(t, pipeline.Validate())
+	result, err = transformer.TransformResponse(data, pipeline, 200)
+	require.NoError(t, err)
+	assert.Equal(t, "John, Jane", result)
+
+	invalid := &models.ProxyRequest{
+		Method:             "GET",
+		TransformationMode: models.TransformationModeJMESPath,
+		JMESPathQuery:      "data.users[",
+	}
+	err = transformer.ValidateTransformation(invalid)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid jmespath query")
+}
@@ -2,74 +2,412 @@
 package transform
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"jq-proxy-service/internal/models"
 
 	"github.com/itchyny/gojq"
 )
 
+// ErrTransformTimeout is returned when a jq query exceeds the maximum execution time
+var ErrTransformTimeout = errors.New("jq query exceeded maximum execution time")
+
+// ErrEnvDisabled is returned for jq queries that read the environment with env
+// or $ENV while no environment variables are exposed to them
+var ErrEnvDisabled = errors.New("env and $ENV are disabled; list the variables to expose in server.jq_env")
+
+// snippetRadius is how many bytes of the query are shown on each side of a syntax error
+const snippetRadius = 20
+
+// QuerySyntaxError describes where a jq query failed to parse
+type QuerySyntaxError struct {
+	// Offset is the 0-based byte offset of the offending token in the query
+	Offset int
+	// Line and Column are the 1-based position of the offending token;
+	// Column counts characters, not bytes
+	Line   int
+	Column int
+	// Snippet is the part of the offending line around the error
+	Snippet string
+
+	err error
+}
+
+// newQuerySyntaxError locates a gojq parse error within the query. Errors
+// that carry no position are returned unchanged.
+func newQuerySyntaxError(query string, err error) error {
+	var parseErr *gojq.ParseError
+	if !errors.As(err, &parseErr) {
+		return err
+	}
+
+	offset := min(max(parseErr.Offset-len(parseErr.Token), 0), len(query))
+	lineStart := strings.LastIndexByte(query[:offset], '\n') + 1
+	lineEnd := len(query)
+	if i := strings.IndexByte(query[offset:], '\n'); i >= 0 {
+		lineEnd = offset + i
+	}
+
+	// Keep the snippet within the line and on character boundaries
+	from := max(offset-snippetRadius, lineStart)
+	for from > lineStart && !utf8.RuneStart(query[from]) {
+		from--
+	}
+	to := min(offset+snippetRadius, lineEnd)
+	for to < lineEnd && !utf8.RuneStart(query[to]) {
+		to++
+	}
+
+	return &QuerySyntaxError{
+		Offset:  offset,
+		Line:    strings.Count(query[:offset], "\n") + 1,
+		Column:  utf8.RuneCountInString(query[lineStart:offset]) + 1,
+		Snippet: query[from:to],
+		err:     err,
+	}
+}
+
+func (e *QuerySyntaxError) Error() string {
+	return fmt.Sprintf("%v at line %d, column %d", e.err, e.Line, e.Column)
+}
+
+func (e *QuerySyntaxError) Unwrap() error {
+	return e.err
+}
+
 // JQTransformer implements jq-based transformations
-type JQTransformer struct{}
+type JQTransformer struct {
+	maxExecutionTime time.Duration
+
+	// maxComplexity is the budget for EstimateComplexity; zero disables the check
+	maxComplexity int
+
+	// library holds function definitions made available to every query
+	library []*gojq.FuncDef
+
+	// env names the environment variables env and $ENV expose; when empty,
+	// queries reading them are rejected
+	env []string
+}
 
 // NewJQTransformer creates a new jq transformer
 func NewJQTransformer() *JQTransformer {
 	return &JQTransformer{}
 }
 
-// TransformWithQuery applies a jq query to the input data
-func (jt *JQTransformer) TransformWithQuery(data any, query string) (any, error) {
+// SetMaxExecutionTime bounds how long a single query may run; zero disables the limit
+func (jt *JQTransformer) SetMaxExecutionTime(d time.Duration) {
+	jt.maxExecutionTime = d
+}
+
+// SetMaxComplexity rejects queries whose estimated cost exceeds budget during
+// validation; zero disables the check
+func (jt *JQTransformer) SetMaxComplexity(budget int) {
+	jt.maxComplexity = budget
+}
+
+// SetEnv exposes the named environment variables to queries through env and
+// $ENV. Without any, queries that read the environment are rejected so they
+// cannot leak the process environment.
+func (jt *JQTransformer) SetEnv(names []string) {
+	jt.env = slices.Clone(names)
+}
+
+// SetLibrary makes the jq function definitions in src available to every
+// query. The library may only contain definitions, e.g.
+// "def cents: . * 100 | round;", and must compile on its own. Queries may
+// redefine library functions. An empty src removes the library.
+func (jt *JQTransformer) SetLibrary(src string) error {
+	if strings.TrimSpace(src) == "" {
+		jt.library = nil
+		return nil
+	}
+
+	// Definitions need a body to parse; anything besides definitions in the
+	// library makes the added body a syntax error
+	q, err := gojq.Parse(src + "\n.")
+	if err != nil {
+		return fmt.Errorf("invalid jq library: %w", err)
+	}
+	if _, err := gojq.Compile(q); err != nil {
+		return fmt.Errorf("failed to compile jq library: %w", err)
+	}
+
+	jt.library = q.FuncDefs
+	return nil
+}
+
+// Transform applies the request's jq query to data, honoring its JQCollect
+// and OnError settings
+func (jt *JQTransformer) Transform(data any, req *models.ProxyRequest) (any, error) {
+	query := guardQuery(req.JQQuery, req.OnError)
+	if req.JQCollect {
+		return jt.TransformAll(data, query, req.Variables)
+	}
+	return jt.TransformWithQuery(data, query, req.Variables)
+}
+
+// Validate checks that the request's jq query is syntactically correct
+func (jt *JQTransformer) Validate(req *models.ProxyRequest) error {
+	return jt.ValidateQuery(req.JQQuery)
+}
+
+// guardQuery wraps a query in try/catch so runtime errors end it with null or
+// no further values instead of failing. Values produced before the error are
+// kept. The newline keeps a trailing comment from swallowing the closing
+// parenthesis. Execution time limits still fail the query.
+func guardQuery(query, onError string) string {
+	if query == "" {
+		return query
+	}
+	switch onError {
+	case models.OnErrorNull:
+		return "try (" + query + "\n) catch null"
+	case models.OnErrorEmpty:
+		return "try (" + query + "\n)"
+	default:
+		return query
+	}
+}
+
+// TransformWithQuery applies a jq query to the input data, with vars available
+// to it as $name variables. A query yielding a single value returns that
+// value, no values returns nil, and several values are returned as an array.
+func (jt *JQTransformer) TransformWithQuery(data any, query string, vars map[string]any) (any, error) {
 	if query == "" {
 		return data, nil
 	}
 
+	results, err := jt.TransformAll(data, query, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	// Return single result if only one, otherwise return array
+	switch {
+	case len(results) == 0:
+		return nil, nil
+	case len(results) == 1:
+		return results[0], nil
+	default:
+		return results, nil
+	}
+}
+
+// TransformAll applies a jq query to the input data and returns every value it
+// yields as an array, regardless of how many there are
+func (jt *JQTransformer) TransformAll(data any, query string, vars map[string]any) ([]any, error) {
+	if query == "" {
+		return []any{data}, nil
+	}
+
+	next, cancel, err := jt.run(data, query, vars)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	results := []any{}
+	for {
+		v, ok, err := next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		results = append(results, v)
+	}
+
+	return results, nil
+}
+
+// StreamWithQuery applies a jq query like TransformWithQuery, or like
+// TransformAll when collect is set, and hands the result to the sink as it is
+// produced. Array results are delivered element by element, so a query
+// yielding many values starts emitting before it has finished running.
+func (jt *JQTransformer) StreamWithQuery(data any, query string, vars map[string]any, collect bool, sink models.ResultSink) error {
+	if query == "" {
+		query = "."
+	}
+
+	next, cancel, err := jt.run(data, query, vars)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	// Collected results are always an array of every value
+	if collect {
+		return streamArray(sink, nil, next)
+	}
+
+	// Otherwise look ahead one value to tell a single result from several
+	first, ok, err := next()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return sink.Value(nil)
+	}
+	second, ok, err := next()
+	if err != nil {
+		return err
+	}
+	if ok {
+		return streamArray(sink, []any{first, second}, next)
+	}
+	if elements, isArray := first.([]any); isArray {
+		return streamArray(sink, elements, func() (any, bool, error) { return nil, false, nil })
+	}
+	return sink.Value(first)
+}
+
+// streamArray delivers the given elements followed by the remaining values
+// of next as an array
+func streamArray(sink models.ResultSink, elements []any, next func() (any, bool, error)) error {
+	if err := sink.BeginArray(); err != nil {
+		return err
+	}
+	for _, v := range elements {
+		if err := sink.Element(v); err != nil {
+			return err
+		}
+	}
+	for {
+		v, ok, err := next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return sink.EndArray()
+		}
+		if err := sink.Element(v); err != nil {
+			return err
+		}
+	}
+}
+
+// run compiles a jq query and starts it on the input data, binding each of
+// vars to a $name variable. The returned function yields the query's values
+// one at a time; cancel releases the execution deadline and must be called
+// once iteration is done.
+func (jt *JQTransformer) run(data any, query string, vars map[string]any) (next func() (any, bool, error), cancel context.CancelFunc, err error) {
 	// Parse the jq query
 	q, err := gojq.Parse(query)
 	if err != nil {
-		return nil, fmt.Errorf("invalid jq query: %w", err)
+		return nil, nil, fmt.Errorf("invalid jq query: %w", newQuerySyntaxError(query, err))
+	}
+
+	jt.addLibrary(q)
+
+	// Declare the variables in a fixed order so their values line up
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	values := make([]any, len(names))
+	for i, name := range names {
+		values[i] = vars[name]
+		names[i] = "$" + name
 	}
 
 	// Compile the query for better performance
-	code, err := gojq.Compile(q)
+	var envBlocked bool
+	code, err := gojq.Compile(q, gojq.WithVariables(names), jt.environLoader(&envBlocked))
 	if err != nil {
-		return nil, fmt.Errorf("failed to compile jq query: %w", err)
+		return nil, nil, fmt.Errorf("failed to compile jq query: %w", err)
+	}
+	if envBlocked {
+		return nil, nil, fmt.Errorf("invalid jq query: %w", ErrEnvDisabled)
 	}
 
-	// Execute the query
-	iter := code.Run(data)
+	// Execute the query, bounded by the maximum execution time if configured
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if jt.maxExecutionTime > 0 {
+		ctx, cancel = context.WithTimeout(ctx, jt.maxExecutionTime)
+	}
+	iter := code.RunWithContext(ctx, data, values...)
 
-	var results []interface{}
-	for {
+	next = func() (any, bool, error) {
 		v, ok := iter.Next()
 		if !ok {
-			break
+			return nil, false, nil
 		}
 		if err, ok := v.(error); ok {
-			return nil, fmt.Errorf("jq query execution failed: %w", err)
+			if errors.Is(err, context.DeadlineExceeded) {
+				return nil, false, fmt.Errorf("%w (%s)", ErrTransformTimeout, jt.maxExecutionTime)
+			}
+			return nil, false, fmt.Errorf("jq query execution failed: %w", err)
 		}
-		results = append(results, v)
-	}
-
-	// Return single result if only one, otherwise return array
-	switch {
-	case len(results) == 0:
-		return nil, nil
-	case len(results) == 1:
-		return results[0], nil
-	default:
-		return results, nil
+		return v, true, nil
 	}
+	return next, cancel, nil
 }
 
-// ValidateQuery validates that a jq query is syntactically correct
+// ValidateQuery validates that a jq query is syntactically correct and
+// within the complexity budget
 func (jt *JQTransformer) ValidateQuery(query string) error {
 	if query == "" {
 		return nil
 	}
 
 	// Try to parse the jq query
-	_, err := gojq.Parse(query)
+	parsed, err := gojq.Parse(query)
 	if err != nil {
-		return fmt.Errorf("invalid jq query: %w", err)
+		return fmt.Errorf("invalid jq query: %w", newQuerySyntaxError(query, err))
+	}
+
+	if err := checkComplexity(parsed, jt.maxComplexity); err != nil {
+		return err
+	}
+
+	// Reject reading a blocked environment before the target is called.
+	// Other compile errors, such as variables bound per request, are left
+	// for when the query runs.
+	if len(jt.env) == 0 {
+		var envBlocked bool
+		jt.addLibrary(parsed)
+		_, _ = gojq.Compile(parsed, jt.environLoader(&envBlocked))
+		if envBlocked {
+			return fmt.Errorf("invalid jq query: %w", ErrEnvDisabled)
+		}
 	}
 
 	return nil
 }
+
+// addLibrary makes the library functions available to a parsed query,
+// letting the query's own definitions shadow them
+func (jt *JQTransformer) addLibrary(q *gojq.Query) {
+	if len(jt.library) > 0 {
+		q.FuncDefs = append(slices.Clone(jt.library), q.FuncDefs...)
+	}
+}
+
+// environLoader provides env and $ENV with the exposed environment variables.
+// When none are exposed it provides nothing and sets *blocked, which the
+// compiler only triggers for queries that read the environment.
+func (jt *JQTransformer) environLoader(blocked *bool) gojq.CompilerOption {
+	return gojq.WithEnvironLoader(func() []string {
+		if len(jt.env) == 0 {
+			*blocked = true
+			return nil
+		}
+		var environ []string
+		for _, name := range jt.env {
+			if value, ok := os.LookupEnv(name); ok {
+				environ = append(environ, name+"="+value)
+			}
+		}
+		return environ
+	})
+}
@@ -1,7 +1,9 @@
 package transform
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -123,7 +125,7 @@ func TestJQTransformer_TransformWithQuery(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result, err := transformer.TransformWithQuery(tt.data, tt.query)
+			result, err := transformer.TransformWithQuery(tt.data, tt.query, nil)
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -186,11 +188,51 @@ func TestJQTransformer_ValidateQuery(t *testing.T) {
 	}
 }
 
+func TestJQTransformer_ValidateQuery_SyntaxErrorPosition(t *testing.T) {
+	transformer := NewJQTransformer()
+
+	tests := []struct {
+		name          string
+		query         string
+		expectOffset  int
+		expectLine    int
+		expectColumn  int
+		expectSnippet string
+	}{
+		{name: "unexpected token", query: ".foo | | .bar", expectOffset: 7, expectLine: 1, expectColumn: 8, expectSnippet: ".foo | | .bar"},
+		{name: "unexpected end", query: ".users | map(", expectOffset: 13, expectLine: 1, expectColumn: 14, expectSnippet: ".users | map("},
+		{name: "second line", query: ".a\n| .b ]", expectOffset: 8, expectLine: 2, expectColumn: 6, expectSnippet: "| .b ]"},
+		{
+			name:          "long query",
+			query:         ".items | map({id: .id, name: .name}) ) | map(select(.id > 10)) | length",
+			expectOffset:  37,
+			expectLine:    1,
+			expectColumn:  38,
+			expectSnippet: " .id, name: .name}) ) | map(select(.id >",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := transformer.ValidateQuery(tt.query)
+			require.Error(t, err)
+
+			var syntaxErr *QuerySyntaxError
+			require.ErrorAs(t, err, &syntaxErr)
+			assert.Equal(t, tt.expectOffset, syntaxErr.Offset)
+			assert.Equal(t, tt.expectLine, syntaxErr.Line)
+			assert.Equal(t, tt.expectColumn, syntaxErr.Column)
+			assert.Equal(t, tt.expectSnippet, syntaxErr.Snippet)
+			assert.Contains(t, err.Error(), fmt.Sprintf("line %d, column %d", tt.expectLine, tt.expectColumn))
+		})
+	}
+}
+
 func TestJQTransformer_Transform_Legacy(t *testing.T) {
 	transformer := NewJQTransformer()
 
 	// The TransformWithQuery method should work with jq queries
-	result, err := transformer.TransformWithQuery(map[string]interface{}{"test": "value"}, "{result: .test}")
+	result, err := transformer.TransformWithQuery(map[string]interface{}{"test": "value"}, "{result: .test}", nil)
 
 	assert.NoError(t, err)
 	expected := map[string]interface{}{"result": "value"}
@@ -246,9 +288,244 @@ func TestJQTransformer_EdgeCases(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result, err := transformer.TransformWithQuery(tt.data, tt.query)
+			result, err := transformer.TransformWithQuery(tt.data, tt.query, nil)
 			require.NoError(t, err)
 			assert.Equal(t, tt.expected, result)
 		})
 	}
 }
+
+func TestJQTransformer_MaxExecutionTime(t *testing.T) {
+	transformer := NewJQTransformer()
+	transformer.SetMaxExecutionTime(50 * time.Millisecond)
+
+	start := time.Now()
+	result, err := transformer.TransformWithQuery(map[string]interface{}{}, "last(range(1e12))", nil)
+
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrTransformTimeout)
+	assert.Nil(t, result)
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	// Cheap queries are unaffected by the limit
+	result, err = transformer.TransformWithQuery(map[string]interface{}{"total": 2}, ".total", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result)
+}
+
+func TestJQTransformer_TransformWithQuery_Variables(t *testing.T) {
+	transformer := NewJQTransformer()
+	data := map[string]interface{}{
+		"orders": []interface{}{
+			map[string]interface{}{"id": "a", "user": "42"},
+			map[string]interface{}{"id": "b", "user": "7"},
+		},
+	}
+	vars := map[string]any{"id": "42", "label": "mine"}
+
+	result, err := transformer.TransformWithQuery(data, `{($label): [.orders[] | select(.user == $id) | .id]}`, vars)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"mine": []interface{}{"a"}}, result)
+
+	sink := &collectingSink{}
+	require.NoError(t, transformer.StreamWithQuery(data, ".orders[] | select(.user == $id) | .id", vars, true, sink))
+	assert.Equal(t, []interface{}{"a"}, sink.result)
+
+	// Undefined variables fail to compile
+	_, err = transformer.TransformWithQuery(data, "$missing", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to compile jq query")
+}
+
+func TestJQTransformer_Env(t *testing.T) {
+	t.Setenv("JQ_PROXY_TEST_REGION", "eu-west-1")
+	t.Setenv("JQ_PROXY_TEST_SECRET", "hunter2")
+	transformer := NewJQTransformer()
+
+	// Reading the environment is blocked by default, at validation and when run
+	for _, query := range []string{"env.JQ_PROXY_TEST_SECRET", "$ENV.JQ_PROXY_TEST_SECRET", "[.[] | env]"} {
+		err := transformer.ValidateQuery(query)
+		assert.ErrorIs(t, err, ErrEnvDisabled, query)
+
+		_, err = transformer.TransformWithQuery(map[string]interface{}{}, query, nil)
+		assert.ErrorIs(t, err, ErrEnvDisabled, query)
+	}
+
+	// Queries defining their own env are unaffected
+	result, err := transformer.TransformWithQuery(nil, "def env: {}; env", nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{}, result)
+
+	// Only the configured variables are exposed
+	transformer.SetEnv([]string{"JQ_PROXY_TEST_REGION", "JQ_PROXY_TEST_UNSET"})
+	require.NoError(t, transformer.ValidateQuery("env.JQ_PROXY_TEST_REGION"))
+	result, err = transformer.TransformWithQuery(nil, "env", nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"JQ_PROXY_TEST_REGION": "eu-west-1"}, result)
+	result, err = transformer.TransformWithQuery(nil, "$ENV.JQ_PROXY_TEST_SECRET", nil)
+	require.NoError(t, err)
+	assert.Nil(t, result)
+}
+
+func TestJQTransformer_TransformAll(t *testing.T) {
+	transformer := NewJQTransformer()
+	data := map[string]interface{}{"items": []interface{}{1, 2}}
+
+	results, err := transformer.TransformAll(data, ".items[]", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{1, 2}, results)
+
+	results, err = transformer.TransformAll(data, ".items[0]", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{1}, results)
+
+	results, err = transformer.TransformAll(data, "empty", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{}, results)
+
+	_, err = transformer.TransformAll(data, ".items | map(", nil)
+	assert.Error(t, err)
+}
+
+// collectingSink rebuilds a streamed result so it can be compared with the buffered one
+type collectingSink struct {
+	result   interface{}
+	array    []interface{}
+	inArray  bool
+	elements int
+}
+
+func (s *collectingSink) Value(v interface{}) error {
+	s.result = v
+	return nil
+}
+
+func (s *collectingSink) BeginArray() error {
+	s.inArray = true
+	s.array = []interface{}{}
+	return nil
+}
+
+func (s *collectingSink) Element(v interface{}) error {
+	s.array = append(s.array, v)
+	s.elements++
+	return nil
+}
+
+func (s *collectingSink) EndArray() error {
+	s.result = s.array
+	return nil
+}
+
+func TestJQTransformer_StreamWithQuery(t *testing.T) {
+	transformer := NewJQTransformer()
+	data := map[string]interface{}{
+		"items": []interface{}{
+			map[string]interface{}{"id": 1},
+			map[string]interface{}{"id": 2},
+			map[string]interface{}{"id": 3},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		query   string
+		collect bool
+		array   bool
+	}{
+		{name: "multiple outputs", query: ".items[] | .id", array: true},
+		{name: "single array", query: "[.items[] | .id]", array: true},
+		{name: "single object", query: ".items[0]"},
+		{name: "scalar", query: ".items | length"},
+		{name: "no output", query: "empty"},
+		{name: "collect single output", query: ".items[0].id", collect: true, array: true},
+		{name: "collect no output", query: "empty", collect: true, array: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var expected interface{}
+			var err error
+			if tt.collect {
+				expected, err = transformer.TransformAll(data, tt.query, nil)
+			} else {
+				expected, err = transformer.TransformWithQuery(data, tt.query, nil)
+			}
+			require.NoError(t, err)
+
+			sink := &collectingSink{}
+			require.NoError(t, transformer.StreamWithQuery(data, tt.query, nil, tt.collect, sink))
+			assert.Equal(t, expected, sink.result)
+			assert.Equal(t, tt.array, sink.inArray)
+		})
+	}
+}
+
+func TestJQTransformer_StreamWithQuery_Errors(t *testing.T) {
+	transformer := NewJQTransformer()
+	data := map[string]interface{}{"items": []interface{}{1, 2, "three", 4}}
+
+	err := transformer.StreamWithQuery(data, ".items | map(", nil, false, &collectingSink{})
+	assert.Error(t, err)
+
+	// Elements produced before the failure have already been delivered
+	sink := &collectingSink{}
+	err = transformer.StreamWithQuery(data, ".items[] | . + 1", nil, false, sink)
+	assert.Error(t, err)
+	assert.True(t, sink.inArray)
+	assert.Equal(t, 2, sink.elements)
+}
+
+func TestJQTransformer_Library(t *testing.T) {
+	transformer := NewJQTransformer()
+	require.NoError(t, transformer.SetLibrary(`
+		# Shared helpers
+		def myhelper(f): f | map(.name);
+		def double: . * 2;
+	`))
+
+	data := map[string]interface{}{
+		"data":  []interface{}{map[string]interface{}{"name": "a"}, map[string]interface{}{"name": "b"}},
+		"count": 3,
+	}
+
+	result, err := transformer.TransformWithQuery(data, "myhelper(.data)", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"a", "b"}, result)
+
+	// Queries may shadow library functions with their own definitions
+	result, err = transformer.TransformWithQuery(data, "def double: . * 3; .count | double", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 9, result)
+
+	// Streaming queries see the library too
+	sink := &collectingSink{}
+	require.NoError(t, transformer.StreamWithQuery(data, ".count | double", nil, false, sink))
+	assert.Equal(t, 6, sink.result)
+
+	// Removing the library makes its functions undefined again
+	require.NoError(t, transformer.SetLibrary(""))
+	_, err = transformer.TransformWithQuery(data, ".count | double", nil)
+	assert.Error(t, err)
+}
+
+func TestJQTransformer_LibraryErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		library string
+		errMsg  string
+	}{
+		{name: "syntax error", library: "def broken: . +;", errMsg: "invalid jq library"},
+		{name: "not only definitions", library: "def ok: 1; .foo", errMsg: "invalid jq library"},
+		{name: "undefined function", library: "def helper: nosuch;", errMsg: "failed to compile jq library"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transformer := NewJQTransformer()
+			err := transformer.SetLibrary(tt.library)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
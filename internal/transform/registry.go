@@ -0,0 +1,89 @@
+package transform
+
+import (
+	"sort"
+	"sync"
+
+	"jq-proxy-service/internal/models"
+)
+
+// Transformer is a transformation engine for one TransformationMode. The
+// request passed to it describes a single stage: the query to run is in
+// JQQuery whatever the mode, and JQCollect and OnError apply to that stage.
+type Transformer interface {
+	// Transform applies the request's query to data
+	Transform(data any, req *models.ProxyRequest) (any, error)
+
+	// Validate checks the request's query before the target is called
+	Validate(req *models.ProxyRequest) error
+}
+
+// singleValueResult applies a stage's JQCollect and OnError settings to the
+// outcome of a transformer that always yields exactly one value. With
+// JQCollect the value is returned as a one-element array, or an empty array
+// for null. A runtime error becomes null or no value when OnError asks for it.
+func singleValueResult(result any, err error, req *models.ProxyRequest) (any, error) {
+	if err != nil {
+		switch req.OnError {
+		case models.OnErrorNull:
+			if req.JQCollect {
+				return []any{nil}, nil
+			}
+			return nil, nil
+		case models.OnErrorEmpty:
+			result = nil
+		default:
+			return nil, err
+		}
+	}
+
+	if !req.JQCollect {
+		return result, nil
+	}
+	if result == nil {
+		return []any{}, nil
+	}
+	return []any{result}, nil
+}
+
+// Registry maps transformation modes to the transformers that implement them
+type Registry struct {
+	mu           sync.RWMutex
+	transformers map[models.TransformationMode]Transformer
+}
+
+// NewRegistry creates an empty transformer registry
+func NewRegistry() *Registry {
+	return &Registry{transformers: make(map[models.TransformationMode]Transformer)}
+}
+
+// Register makes t handle requests for the given mode, replacing any
+// transformer already registered for it
+func (r *Registry) Register(mode models.TransformationMode, t Transformer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.transformers[mode] = t
+}
+
+// Get returns the transformer registered for mode. An empty mode means jq.
+func (r *Registry) Get(mode models.TransformationMode) (Transformer, bool) {
+	if mode == "" {
+		mode = models.TransformationModeJQ
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.transformers[mode]
+	return t, ok
+}
+
+// Modes returns the registered modes in sorted order
+func (r *Registry) Modes() []models.TransformationMode {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	modes := make([]models.TransformationMode, 0, len(r.transformers))
+	for mode := range r.transformers {
+		modes = append(modes, mode)
+	}
+	sort.Slice(modes, func(i, j int) bool { return modes[i] < modes[j] })
+	return modes
+}
@@ -0,0 +1,109 @@
+package transform
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"jq-proxy-service/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// upperTransformer is a fake transformer that upper-cases the string field
+// named by the query
+type upperTransformer struct {
+	requests []*models.ProxyRequest
+}
+
+func (u *upperTransformer) Transform(data any, req *models.ProxyRequest) (any, error) {
+	u.requests = append(u.requests, req)
+	object, ok := data.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("input must be an object")
+	}
+	value, _ := object[req.JQQuery].(string)
+	return strings.ToUpper(value), nil
+}
+
+func (u *upperTransformer) Validate(req *models.ProxyRequest) error {
+	if strings.ContainsAny(req.JQQuery, ".[") {
+		return errors.New("query must be a field name")
+	}
+	return nil
+}
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+	upper := &upperTransformer{}
+	registry.Register("upper", upper)
+	registry.Register(models.TransformationModeJQ, NewJQTransformer())
+
+	got, ok := registry.Get("upper")
+	require.True(t, ok)
+	assert.Same(t, upper, got)
+
+	_, ok = registry.Get("")
+	assert.True(t, ok, "an empty mode is jq")
+
+	_, ok = registry.Get("jsonpath")
+	assert.False(t, ok)
+
+	assert.Equal(t, []models.TransformationMode{models.TransformationModeJQ, "upper"}, registry.Modes())
+}
+
+func TestUnifiedTransformer_RegisteredTransformer(t *testing.T) {
+	transformer := NewUnifiedTransformer()
+	upper := &upperTransformer{}
+	transformer.Register("upper", upper)
+
+	data := map[string]interface{}{"user": map[string]interface{}{"name": "jane"}}
+
+	t.Run("request mode", func(t *testing.T) {
+		req := &models.ProxyRequest{Method: "GET", TransformationMode: "upper", JQQuery: "greeting"}
+		require.NoError(t, transformer.ValidateTransformation(req))
+
+		result, err := transformer.TransformResponse(map[string]interface{}{"greeting": "hello"}, req, 200)
+		require.NoError(t, err)
+		assert.Equal(t, "HELLO", result)
+	})
+
+	t.Run("pipeline stage after jq", func(t *testing.T) {
+		req := &models.ProxyRequest{
+			Method:             "GET",
+			TransformationMode: models.TransformationModeJQ,
+			JQCollect:          true,
+			Pipeline: []models.TransformationStage{
+				{Mode: models.TransformationModeJQ, Query: ".user"},
+				{Mode: "upper", Query: "name"},
+			},
+		}
+		require.NoError(t, transformer.ValidateTransformation(req))
+
+		result, err := transformer.TransformResponse(data, req, 200)
+		require.NoError(t, err)
+		assert.Equal(t, "JANE", result)
+
+		// The transformer sees only its own stage
+		last := upper.requests[len(upper.requests)-1]
+		assert.Equal(t, models.TransformationMode("upper"), last.TransformationMode)
+		assert.Equal(t, "name", last.JQQuery)
+		assert.Empty(t, last.Pipeline)
+		assert.True(t, last.JQCollect)
+	})
+
+	t.Run("validation uses the registered transformer", func(t *testing.T) {
+		req := &models.ProxyRequest{Method: "GET", TransformationMode: "upper", JQQuery: ".greeting"}
+		err := transformer.ValidateTransformation(req)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "query must be a field name")
+	})
+
+	t.Run("streamed", func(t *testing.T) {
+		req := &models.ProxyRequest{Method: "GET", TransformationMode: "upper", JQQuery: "greeting"}
+		sink := &collectingSink{}
+		require.NoError(t, transformer.StreamResponse(map[string]interface{}{"greeting": "hi"}, req, 200, sink))
+		assert.Equal(t, "HI", sink.result)
+	})
+}
@@ -0,0 +1,77 @@
+package transform
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"jq-proxy-service/internal/models"
+)
+
+// templateFuncs are available to every template in addition to the text/template builtins
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"join": func(sep string, values []any) string {
+		parts := make([]string, len(values))
+		for i, v := range values {
+			parts[i] = fmt.Sprint(v)
+		}
+		return strings.Join(parts, sep)
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// TemplateTransformer renders Go text/template templates over the response
+// data, producing text rather than JSON
+type TemplateTransformer struct{}
+
+// NewTemplateTransformer creates a new template transformer
+func NewTemplateTransformer() *TemplateTransformer {
+	return &TemplateTransformer{}
+}
+
+// Transform renders the request's template, held in JQQuery for the stage,
+// against data and returns the text as a string. JQCollect and OnError apply
+// as described for singleValueResult.
+func (tt *TemplateTransformer) Transform(data any, req *models.ProxyRequest) (any, error) {
+	result, err := tt.Render(data, req.JQQuery)
+	if err != nil {
+		return singleValueResult(nil, err, req)
+	}
+	return singleValueResult(result, nil, req)
+}
+
+// Validate checks that the request's template parses
+func (tt *TemplateTransformer) Validate(req *models.ProxyRequest) error {
+	_, err := parseTemplate(req.JQQuery)
+	return err
+}
+
+// Render executes a template against the input data
+func (tt *TemplateTransformer) Render(data any, text string) (string, error) {
+	tmpl, err := parseTemplate(text)
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("template execution failed: %w", err)
+	}
+	return out.String(), nil
+}
+
+// parseTemplate parses a template, failing on references to missing map keys
+// so typos are reported rather than rendered as "<no value>"
+func parseTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("response").Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	return tmpl, nil
+}
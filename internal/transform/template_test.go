@@ -0,0 +1,124 @@
+package transform
+
+import (
+	"testing"
+
+	"jq-proxy-service/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplateTransformer_Render(t *testing.T) {
+	transformer := NewTemplateTransformer()
+
+	sampleData := map[string]interface{}{
+		"title": "Weekly report",
+		"users": []interface{}{
+			map[string]interface{}{"name": "John Doe", "age": float64(30)},
+			map[string]interface{}{"name": "Jane Smith", "age": float64(25)},
+		},
+		"tags": []interface{}{"a", "b"},
+	}
+
+	tests := []struct {
+		name        string
+		template    string
+		expected    string
+		expectError bool
+		errorMsg    string
+	}{
+		{
+			name:     "field access",
+			template: "{{.title}}",
+			expected: "Weekly report",
+		},
+		{
+			name:     "range over array",
+			template: "{{range .users}}- {{.name}} ({{.age}})\n{{end}}",
+			expected: "- John Doe (30)\n- Jane Smith (25)\n",
+		},
+		{
+			name:     "conditionals and builtins",
+			template: "{{len .users}} users{{if gt (len .users) 1}}, several{{end}}",
+			expected: "2 users, several",
+		},
+		{
+			name:     "helper functions",
+			template: "{{upper .title}}|{{join \", \" .tags}}|{{json .tags}}",
+			expected: "WEEKLY REPORT|a, b|[\"a\",\"b\"]",
+		},
+		{
+			name:        "missing key",
+			template:    "{{.titel}}",
+			expectError: true,
+			errorMsg:    "template execution failed",
+		},
+		{
+			name:        "syntax error",
+			template:    "{{range .users}}",
+			expectError: true,
+			errorMsg:    "invalid template",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := transformer.Render(sampleData, tt.template)
+
+			if tt.expectError {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestUnifiedTransformer_TransformResponse_Template(t *testing.T) {
+	transformer := NewUnifiedTransformer()
+	data := map[string]interface{}{
+		"data": map[string]interface{}{
+			"users": []interface{}{
+				map[string]interface{}{"name": "John", "active": true},
+				map[string]interface{}{"name": "Jane", "active": false},
+			},
+		},
+	}
+
+	req := &models.ProxyRequest{
+		Method:             "GET",
+		TransformationMode: models.TransformationModeTemplate,
+		Template:           "{{range .data.users}}{{.name}}: {{if .active}}active{{else}}inactive{{end}}\n{{end}}",
+	}
+	require.NoError(t, req.Validate())
+	require.NoError(t, transformer.ValidateTransformation(req))
+	result, err := transformer.TransformResponse(data, req, 200)
+	require.NoError(t, err)
+	assert.Equal(t, "John: active\nJane: inactive\n", result)
+
+	// A jq stage can reshape the data before it is rendered
+	pipeline := &models.ProxyRequest{
+		Method: "GET",
+		Pipeline: []models.TransformationStage{
+			{Mode: models.TransformationModeJQ, Query: "{names: [.data.users[] | select(.active) | .name]}"},
+			{Mode: models.TransformationModeTemplate, Query: "Active: {{join \", \" .names}}"},
+		},
+	}
+	require.NoError(t, pipeline.Validate())
+	result, err = transformer.TransformResponse(data, pipeline, 200)
+	require.NoError(t, err)
+	assert.Equal(t, "Active: John", result)
+
+	// Templates are parsed before the target is called
+	invalid := &models.ProxyRequest{
+		Method:             "GET",
+		TransformationMode: models.TransformationModeTemplate,
+		Template:           "{{if .data}}",
+	}
+	err = transformer.ValidateTransformation(invalid)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid template")
+}
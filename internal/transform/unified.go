@@ -3,36 +3,285 @@ package transform
 
 import (
 	"fmt"
+	"math"
+	"time"
 
 	"jq-proxy-service/internal/models"
 )
 
-// UnifiedTransformer handles jq transformations
+// Engine runs the transformations a proxy request describes. The service
+// depends on it rather than on UnifiedTransformer, its implementation.
+type Engine interface {
+	// ValidateTransformation checks every query of the request, including
+	// that a transformer is registered for each mode it uses
+	ValidateTransformation(req *models.ProxyRequest) error
+	// TransformRequestBody applies the request query to the request body
+	TransformRequestBody(req *models.ProxyRequest) (interface{}, error)
+	// TransformResponse applies the stages for the upstream status to data
+	TransformResponse(data interface{}, req *models.ProxyRequest, statusCode int) (interface{}, error)
+	// StreamResponse is like TransformResponse, writing results to sink as they are produced
+	StreamResponse(data interface{}, req *models.ProxyRequest, statusCode int, sink models.ResultSink) error
+	// ResponseStatus evaluates the request's status query against the result
+	ResponseStatus(data interface{}, req *models.ProxyRequest) (int, bool)
+}
+
+// UnifiedTransformer dispatches transformations to the transformer registered
+// for each stage's mode. Error, status and request queries are always jq.
 type UnifiedTransformer struct {
 	jqTransformer *JQTransformer
+	registry      *Registry
 }
 
-// NewUnifiedTransformer creates a new unified transformer
+// NewUnifiedTransformer creates a new unified transformer with jq registered
 func NewUnifiedTransformer() *UnifiedTransformer {
-	return &UnifiedTransformer{
+	ut := &UnifiedTransformer{
 		jqTransformer: NewJQTransformer(),
+		registry:      NewRegistry(),
 	}
+	ut.registry.Register(models.TransformationModeJQ, ut.jqTransformer)
+	ut.registry.Register(models.TransformationModeJMESPath, NewJMESPathTransformer())
+	ut.registry.Register(models.TransformationModeTemplate, NewTemplateTransformer())
+	return ut
+}
+
+// Register adds a transformer for an additional transformation mode
+func (ut *UnifiedTransformer) Register(mode models.TransformationMode, t Transformer) {
+	ut.registry.Register(mode, t)
+}
+
+// SetMaxTransformTime bounds how long a single jq query may run; zero disables the limit
+func (ut *UnifiedTransformer) SetMaxTransformTime(d time.Duration) {
+	ut.jqTransformer.SetMaxExecutionTime(d)
+}
+
+// SetMaxQueryComplexity rejects jq queries whose estimated cost exceeds budget;
+// zero disables the check
+func (ut *UnifiedTransformer) SetMaxQueryComplexity(budget int) {
+	ut.jqTransformer.SetMaxComplexity(budget)
+}
+
+// SetJQEnv exposes the named environment variables to jq queries through env
+// and $ENV; without any, queries that read the environment are rejected
+func (ut *UnifiedTransformer) SetJQEnv(names []string) {
+	ut.jqTransformer.SetEnv(names)
+}
+
+// SetJQLibrary makes the jq function definitions in src available to every query
+func (ut *UnifiedTransformer) SetJQLibrary(src string) error {
+	return ut.jqTransformer.SetLibrary(src)
 }
 
 // TransformRequest applies transformation based on the proxy request configuration
 func (ut *UnifiedTransformer) TransformRequest(data interface{}, req *models.ProxyRequest) (interface{}, error) {
-	if req.TransformationMode != models.TransformationModeJQ {
+	return ut.TransformResponse(data, req, 0)
+}
+
+// TransformResponse applies the transformation for an upstream response with the
+// given status code, using the request's error query for 4xx/5xx responses if set
+func (ut *UnifiedTransformer) TransformResponse(data interface{}, req *models.ProxyRequest, statusCode int) (interface{}, error) {
+	if !ut.supportsMode(req.TransformationMode) {
 		return nil, fmt.Errorf("unsupported transformation mode: %s", req.TransformationMode)
 	}
-	return ut.jqTransformer.TransformWithQuery(data, req.JQQuery)
+
+	// Path checks replace the transformation, unless an error query applies
+	if len(req.Exists) > 0 && (statusCode < 400 || req.ErrorJQQuery == "") {
+		return CheckPaths(data, req.Exists)
+	}
+
+	stages := req.StagesForStatus(statusCode)
+	if len(stages) == 1 {
+		result, err := ut.transformStage(data, req, stages[0], req.JQCollect)
+		if err != nil {
+			return nil, err
+		}
+		return applyFallbacks(result, req.Fallbacks), nil
+	}
+
+	// Each pipeline stage transforms the previous stage's output; collection
+	// only applies to the final stage
+	result := data
+	for i, stage := range stages {
+		var err error
+		result, err = ut.transformStage(result, req, stage, req.JQCollect && i == len(stages)-1)
+		if err != nil {
+			return nil, fmt.Errorf("pipeline stage %d: %w", i+1, err)
+		}
+	}
+	return applyFallbacks(result, req.Fallbacks), nil
+}
+
+// applyFallbacks fills the top-level fields of an object result that are null
+// or missing with their fallback values. Other results are returned unchanged.
+func applyFallbacks(result interface{}, fallbacks map[string]interface{}) interface{} {
+	object, ok := result.(map[string]interface{})
+	if !ok {
+		return result
+	}
+	for field, fallback := range fallbacks {
+		if value, exists := object[field]; !exists || value == nil {
+			object[field] = fallback
+		}
+	}
+	return object
+}
+
+// StreamResponse applies the transformation like TransformResponse but hands
+// the final stage's result to the sink as it is produced. Earlier pipeline
+// stages are evaluated in full first, as is a final stage in a mode other
+// than jq.
+func (ut *UnifiedTransformer) StreamResponse(data interface{}, req *models.ProxyRequest, statusCode int, sink models.ResultSink) error {
+	if !ut.supportsMode(req.TransformationMode) {
+		return fmt.Errorf("unsupported transformation mode: %s", req.TransformationMode)
+	}
+
+	stages := req.StagesForStatus(statusCode)
+	last := len(stages) - 1
+	result := data
+	for i, stage := range stages[:last] {
+		var err error
+		result, err = ut.transformStage(result, req, stage, false)
+		if err != nil {
+			return fmt.Errorf("pipeline stage %d: %w", i+1, err)
+		}
+	}
+
+	if err := ut.streamStage(result, req, stages[last], sink); err != nil {
+		if last > 0 {
+			return fmt.Errorf("pipeline stage %d: %w", last+1, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// streamStage streams a jq stage's values as they are produced. Stages in
+// other modes are transformed in full and their result streamed afterwards.
+func (ut *UnifiedTransformer) streamStage(data interface{}, req *models.ProxyRequest, stage models.TransformationStage, sink models.ResultSink) error {
+	if stage.Mode == "" || stage.Mode == models.TransformationModeJQ {
+		return ut.jqTransformer.StreamWithQuery(data, guardQuery(stage.Query, req.OnError), req.Variables, req.JQCollect, sink)
+	}
+
+	result, err := ut.transformStage(data, req, stage, req.JQCollect)
+	if err != nil {
+		return err
+	}
+	if elements, isArray := result.([]interface{}); isArray {
+		return streamArray(sink, elements, func() (any, bool, error) { return nil, false, nil })
+	}
+	return sink.Value(result)
+}
+
+// TransformRequestBody rewrites the request body with the request query before
+// it is forwarded upstream. Without a request query the body is returned unchanged.
+func (ut *UnifiedTransformer) TransformRequestBody(req *models.ProxyRequest) (interface{}, error) {
+	return ut.jqTransformer.TransformWithQuery(req.Body, req.RequestJQQuery, req.Variables)
+}
+
+// ResponseStatus evaluates the request's status query against the transformed
+// data. It reports false when there is no status query or when the query fails
+// or does not yield a single integer between 100 and 599.
+func (ut *UnifiedTransformer) ResponseStatus(data interface{}, req *models.ProxyRequest) (int, bool) {
+	if req.StatusJQQuery == "" {
+		return 0, false
+	}
+
+	result, err := ut.jqTransformer.TransformWithQuery(data, req.StatusJQQuery, req.Variables)
+	if err != nil {
+		return 0, false
+	}
+
+	var status int
+	switch v := result.(type) {
+	case int:
+		status = v
+	case float64:
+		if v != math.Trunc(v) || v < 100 || v > 599 {
+			return 0, false
+		}
+		status = int(v)
+	default:
+		return 0, false
+	}
+
+	if status < 100 || status > 599 {
+		return 0, false
+	}
+	return status, true
+}
+
+// supportsMode reports whether a transformer is registered for a request's
+// mode. Requests must name their mode; it is defaulted during validation.
+func (ut *UnifiedTransformer) supportsMode(mode models.TransformationMode) bool {
+	_, ok := ut.registry.Get(mode)
+	return ok && mode != ""
+}
+
+// transformStage applies a single transformation stage with the transformer
+// registered for its mode, returning every result as an array when collect is set
+func (ut *UnifiedTransformer) transformStage(data interface{}, req *models.ProxyRequest, stage models.TransformationStage, collect bool) (interface{}, error) {
+	t, ok := ut.registry.Get(stage.Mode)
+	if !ok {
+		return nil, fmt.Errorf("unsupported transformation mode: %s", stage.Mode)
+	}
+	return t.Transform(data, stageRequest(req, stage, collect))
+}
+
+// stageRequest returns a copy of req describing only the given stage, the
+// form in which transformers receive it
+func stageRequest(req *models.ProxyRequest, stage models.TransformationStage, collect bool) *models.ProxyRequest {
+	stageReq := *req
+	stageReq.TransformationMode = stage.Mode
+	if stageReq.TransformationMode == "" {
+		stageReq.TransformationMode = models.TransformationModeJQ
+	}
+	stageReq.JQQuery = stage.Query
+	stageReq.ErrorJQQuery = ""
+	stageReq.Pipeline = nil
+	stageReq.JQCollect = collect
+	return &stageReq
 }
 
 // ValidateTransformation validates transformation configuration
 func (ut *UnifiedTransformer) ValidateTransformation(req *models.ProxyRequest) error {
-	if req.TransformationMode != models.TransformationModeJQ {
+	if req.TransformationMode == "" {
 		return fmt.Errorf("unsupported transformation mode: %s", req.TransformationMode)
 	}
-	return ut.jqTransformer.ValidateQuery(req.JQQuery)
+	if err := ValidatePaths(req.Exists); err != nil {
+		return err
+	}
+	if err := ut.validateStage(req, models.TransformationStage{Mode: req.TransformationMode, Query: req.ModeQuery()}); err != nil {
+		return err
+	}
+	for value, query := range req.JQQueryByHeader {
+		if err := ut.jqTransformer.ValidateQuery(query); err != nil {
+			return fmt.Errorf("query for %s %s: %w", req.JQQueryHeader, value, err)
+		}
+	}
+	if err := ut.jqTransformer.ValidateQuery(req.ErrorJQQuery); err != nil {
+		return fmt.Errorf("error query: %w", err)
+	}
+	if err := ut.jqTransformer.ValidateQuery(req.StatusJQQuery); err != nil {
+		return fmt.Errorf("status query: %w", err)
+	}
+	if err := ut.jqTransformer.ValidateQuery(req.RequestJQQuery); err != nil {
+		return fmt.Errorf("request query: %w", err)
+	}
+	for i, stage := range req.Pipeline {
+		if err := ut.validateStage(req, stage); err != nil {
+			return fmt.Errorf("pipeline stage %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// validateStage validates a stage's query with the transformer registered for
+// its mode. Error, status and request queries are always jq and are validated directly.
+func (ut *UnifiedTransformer) validateStage(req *models.ProxyRequest, stage models.TransformationStage) error {
+	t, ok := ut.registry.Get(stage.Mode)
+	if !ok {
+		return fmt.Errorf("unsupported transformation mode: %s", stage.Mode)
+	}
+	return t.Validate(stageRequest(req, stage, false))
 }
 
 // GetJQTransformer returns the jq transformer
@@ -72,6 +72,187 @@ func TestUnifiedTransformer_TransformRequest_InvalidMode(t *testing.T) {
 	assert.Nil(t, result)
 }
 
+func TestUnifiedTransformer_TransformResponse_ErrorQuery(t *testing.T) {
+	transformer := NewUnifiedTransformer()
+	data := map[string]interface{}{"data": "ok", "error": "failed"}
+
+	req := &models.ProxyRequest{
+		TransformationMode: models.TransformationModeJQ,
+		JQQuery:            ".data",
+		ErrorJQQuery:       ".error",
+	}
+
+	result, err := transformer.TransformResponse(data, req, 200)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", result)
+
+	result, err = transformer.TransformResponse(data, req, 404)
+	require.NoError(t, err)
+	assert.Equal(t, "failed", result)
+
+	// Without an error query the main query applies to error responses
+	req.ErrorJQQuery = ""
+	result, err = transformer.TransformResponse(data, req, 500)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", result)
+}
+
+func TestUnifiedTransformer_TransformResponse_Pipeline(t *testing.T) {
+	transformer := NewUnifiedTransformer()
+	data := map[string]interface{}{
+		"data": map[string]interface{}{
+			"users": []interface{}{
+				map[string]interface{}{"name": "John", "age": 30},
+				map[string]interface{}{"name": "Jane", "age": 25},
+			},
+		},
+	}
+
+	req := &models.ProxyRequest{
+		TransformationMode: models.TransformationModeJQ,
+		Pipeline: []models.TransformationStage{
+			{Mode: models.TransformationModeJQ, Query: ".data.users"},
+			{Query: "map(select(.age > 26) | .name)"},
+		},
+	}
+
+	result, err := transformer.TransformResponse(data, req, 200)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"John"}, result)
+
+	// Failures report the stage that failed
+	req.Pipeline[1].Query = "error(\"boom\")"
+	_, err = transformer.TransformResponse(data, req, 200)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "pipeline stage 2")
+}
+
+func TestUnifiedTransformer_TransformResponse_OnError(t *testing.T) {
+	transformer := NewUnifiedTransformer()
+
+	// The second item has no numeric price, so the query errors part way through
+	data := []interface{}{
+		map[string]interface{}{"price": "10"},
+		map[string]interface{}{"price": "n/a"},
+		map[string]interface{}{"price": "30"},
+	}
+
+	tests := []struct {
+		name        string
+		query       string
+		onError     string
+		collect     bool
+		expected    interface{}
+		expectError bool
+	}{
+		{name: "fail by default", query: "map(.price | tonumber)", expectError: true},
+		{name: "explicit fail", query: "map(.price | tonumber)", onError: models.OnErrorFail, expectError: true},
+		{name: "null on error", query: "map(.price | tonumber)", onError: models.OnErrorNull, expected: nil},
+		{name: "empty on error", query: "map(.price | tonumber)", onError: models.OnErrorEmpty, collect: true, expected: []interface{}{}},
+		{name: "values before the error are kept", query: ".[] | .price | tonumber", onError: models.OnErrorEmpty, collect: true, expected: []interface{}{10}},
+		{name: "null is appended after kept values", query: ".[] | .price | tonumber", onError: models.OnErrorNull, expected: []interface{}{10, nil}},
+		{name: "successful query unaffected", query: ".[0].price | tonumber # trailing comment", onError: models.OnErrorNull, expected: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &models.ProxyRequest{
+				Method:             "GET",
+				TransformationMode: models.TransformationModeJQ,
+				JQQuery:            tt.query,
+				JQCollect:          tt.collect,
+				OnError:            tt.onError,
+			}
+
+			result, err := transformer.TransformResponse(data, req, 200)
+			if tt.expectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestUnifiedTransformer_TransformResponse_Fallbacks(t *testing.T) {
+	transformer := NewUnifiedTransformer()
+	data := map[string]interface{}{
+		"name":  "John",
+		"email": nil,
+		"tags":  []interface{}{"admin"},
+	}
+	fallbacks := map[string]interface{}{
+		"email":   "unknown",
+		"country": "n/a",
+		"name":    "anonymous",
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		expected interface{}
+	}{
+		{
+			name:  "null and missing fields are filled",
+			query: "{name, email, country: .address.country}",
+			expected: map[string]interface{}{
+				"name":    "John",
+				"email":   "unknown",
+				"country": "n/a",
+			},
+		},
+		{
+			name:  "fields absent from the result are added",
+			query: "{name}",
+			expected: map[string]interface{}{
+				"name":    "John",
+				"email":   "unknown",
+				"country": "n/a",
+			},
+		},
+		{
+			name:     "non-object results are unchanged",
+			query:    ".tags",
+			expected: []interface{}{"admin"},
+		},
+		{
+			name:     "null result is unchanged",
+			query:    ".missing",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &models.ProxyRequest{
+				Method:             "GET",
+				TransformationMode: models.TransformationModeJQ,
+				JQQuery:            tt.query,
+				Fallbacks:          fallbacks,
+			}
+
+			result, err := transformer.TransformResponse(data, req, 200)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+
+	// Fallbacks apply to the final stage of a pipeline
+	req := &models.ProxyRequest{
+		Method:             "GET",
+		TransformationMode: models.TransformationModeJQ,
+		Pipeline: []models.TransformationStage{
+			{Query: "{user: .}"},
+			{Query: "{name: .user.name, email: .user.email}"},
+		},
+		Fallbacks: map[string]interface{}{"email": "unknown"},
+	}
+	result, err := transformer.TransformResponse(data, req, 200)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"name": "John", "email": "unknown"}, result)
+}
+
 func TestUnifiedTransformer_ValidateTransformation_JQ(t *testing.T) {
 	transformer := NewUnifiedTransformer()
 
@@ -98,6 +279,49 @@ func TestUnifiedTransformer_ValidateTransformation_JQ(t *testing.T) {
 			expectError: true,
 			errorMsg:    "invalid jq query",
 		},
+		{
+			name: "invalid error query",
+			req: &models.ProxyRequest{
+				TransformationMode: models.TransformationModeJQ,
+				JQQuery:            ".data",
+				ErrorJQQuery:       ".error | map(",
+			},
+			expectError: true,
+			errorMsg:    "error query: invalid jq query",
+		},
+		{
+			name: "invalid request query",
+			req: &models.ProxyRequest{
+				TransformationMode: models.TransformationModeJQ,
+				JQQuery:            ".data",
+				RequestJQQuery:     "{name: ",
+			},
+			expectError: true,
+			errorMsg:    "request query: invalid jq query",
+		},
+		{
+			name: "invalid pipeline stage",
+			req: &models.ProxyRequest{
+				TransformationMode: models.TransformationModeJQ,
+				Pipeline: []models.TransformationStage{
+					{Query: ".data"},
+					{Query: "map("},
+				},
+			},
+			expectError: true,
+			errorMsg:    "pipeline stage 2: invalid jq query",
+		},
+		{
+			name: "unsupported pipeline stage mode",
+			req: &models.ProxyRequest{
+				TransformationMode: models.TransformationModeJQ,
+				Pipeline: []models.TransformationStage{
+					{Mode: "jsonpath", Query: "$.data"},
+				},
+			},
+			expectError: true,
+			errorMsg:    "pipeline stage 1: unsupported transformation mode: jsonpath",
+		},
 	}
 
 	for _, tt := range tests {
@@ -133,3 +357,93 @@ func TestUnifiedTransformer_GetTransformers(t *testing.T) {
 	assert.NotNil(t, jqTransformer)
 	assert.IsType(t, &JQTransformer{}, jqTransformer)
 }
+
+func TestUnifiedTransformer_TransformResponse_Collect(t *testing.T) {
+	transformer := NewUnifiedTransformer()
+	data := map[string]interface{}{
+		"none": []interface{}{},
+		"one":  []interface{}{"a"},
+		"many": []interface{}{"a", "b"},
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		collect  bool
+		expected interface{}
+	}{
+		{name: "zero results", query: ".none[]", collect: false, expected: nil},
+		{name: "one result", query: ".one[]", collect: false, expected: "a"},
+		{name: "many results", query: ".many[]", collect: false, expected: []interface{}{"a", "b"}},
+		{name: "zero results collected", query: ".none[]", collect: true, expected: []interface{}{}},
+		{name: "one result collected", query: ".one[]", collect: true, expected: []interface{}{"a"}},
+		{name: "many results collected", query: ".many[]", collect: true, expected: []interface{}{"a", "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &models.ProxyRequest{
+				TransformationMode: models.TransformationModeJQ,
+				JQQuery:            tt.query,
+				JQCollect:          tt.collect,
+			}
+
+			result, err := transformer.TransformResponse(data, req, 200)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestUnifiedTransformer_TransformResponse_CollectPipeline(t *testing.T) {
+	transformer := NewUnifiedTransformer()
+	data := map[string]interface{}{"items": []interface{}{"a", "b"}}
+
+	// Intermediate stages are unaffected; only the final stage is collected
+	req := &models.ProxyRequest{
+		TransformationMode: models.TransformationModeJQ,
+		Pipeline: []models.TransformationStage{
+			{Query: ".items[]"},
+			{Query: ".[0]"},
+		},
+		JQCollect: true,
+	}
+
+	result, err := transformer.TransformResponse(data, req, 200)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"a"}, result)
+}
+
+func TestUnifiedTransformer_ResponseStatus(t *testing.T) {
+	transformer := NewUnifiedTransformer()
+
+	tests := []struct {
+		name       string
+		data       interface{}
+		query      string
+		expectOK   bool
+		expectCode int
+	}{
+		{name: "no status query", data: nil, query: "", expectOK: false},
+		{name: "404 on empty results", data: []interface{}{}, query: "if length == 0 then 404 else 200 end", expectOK: true, expectCode: 404},
+		{name: "200 on results", data: []interface{}{"a"}, query: "if length == 0 then 404 else 200 end", expectOK: true, expectCode: 200},
+		{name: "float status", data: map[string]interface{}{"code": 201.0}, query: ".code", expectOK: true, expectCode: 201},
+		{name: "non-integer status", data: nil, query: "404.5", expectOK: false},
+		{name: "string status", data: nil, query: `"404"`, expectOK: false},
+		{name: "out of range status", data: nil, query: "600", expectOK: false},
+		{name: "multiple values", data: nil, query: "404, 500", expectOK: false},
+		{name: "query error", data: nil, query: `error("boom")`, expectOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &models.ProxyRequest{StatusJQQuery: tt.query}
+
+			code, ok := transformer.ResponseStatus(tt.data, req)
+			assert.Equal(t, tt.expectOK, ok)
+			if tt.expectOK {
+				assert.Equal(t, tt.expectCode, code)
+			}
+		})
+	}
+}
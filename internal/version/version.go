@@ -0,0 +1,32 @@
+// Package version exposes build information injected at link time.
+package version
+
+// Build information, populated via -ldflags, e.g.
+//
+//	go build -ldflags "-X jq-proxy-service/internal/version.Version=v1.2.3"
+var (
+	Version   = "dev"
+	GitCommit = "unknown"
+	BuildDate = "unknown"
+)
+
+// Info represents the build information of the running binary
+type Info struct {
+	Version   string `json:"version"`
+	GitCommit string `json:"git_commit"`
+	BuildDate string `json:"build_date"`
+}
+
+// UserAgent returns the User-Agent sent to upstreams by default
+func UserAgent() string {
+	return "jq-proxy-service/" + Version
+}
+
+// Get returns the build information of the running binary
+func Get() Info {
+	return Info{
+		Version:   Version,
+		GitCommit: GitCommit,
+		BuildDate: BuildDate,
+	}
+}
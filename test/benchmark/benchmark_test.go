@@ -323,6 +323,75 @@ func BenchmarkHighConcurrency(b *testing.B) {
 	}
 }
 
+// BenchmarkTransformCache compares a heavy query over an unchanged upstream
+// response with and without the transformation cache
+func BenchmarkTransformCache(b *testing.B) {
+	largeData := make([]interface{}, 2000)
+	for i := range largeData {
+		largeData[i] = map[string]interface{}{
+			"id":    i,
+			"name":  fmt.Sprintf("User %d", i),
+			"group": fmt.Sprintf("group-%d", i%20),
+			"score": (i * 7919) % 1000,
+		}
+	}
+	payload, _ := json.Marshal(map[string]interface{}{"data": largeData})
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(payload)
+	}))
+	defer mockServer.Close()
+
+	configData := fmt.Sprintf(`{
+		"server": {"port": 8080, "read_timeout": 30, "write_timeout": 30},
+		"endpoints": {
+			"test-api": {"name": "test-api", "target": "%s"}
+		}
+	}`, mockServer.URL)
+
+	requestBody := map[string]interface{}{
+		"method":              "GET",
+		"body":                nil,
+		"transformation_mode": "jq",
+		"jq_query":            "[.data | group_by(.group)[] | {group: .[0].group, top: (sort_by(-.score) | .[:5] | map(.name)), total: (map(.score) | add)}]",
+	}
+	body, _ := json.Marshal(requestBody)
+
+	for _, tc := range []struct {
+		name    string
+		options []proxy.Option
+	}{
+		{name: "uncached"},
+		{name: "cached", options: []proxy.Option{proxy.WithTransformCacheSize(100)}},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			configProvider := &mockConfigProvider{configData: configData}
+			httpClient := client.NewClient(30 * time.Second)
+			transformer := transform.NewUnifiedTransformer()
+			logger, _ := logging.NewLogger("error")
+
+			proxyService := proxy.NewService(configProvider, httpClient, transformer, logger, tc.options...)
+			proxyHandler := proxy.NewHandler(proxyService, logger).SetupRoutes()
+
+			b.ResetTimer()
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				req := httptest.NewRequest("POST", "/proxy/test-api/users", bytes.NewReader(body))
+				req.Header.Set("Content-Type", "application/json")
+
+				rr := httptest.NewRecorder()
+				proxyHandler.ServeHTTP(rr, req)
+
+				if rr.Code != http.StatusOK {
+					b.Fatalf("Expected status 200, got %d", rr.Code)
+				}
+			}
+		})
+	}
+}
+
 // mockConfigProvider for benchmarks
 type mockConfigProvider struct {
 	configData string